@@ -38,10 +38,16 @@ type Config struct {
 
 	// Server settings
 	DefaultMaxTokens int  `json:"default_max_tokens"` // Default max_tokens for anthropic API requests
+	MaxChoices       int  `json:"max_choices"`        // Maximum n for providers where each choice is a separate request
+	HeartbeatSeconds int  `json:"heartbeat_seconds"`  // Seconds of upstream silence before streams get a keepalive (negative disables)
+	BatchConcurrency int  `json:"batch_concurrency"`  // Number of emulated batch requests executed in parallel
 	Verbose          bool `json:"verbose"`            // Verbose mode for detailed logging
 	Debug            bool `json:"debug"`              // Debug mode for Gin debug level logging
 	OpenBrowser      bool `yaml:"-" json:"-"`         // Auto-open browser in web UI mode (default: true)
 
+	// Passthrough settings
+	EnablePassthrough bool `json:"enable_passthrough"` // Expose /passthrough/:provider_uuid/*path for raw provider access (default false)
+
 	// Error log settings
 	ErrorLogFilterExpression string `json:"error_log_filter_expression"` // Expression for filtering error log entries (default: "StatusCode >= 400 && Path matches '^/api/'")
 
@@ -147,6 +153,18 @@ func NewConfigWithDir(configDir string) (*Config, error) {
 		cfg.DefaultMaxTokens = constant.DefaultMaxTokens
 		updated = true
 	}
+	if cfg.MaxChoices == 0 {
+		cfg.MaxChoices = constant.DefaultMaxChoices
+		updated = true
+	}
+	if cfg.BatchConcurrency == 0 {
+		cfg.BatchConcurrency = constant.DefaultBatchConcurrency
+		updated = true
+	}
+	if cfg.HeartbeatSeconds == 0 {
+		cfg.HeartbeatSeconds = constant.DefaultHeartbeatSeconds
+		updated = true
+	}
 	if cfg.ErrorLogFilterExpression == "" {
 		cfg.ErrorLogFilterExpression = "StatusCode >= 400 && Path matches '^/api/'"
 		updated = true
@@ -787,6 +805,36 @@ func (c *Config) SetDefaultMaxTokens(maxTokens int) error {
 	return c.save()
 }
 
+// GetMaxChoices returns the maximum n for providers where each choice is a separate request
+func (c *Config) GetMaxChoices() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.MaxChoices
+}
+
+// GetBatchConcurrency returns the number of emulated batch requests executed in parallel
+func (c *Config) GetBatchConcurrency() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.BatchConcurrency
+}
+
+// GetHeartbeatSeconds returns how long a stream upstream may be silent before clients get a keepalive
+func (c *Config) GetHeartbeatSeconds() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.HeartbeatSeconds
+}
+
+// SetHeartbeatSeconds updates how long a stream upstream may be silent before clients get a
+// keepalive; a negative value disables keepalives
+func (c *Config) SetHeartbeatSeconds(seconds int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.HeartbeatSeconds = seconds
+	return c.save()
+}
+
 // GetVerbose returns the verbose setting
 func (c *Config) GetVerbose() bool {
 	c.mu.RLock()
@@ -832,6 +880,21 @@ func (c *Config) SetOpenBrowser(openBrowser bool) error {
 	return nil
 }
 
+// GetEnablePassthrough returns whether the raw passthrough proxy is enabled
+func (c *Config) GetEnablePassthrough() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.EnablePassthrough
+}
+
+// SetEnablePassthrough enables or disables the raw passthrough proxy
+func (c *Config) SetEnablePassthrough(enabled bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.EnablePassthrough = enabled
+	return c.save()
+}
+
 // GetErrorLogFilterExpression returns the error log filter expression
 func (c *Config) GetErrorLogFilterExpression() string {
 	c.mu.RLock()
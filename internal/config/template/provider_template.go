@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -45,6 +46,7 @@ type ProviderTemplate struct {
 	Models                 []string          `json:"models"`                 // List of model IDs
 	ModelLimits            map[string]int    `json:"model_limits,omitempty"` // Model name -> max_tokens mapping
 	SupportsModelsEndpoint bool              `json:"supports_models_endpoint"`
+	Dialect                string            `json:"dialect,omitempty"` // OpenAI-compatible dialect, see adaptor.Dialect
 	Tags                   []string          `json:"tags,omitempty"`
 	Metadata               map[string]string `json:"metadata,omitempty"`
 }
@@ -424,6 +426,31 @@ func (tm *TemplateManager) GetModelsForProvider(provider *typ.Provider) ([]strin
 	return nil, TemplateSourceLocal, fmt.Errorf("no models found for provider '%s'", provider.Name)
 }
 
+// GetDialectForProvider returns the dialect configured on the provider, falling back to the
+// template with the provider's name and then to the template sharing its OpenAI base URL
+func (tm *TemplateManager) GetDialectForProvider(provider *typ.Provider) string {
+	if provider.Dialect != "" || tm == nil {
+		return provider.Dialect
+	}
+
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	if tmpl := tm.templates[provider.Name]; tmpl != nil && tmpl.Dialect != "" {
+		return tmpl.Dialect
+	}
+	apiBase := strings.TrimRight(provider.APIBase, "/")
+	if apiBase == "" {
+		return ""
+	}
+	for _, tmpl := range tm.templates {
+		if tmpl.Dialect != "" && strings.TrimRight(tmpl.BaseURLOpenAI, "/") == apiBase {
+			return tmpl.Dialect
+		}
+	}
+	return ""
+}
+
 // GetMaxTokensForModel returns the maximum allowed tokens for a specific model
 // using the provider templates. If templates are not available, falls back to
 // the global default.
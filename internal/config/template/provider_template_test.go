@@ -386,3 +386,46 @@ func TestTemplateManagerHTTPTimeout(t *testing.T) {
 		t.Error("Expected positive timeout, got", tm.httpClient.Timeout)
 	}
 }
+
+// TestTemplateManagerGetDialectForProvider tests the dialect fallback from provider to template
+func TestTemplateManagerGetDialectForProvider(t *testing.T) {
+	tm := NewTemplateManager("")
+	if err := tm.Initialize(); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		provider *typ.Provider
+		expected string
+	}{
+		{
+			name:     "Configured dialect wins",
+			provider: &typ.Provider{Name: "deepseek", Dialect: "openai"},
+			expected: "openai",
+		},
+		{
+			name:     "Template by provider name",
+			provider: &typ.Provider{Name: "deepseek"},
+			expected: "deepseek",
+		},
+		{
+			name:     "Template by API base",
+			provider: &typ.Provider{Name: "my-mistral", APIBase: "https://api.mistral.ai/v1/"},
+			expected: "mistral",
+		},
+		{
+			name:     "Unknown provider",
+			provider: &typ.Provider{Name: "local", APIBase: "http://localhost:11434/v1"},
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tm.GetDialectForProvider(tt.provider); got != tt.expected {
+				t.Errorf("expected dialect %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -24,6 +24,15 @@ const (
 	// DefaultMaxTokens is the default max_tokens value for API requests
 	DefaultMaxTokens = 8192
 
+	// DefaultMaxChoices is the default limit on n for providers that need one request per choice
+	DefaultMaxChoices = 8
+
+	// DefaultBatchConcurrency is the default number of batch requests executed in parallel
+	DefaultBatchConcurrency = 4
+
+	// DefaultHeartbeatSeconds is how long a stream upstream may be silent before clients get a keepalive
+	DefaultHeartbeatSeconds = 15
+
 	// Template cache constants
 
 )
@@ -32,6 +41,8 @@ const StateDirName = "state"
 
 const StatsDBFileName = "stats.db" // SQLite database file
 
+const BatchDBFileName = "batches.db" // SQLite database file for locally emulated batch jobs
+
 // Load balancing threshold defaults
 const DefaultRequestThreshold = int64(10)  // Default request threshold for round-robin and hybrid tactics
 const DefaultTokenThreshold = int64(10000) // Default token threshold for token-based and hybrid tactics
@@ -0,0 +1,481 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"tingly-box/internal/constant"
+)
+
+// Batch API formats
+const (
+	BatchFormatOpenAI    = "openai"
+	BatchFormatAnthropic = "anthropic"
+)
+
+// Batch statuses, following the OpenAI batch lifecycle
+const (
+	BatchStatusInProgress = "in_progress"
+	BatchStatusFinalizing = "finalizing"
+	BatchStatusCompleted  = "completed"
+	BatchStatusFailed     = "failed"
+	BatchStatusCancelling = "cancelling"
+	BatchStatusCancelled  = "cancelled"
+	BatchStatusExpired    = "expired"
+)
+
+// Batch request statuses, following the Anthropic result types
+const (
+	BatchRequestPending   = "pending"
+	BatchRequestSucceeded = "succeeded"
+	BatchRequestErrored   = "errored"
+	BatchRequestCanceled  = "canceled"
+	BatchRequestExpired   = "expired"
+)
+
+// BatchFileRecord is the GORM model for an uploaded (or generated) file
+type BatchFileRecord struct {
+	ID        string    `gorm:"primaryKey;column:id"`
+	Purpose   string    `gorm:"column:purpose;index"`
+	Filename  string    `gorm:"column:filename"`
+	Bytes     int64     `gorm:"column:bytes"`
+	Content   []byte    `gorm:"column:content"`
+	CreatedAt time.Time `gorm:"column:created_at"`
+}
+
+// TableName specifies the table name for GORM
+func (BatchFileRecord) TableName() string {
+	return "batch_files"
+}
+
+// BatchRecord is the GORM model for a batch job
+type BatchRecord struct {
+	ID               string     `gorm:"primaryKey;column:id"`
+	Format           string     `gorm:"column:format;index"`
+	Endpoint         string     `gorm:"column:endpoint"`
+	Status           string     `gorm:"column:status;index"`
+	InputFileID      string     `gorm:"column:input_file_id"`
+	OutputFileID     string     `gorm:"column:output_file_id"`
+	ErrorFileID      string     `gorm:"column:error_file_id"`
+	CompletionWindow string     `gorm:"column:completion_window"`
+	Metadata         string     `gorm:"column:metadata"` // JSON object
+	TotalCount       int        `gorm:"column:total_count"`
+	CompletedCount   int        `gorm:"column:completed_count"`
+	FailedCount      int        `gorm:"column:failed_count"`
+	CanceledCount    int        `gorm:"column:canceled_count"`
+	ExpiredCount     int        `gorm:"column:expired_count"`
+	CreatedAt        time.Time  `gorm:"column:created_at"`
+	InProgressAt     *time.Time `gorm:"column:in_progress_at"`
+	FinalizingAt     *time.Time `gorm:"column:finalizing_at"`
+	CompletedAt      *time.Time `gorm:"column:completed_at"`
+	CancellingAt     *time.Time `gorm:"column:cancelling_at"`
+	CancelledAt      *time.Time `gorm:"column:cancelled_at"`
+	ExpiredAt        *time.Time `gorm:"column:expired_at"`
+	ExpiresAt        *time.Time `gorm:"column:expires_at"`
+}
+
+// TableName specifies the table name for GORM
+func (BatchRecord) TableName() string {
+	return "batches"
+}
+
+// IsFinished reports whether the batch reached a terminal status
+func (b *BatchRecord) IsFinished() bool {
+	switch b.Status {
+	case BatchStatusCompleted, BatchStatusFailed, BatchStatusCancelled, BatchStatusExpired:
+		return true
+	}
+	return false
+}
+
+// BatchRequestRecord is the GORM model for a single request inside a batch
+type BatchRequestRecord struct {
+	BatchID     string     `gorm:"primaryKey;column:batch_id"`
+	Index       int        `gorm:"primaryKey;column:idx"`
+	CustomID    string     `gorm:"column:custom_id"`
+	Body        []byte     `gorm:"column:body"`
+	Status      string     `gorm:"column:status;index"`
+	StatusCode  int        `gorm:"column:status_code"`
+	Response    []byte     `gorm:"column:response"`
+	CompletedAt *time.Time `gorm:"column:completed_at"`
+}
+
+// TableName specifies the table name for GORM
+func (BatchRequestRecord) TableName() string {
+	return "batch_requests"
+}
+
+// ErrBatchNotFound is returned when a batch or file does not exist
+var ErrBatchNotFound = errors.New("not found")
+
+// BatchStore persists locally emulated batch jobs, their requests and files in SQLite using GORM.
+type BatchStore struct {
+	db     *gorm.DB
+	dbPath string
+	mu     sync.Mutex
+}
+
+// NewBatchStore creates or loads a batch store next to the stats database.
+func NewBatchStore(baseDir string) (*BatchStore, error) {
+	if err := os.MkdirAll(baseDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create batch store directory: %w", err)
+	}
+
+	dbPath := filepath.Join(baseDir, constant.BatchDBFileName)
+	log.Printf("Opening batch database: %s", dbPath)
+	dsn := dbPath + "?_busy_timeout=5000&_journal_mode=WAL&_foreign_keys=1"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open batch database: %w", err)
+	}
+
+	if err := db.AutoMigrate(&BatchFileRecord{}, &BatchRecord{}, &BatchRequestRecord{}); err != nil {
+		return nil, fmt.Errorf("failed to migrate batch database: %w", err)
+	}
+
+	return &BatchStore{
+		db:     db,
+		dbPath: dbPath,
+	}, nil
+}
+
+// CreateFile stores a file.
+func (bs *BatchStore) CreateFile(file *BatchFileRecord) error {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	if file.CreatedAt.IsZero() {
+		file.CreatedAt = time.Now()
+	}
+	file.Bytes = int64(len(file.Content))
+	return bs.db.Create(file).Error
+}
+
+// GetFile returns a file including its content.
+func (bs *BatchStore) GetFile(id string) (*BatchFileRecord, error) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	var file BatchFileRecord
+	err := bs.db.Where("id = ?", id).First(&file).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrBatchNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// ListFiles returns file metadata (without content), newest first, optionally filtered by purpose.
+func (bs *BatchStore) ListFiles(purpose string) ([]BatchFileRecord, error) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	query := bs.db.Omit("content").Order("created_at desc")
+	if purpose != "" {
+		query = query.Where("purpose = ?", purpose)
+	}
+	var files []BatchFileRecord
+	if err := query.Find(&files).Error; err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// DeleteFile removes a file.
+func (bs *BatchStore) DeleteFile(id string) error {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	result := bs.db.Where("id = ?", id).Delete(&BatchFileRecord{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrBatchNotFound
+	}
+	return nil
+}
+
+// CreateBatch stores a batch together with its requests in a single transaction.
+func (bs *BatchStore) CreateBatch(batch *BatchRecord, requests []BatchRequestRecord) error {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	now := time.Now()
+	if batch.CreatedAt.IsZero() {
+		batch.CreatedAt = now
+	}
+	if batch.Status == "" {
+		batch.Status = BatchStatusInProgress
+		batch.InProgressAt = &now
+	}
+	batch.TotalCount = len(requests)
+
+	return bs.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(batch).Error; err != nil {
+			return err
+		}
+		for i := range requests {
+			requests[i].BatchID = batch.ID
+			requests[i].Index = i
+			if requests[i].Status == "" {
+				requests[i].Status = BatchRequestPending
+			}
+		}
+		if len(requests) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(requests, 100).Error
+	})
+}
+
+// GetBatch returns a batch.
+func (bs *BatchStore) GetBatch(id string) (*BatchRecord, error) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	return bs.getBatch(bs.db, id)
+}
+
+func (bs *BatchStore) getBatch(tx *gorm.DB, id string) (*BatchRecord, error) {
+	var batch BatchRecord
+	err := tx.Where("id = ?", id).First(&batch).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrBatchNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// ListBatches returns batches of the given format, newest first.
+func (bs *BatchStore) ListBatches(format string, limit int) ([]BatchRecord, error) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	query := bs.db.Where("format = ?", format).Order("created_at desc")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	var batches []BatchRecord
+	if err := query.Find(&batches).Error; err != nil {
+		return nil, err
+	}
+	return batches, nil
+}
+
+// ListActiveBatches returns batches that still need work from the worker, oldest first.
+func (bs *BatchStore) ListActiveBatches() ([]BatchRecord, error) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	var batches []BatchRecord
+	err := bs.db.Where("status IN ?", []string{BatchStatusInProgress, BatchStatusCancelling}).
+		Order("created_at asc").
+		Find(&batches).Error
+	if err != nil {
+		return nil, err
+	}
+	return batches, nil
+}
+
+// PendingRequests returns up to limit requests of a batch that have not been executed yet.
+func (bs *BatchStore) PendingRequests(batchID string, limit int) ([]BatchRequestRecord, error) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	var requests []BatchRequestRecord
+	err := bs.db.Where("batch_id = ? AND status = ?", batchID, BatchRequestPending).
+		Order("idx asc").
+		Limit(limit).
+		Find(&requests).Error
+	if err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
+
+// ListRequests returns all requests of a batch in submission order.
+func (bs *BatchStore) ListRequests(batchID string) ([]BatchRequestRecord, error) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	var requests []BatchRequestRecord
+	if err := bs.db.Where("batch_id = ?", batchID).Order("idx asc").Find(&requests).Error; err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
+
+// CompleteRequest stores the result of a request and updates the batch counters.
+func (bs *BatchStore) CompleteRequest(batchID string, index int, status string, statusCode int, response []byte) error {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	now := time.Now()
+	return bs.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&BatchRequestRecord{}).
+			Where("batch_id = ? AND idx = ? AND status = ?", batchID, index, BatchRequestPending).
+			Updates(map[string]interface{}{
+				"status":       status,
+				"status_code":  statusCode,
+				"response":     response,
+				"completed_at": now,
+			})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		counter := "completed_count"
+		if status == BatchRequestErrored {
+			counter = "failed_count"
+		}
+		return tx.Model(&BatchRecord{}).Where("id = ?", batchID).
+			UpdateColumn(counter, gorm.Expr(counter+" + 1")).Error
+	})
+}
+
+// CancelBatch moves an unfinished batch to the cancelling status; the worker finishes the cancellation.
+func (bs *BatchStore) CancelBatch(id string) (*BatchRecord, error) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	var batch *BatchRecord
+	err := bs.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		batch, err = bs.getBatch(tx, id)
+		if err != nil {
+			return err
+		}
+		if batch.IsFinished() || batch.Status == BatchStatusCancelling {
+			return nil
+		}
+		now := time.Now()
+		batch.Status = BatchStatusCancelling
+		batch.CancellingAt = &now
+		return tx.Save(batch).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
+
+// CancelPendingRequests marks all remaining requests of a batch as canceled.
+func (bs *BatchStore) CancelPendingRequests(batchID string) error {
+	return bs.closePendingRequests(batchID, BatchRequestCanceled, "canceled_count")
+}
+
+// ExpirePendingRequests marks all remaining requests of a batch as expired.
+func (bs *BatchStore) ExpirePendingRequests(batchID string) error {
+	return bs.closePendingRequests(batchID, BatchRequestExpired, "expired_count")
+}
+
+func (bs *BatchStore) closePendingRequests(batchID, status, counter string) error {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	return bs.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&BatchRequestRecord{}).
+			Where("batch_id = ? AND status = ?", batchID, BatchRequestPending).
+			Updates(map[string]interface{}{
+				"status":       status,
+				"completed_at": time.Now(),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		return tx.Model(&BatchRecord{}).Where("id = ?", batchID).
+			UpdateColumn(counter, gorm.Expr(counter+" + ?", result.RowsAffected)).Error
+	})
+}
+
+// unfinishedBatchStatuses are the statuses the worker may still move a batch out of
+var unfinishedBatchStatuses = []string{BatchStatusInProgress, BatchStatusFinalizing, BatchStatusCancelling}
+
+// FinalizeBatch records that the worker started writing the results of an unfinished batch.
+func (bs *BatchStore) FinalizeBatch(id string) error {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	return bs.db.Model(&BatchRecord{}).
+		Where("id = ? AND status IN ?", id, unfinishedBatchStatuses).
+		Update("finalizing_at", time.Now()).Error
+}
+
+// FailBatch moves an unfinished batch to the failed status. Only the status and its timestamp
+// are written, so fields stored meanwhile, such as a cancellation, are kept.
+func (bs *BatchStore) FailBatch(id string) error {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	return bs.db.Model(&BatchRecord{}).
+		Where("id = ? AND status IN ?", id, unfinishedBatchStatuses).
+		Updates(map[string]interface{}{
+			"status":       BatchStatusFailed,
+			"completed_at": time.Now(),
+		}).Error
+}
+
+// FinishBatch moves an unfinished batch to its terminal status: cancelled when a cancellation was
+// requested, expired when requests expired and completed otherwise. The status is decided and
+// written in one transaction, so a cancellation is never overwritten. Output and error file IDs
+// are stored when set.
+func (bs *BatchStore) FinishBatch(id, outputFileID, errorFileID string) (*BatchRecord, error) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	var batch *BatchRecord
+	err := bs.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		batch, err = bs.getBatch(tx, id)
+		if err != nil || batch.IsFinished() {
+			return err
+		}
+
+		now := time.Now()
+		updates := make(map[string]interface{})
+		switch {
+		case batch.Status == BatchStatusCancelling:
+			updates["status"], updates["cancelled_at"] = BatchStatusCancelled, now
+		case batch.ExpiredCount > 0:
+			updates["status"], updates["expired_at"] = BatchStatusExpired, now
+		default:
+			updates["status"], updates["completed_at"] = BatchStatusCompleted, now
+		}
+		if outputFileID != "" {
+			updates["output_file_id"] = outputFileID
+		}
+		if errorFileID != "" {
+			updates["error_file_id"] = errorFileID
+		}
+
+		result := tx.Model(&BatchRecord{}).Where("id = ? AND status = ?", id, batch.Status).Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("batch %s changed status while finishing", id)
+		}
+		batch, err = bs.getBatch(tx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
@@ -34,6 +34,13 @@ type ServiceStatsRecord struct {
 	WindowInputTokens    int64     `gorm:"column:window_input_tokens"`
 	WindowOutputTokens   int64     `gorm:"column:window_output_tokens"`
 	TimeWindow           int       `gorm:"column:time_window"`
+	ImageCount           int64     `gorm:"column:image_count"`
+	AudioSeconds         float64   `gorm:"column:audio_seconds"`
+	AudioCharacters      int64     `gorm:"column:audio_characters"`
+	RealtimeSessions     int64     `gorm:"column:realtime_sessions"`
+	RealtimeSeconds      float64   `gorm:"column:realtime_seconds"`
+	CacheReadTokens      int64     `gorm:"column:cache_read_tokens"`
+	CacheCreationTokens  int64     `gorm:"column:cache_creation_tokens"`
 }
 
 // TableName specifies the table name for GORM
@@ -149,6 +156,13 @@ func (ss *StatsStore) UpdateFromService(service *loadbalance.Service) error {
 		WindowInputTokens:    stat.WindowInputTokens,
 		WindowOutputTokens:   stat.WindowOutputTokens,
 		TimeWindow:           stat.TimeWindow,
+		ImageCount:           stat.ImageCount,
+		AudioSeconds:         stat.AudioSeconds,
+		AudioCharacters:      stat.AudioCharacters,
+		RealtimeSessions:     stat.RealtimeSessions,
+		RealtimeSeconds:      stat.RealtimeSeconds,
+		CacheReadTokens:      stat.CacheReadTokens,
+		CacheCreationTokens:  stat.CacheCreationTokens,
 	}
 
 	// Normalize time window if needed
@@ -266,6 +280,13 @@ func (ss *StatsStore) HydrateRules(rules []typ.Rule) error {
 					WindowInputTokens:    statCopy.WindowInputTokens,
 					WindowOutputTokens:   statCopy.WindowOutputTokens,
 					TimeWindow:           statCopy.TimeWindow,
+					ImageCount:           statCopy.ImageCount,
+					AudioSeconds:         statCopy.AudioSeconds,
+					AudioCharacters:      statCopy.AudioCharacters,
+					RealtimeSessions:     statCopy.RealtimeSessions,
+					RealtimeSeconds:      statCopy.RealtimeSeconds,
+					CacheReadTokens:      statCopy.CacheReadTokens,
+					CacheCreationTokens:  statCopy.CacheCreationTokens,
 				}
 				if record.TimeWindow == 0 {
 					if service.TimeWindow > 0 {
@@ -312,5 +333,12 @@ func (r *ServiceStatsRecord) toServiceStats() loadbalance.ServiceStats {
 		WindowInputTokens:    r.WindowInputTokens,
 		WindowOutputTokens:   r.WindowOutputTokens,
 		TimeWindow:           r.TimeWindow,
+		ImageCount:           r.ImageCount,
+		AudioSeconds:         r.AudioSeconds,
+		AudioCharacters:      r.AudioCharacters,
+		RealtimeSessions:     r.RealtimeSessions,
+		RealtimeSeconds:      r.RealtimeSeconds,
+		CacheReadTokens:      r.CacheReadTokens,
+		CacheCreationTokens:  r.CacheCreationTokens,
 	}
 }
@@ -6,6 +6,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"sort"
 	"strings"
 	"time"
 
@@ -16,6 +17,16 @@ import (
 
 // GetProviderModelsFromAPI fetches models from provider API via real HTTP requests
 func GetProviderModelsFromAPI(provider *typ.Provider) ([]string, error) {
+	if provider.IsAzure() {
+		return getAzureModels(provider)
+	}
+	if provider.IsBedrock() {
+		return getBedrockModels(provider)
+	}
+	if provider.IsVertex() {
+		return getVertexModels(provider)
+	}
+
 	// Construct the models endpoint URL
 	// For Anthropic-style providers, ensure they have a version suffix
 	apiBase := strings.TrimSuffix(provider.APIBase, "/")
@@ -122,3 +133,166 @@ func GetProviderModelsFromAPI(provider *typ.Provider) ([]string, error) {
 
 	return models, nil
 }
+
+// getAzureModels lists the models an Azure OpenAI resource can serve. Configured deployment
+// mappings win; otherwise the chat models of the resource are listed, which are served by
+// deployments named after them. Data-plane api-versions list models, not deployments.
+func getAzureModels(provider *typ.Provider) ([]string, error) {
+	if len(provider.Deployments) > 0 {
+		models := make([]string, 0, len(provider.Deployments))
+		for model := range provider.Deployments {
+			models = append(models, model)
+		}
+		sort.Strings(models)
+		return models, nil
+	}
+
+	req, err := http.NewRequest("GET", client.AzureURL(provider.APIBase, "/models", provider.AzureAPIVersion()), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	client.SetAzureAuth(req.Header, provider.GetAccessToken())
+
+	httpClient := client.CreateHTTPClientWithProxy(provider.ProxyURL)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("provider returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var modelsResponse struct {
+		Data []struct {
+			ID           string `json:"id"`
+			Capabilities *struct {
+				ChatCompletion bool `json:"chat_completion"`
+			} `json:"capabilities"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &modelsResponse); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON response: %w", err)
+	}
+
+	var models []string
+	for _, model := range modelsResponse.Data {
+		if model.ID == "" || (model.Capabilities != nil && !model.Capabilities.ChatCompletion) {
+			continue
+		}
+		models = append(models, model.ID)
+	}
+	if len(models) == 0 {
+		return nil, fmt.Errorf("no chat models found in Azure OpenAI resource, configure deployments for the provider")
+	}
+	return models, nil
+}
+
+// getBedrockModels lists the Anthropic foundation models available in the provider's Bedrock region
+func getBedrockModels(provider *typ.Provider) ([]string, error) {
+	creds, err := client.ResolveAWSCredentials(provider.AWSAccessKeyID, provider.AWSSecretAccessKey, provider.AWSSessionToken, provider.AWSProfile)
+	if err != nil {
+		return nil, err
+	}
+
+	modelsURL := client.BedrockControlEndpoint(provider.APIBase, provider.Region) + "/foundation-models?byProvider=anthropic"
+	req, err := http.NewRequest("GET", modelsURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	client.SignBedrockRequest(req, nil, creds, provider.Region)
+
+	httpClient := client.CreateHTTPClientWithProxy(provider.ProxyURL)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("provider returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var modelsResponse struct {
+		ModelSummaries []struct {
+			ModelID string `json:"modelId"`
+		} `json:"modelSummaries"`
+	}
+	if err := json.Unmarshal(body, &modelsResponse); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON response: %w", err)
+	}
+
+	var models []string
+	for _, model := range modelsResponse.ModelSummaries {
+		if model.ModelID != "" {
+			models = append(models, model.ModelID)
+		}
+	}
+	if len(models) == 0 {
+		return nil, fmt.Errorf("no Anthropic models found in Bedrock region %s", provider.Region)
+	}
+	return models, nil
+}
+
+// getVertexModels lists the Model Garden models of the publisher matching the provider's
+// API style: Anthropic models for anthropic style, Google (Gemini) models otherwise
+func getVertexModels(provider *typ.Provider) ([]string, error) {
+	publisher := "google"
+	if provider.APIStyle == typ.APIStyleAnthropic {
+		publisher = "anthropic"
+	}
+
+	modelsURL := client.VertexEndpoint(provider.APIBase, provider.Region) + "v1beta1/publishers/" + publisher + "/models"
+	req, err := http.NewRequest("GET", modelsURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+provider.GetAccessToken())
+	if projectID := provider.VertexProjectID(); projectID != "" {
+		req.Header.Set("x-goog-user-project", projectID)
+	}
+
+	httpClient := client.CreateHTTPClientWithProxy(provider.ProxyURL)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("provider returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var modelsResponse struct {
+		PublisherModels []struct {
+			Name string `json:"name"` // publishers/<publisher>/models/<model>
+		} `json:"publisherModels"`
+	}
+	if err := json.Unmarshal(body, &modelsResponse); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON response: %w", err)
+	}
+
+	var models []string
+	for _, model := range modelsResponse.PublisherModels {
+		if i := strings.LastIndex(model.Name, "/"); i >= 0 && i < len(model.Name)-1 {
+			models = append(models, model.Name[i+1:])
+		}
+	}
+	if len(models) == 0 {
+		return nil, fmt.Errorf("no %s models found in Vertex AI", publisher)
+	}
+	return models, nil
+}
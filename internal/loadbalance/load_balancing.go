@@ -39,6 +39,30 @@ func (s *Service) RecordUsage(inputTokens, outputTokens int) {
 	s.Stats.RecordUsage(inputTokens, outputTokens)
 }
 
+// RecordImageUsage records generated images for this service
+func (s *Service) RecordImageUsage(images int) {
+	s.InitializeStats()
+	s.Stats.RecordImageUsage(images)
+}
+
+// RecordAudioUsage records transcribed audio duration and synthesized characters for this service
+func (s *Service) RecordAudioUsage(seconds float64, characters int) {
+	s.InitializeStats()
+	s.Stats.RecordAudioUsage(seconds, characters)
+}
+
+// RecordCacheUsage records prompt cache reads and writes for this service
+func (s *Service) RecordCacheUsage(readTokens, creationTokens int) {
+	s.InitializeStats()
+	s.Stats.RecordCacheUsage(readTokens, creationTokens)
+}
+
+// RecordRealtimeSession records a finished realtime session for this service
+func (s *Service) RecordRealtimeSession(seconds float64) {
+	s.InitializeStats()
+	s.Stats.RecordRealtimeSession(seconds)
+}
+
 // GetWindowStats returns current window statistics for this service
 func (s *Service) GetWindowStats() (requestCount int64, tokensConsumed int64) {
 	s.InitializeStats()
@@ -56,6 +80,13 @@ type ServiceStats struct {
 	WindowInputTokens    int64        `json:"window_input_tokens"`    // Input tokens in current window
 	WindowOutputTokens   int64        `json:"window_output_tokens"`   // Output tokens in current window
 	TimeWindow           int          `json:"time_window"`            // Copy of service's time window
+	ImageCount           int64        `json:"image_count"`            // Total images generated or edited
+	AudioSeconds         float64      `json:"audio_seconds"`          // Total seconds of audio transcribed or translated
+	AudioCharacters      int64        `json:"audio_characters"`       // Total characters synthesized to speech
+	RealtimeSessions     int64        `json:"realtime_sessions"`      // Total realtime (WebSocket) sessions
+	RealtimeSeconds      float64      `json:"realtime_seconds"`       // Total duration of realtime sessions
+	CacheReadTokens      int64        `json:"cache_read_tokens"`      // Total prompt tokens read from the prompt cache
+	CacheCreationTokens  int64        `json:"cache_creation_tokens"`  // Total prompt tokens written to the prompt cache
 	mutex                sync.RWMutex `json:"-"`                      // Thread safety
 }
 
@@ -83,6 +114,44 @@ func (ss *ServiceStats) RecordUsage(inputTokens, outputTokens int) {
 	ss.LastUsed = now
 }
 
+// RecordImageUsage adds generated images to the running total
+func (ss *ServiceStats) RecordImageUsage(images int) {
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+
+	ss.ImageCount += int64(images)
+	ss.LastUsed = time.Now()
+}
+
+// RecordAudioUsage adds audio duration and speech characters to the running totals
+func (ss *ServiceStats) RecordAudioUsage(seconds float64, characters int) {
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+
+	ss.AudioSeconds += seconds
+	ss.AudioCharacters += int64(characters)
+	ss.LastUsed = time.Now()
+}
+
+// RecordCacheUsage adds prompt cache reads and writes to the running totals
+func (ss *ServiceStats) RecordCacheUsage(readTokens, creationTokens int) {
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+
+	ss.CacheReadTokens += int64(readTokens)
+	ss.CacheCreationTokens += int64(creationTokens)
+}
+
+// RecordRealtimeSession adds a realtime session and its duration to the running totals
+func (ss *ServiceStats) RecordRealtimeSession(seconds float64) {
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+
+	ss.RealtimeSessions++
+	ss.RealtimeSeconds += seconds
+	ss.LastUsed = time.Now()
+}
+
 // GetWindowStats returns current window statistics
 func (ss *ServiceStats) GetWindowStats() (requestCount int64, tokensConsumed int64) {
 	// Check if window has expired without locking first
@@ -150,6 +219,13 @@ func (ss *ServiceStats) GetStats() ServiceStats {
 		WindowInputTokens:    ss.WindowInputTokens,
 		WindowOutputTokens:   ss.WindowOutputTokens,
 		TimeWindow:           ss.TimeWindow,
+		ImageCount:           ss.ImageCount,
+		AudioSeconds:         ss.AudioSeconds,
+		AudioCharacters:      ss.AudioCharacters,
+		RealtimeSessions:     ss.RealtimeSessions,
+		RealtimeSeconds:      ss.RealtimeSeconds,
+		CacheReadTokens:      ss.CacheReadTokens,
+		CacheCreationTokens:  ss.CacheCreationTokens,
 	}
 }
 
@@ -3,6 +3,7 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -12,11 +13,14 @@ import (
 	"github.com/anthropics/anthropic-sdk-go"
 	anthropicstream "github.com/anthropics/anthropic-sdk-go/packages/ssestream"
 	"github.com/gin-gonic/gin"
+	"github.com/openai/openai-go/v3"
 	"github.com/sirupsen/logrus"
 
 	"tingly-box/internal/loadbalance"
+	"tingly-box/internal/tokencount"
 	"tingly-box/internal/typ"
 	"tingly-box/pkg/adaptor"
+	"tingly-box/pkg/client"
 )
 
 // Use official Anthropic SDK types directly
@@ -61,12 +65,7 @@ func (s *Server) AnthropicMessages(c *gin.Context) {
 	var rawReq map[string]interface{}
 	if err := json.Unmarshal(bodyBytes, &rawReq); err != nil {
 		logrus.Debugf("Invalid JSON in request body: %v", err)
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error: ErrorDetail{
-				Message: "Invalid JSON: " + err.Error(),
-				Type:    "invalid_request_error",
-			},
-		})
+		requestError("Invalid JSON: "+err.Error()).respond(c, typ.APIStyleAnthropic)
 		return
 	}
 
@@ -82,24 +81,14 @@ func (s *Server) AnthropicMessages(c *gin.Context) {
 	if err := c.ShouldBindJSON(&req); err != nil {
 		// Log the invalid request for debugging
 		logrus.Debugf("Invalid JSON request received: %v\nBody: %s", err, string(bodyBytes))
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error: ErrorDetail{
-				Message: "Invalid request body: " + err.Error(),
-				Type:    "invalid_request_error",
-			},
-		})
+		requestError("Invalid request body: "+err.Error()).respond(c, typ.APIStyleAnthropic)
 		return
 	}
 
 	// Get model from request
 	proxyModel := string(req.Model)
 	if proxyModel == "" {
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error: ErrorDetail{
-				Message: "Model is required",
-				Type:    "invalid_request_error",
-			},
-		})
+		requestError("Model is required").respond(c, typ.APIStyleAnthropic)
 		return
 	}
 
@@ -112,34 +101,19 @@ func (s *Server) AnthropicMessages(c *gin.Context) {
 	if scenario == "" {
 		provider, selectedService, rule, err = s.DetermineProviderAndModel(proxyModel)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, ErrorResponse{
-				Error: ErrorDetail{
-					Message: err.Error(),
-					Type:    "invalid_request_error",
-				},
-			})
+			routingError(err).respond(c, typ.APIStyleAnthropic)
 			return
 		}
 	} else {
 		// Convert string to RuleScenario and validate
 		scenarioType := typ.RuleScenario(scenario)
 		if !isValidRuleScenario(scenarioType) {
-			c.JSON(http.StatusBadRequest, ErrorResponse{
-				Error: ErrorDetail{
-					Message: fmt.Sprintf("invalid scenario: %s", scenario),
-					Type:    "invalid_request_error",
-				},
-			})
+			requestError(fmt.Sprintf("invalid scenario: %s", scenario)).respond(c, typ.APIStyleAnthropic)
 			return
 		}
 		provider, selectedService, rule, err = s.DetermineProviderAndModelWithScenario(scenarioType, proxyModel)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, ErrorResponse{
-				Error: ErrorDetail{
-					Message: err.Error(),
-					Type:    "invalid_request_error",
-				},
-			})
+			routingError(err).respond(c, typ.APIStyleAnthropic)
 			return
 		}
 	}
@@ -171,6 +145,14 @@ func (s *Server) AnthropicMessages(c *gin.Context) {
 	c.Set("provider", provider.UUID)
 	c.Set("model", selectedService.Model)
 
+	// Inline remote images for providers that only accept base64 image data
+	if provider.InlineImages {
+		if err := adaptor.InlineAnthropicImages(c.Request.Context(), &req, client.CreateHTTPClientWithProxy(provider.ProxyURL)); err != nil {
+			adaptorError(http.StatusBadRequest, err.Error()).respond(c, typ.APIStyleAnthropic)
+			return
+		}
+	}
+
 	// Check provider's API style to decide which path to take
 	apiStyle := string(provider.APIStyle)
 	if apiStyle == "" {
@@ -178,31 +160,24 @@ func (s *Server) AnthropicMessages(c *gin.Context) {
 	}
 
 	if apiStyle == "anthropic" {
+		applyAutoCache(rule, &req)
+		req.Tools = adaptor.SanitizeAnthropicTools(req.Tools, s.toolSchemaFor(provider))
+
 		// Use direct Anthropic SDK call
 		if isStreaming {
 			// Handle streaming request
-			stream, err := s.forwardAnthropicStreamRequest(provider, req)
+			stream, err := s.forwardAnthropicStreamRequest(context.Background(), provider, req)
 			if err != nil {
-				c.JSON(http.StatusInternalServerError, ErrorResponse{
-					Error: ErrorDetail{
-						Message: "Failed to create streaming request: " + err.Error(),
-						Type:    "api_error",
-					},
-				})
+				upstreamError(err).respond(c, typ.APIStyleAnthropic)
 				return
 			}
 			// Handle the streaming response
 			s.handleAnthropicStreamResponse(c, stream, proxyModel)
 		} else {
 			// Handle non-streaming request
-			anthropicResp, err := s.forwardAnthropicRequest(provider, req)
+			anthropicResp, err := s.forwardAnthropicRequest(context.Background(), provider, req)
 			if err != nil {
-				c.JSON(http.StatusInternalServerError, ErrorResponse{
-					Error: ErrorDetail{
-						Message: "Failed to forward Anthropic request: " + err.Error(),
-						Type:    "api_error",
-					},
-				})
+				upstreamError(err).respond(c, typ.APIStyleAnthropic)
 				return
 			}
 			// FIXME: now we use req model as resp model
@@ -213,55 +188,47 @@ func (s *Server) AnthropicMessages(c *gin.Context) {
 	} else {
 		// Check if adaptor is enabled
 		if !s.enableAdaptor {
-			c.JSON(http.StatusUnprocessableEntity, ErrorResponse{
-				Error: ErrorDetail{
-					Message: fmt.Sprintf("Request format adaptation is disabled. Cannot send Anthropic request to OpenAI-style provider '%s'. Use --adapter flag to enable format conversion.", provider.Name),
-					Type:    "adapter_disabled",
-				},
-			})
+			gatewayErr := adaptorError(http.StatusUnprocessableEntity, fmt.Sprintf("Request format adaptation is disabled. Cannot send Anthropic request to OpenAI-style provider '%s'. Use --adapter flag to enable format conversion.", provider.Name))
+			gatewayErr.Code = "adapter_disabled"
+			gatewayErr.respond(c, typ.APIStyleAnthropic)
 			return
 		}
 
 		// Use OpenAI conversion path (default behavior)
+		openaiReq, err := adaptor.ConvertAnthropicToOpenAIRequestWithOptions(&req, s.convertOptions(provider))
+		if err != nil {
+			adaptorError(http.StatusBadRequest, err.Error()).respond(c, typ.APIStyleAnthropic)
+			return
+		}
+
 		if isStreaming {
-			// Convert Anthropic request to OpenAI format for streaming
-			openaiReq := adaptor.ConvertAnthropicToOpenAIRequest(&req)
+			// Usage is only streamed on request, and is needed for message_delta
+			openaiReq.StreamOptions.IncludeUsage = openai.Bool(true)
 
 			// Create streaming request
 			stream, err := s.forwardOpenAIStreamRequest(provider, openaiReq)
 			if err != nil {
-				c.JSON(http.StatusInternalServerError, ErrorResponse{
-					Error: ErrorDetail{
-						Message: "Failed to create streaming request: " + err.Error(),
-						Type:    "api_error",
-					},
-				})
+				upstreamError(err).respond(c, typ.APIStyleAnthropic)
 				return
 			}
 
+			// message_start comes before the upstream reports usage, so it carries an estimate
+			streamOpts := adaptor.StreamOptions{Heartbeat: s.heartbeatInterval()}
+			if count, err := tokencount.CountAnthropicMessages(actualModel, bodyBytes); err == nil {
+				streamOpts.InputTokens = int64(count)
+			}
+
 			// Handle the streaming response
-			err = adaptor.HandleOpenAIToAnthropicStreamResponse(c, stream, proxyModel)
+			err = adaptor.HandleOpenAIToAnthropicStreamResponseWithOptions(c, stream, proxyModel, streamOpts)
 			if err != nil {
-				c.JSON(http.StatusInternalServerError, ErrorResponse{
-					Error: ErrorDetail{
-						Message: err.Error(),
-						Type:    "api_error",
-						Code:    "streaming_unsupported",
-					},
-				})
+				streamError(err).respond(c, typ.APIStyleAnthropic)
 			}
 
 		} else {
 			// Handle non-streaming request
-			openaiReq := adaptor.ConvertAnthropicToOpenAIRequest(&req)
 			response, err := s.forwardOpenAIRequest(provider, openaiReq)
 			if err != nil {
-				c.JSON(http.StatusInternalServerError, ErrorResponse{
-					Error: ErrorDetail{
-						Message: "Failed to forward request: " + err.Error(),
-						Type:    "api_error",
-					},
-				})
+				upstreamError(err).respond(c, typ.APIStyleAnthropic)
 				return
 			}
 			// Convert OpenAI response back to Anthropic format
@@ -339,12 +306,7 @@ func (s *Server) AnthropicCountTokens(c *gin.Context) {
 	// Check if beta parameter is set to true
 	beta := c.Query("beta") == "true"
 	if !beta {
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error: ErrorDetail{
-				Message: "The count_tokens endpoint requires beta=true parameter",
-				Type:    "invalid_request_error",
-			},
-		})
+		requestError("The count_tokens endpoint requires beta=true parameter").respond(c, typ.APIStyleAnthropic)
 		return
 	}
 
@@ -361,12 +323,7 @@ func (s *Server) AnthropicCountTokens(c *gin.Context) {
 	var rawReq map[string]interface{}
 	if err := json.Unmarshal(bodyBytes, &rawReq); err != nil {
 		logrus.Debugf("Invalid JSON in request body: %v", err)
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error: ErrorDetail{
-				Message: "Invalid JSON: " + err.Error(),
-				Type:    "invalid_request_error",
-			},
-		})
+		requestError("Invalid JSON: "+err.Error()).respond(c, typ.APIStyleAnthropic)
 		return
 	}
 
@@ -382,36 +339,21 @@ func (s *Server) AnthropicCountTokens(c *gin.Context) {
 	if err := c.ShouldBindJSON(&req); err != nil {
 		// Log the invalid request for debugging
 		logrus.Debugf("Invalid JSON request received: %v\nBody: %s", err, string(bodyBytes))
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error: ErrorDetail{
-				Message: "Invalid request body: " + err.Error(),
-				Type:    "invalid_request_error",
-			},
-		})
+		requestError("Invalid request body: "+err.Error()).respond(c, typ.APIStyleAnthropic)
 		return
 	}
 
 	// Get model from request
 	model := string(req.Model)
 	if model == "" {
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error: ErrorDetail{
-				Message: "Model is required",
-				Type:    "invalid_request_error",
-			},
-		})
+		requestError("Model is required").respond(c, typ.APIStyleAnthropic)
 		return
 	}
 
 	// Determine provider and model based on request
 	provider, selectedService, _, err := s.DetermineProviderAndModel(model)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error: ErrorDetail{
-				Message: err.Error(),
-				Type:    "invalid_request_error",
-			},
-		})
+		routingError(err).respond(c, typ.APIStyleAnthropic)
 		return
 	}
 
@@ -440,25 +382,15 @@ func (s *Server) AnthropicCountTokens(c *gin.Context) {
 		defer cancel()
 		message, err := client.Messages.CountTokens(ctx, req)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, ErrorResponse{
-				Error: ErrorDetail{
-					Message: "Invalid request body: " + err.Error(),
-					Type:    "invalid_request_error",
-				},
-			})
+			requestError("Invalid request body: "+err.Error()).respond(c, typ.APIStyleAnthropic)
 			return
 		}
 
 		c.JSON(http.StatusOK, message)
 	} else {
-		count, err := countTokensWithTiktoken(string(req.Model), req.Messages, req.System.OfTextBlockArray)
+		count, err := tokencount.CountAnthropicMessages(actualModel, bodyBytes)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, ErrorResponse{
-				Error: ErrorDetail{
-					Message: "Invalid request body: " + err.Error(),
-					Type:    "invalid_request_error",
-				},
-			})
+			requestError("Invalid request body: "+err.Error()).respond(c, typ.APIStyleAnthropic)
 			return
 		}
 		c.JSON(http.StatusOK, anthropic.MessageTokensCount{
@@ -552,14 +484,14 @@ func (s *Server) forwardAnthropicRequestRaw(provider *typ.Provider, rawReq map[s
 	return message, nil
 }
 
-// forwardAnthropicRequest forwards request using Anthropic SDK with proper types
-func (s *Server) forwardAnthropicRequest(provider *typ.Provider, req anthropic.MessageNewParams) (*anthropic.Message, error) {
+// forwardAnthropicRequest forwards request using Anthropic SDK with proper types; cancelling ctx aborts it
+func (s *Server) forwardAnthropicRequest(ctx context.Context, provider *typ.Provider, req anthropic.MessageNewParams) (*anthropic.Message, error) {
 	// Get or create Anthropic client from pool
 	client := s.clientPool.GetAnthropicClient(provider)
 
 	// Make the request using Anthropic SDK with timeout (provider.Timeout is in seconds)
 	timeout := time.Duration(provider.Timeout) * time.Second
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 	message, err := client.Messages.New(ctx, req)
 	if err != nil {
@@ -569,18 +501,20 @@ func (s *Server) forwardAnthropicRequest(provider *typ.Provider, req anthropic.M
 	return message, nil
 }
 
-// forwardAnthropicStreamRequest forwards streaming request using Anthropic SDK
-func (s *Server) forwardAnthropicStreamRequest(provider *typ.Provider, req anthropic.MessageNewParams) (*anthropicstream.Stream[anthropic.MessageStreamEventUnion], error) {
+// forwardAnthropicStreamRequest forwards streaming request using Anthropic SDK. Cancelling ctx
+// aborts the upstream request; a request the upstream rejects returns its error.
+func (s *Server) forwardAnthropicStreamRequest(ctx context.Context, provider *typ.Provider, req anthropic.MessageNewParams) (*anthropicstream.Stream[anthropic.MessageStreamEventUnion], error) {
 	// Get or create Anthropic client from pool
 	client := s.clientPool.GetAnthropicClient(provider)
 
 	logrus.Debugln("Creating Anthropic streaming request")
 
-	// Use background context for streaming
-	// The stream will manage its own lifecycle and timeout
-	// We don't use a timeout here because streaming responses can take longer
-	ctx := context.Background()
+	// No timeout here because streaming responses can take longer
 	stream := client.Messages.NewStreaming(ctx, req)
+	if err := stream.Err(); err != nil {
+		stream.Close()
+		return nil, err
+	}
 
 	return stream, nil
 }
@@ -617,16 +551,14 @@ func (s *Server) handleAnthropicStreamResponse(c *gin.Context, stream *anthropic
 	// Create a flusher to ensure immediate sending of data
 	flusher, ok := c.Writer.(http.Flusher)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Error: ErrorDetail{
-				Message: "Streaming not supported by this connection",
-				Type:    "api_error",
-				Code:    "streaming_unsupported",
-			},
-		})
+		streamError(errors.New("Streaming not supported by this connection")).respond(c, typ.APIStyleAnthropic)
 		return
 	}
 
+	// Keepalives go through the same writer as the events until the upstream ends
+	heartbeat := adaptor.StartHeartbeat(c.Writer, s.heartbeatInterval(), adaptor.AnthropicPing)
+	defer heartbeat.Stop()
+
 	// Process the stream
 	for stream.Next() {
 		event := stream.Current()
@@ -646,38 +578,25 @@ func (s *Server) handleAnthropicStreamResponse(c *gin.Context, stream *anthropic
 		// event: xxx
 		// data: xxx
 		// (extra \n here)
-		c.Writer.Write(
+		heartbeat.Write(
 			[]byte(
 				fmt.Sprintf(
 					"event: %s\ndata: %s\n\n",
 					event.Type, string(eventJSON)),
 			),
 		)
-		flusher.Flush()
+		heartbeat.Flush()
 	}
+	heartbeat.Stop()
 
 	// Check for stream errors
 	if err := stream.Err(); err != nil {
-		logrus.Debugf("Anthropic stream error: %v", err)
-
-		// Send error event
-		errorEvent := map[string]interface{}{
-			"type": "error",
-			"error": map[string]interface{}{
-				"message": err.Error(),
-				"type":    "stream_error",
-				"code":    "stream_failed",
-			},
-		}
-
-		errorJSON, marshalErr := json.Marshal(errorEvent)
-		if marshalErr != nil {
-			logrus.Debugf("Failed to marshal Anthropic error event: %v", marshalErr)
-			c.Writer.Write([]byte("event: error\ndata: {\"error\":{\"message\":\"Failed to marshal error\",\"type\":\"internal_error\"}}\n\n"))
-		} else {
-			c.Writer.Write([]byte(fmt.Sprintf("event: error\ndata: %s\n\n", string(errorJSON))))
+		if !c.Writer.Written() {
+			// Nothing was sent yet, so the client gets the status of the upstream
+			upstreamError(err).respond(c, typ.APIStyleAnthropic)
+			return
 		}
-		flusher.Flush()
+		adaptor.SendAnthropicStreamError(c, err, flusher)
 		return
 	}
 
@@ -0,0 +1,338 @@
+package background
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"tingly-box/internal/constant"
+	"tingly-box/internal/db"
+)
+
+// BatchDispatcher executes a single batch request against a gateway endpoint
+// and returns the HTTP status code and response body
+type BatchDispatcher func(ctx context.Context, path string, body []byte) (int, []byte)
+
+// BatchWorker executes locally emulated batch jobs with a bounded pool of concurrent requests.
+// Requests go through the regular gateway endpoints, so routing, load balancing and
+// usage statistics apply exactly as for interactive traffic.
+type BatchWorker struct {
+	store        *db.BatchStore
+	dispatch     BatchDispatcher
+	concurrency  int
+	pollInterval time.Duration
+	notifyChan   chan struct{}
+	cancel       context.CancelFunc
+	mu           sync.RWMutex
+	running      bool
+}
+
+// NewBatchWorker creates a new batch worker executing up to concurrency requests in parallel
+func NewBatchWorker(store *db.BatchStore, dispatch BatchDispatcher, concurrency int) *BatchWorker {
+	if concurrency <= 0 {
+		concurrency = constant.DefaultBatchConcurrency
+	}
+	return &BatchWorker{
+		store:        store,
+		dispatch:     dispatch,
+		concurrency:  concurrency,
+		pollInterval: 30 * time.Second,
+		notifyChan:   make(chan struct{}, 1),
+	}
+}
+
+// Start begins the background batch processing loop
+func (bw *BatchWorker) Start(ctx context.Context) {
+	bw.mu.Lock()
+	if bw.running {
+		bw.mu.Unlock()
+		return
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	bw.cancel = cancel
+	bw.running = true
+	bw.mu.Unlock()
+
+	defer func() {
+		bw.mu.Lock()
+		bw.running = false
+		bw.cancel = nil
+		bw.mu.Unlock()
+		cancel()
+	}()
+
+	ticker := time.NewTicker(bw.pollInterval)
+	defer ticker.Stop()
+
+	// Resume batches left unfinished by a previous run
+	bw.RunOnce(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			bw.RunOnce(ctx)
+		case <-bw.notifyChan:
+			bw.RunOnce(ctx)
+		}
+	}
+}
+
+// Stop stops the background batch processing loop; requests in flight are left pending and
+// executed again on the next start
+func (bw *BatchWorker) Stop() {
+	bw.mu.Lock()
+	defer bw.mu.Unlock()
+
+	if bw.cancel != nil {
+		bw.cancel()
+	}
+}
+
+// Running returns true if the worker is currently running
+func (bw *BatchWorker) Running() bool {
+	bw.mu.RLock()
+	defer bw.mu.RUnlock()
+	return bw.running
+}
+
+// Notify wakes the worker up after a batch was created or cancelled
+func (bw *BatchWorker) Notify() {
+	select {
+	case bw.notifyChan <- struct{}{}:
+	default:
+	}
+}
+
+// RunOnce processes all active batches until they are finished or the context is done
+func (bw *BatchWorker) RunOnce(ctx context.Context) {
+	batches, err := bw.store.ListActiveBatches()
+	if err != nil {
+		logrus.Errorf("[BatchWorker] Failed to list batches: %v", err)
+		return
+	}
+
+	for i := range batches {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := bw.processBatch(ctx, &batches[i]); err != nil {
+			logrus.Errorf("[BatchWorker] Failed to process batch %s: %v", batches[i].ID, err)
+		}
+	}
+}
+
+// processBatch executes the pending requests of a batch in chunks, checking for cancellation
+// and expiry between chunks
+func (bw *BatchWorker) processBatch(ctx context.Context, batch *db.BatchRecord) error {
+	bw.mu.RLock()
+	concurrency := bw.concurrency
+	bw.mu.RUnlock()
+
+	path := batchGatewayPath(batch)
+	for batch.Status == db.BatchStatusInProgress && !batchExpired(batch) {
+		requests, err := bw.store.PendingRequests(batch.ID, concurrency)
+		if err != nil {
+			return err
+		}
+		if len(requests) == 0 {
+			break
+		}
+
+		var wg sync.WaitGroup
+		errs := make([]error, len(requests))
+		for i := range requests {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				errs[i] = bw.executeRequest(ctx, batch.ID, path, &requests[i])
+			}(i)
+		}
+		wg.Wait()
+
+		// A result that cannot be stored leaves its request pending; ending the batch keeps the
+		// worker from sending it upstream again on every pass
+		if err := errors.Join(errs...); err != nil {
+			return bw.failBatch(batch.ID, err)
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		if batch, err = bw.store.GetBatch(batch.ID); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case batch.Status == db.BatchStatusCancelling:
+		if err := bw.store.CancelPendingRequests(batch.ID); err != nil {
+			return err
+		}
+	case batchExpired(batch):
+		if err := bw.store.ExpirePendingRequests(batch.ID); err != nil {
+			return err
+		}
+	}
+	return bw.finishBatch(batch.ID)
+}
+
+// executeRequest dispatches one request and stores its result
+func (bw *BatchWorker) executeRequest(ctx context.Context, batchID, path string, req *db.BatchRequestRecord) error {
+	statusCode, body := bw.dispatch(ctx, path, req.Body)
+	if ctx.Err() != nil {
+		// Leave the request pending so it is retried when the worker resumes
+		return nil
+	}
+
+	status := db.BatchRequestSucceeded
+	if statusCode < 200 || statusCode >= 300 {
+		status = db.BatchRequestErrored
+	}
+	if err := bw.store.CompleteRequest(batchID, req.Index, status, statusCode, body); err != nil {
+		return fmt.Errorf("failed to store result %d: %w", req.Index, err)
+	}
+	return nil
+}
+
+// failBatch moves a batch to the failed status and returns cause
+func (bw *BatchWorker) failBatch(batchID string, cause error) error {
+	if err := bw.store.FailBatch(batchID); err != nil {
+		return fmt.Errorf("%w (failed to mark the batch failed: %v)", cause, err)
+	}
+	return cause
+}
+
+// finishBatch moves a batch to its terminal status, writing output files for OpenAI batches
+func (bw *BatchWorker) finishBatch(batchID string) error {
+	batch, err := bw.store.GetBatch(batchID)
+	if err != nil {
+		return err
+	}
+
+	var outputFileID, errorFileID string
+	if batch.Format == db.BatchFormatOpenAI {
+		if err := bw.store.FinalizeBatch(batch.ID); err != nil {
+			return err
+		}
+		if outputFileID, errorFileID, err = bw.writeOpenAIOutputFiles(batch); err != nil {
+			return bw.failBatch(batch.ID, err)
+		}
+	}
+
+	if batch, err = bw.store.FinishBatch(batch.ID, outputFileID, errorFileID); err != nil {
+		return err
+	}
+	logrus.Infof("[BatchWorker] Batch %s %s: %d succeeded, %d failed, %d canceled, %d expired",
+		batch.ID, batch.Status, batch.CompletedCount, batch.FailedCount, batch.CanceledCount, batch.ExpiredCount)
+	return nil
+}
+
+// openAIBatchOutputLine is one line of an OpenAI batch output or error file
+type openAIBatchOutputLine struct {
+	ID       string                   `json:"id"`
+	CustomID string                   `json:"custom_id"`
+	Response *openAIBatchLineResponse `json:"response"`
+	Error    *openAIBatchLineError    `json:"error"`
+}
+
+type openAIBatchLineResponse struct {
+	StatusCode int             `json:"status_code"`
+	RequestID  string          `json:"request_id"`
+	Body       json.RawMessage `json:"body"`
+}
+
+type openAIBatchLineError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// writeOpenAIOutputFiles renders succeeded requests into the output file and
+// failed or cancelled requests into the error file, returning the IDs of the files written
+func (bw *BatchWorker) writeOpenAIOutputFiles(batch *db.BatchRecord) (outputFileID, errorFileID string, err error) {
+	requests, err := bw.store.ListRequests(batch.ID)
+	if err != nil {
+		return "", "", err
+	}
+
+	var output, errorsOut bytes.Buffer
+	for _, req := range requests {
+		line := openAIBatchOutputLine{
+			ID:       fmt.Sprintf("batch_req_%s_%d", batch.ID, req.Index),
+			CustomID: req.CustomID,
+		}
+		target := &errorsOut
+		switch req.Status {
+		case db.BatchRequestSucceeded:
+			target = &output
+			line.Response = &openAIBatchLineResponse{StatusCode: req.StatusCode, RequestID: line.ID, Body: jsonOrString(req.Response)}
+		case db.BatchRequestErrored:
+			line.Response = &openAIBatchLineResponse{StatusCode: req.StatusCode, RequestID: line.ID, Body: jsonOrString(req.Response)}
+		case db.BatchRequestExpired:
+			line.Error = &openAIBatchLineError{Code: "batch_expired", Message: "The batch expired before this request was executed"}
+		default:
+			line.Error = &openAIBatchLineError{Code: "batch_cancelled", Message: "The batch was cancelled before this request was executed"}
+		}
+
+		data, err := json.Marshal(line)
+		if err != nil {
+			return "", "", err
+		}
+		target.Write(data)
+		target.WriteByte('\n')
+	}
+
+	if output.Len() > 0 {
+		file := &db.BatchFileRecord{
+			ID:       "file-" + batch.ID + "_output",
+			Purpose:  "batch_output",
+			Filename: batch.ID + "_output.jsonl",
+			Content:  output.Bytes(),
+		}
+		if err := bw.store.CreateFile(file); err != nil {
+			return "", "", err
+		}
+		outputFileID = file.ID
+	}
+	if errorsOut.Len() > 0 {
+		file := &db.BatchFileRecord{
+			ID:       "file-" + batch.ID + "_error",
+			Purpose:  "batch_output",
+			Filename: batch.ID + "_error.jsonl",
+			Content:  errorsOut.Bytes(),
+		}
+		if err := bw.store.CreateFile(file); err != nil {
+			return "", "", err
+		}
+		errorFileID = file.ID
+	}
+	return outputFileID, errorFileID, nil
+}
+
+// batchExpired reports whether a batch outlived its completion window
+func batchExpired(batch *db.BatchRecord) bool {
+	return batch.ExpiresAt != nil && time.Now().After(*batch.ExpiresAt)
+}
+
+// batchGatewayPath returns the gateway endpoint that serves the requests of a batch
+func batchGatewayPath(batch *db.BatchRecord) string {
+	if batch.Format == db.BatchFormatAnthropic {
+		return "/anthropic/v1/messages"
+	}
+	return "/openai" + batch.Endpoint
+}
+
+// jsonOrString keeps a JSON body as is and wraps anything else into a JSON string
+func jsonOrString(body []byte) json.RawMessage {
+	if json.Valid(body) {
+		return body
+	}
+	data, _ := json.Marshal(string(body))
+	return data
+}
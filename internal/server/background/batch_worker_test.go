@@ -0,0 +1,103 @@
+package background
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tingly-box/internal/db"
+)
+
+// TestBatchWorkerExpiresPendingRequests tests that requests still pending after the completion
+// window are expired instead of sent upstream
+func TestBatchWorkerExpiresPendingRequests(t *testing.T) {
+	store, err := db.NewBatchStore(t.TempDir())
+	require.NoError(t, err)
+
+	var dispatched atomic.Int32
+	worker := NewBatchWorker(store, func(ctx context.Context, path string, body []byte) (int, []byte) {
+		dispatched.Add(1)
+		return http.StatusOK, []byte(`{}`)
+	}, 2)
+
+	expiresAt := time.Now().Add(-time.Minute)
+	batch := &db.BatchRecord{ID: "batch_expired", Format: db.BatchFormatOpenAI, Endpoint: "/v1/chat/completions", ExpiresAt: &expiresAt}
+	require.NoError(t, store.CreateBatch(batch, []db.BatchRequestRecord{
+		{CustomID: "a", Body: []byte(`{}`)},
+		{CustomID: "b", Body: []byte(`{}`)},
+	}))
+
+	worker.RunOnce(context.Background())
+
+	assert.Zero(t, dispatched.Load())
+	batch, err = store.GetBatch(batch.ID)
+	require.NoError(t, err)
+	assert.Equal(t, db.BatchStatusExpired, batch.Status)
+	assert.Equal(t, 2, batch.ExpiredCount)
+	assert.NotNil(t, batch.ExpiredAt)
+
+	errorFile, err := store.GetFile(batch.ErrorFileID)
+	require.NoError(t, err)
+	assert.Contains(t, string(errorFile.Content), `"code":"batch_expired"`)
+}
+
+// TestBatchWorkerKeepsCancellation tests that a batch cancelled while its requests run ends
+// cancelled with its cancellation time kept
+func TestBatchWorkerKeepsCancellation(t *testing.T) {
+	store, err := db.NewBatchStore(t.TempDir())
+	require.NoError(t, err)
+
+	batch := &db.BatchRecord{ID: "batch_cancelled", Format: db.BatchFormatOpenAI, Endpoint: "/v1/chat/completions"}
+	worker := NewBatchWorker(store, func(ctx context.Context, path string, body []byte) (int, []byte) {
+		_, err := store.CancelBatch(batch.ID)
+		assert.NoError(t, err)
+		return http.StatusOK, []byte(`{}`)
+	}, 1)
+	require.NoError(t, store.CreateBatch(batch, []db.BatchRequestRecord{
+		{CustomID: "a", Body: []byte(`{}`)},
+		{CustomID: "b", Body: []byte(`{}`)},
+	}))
+
+	worker.RunOnce(context.Background())
+
+	batch, err = store.GetBatch(batch.ID)
+	require.NoError(t, err)
+	assert.Equal(t, db.BatchStatusCancelled, batch.Status)
+	assert.NotNil(t, batch.CancellingAt)
+	assert.NotNil(t, batch.CancelledAt)
+	assert.Equal(t, 1, batch.CompletedCount)
+	assert.Equal(t, 1, batch.CanceledCount)
+	assert.NotEmpty(t, batch.OutputFileID)
+	assert.NotEmpty(t, batch.ErrorFileID)
+}
+
+// TestBatchWorkerStartStop tests that a stopped worker can be started again
+func TestBatchWorkerStartStop(t *testing.T) {
+	store, err := db.NewBatchStore(t.TempDir())
+	require.NoError(t, err)
+	worker := NewBatchWorker(store, func(ctx context.Context, path string, body []byte) (int, []byte) {
+		return http.StatusOK, nil
+	}, 0)
+
+	for i := 0; i < 2; i++ {
+		done := make(chan struct{})
+		go func() {
+			worker.Start(context.Background())
+			close(done)
+		}()
+		require.Eventually(t, worker.Running, time.Second, time.Millisecond)
+
+		worker.Stop()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("worker did not stop")
+		}
+		assert.False(t, worker.Running())
+	}
+}
@@ -138,6 +138,12 @@ func (tr *OAuthRefresher) CheckAndRefreshTokens() {
 	}
 }
 
+// RefreshProvider refreshes a provider's token right away, for connections that cannot wait for
+// the next check
+func (tr *OAuthRefresher) RefreshProvider(provider *typ.Provider) {
+	tr.refreshProviderToken(provider)
+}
+
 // refreshProviderToken refreshes a single provider's token
 func (tr *OAuthRefresher) refreshProviderToken(provider *typ.Provider) {
 	providerType, err := oauth2.ParseProviderType(provider.OAuthDetail.ProviderType)
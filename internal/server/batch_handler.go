@@ -0,0 +1,718 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"tingly-box/internal/db"
+	"tingly-box/internal/server/background"
+	"tingly-box/pkg/adaptor"
+)
+
+// Batches are emulated locally: requests are stored in SQLite and executed by the background
+// worker through the regular chat endpoints, so any routed provider can serve them.
+
+const (
+	// openAIBatchEndpoint is the only OpenAI batch endpoint served by the gateway
+	openAIBatchEndpoint = "/v1/chat/completions"
+	// openAIBatchCompletionWindow is the only completion window OpenAI accepts
+	openAIBatchCompletionWindow = "24h"
+	// batchExpiry mirrors the 24h completion window of the upstream batch APIs; the worker
+	// expires requests still pending after it
+	batchExpiry = 24 * time.Hour
+)
+
+// OpenAIFileObject represents an OpenAI file
+type OpenAIFileObject struct {
+	ID        string `json:"id"`
+	Object    string `json:"object"`
+	Bytes     int64  `json:"bytes"`
+	CreatedAt int64  `json:"created_at"`
+	Filename  string `json:"filename"`
+	Purpose   string `json:"purpose"`
+	Status    string `json:"status"`
+}
+
+// OpenAIBatchObject represents an OpenAI batch
+type OpenAIBatchObject struct {
+	ID               string                   `json:"id"`
+	Object           string                   `json:"object"`
+	Endpoint         string                   `json:"endpoint"`
+	Errors           interface{}              `json:"errors"`
+	InputFileID      string                   `json:"input_file_id"`
+	CompletionWindow string                   `json:"completion_window"`
+	Status           string                   `json:"status"`
+	OutputFileID     *string                  `json:"output_file_id"`
+	ErrorFileID      *string                  `json:"error_file_id"`
+	CreatedAt        int64                    `json:"created_at"`
+	InProgressAt     *int64                   `json:"in_progress_at"`
+	ExpiresAt        *int64                   `json:"expires_at"`
+	FinalizingAt     *int64                   `json:"finalizing_at"`
+	CompletedAt      *int64                   `json:"completed_at"`
+	FailedAt         *int64                   `json:"failed_at"`
+	ExpiredAt        *int64                   `json:"expired_at"`
+	CancellingAt     *int64                   `json:"cancelling_at"`
+	CancelledAt      *int64                   `json:"cancelled_at"`
+	RequestCounts    OpenAIBatchRequestCounts `json:"request_counts"`
+	Metadata         map[string]string        `json:"metadata"`
+}
+
+// OpenAIBatchRequestCounts represents the request counters of an OpenAI batch
+type OpenAIBatchRequestCounts struct {
+	Total     int `json:"total"`
+	Completed int `json:"completed"`
+	Failed    int `json:"failed"`
+}
+
+// AnthropicMessageBatch represents an Anthropic message batch
+type AnthropicMessageBatch struct {
+	ID                string                     `json:"id"`
+	Type              string                     `json:"type"`
+	ProcessingStatus  string                     `json:"processing_status"`
+	RequestCounts     AnthropicBatchRequestCount `json:"request_counts"`
+	EndedAt           *string                    `json:"ended_at"`
+	CreatedAt         string                     `json:"created_at"`
+	ExpiresAt         string                     `json:"expires_at"`
+	ArchivedAt        *string                    `json:"archived_at"`
+	CancelInitiatedAt *string                    `json:"cancel_initiated_at"`
+	ResultsURL        *string                    `json:"results_url"`
+}
+
+// AnthropicBatchRequestCount represents the request counters of an Anthropic message batch
+type AnthropicBatchRequestCount struct {
+	Processing int `json:"processing"`
+	Succeeded  int `json:"succeeded"`
+	Errored    int `json:"errored"`
+	Canceled   int `json:"canceled"`
+	Expired    int `json:"expired"`
+}
+
+// OpenAIUploadFile handles file uploads (multipart/form-data with `file` and `purpose`)
+func (s *Server) OpenAIUploadFile(c *gin.Context) {
+	if !s.requireBatchStore(c) {
+		return
+	}
+
+	purpose := c.PostForm("purpose")
+	if purpose == "" {
+		writeBatchError(c, "purpose is required")
+		return
+	}
+	header, err := c.FormFile("file")
+	if err != nil {
+		writeBatchError(c, "file is required")
+		return
+	}
+	src, err := header.Open()
+	if err != nil {
+		writeBatchError(c, "Failed to read file: "+err.Error())
+		return
+	}
+	defer src.Close()
+	content, err := io.ReadAll(src)
+	if err != nil {
+		writeBatchError(c, "Failed to read file: "+err.Error())
+		return
+	}
+
+	file := &db.BatchFileRecord{
+		ID:       "file-" + newBatchID(),
+		Purpose:  purpose,
+		Filename: header.Filename,
+		Content:  content,
+	}
+	if err := s.batchStore.CreateFile(file); err != nil {
+		writeBatchStoreError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, toOpenAIFileObject(file))
+}
+
+// OpenAIListFiles lists stored files, optionally filtered by purpose
+func (s *Server) OpenAIListFiles(c *gin.Context) {
+	if !s.requireBatchStore(c) {
+		return
+	}
+
+	files, err := s.batchStore.ListFiles(c.Query("purpose"))
+	if err != nil {
+		writeBatchStoreError(c, err)
+		return
+	}
+	data := make([]OpenAIFileObject, 0, len(files))
+	for i := range files {
+		data = append(data, toOpenAIFileObject(&files[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{"object": "list", "data": data})
+}
+
+// OpenAIGetFile returns the metadata of a file
+func (s *Server) OpenAIGetFile(c *gin.Context) {
+	if !s.requireBatchStore(c) {
+		return
+	}
+
+	file, err := s.batchStore.GetFile(c.Param("file_id"))
+	if err != nil {
+		writeBatchStoreError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, toOpenAIFileObject(file))
+}
+
+// OpenAIGetFileContent returns the raw content of a file
+func (s *Server) OpenAIGetFileContent(c *gin.Context) {
+	if !s.requireBatchStore(c) {
+		return
+	}
+
+	file, err := s.batchStore.GetFile(c.Param("file_id"))
+	if err != nil {
+		writeBatchStoreError(c, err)
+		return
+	}
+	c.Data(http.StatusOK, "application/octet-stream", file.Content)
+}
+
+// OpenAIDeleteFile deletes a file
+func (s *Server) OpenAIDeleteFile(c *gin.Context) {
+	if !s.requireBatchStore(c) {
+		return
+	}
+
+	fileID := c.Param("file_id")
+	if err := s.batchStore.DeleteFile(fileID); err != nil {
+		writeBatchStoreError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"id": fileID, "object": "file", "deleted": true})
+}
+
+// OpenAICreateBatch creates a batch from an uploaded JSONL input file
+func (s *Server) OpenAICreateBatch(c *gin.Context) {
+	if !s.requireBatchStore(c) {
+		return
+	}
+
+	var req struct {
+		InputFileID      string            `json:"input_file_id"`
+		Endpoint         string            `json:"endpoint"`
+		CompletionWindow string            `json:"completion_window"`
+		Metadata         map[string]string `json:"metadata"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeBatchError(c, "Invalid request body: "+err.Error())
+		return
+	}
+	if req.Endpoint != openAIBatchEndpoint {
+		writeBatchError(c, fmt.Sprintf("Unsupported endpoint '%s', only %s is supported", req.Endpoint, openAIBatchEndpoint))
+		return
+	}
+	if req.CompletionWindow == "" {
+		req.CompletionWindow = openAIBatchCompletionWindow
+	}
+	if req.CompletionWindow != openAIBatchCompletionWindow {
+		writeBatchError(c, fmt.Sprintf("Unsupported completion_window '%s', only %s is supported", req.CompletionWindow, openAIBatchCompletionWindow))
+		return
+	}
+
+	file, err := s.batchStore.GetFile(req.InputFileID)
+	if err != nil {
+		writeBatchStoreError(c, err)
+		return
+	}
+	requests, err := parseOpenAIBatchInput(file.Content, req.Endpoint)
+	if err != nil {
+		writeBatchError(c, err.Error())
+		return
+	}
+
+	metadata, _ := json.Marshal(req.Metadata)
+	expiresAt := time.Now().Add(batchExpiry)
+	batch := &db.BatchRecord{
+		ID:               "batch_" + newBatchID(),
+		Format:           db.BatchFormatOpenAI,
+		Endpoint:         req.Endpoint,
+		InputFileID:      req.InputFileID,
+		CompletionWindow: req.CompletionWindow,
+		Metadata:         string(metadata),
+		ExpiresAt:        &expiresAt,
+	}
+	if err := s.batchStore.CreateBatch(batch, requests); err != nil {
+		writeBatchStoreError(c, err)
+		return
+	}
+	s.notifyBatchWorker()
+
+	c.JSON(http.StatusOK, toOpenAIBatchObject(batch))
+}
+
+// OpenAIListBatches lists OpenAI batches, newest first
+func (s *Server) OpenAIListBatches(c *gin.Context) {
+	if !s.requireBatchStore(c) {
+		return
+	}
+
+	batches, err := s.batchStore.ListBatches(db.BatchFormatOpenAI, batchListLimit(c))
+	if err != nil {
+		writeBatchStoreError(c, err)
+		return
+	}
+	data := make([]OpenAIBatchObject, 0, len(batches))
+	for i := range batches {
+		data = append(data, toOpenAIBatchObject(&batches[i]))
+	}
+	response := gin.H{"object": "list", "data": data, "has_more": false}
+	if len(data) > 0 {
+		response["first_id"] = data[0].ID
+		response["last_id"] = data[len(data)-1].ID
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// OpenAIGetBatch returns the status of an OpenAI batch
+func (s *Server) OpenAIGetBatch(c *gin.Context) {
+	batch, ok := s.lookupBatch(c, c.Param("batch_id"), db.BatchFormatOpenAI)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, toOpenAIBatchObject(batch))
+}
+
+// OpenAICancelBatch cancels an OpenAI batch; requests already executed keep their results
+func (s *Server) OpenAICancelBatch(c *gin.Context) {
+	batch, ok := s.cancelBatch(c, c.Param("batch_id"), db.BatchFormatOpenAI)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, toOpenAIBatchObject(batch))
+}
+
+// AnthropicCreateMessageBatch creates a message batch from inline requests
+func (s *Server) AnthropicCreateMessageBatch(c *gin.Context) {
+	if !s.requireBatchStore(c) {
+		return
+	}
+
+	var req struct {
+		Requests []struct {
+			CustomID string                 `json:"custom_id"`
+			Params   map[string]interface{} `json:"params"`
+		} `json:"requests"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeBatchError(c, "Invalid request body: "+err.Error())
+		return
+	}
+	if len(req.Requests) == 0 {
+		writeBatchError(c, "requests must not be empty")
+		return
+	}
+
+	requests := make([]db.BatchRequestRecord, 0, len(req.Requests))
+	seen := make(map[string]bool, len(req.Requests))
+	for i, r := range req.Requests {
+		if r.CustomID == "" || seen[r.CustomID] {
+			writeBatchError(c, fmt.Sprintf("requests[%d]: custom_id must be unique and non-empty", i))
+			return
+		}
+		seen[r.CustomID] = true
+		body, err := batchRequestBody(r.Params)
+		if err != nil {
+			writeBatchError(c, fmt.Sprintf("requests[%d]: %v", i, err))
+			return
+		}
+		requests = append(requests, db.BatchRequestRecord{CustomID: r.CustomID, Body: body})
+	}
+
+	expiresAt := time.Now().Add(batchExpiry)
+	batch := &db.BatchRecord{
+		ID:        "msgbatch_" + newBatchID(),
+		Format:    db.BatchFormatAnthropic,
+		Endpoint:  "/v1/messages",
+		ExpiresAt: &expiresAt,
+	}
+	if err := s.batchStore.CreateBatch(batch, requests); err != nil {
+		writeBatchStoreError(c, err)
+		return
+	}
+	s.notifyBatchWorker()
+
+	c.JSON(http.StatusOK, toAnthropicMessageBatch(c, batch))
+}
+
+// AnthropicListMessageBatches lists Anthropic message batches, newest first
+func (s *Server) AnthropicListMessageBatches(c *gin.Context) {
+	if !s.requireBatchStore(c) {
+		return
+	}
+
+	batches, err := s.batchStore.ListBatches(db.BatchFormatAnthropic, batchListLimit(c))
+	if err != nil {
+		writeBatchStoreError(c, err)
+		return
+	}
+	data := make([]AnthropicMessageBatch, 0, len(batches))
+	for i := range batches {
+		data = append(data, toAnthropicMessageBatch(c, &batches[i]))
+	}
+	response := gin.H{"data": data, "has_more": false, "first_id": nil, "last_id": nil}
+	if len(data) > 0 {
+		response["first_id"] = data[0].ID
+		response["last_id"] = data[len(data)-1].ID
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// AnthropicGetMessageBatch returns the status of an Anthropic message batch
+func (s *Server) AnthropicGetMessageBatch(c *gin.Context) {
+	batch, ok := s.lookupBatch(c, c.Param("batch_id"), db.BatchFormatAnthropic)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, toAnthropicMessageBatch(c, batch))
+}
+
+// AnthropicCancelMessageBatch cancels an Anthropic message batch
+func (s *Server) AnthropicCancelMessageBatch(c *gin.Context) {
+	batch, ok := s.cancelBatch(c, c.Param("batch_id"), db.BatchFormatAnthropic)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, toAnthropicMessageBatch(c, batch))
+}
+
+// AnthropicMessageBatchResults streams the results of an ended message batch as JSONL
+func (s *Server) AnthropicMessageBatchResults(c *gin.Context) {
+	batch, ok := s.lookupBatch(c, c.Param("batch_id"), db.BatchFormatAnthropic)
+	if !ok {
+		return
+	}
+	if !batch.IsFinished() {
+		writeBatchError(c, fmt.Sprintf("Message batch %s is still processing", batch.ID))
+		return
+	}
+
+	requests, err := s.batchStore.ListRequests(batch.ID)
+	if err != nil {
+		writeBatchStoreError(c, err)
+		return
+	}
+
+	var buf bytes.Buffer
+	for _, req := range requests {
+		line, _ := json.Marshal(gin.H{"custom_id": req.CustomID, "result": anthropicBatchResult(&req)})
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	c.Data(http.StatusOK, "application/x-jsonl", buf.Bytes())
+}
+
+// dispatchBatchRequest executes a batch request through the gateway's own router,
+// reusing authentication, rule routing, load balancing and statistics
+func (s *Server) dispatchBatchRequest(ctx context.Context, path string, body []byte) (int, []byte) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, path, bytes.NewReader(body))
+	if err != nil {
+		gatewayErr := internalError(http.StatusInternalServerError, err.Error())
+		data, _ := json.Marshal(gatewayErr.Response(apiStyleForPath(path)))
+		return gatewayErr.Status, data
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+s.config.GetModelToken())
+
+	recorder := httptest.NewRecorder()
+	s.engine.ServeHTTP(recorder, req)
+	return recorder.Code, recorder.Body.Bytes()
+}
+
+// GetBatchWorker returns the batch worker (nil if the batch store is unavailable)
+func (s *Server) GetBatchWorker() *background.BatchWorker {
+	return s.batchWorker
+}
+
+// notifyBatchWorker wakes up the batch worker if it is running
+func (s *Server) notifyBatchWorker() {
+	if s.batchWorker != nil {
+		s.batchWorker.Notify()
+	}
+}
+
+// requireBatchStore writes an error response if batch emulation is unavailable
+func (s *Server) requireBatchStore(c *gin.Context) bool {
+	if s.batchStore == nil {
+		internalError(http.StatusServiceUnavailable, "Batch store is not available").respond(c, apiStyleForPath(c.Request.URL.Path))
+		return false
+	}
+	return true
+}
+
+// lookupBatch loads a batch of the given format, writing a 404 if it does not exist
+func (s *Server) lookupBatch(c *gin.Context, id, format string) (*db.BatchRecord, bool) {
+	if !s.requireBatchStore(c) {
+		return nil, false
+	}
+	batch, err := s.batchStore.GetBatch(id)
+	if err == nil && batch.Format != format {
+		err = db.ErrBatchNotFound
+	}
+	if err != nil {
+		writeBatchStoreError(c, err)
+		return nil, false
+	}
+	return batch, true
+}
+
+// cancelBatch requests cancellation of a batch of the given format
+func (s *Server) cancelBatch(c *gin.Context, id, format string) (*db.BatchRecord, bool) {
+	if _, ok := s.lookupBatch(c, id, format); !ok {
+		return nil, false
+	}
+	batch, err := s.batchStore.CancelBatch(id)
+	if err != nil {
+		writeBatchStoreError(c, err)
+		return nil, false
+	}
+	s.notifyBatchWorker()
+	return batch, true
+}
+
+// parseOpenAIBatchInput parses a JSONL batch input file into batch requests
+func parseOpenAIBatchInput(content []byte, endpoint string) ([]db.BatchRequestRecord, error) {
+	var requests []db.BatchRequestRecord
+	seen := make(map[string]bool)
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var item struct {
+			CustomID string                 `json:"custom_id"`
+			Method   string                 `json:"method"`
+			URL      string                 `json:"url"`
+			Body     map[string]interface{} `json:"body"`
+		}
+		if err := json.Unmarshal(line, &item); err != nil {
+			return nil, fmt.Errorf("line %d: invalid JSON: %v", lineNo, err)
+		}
+		if item.CustomID == "" || seen[item.CustomID] {
+			return nil, fmt.Errorf("line %d: custom_id must be unique and non-empty", lineNo)
+		}
+		seen[item.CustomID] = true
+		if item.Method != "" && !strings.EqualFold(item.Method, http.MethodPost) {
+			return nil, fmt.Errorf("line %d: method must be POST", lineNo)
+		}
+		if item.URL != endpoint {
+			return nil, fmt.Errorf("line %d: url '%s' does not match batch endpoint %s", lineNo, item.URL, endpoint)
+		}
+		body, err := batchRequestBody(item.Body)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNo, err)
+		}
+		requests = append(requests, db.BatchRequestRecord{CustomID: item.CustomID, Body: body})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read input file: %v", err)
+	}
+	if len(requests) == 0 {
+		return nil, errors.New("input file contains no requests")
+	}
+	return requests, nil
+}
+
+// batchRequestBody validates request params and disables streaming, which batches do not support
+func batchRequestBody(params map[string]interface{}) ([]byte, error) {
+	if params == nil {
+		return nil, errors.New("request body is required")
+	}
+	if model, _ := params["model"].(string); model == "" {
+		return nil, errors.New("model is required")
+	}
+	delete(params, "stream")
+	delete(params, "stream_options")
+	return json.Marshal(params)
+}
+
+// anthropicBatchResult converts a stored request into an Anthropic batch result
+func anthropicBatchResult(req *db.BatchRequestRecord) gin.H {
+	switch req.Status {
+	case db.BatchRequestSucceeded:
+		var message interface{}
+		if err := json.Unmarshal(req.Response, &message); err == nil {
+			return gin.H{"type": "succeeded", "message": message}
+		}
+		return gin.H{"type": "errored", "error": gin.H{"type": "error", "error": gin.H{"type": "api_error", "message": "Invalid upstream response"}}}
+	case db.BatchRequestErrored:
+		var errBody struct {
+			Error ErrorDetail `json:"error"`
+		}
+		_ = json.Unmarshal(req.Response, &errBody)
+		if errBody.Error.Type == "" {
+			errBody.Error.Type = "api_error"
+		}
+		if errBody.Error.Message == "" {
+			errBody.Error.Message = fmt.Sprintf("Request failed with status %d", req.StatusCode)
+		}
+		return gin.H{"type": "errored", "error": gin.H{"type": "error", "error": gin.H{"type": errBody.Error.Type, "message": errBody.Error.Message}}}
+	case db.BatchRequestExpired:
+		return gin.H{"type": "expired"}
+	default:
+		return gin.H{"type": "canceled"}
+	}
+}
+
+func toOpenAIFileObject(file *db.BatchFileRecord) OpenAIFileObject {
+	return OpenAIFileObject{
+		ID:        file.ID,
+		Object:    "file",
+		Bytes:     file.Bytes,
+		CreatedAt: file.CreatedAt.Unix(),
+		Filename:  file.Filename,
+		Purpose:   file.Purpose,
+		Status:    "processed",
+	}
+}
+
+func toOpenAIBatchObject(batch *db.BatchRecord) OpenAIBatchObject {
+	obj := OpenAIBatchObject{
+		ID:               batch.ID,
+		Object:           "batch",
+		Endpoint:         batch.Endpoint,
+		InputFileID:      batch.InputFileID,
+		CompletionWindow: batch.CompletionWindow,
+		Status:           batch.Status,
+		CreatedAt:        batch.CreatedAt.Unix(),
+		InProgressAt:     unixOrNil(batch.InProgressAt),
+		ExpiresAt:        unixOrNil(batch.ExpiresAt),
+		FinalizingAt:     unixOrNil(batch.FinalizingAt),
+		CompletedAt:      unixOrNil(batch.CompletedAt),
+		CancellingAt:     unixOrNil(batch.CancellingAt),
+		ExpiredAt:        unixOrNil(batch.ExpiredAt),
+		CancelledAt:      unixOrNil(batch.CancelledAt),
+		RequestCounts: OpenAIBatchRequestCounts{
+			Total:     batch.TotalCount,
+			Completed: batch.CompletedCount,
+			Failed:    batch.FailedCount,
+		},
+	}
+	if batch.Status == db.BatchStatusFailed {
+		obj.FailedAt, obj.CompletedAt = obj.CompletedAt, nil
+	}
+	if batch.OutputFileID != "" {
+		obj.OutputFileID = &batch.OutputFileID
+	}
+	if batch.ErrorFileID != "" {
+		obj.ErrorFileID = &batch.ErrorFileID
+	}
+	if batch.Metadata != "" {
+		_ = json.Unmarshal([]byte(batch.Metadata), &obj.Metadata)
+	}
+	return obj
+}
+
+func toAnthropicMessageBatch(c *gin.Context, batch *db.BatchRecord) AnthropicMessageBatch {
+	obj := AnthropicMessageBatch{
+		ID:               batch.ID,
+		Type:             "message_batch",
+		ProcessingStatus: "in_progress",
+		RequestCounts: AnthropicBatchRequestCount{
+			Processing: batch.TotalCount - batch.CompletedCount - batch.FailedCount - batch.CanceledCount - batch.ExpiredCount,
+			Succeeded:  batch.CompletedCount,
+			Errored:    batch.FailedCount,
+			Canceled:   batch.CanceledCount,
+			Expired:    batch.ExpiredCount,
+		},
+		CreatedAt:         batch.CreatedAt.UTC().Format(time.RFC3339),
+		CancelInitiatedAt: rfc3339OrNil(batch.CancellingAt),
+	}
+	if batch.ExpiresAt != nil {
+		obj.ExpiresAt = batch.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+
+	switch {
+	case batch.IsFinished():
+		obj.ProcessingStatus = "ended"
+		obj.EndedAt = rfc3339OrNil(batch.CompletedAt)
+		if batch.CancelledAt != nil {
+			obj.EndedAt = rfc3339OrNil(batch.CancelledAt)
+		}
+		if batch.ExpiredAt != nil {
+			obj.EndedAt = rfc3339OrNil(batch.ExpiredAt)
+		}
+		scheme := "http"
+		if c.Request.TLS != nil {
+			scheme = "https"
+		}
+		resultsURL := fmt.Sprintf("%s://%s/anthropic/v1/messages/batches/%s/results", scheme, c.Request.Host, batch.ID)
+		obj.ResultsURL = &resultsURL
+	case batch.Status == db.BatchStatusCancelling:
+		obj.ProcessingStatus = "canceling"
+	}
+	return obj
+}
+
+func unixOrNil(t *time.Time) *int64 {
+	if t == nil {
+		return nil
+	}
+	v := t.Unix()
+	return &v
+}
+
+func rfc3339OrNil(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	v := t.UTC().Format(time.RFC3339)
+	return &v
+}
+
+// batchListLimit reads the `limit` query parameter (default 20, max 100)
+func batchListLimit(c *gin.Context) int {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		return 20
+	}
+	if limit > 100 {
+		return 100
+	}
+	return limit
+}
+
+// newBatchID generates a random identifier for files and batches
+func newBatchID() string {
+	return strings.ReplaceAll(uuid.NewString(), "-", "")
+}
+
+// writeBatchError responds to an invalid batch request in the style of the batch endpoint
+func writeBatchError(c *gin.Context, message string) {
+	requestError(message).respond(c, apiStyleForPath(c.Request.URL.Path))
+}
+
+// writeBatchStoreError responds to a batch store failure in the style of the batch endpoint
+func writeBatchStoreError(c *gin.Context, err error) {
+	gatewayErr := internalError(http.StatusInternalServerError, err.Error())
+	if errors.Is(err, db.ErrBatchNotFound) {
+		gatewayErr = requestError("Not found")
+		gatewayErr.Status, gatewayErr.Type = http.StatusNotFound, adaptor.ErrorTypeNotFound
+	}
+	gatewayErr.respond(c, apiStyleForPath(c.Request.URL.Path))
+}
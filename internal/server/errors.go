@@ -0,0 +1,134 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"tingly-box/internal/typ"
+	"tingly-box/pkg/adaptor"
+)
+
+// Gateway error codes tell which part of the gateway failed a request
+const (
+	// GatewayErrorRequest is a malformed client request
+	GatewayErrorRequest = "invalid_request"
+	// GatewayErrorRouting is a request model no rule, service or provider serves
+	GatewayErrorRouting = "routing"
+	// GatewayErrorAuth is a client that failed authentication
+	GatewayErrorAuth = "auth"
+	// GatewayErrorUpstream is a provider that failed the request
+	GatewayErrorUpstream = "upstream"
+	// GatewayErrorAdaptor is a request or response that could not be converted between API styles
+	GatewayErrorAdaptor = "adaptor"
+	// GatewayErrorInternal is a failure of the gateway itself, such as its batch store
+	GatewayErrorInternal = "internal"
+)
+
+// GatewayError is a failed request, normalized so it can be rendered in either API style
+type GatewayError struct {
+	// Status is the HTTP status of the response; for upstream failures, the status of the upstream
+	Status int
+	// Type is the Anthropic error type
+	Type string
+	// Code refines the type for OpenAI clients; the type's own code is used when empty
+	Code string
+	// Gateway is the gateway error code
+	Gateway string
+	Message string
+	// RetryAfter is the Retry-After header of the upstream
+	RetryAfter string
+	// upstream keeps the OpenAI type and code of an upstream error
+	upstream *adaptor.UpstreamError
+}
+
+// requestError is a malformed client request
+func requestError(message string) *GatewayError {
+	return &GatewayError{Status: http.StatusBadRequest, Type: adaptor.ErrorTypeInvalidRequest, Gateway: GatewayErrorRequest, Message: message}
+}
+
+// routingError is a request model that no active rule, service or provider serves
+func routingError(err error) *GatewayError {
+	return &GatewayError{Status: http.StatusBadRequest, Type: adaptor.ErrorTypeInvalidRequest, Gateway: GatewayErrorRouting, Message: err.Error()}
+}
+
+// adaptorError is a request or response that could not be converted between API styles
+func adaptorError(status int, message string) *GatewayError {
+	return &GatewayError{Status: status, Type: adaptor.ErrorTypeForStatus(status), Gateway: GatewayErrorAdaptor, Message: message}
+}
+
+// internalError is a failure of the gateway itself
+func internalError(status int, message string) *GatewayError {
+	return &GatewayError{Status: status, Type: adaptor.ErrorTypeAPI, Gateway: GatewayErrorInternal, Message: message}
+}
+
+// upstreamError is a failed upstream request, keeping the status and error type of the upstream.
+// Upstreams that did not answer are a bad gateway, or a gateway timeout.
+func upstreamError(err error) *GatewayError {
+	upstream := adaptor.ParseUpstreamError(err)
+	status := upstream.Status
+	if status == 0 {
+		status = http.StatusBadGateway
+		if upstream.Timeout {
+			status = http.StatusGatewayTimeout
+		}
+	}
+	return &GatewayError{
+		Status:     status,
+		Type:       upstream.Type,
+		Gateway:    GatewayErrorUpstream,
+		Message:    upstream.Message,
+		RetryAfter: upstream.RetryAfter,
+		upstream:   upstream,
+	}
+}
+
+// streamError responds to an error returned by a stream converter: an upstream that failed before the
+// stream started, or a connection that cannot stream
+func streamError(err error) *GatewayError {
+	var upstream *adaptor.UpstreamError
+	if errors.As(err, &upstream) {
+		return upstreamError(upstream)
+	}
+	gatewayErr := adaptorError(http.StatusInternalServerError, err.Error())
+	gatewayErr.Code = "streaming_unsupported"
+	return gatewayErr
+}
+
+// Response renders the error in the error schema of the client's API style
+func (e *GatewayError) Response(style typ.APIStyle) ErrorResponse {
+	detail := ErrorDetail{Message: e.Message, Type: e.Type, GatewayCode: e.Gateway}
+	if style == typ.APIStyleAnthropic {
+		return ErrorResponse{Type: "error", Error: detail}
+	}
+
+	if e.upstream != nil {
+		detail.Type, detail.Code = e.upstream.OpenAIType()
+	} else {
+		detail.Type, detail.Code = adaptor.OpenAIErrorType(e.Type)
+	}
+	if e.Code != "" {
+		detail.Code = e.Code
+	}
+	return ErrorResponse{Error: detail}
+}
+
+// apiStyleForPath returns the API style of the gateway endpoint serving path
+func apiStyleForPath(path string) typ.APIStyle {
+	if strings.HasPrefix(path, "/anthropic/") {
+		return typ.APIStyleAnthropic
+	}
+	return typ.APIStyleOpenAI
+}
+
+// respond sends the error to a client of the given API style
+func (e *GatewayError) respond(c *gin.Context, style typ.APIStyle) {
+	if e.RetryAfter != "" {
+		c.Header("Retry-After", e.RetryAfter)
+	}
+	// A stream may have set the SSE content type before it failed
+	c.Header("Content-Type", "application/json; charset=utf-8")
+	c.JSON(e.Status, e.Response(style))
+}
@@ -18,6 +18,8 @@ type AuthMiddleware struct {
 
 // ErrorResponse represents an error response
 type ErrorResponse struct {
+	// Type is "error" for Anthropic clients
+	Type  string      `json:"type,omitempty"`
 	Error ErrorDetail `json:"error"`
 }
 
@@ -26,6 +28,22 @@ type ErrorDetail struct {
 	Message string `json:"message"`
 	Type    string `json:"type"`
 	Code    string `json:"code,omitempty"`
+	// GatewayCode tells which part of the gateway failed the request
+	GatewayCode string `json:"gateway_code,omitempty"`
+}
+
+// gatewayErrorAuth is the gateway error code of a client that failed authentication
+const gatewayErrorAuth = "auth"
+
+// rejectModelRequest rejects a request to the model API in the error schema of its endpoint
+func rejectModelRequest(c *gin.Context, status int, errorType, message string) {
+	resp := ErrorResponse{Error: ErrorDetail{Message: message, Type: errorType, GatewayCode: gatewayErrorAuth}}
+	path := c.FullPath()
+	if strings.HasPrefix(path, "/anthropic/") || strings.Contains(path, "/messages") {
+		resp.Type = "error"
+	}
+	c.JSON(status, resp)
+	c.Abort()
 }
 
 // NewAuthMiddleware creates a new authentication middleware
@@ -103,13 +121,7 @@ func (am *AuthMiddleware) ModelAuthMiddleware() gin.HandlerFunc {
 		authHeader := c.GetHeader("Authorization")
 		xApiKey := c.GetHeader("X-Api-Key")
 		if authHeader == "" && xApiKey == "" {
-			c.JSON(http.StatusUnauthorized, ErrorResponse{
-				Error: ErrorDetail{
-					Message: "Authorization header required",
-					Type:    "invalid_request_error",
-				},
-			})
-			c.Abort()
+			rejectModelRequest(c, http.StatusUnauthorized, "authentication_error", "Authorization header required")
 			return
 		}
 
@@ -122,12 +134,7 @@ func (am *AuthMiddleware) ModelAuthMiddleware() gin.HandlerFunc {
 		// Check against global config model token first
 		cfg := am.config
 		if cfg == nil || !cfg.HasModelToken() {
-			c.JSON(http.StatusInternalServerError, ErrorResponse{
-				Error: ErrorDetail{
-					Message: "config or config model token missing",
-					Type:    "invalid_request_error",
-				},
-			})
+			rejectModelRequest(c, http.StatusInternalServerError, "api_error", "config or config model token missing")
 			return
 		}
 
@@ -141,13 +148,7 @@ func (am *AuthMiddleware) ModelAuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		c.JSON(http.StatusUnauthorized, ErrorResponse{
-			Error: ErrorDetail{
-				Message: "Invalid authorization header format. Expected: 'Bearer <token>'",
-				Type:    "invalid_request_error",
-			},
-		})
-		c.Abort()
+		rejectModelRequest(c, http.StatusUnauthorized, "authentication_error", "Invalid authorization header format. Expected: 'Bearer <token>'")
 		return
 	}
 }
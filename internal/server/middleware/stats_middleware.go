@@ -43,6 +43,13 @@ func (sm *StatsMiddleware) Middleware() gin.HandlerFunc {
 			return
 		}
 
+		// Speech responses are binary audio; usage comes from the request, so skip buffering
+		if isAudioSpeechEndpoint(c.Request.URL.Path) {
+			c.Next()
+			sm.extractAndRecordUsage(c, "")
+			return
+		}
+
 		// Capture response body
 		responseWriter := &responseBodyWriter{
 			ResponseWriter: c.Writer,
@@ -66,7 +73,27 @@ func (sm *StatsMiddleware) shouldTrackEndpoint(path, method string) bool {
 	}
 
 	return strings.HasSuffix(path, "/chat/completions") ||
-		strings.HasSuffix(path, "/messages")
+		strings.HasSuffix(path, "/messages") ||
+		isImageEndpoint(path) ||
+		isAudioEndpoint(path)
+}
+
+// isImageEndpoint checks if the path is an image generation or edit endpoint
+func isImageEndpoint(path string) bool {
+	return strings.HasSuffix(path, "/images/generations") ||
+		strings.HasSuffix(path, "/images/edits")
+}
+
+// isAudioEndpoint checks if the path is a transcription, translation or speech endpoint
+func isAudioEndpoint(path string) bool {
+	return strings.HasSuffix(path, "/audio/transcriptions") ||
+		strings.HasSuffix(path, "/audio/translations") ||
+		isAudioSpeechEndpoint(path)
+}
+
+// isAudioSpeechEndpoint checks if the path is the text-to-speech endpoint
+func isAudioSpeechEndpoint(path string) bool {
+	return strings.HasSuffix(path, "/audio/speech")
 }
 
 // extractAndRecordUsage extracts token usage from response and records it
@@ -77,6 +104,22 @@ func (sm *StatsMiddleware) extractAndRecordUsage(c *gin.Context, responseBody st
 		return
 	}
 
+	if isImageEndpoint(c.Request.URL.Path) {
+		sm.recordImageUsage(c, provider, model, responseBody)
+		return
+	}
+	if isAudioEndpoint(c.Request.URL.Path) {
+		sm.recordAudioUsage(c, provider, model, responseBody)
+		return
+	}
+
+	// Prompt cache usage is kept with the rule's service before its stats are persisted below
+	if cacheRead, cacheCreation := extractCacheUsage(responseBody); cacheRead > 0 || cacheCreation > 0 {
+		if service := sm.findRuleService(c, provider, model); service != nil {
+			service.RecordCacheUsage(cacheRead, cacheCreation)
+		}
+	}
+
 	// Get the rule information from context (set by handlers)
 	if rule, exists := c.Get("rule"); exists {
 		if rulePtr, ok := rule.(*typ.Rule); ok {
@@ -169,10 +212,16 @@ func (sm *StatsMiddleware) extractTokenUsage(responseBody, endpoint string) (int
 			return inputTokens, outputTokens
 		}
 
-		// Anthropic format
+		// Anthropic format, whose input tokens exclude cached tokens
 		if inputTok, ok := usage["input_tokens"].(float64); ok {
 			inputTokens = int(inputTok)
 		}
+		if cacheTok, ok := usage["cache_read_input_tokens"].(float64); ok {
+			inputTokens += int(cacheTok)
+		}
+		if cacheTok, ok := usage["cache_creation_input_tokens"].(float64); ok {
+			inputTokens += int(cacheTok)
+		}
 		if outputTok, ok := usage["output_tokens"].(float64); ok {
 			outputTokens = int(outputTok)
 		}
@@ -196,6 +245,119 @@ func (sm *StatsMiddleware) extractTokenUsage(responseBody, endpoint string) (int
 	return totalEstimated / 2, totalEstimated - totalEstimated/2
 }
 
+// extractCacheUsage extracts prompt cache reads and writes from a chat response body, in
+// OpenAI (prompt_tokens_details.cached_tokens) or Anthropic format
+func extractCacheUsage(responseBody string) (int, int) {
+	if responseBody == "" {
+		return 0, 0
+	}
+	var response struct {
+		Usage struct {
+			PromptTokensDetails struct {
+				CachedTokens int `json:"cached_tokens"`
+			} `json:"prompt_tokens_details"`
+			CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+			CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal([]byte(responseBody), &response); err != nil {
+		return 0, 0
+	}
+	cacheRead := response.Usage.CacheReadInputTokens
+	if cacheRead == 0 {
+		cacheRead = response.Usage.PromptTokensDetails.CachedTokens
+	}
+	return cacheRead, response.Usage.CacheCreationInputTokens
+}
+
+// recordImageUsage records token and per-image usage for image endpoints.
+// Image responses carry base64 payloads, so the body-length estimation used for
+// chat responses is never applied here.
+func (sm *StatsMiddleware) recordImageUsage(c *gin.Context, provider, model, responseBody string) {
+	service := sm.findRuleService(c, provider, model)
+	if service == nil {
+		return
+	}
+	inputTokens, outputTokens, images := extractImageUsage(responseBody)
+	service.RecordUsage(inputTokens, outputTokens)
+	service.RecordImageUsage(images)
+	sm.persistServiceStats(service)
+}
+
+// recordAudioUsage records duration-based usage for transcriptions and translations and
+// character-based usage for speech. Speech characters are counted by the handler and
+// passed through the "audio_characters" context key.
+func (sm *StatsMiddleware) recordAudioUsage(c *gin.Context, provider, model, responseBody string) {
+	service := sm.findRuleService(c, provider, model)
+	if service == nil {
+		return
+	}
+	inputTokens, outputTokens, seconds := extractAudioUsage(responseBody)
+	characters := c.GetInt("audio_characters")
+	service.RecordUsage(inputTokens, outputTokens)
+	service.RecordAudioUsage(seconds, characters)
+	sm.persistServiceStats(service)
+}
+
+// findRuleService returns the service of the request's rule that served a successful request
+func (sm *StatsMiddleware) findRuleService(c *gin.Context, provider, model string) *loadbalance.Service {
+	if c.Writer.Status() >= 400 {
+		return nil
+	}
+	rule, _ := c.Get("rule")
+	rulePtr, ok := rule.(*typ.Rule)
+	if !ok {
+		return nil
+	}
+	for i := range rulePtr.Services {
+		service := &rulePtr.Services[i]
+		if service.Active && service.Provider == provider && service.Model == model {
+			return service
+		}
+	}
+	return nil
+}
+
+// extractImageUsage extracts token usage and the number of returned images from an images response
+func extractImageUsage(responseBody string) (int, int, int) {
+	var response struct {
+		Data  []json.RawMessage `json:"data"`
+		Usage struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal([]byte(responseBody), &response); err != nil {
+		return 0, 0, 0
+	}
+	return response.Usage.InputTokens, response.Usage.OutputTokens, len(response.Data)
+}
+
+// extractAudioUsage extracts token usage and audio duration from a transcription response.
+// Duration-billed models report `usage.seconds`; verbose_json responses report `duration`.
+func extractAudioUsage(responseBody string) (int, int, float64) {
+	if responseBody == "" {
+		return 0, 0, 0
+	}
+	var response struct {
+		Duration float64 `json:"duration"`
+		Usage    struct {
+			Type         string  `json:"type"`
+			Seconds      float64 `json:"seconds"`
+			InputTokens  int     `json:"input_tokens"`
+			OutputTokens int     `json:"output_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal([]byte(responseBody), &response); err != nil {
+		return 0, 0, 0
+	}
+	seconds := response.Usage.Seconds
+	if seconds == 0 {
+		seconds = response.Duration
+	}
+	return response.Usage.InputTokens, response.Usage.OutputTokens, seconds
+}
+
 // RecordUsage records usage for a service by finding it in the rules and updating its embedded stats
 func (sm *StatsMiddleware) RecordUsage(serviceID string, inputTokens, outputTokens int) {
 	if sm.config == nil {
@@ -248,6 +410,18 @@ func (sm *StatsMiddleware) RecordUsageOnRule(rule *typ.Rule, provider, model str
 	}
 }
 
+// RecordRealtimeSessionOnRule records a finished realtime session on a specific rule's service
+func (sm *StatsMiddleware) RecordRealtimeSessionOnRule(rule *typ.Rule, provider, model string, seconds float64) {
+	for i := range rule.Services {
+		service := &rule.Services[i]
+		if service.Active && service.Provider == provider && service.Model == model {
+			service.RecordRealtimeSession(seconds)
+			sm.persistServiceStats(service)
+			return
+		}
+	}
+}
+
 // persistServiceStats writes the updated stats into the dedicated stats store.
 func (sm *StatsMiddleware) persistServiceStats(service *loadbalance.Service) {
 	if sm.statsStore == nil {
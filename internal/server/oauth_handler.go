@@ -695,6 +695,7 @@ func (s *Server) OAuthCallback(c *gin.Context) {
 			UserID:       uuid.New().String(),
 			RefreshToken: token.RefreshToken,
 			ExpiresAt:    expiresAt,
+			ExtraFields:  token.Metadata, // e.g. email and the Google Cloud project_id
 		},
 	}
 
@@ -3,17 +3,24 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"sync"
+	"time"
 
+	"github.com/anthropics/anthropic-sdk-go"
+	anthropicstream "github.com/anthropics/anthropic-sdk-go/packages/ssestream"
 	"github.com/gin-gonic/gin"
 	"github.com/openai/openai-go/v3"
 	"github.com/openai/openai-go/v3/packages/ssestream"
 	"github.com/sirupsen/logrus"
 
+	"tingly-box/internal/constant"
 	"tingly-box/internal/loadbalance"
 	"tingly-box/internal/typ"
 	"tingly-box/pkg/adaptor"
+	"tingly-box/pkg/client"
 )
 
 // OpenAIListModels handles the /v1/models endpoint (OpenAI compatible)
@@ -80,24 +87,14 @@ func (s *Server) OpenAIChatCompletions(c *gin.Context) {
 	// Read raw body
 	bodyBytes, err := c.GetRawData()
 	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error: ErrorDetail{
-				Message: "Failed to read request body: " + err.Error(),
-				Type:    "invalid_request_error",
-			},
-		})
+		requestError("Failed to read request body: "+err.Error()).respond(c, typ.APIStyleOpenAI)
 		return
 	}
 
 	// Inspect stream flag
 	var rawReq map[string]interface{}
 	if err := json.Unmarshal(bodyBytes, &rawReq); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error: ErrorDetail{
-				Message: "Invalid JSON: " + err.Error(),
-				Type:    "invalid_request_error",
-			},
-		})
+		requestError("Invalid JSON: "+err.Error()).respond(c, typ.APIStyleOpenAI)
 		return
 	}
 
@@ -109,34 +106,23 @@ func (s *Server) OpenAIChatCompletions(c *gin.Context) {
 	// Parse OpenAI-style request
 	var req openai.ChatCompletionNewParams
 	if err := json.Unmarshal(bodyBytes, &req); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error: ErrorDetail{
-				Message: "Invalid request body: " + err.Error(),
-				Type:    "invalid_request_error",
-			},
-		})
+		requestError("Invalid request body: "+err.Error()).respond(c, typ.APIStyleOpenAI)
 		return
 	}
 
+	// Keep reasoning fields the SDK types drop, so thinking survives multi-turn tool loops
+	adaptor.PreserveReasoningFields(&req, bodyBytes)
+	adaptor.PreserveCacheControl(&req, bodyBytes)
+
 	// Validate
 	proxyModel := req.Model
 	if req.Model == "" {
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error: ErrorDetail{
-				Message: "Model is required",
-				Type:    "invalid_request_error",
-			},
-		})
+		requestError("Model is required").respond(c, typ.APIStyleOpenAI)
 		return
 	}
 
 	if len(req.Messages) == 0 {
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error: ErrorDetail{
-				Message: "At least one message is required",
-				Type:    "invalid_request_error",
-			},
-		})
+		requestError("At least one message is required").respond(c, typ.APIStyleOpenAI)
 		return
 	}
 
@@ -149,34 +135,19 @@ func (s *Server) OpenAIChatCompletions(c *gin.Context) {
 	if scenario == "" {
 		provider, selectedService, rule, err = s.DetermineProviderAndModel(req.Model)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, ErrorResponse{
-				Error: ErrorDetail{
-					Message: err.Error(),
-					Type:    "invalid_request_error",
-				},
-			})
+			routingError(err).respond(c, typ.APIStyleOpenAI)
 			return
 		}
 	} else {
 		// Convert string to RuleScenario and validate
 		scenarioType := typ.RuleScenario(scenario)
 		if !isValidRuleScenario(scenarioType) {
-			c.JSON(http.StatusBadRequest, ErrorResponse{
-				Error: ErrorDetail{
-					Message: fmt.Sprintf("invalid scenario: %s", scenario),
-					Type:    "invalid_request_error",
-				},
-			})
+			requestError(fmt.Sprintf("invalid scenario: %s", scenario)).respond(c, typ.APIStyleOpenAI)
 			return
 		}
 		provider, selectedService, rule, err = s.DetermineProviderAndModelWithScenario(scenarioType, req.Model)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, ErrorResponse{
-				Error: ErrorDetail{
-					Message: err.Error(),
-					Type:    "invalid_request_error",
-				},
-			})
+			routingError(err).respond(c, typ.APIStyleOpenAI)
 			return
 		}
 	}
@@ -197,6 +168,14 @@ func (s *Server) OpenAIChatCompletions(c *gin.Context) {
 	c.Set("provider", provider.UUID)
 	c.Set("model", actualModel)
 
+	// Inline remote images for providers that only accept base64 image data
+	if provider.InlineImages {
+		if err := adaptor.InlineOpenAIImages(c.Request.Context(), &req, client.CreateHTTPClientWithProxy(provider.ProxyURL)); err != nil {
+			adaptorError(http.StatusBadRequest, err.Error()).respond(c, typ.APIStyleOpenAI)
+			return
+		}
+	}
+
 	apiStyle := string(provider.APIStyle)
 	if apiStyle == "" {
 		apiStyle = "openai"
@@ -205,62 +184,98 @@ func (s *Server) OpenAIChatCompletions(c *gin.Context) {
 	if apiStyle == "anthropic" {
 		// Check if adaptor is enabled
 		if !s.enableAdaptor {
-			c.JSON(http.StatusUnprocessableEntity, ErrorResponse{
-				Error: ErrorDetail{
-					Message: fmt.Sprintf("Request format adaptation is disabled. Cannot send OpenAI request to Anthropic-style provider '%s'. Use --adapter flag to enable format conversion.", provider.Name),
-					Type:    "adapter_disabled",
-				},
-			})
+			gatewayErr := adaptorError(http.StatusUnprocessableEntity, fmt.Sprintf("Request format adaptation is disabled. Cannot send OpenAI request to Anthropic-style provider '%s'. Use --adapter flag to enable format conversion.", provider.Name))
+			gatewayErr.Code = "adapter_disabled"
+			gatewayErr.respond(c, typ.APIStyleOpenAI)
 			return
 		}
 
-		anthropicReq := adaptor.ConvertOpenAIToAnthropicRequest(&req, int64(maxAllowed))
+		anthropicReq, err := adaptor.ConvertOpenAIToAnthropicRequestWithOptions(&req, int64(maxAllowed), s.convertOptions(provider))
+		if err != nil {
+			adaptorError(http.StatusBadRequest, err.Error()).respond(c, typ.APIStyleOpenAI)
+			return
+		}
+		applyAutoCache(rule, &anthropicReq)
 
-		// 🔥 REQUIRED: forward tool_choice
-		if req.ToolChoice.OfAuto.Value != "" || req.ToolChoice.OfAllowedTools != nil || req.ToolChoice.OfFunctionToolChoice != nil || req.ToolChoice.OfCustomToolChoice != nil {
-			anthropicReq.ToolChoice = adaptor.ConvertOpenAIToAnthropicToolChoice(&req.ToolChoice)
+		// Anthropic has no n; each choice is a separate upstream request
+		choices, err := s.requestedChoices(&req)
+		if err != nil {
+			requestError(err.Error()).respond(c, typ.APIStyleOpenAI)
+			return
 		}
 
 		if isStreaming {
-			stream, err := s.forwardAnthropicStreamRequest(provider, anthropicReq)
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, ErrorResponse{
-					Error: ErrorDetail{
-						Message: "Failed to create streaming request: " + err.Error(),
-						Type:    "api_error",
-					},
-				})
+			// Open every choice's stream at once so the slowest upstream sets the time to first chunk.
+			// The first failure fails the whole response, so it cancels the remaining choices.
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			streams := make([]*anthropicstream.Stream[anthropic.MessageStreamEventUnion], choices)
+			errs := make([]error, choices)
+			var wg sync.WaitGroup
+			for i := 0; i < choices; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					streams[i], errs[i] = s.forwardAnthropicStreamRequest(ctx, provider, anthropicReq)
+					if errs[i] != nil {
+						cancel()
+					}
+				}(i)
+			}
+			wg.Wait()
+			if err := firstChoiceError(errs); err != nil {
+				for _, opened := range streams {
+					if opened != nil {
+						opened.Close()
+					}
+				}
+				upstreamError(err).respond(c, typ.APIStyleOpenAI)
 				return
 			}
 
-			err = adaptor.HandleAnthropicToOpenAIStreamResponse(c, stream, responseModel)
+			streamOpts := adaptor.StreamOptions{IncludeUsage: req.StreamOptions.IncludeUsage.Value, Heartbeat: s.heartbeatInterval()}
+			if choices > 1 {
+				err = adaptor.HandleAnthropicToOpenAIMultiStreamResponse(c, streams, cancel, responseModel, streamOpts)
+			} else {
+				err = adaptor.HandleAnthropicToOpenAIStreamResponseWithOptions(c, streams[0], responseModel, streamOpts)
+			}
 			if err != nil {
-				c.JSON(http.StatusInternalServerError, ErrorResponse{
-					Error: ErrorDetail{
-						Message: "Failed to create streaming request: " + err.Error(),
-						Type:    "api_error",
-					},
-				})
+				streamError(err).respond(c, typ.APIStyleOpenAI)
 				return
 			}
 			return
 		}
 
-		anthropicResp, err := s.forwardAnthropicRequest(provider, anthropicReq)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, ErrorResponse{
-				Error: ErrorDetail{
-					Message: "Failed to forward Anthropic request: " + err.Error(),
-					Type:    "api_error",
-				},
-			})
+		// The first failure fails the whole response, so it cancels the remaining choices
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		anthropicResps := make([]*anthropic.Message, choices)
+		errs := make([]error, choices)
+		var wg sync.WaitGroup
+		for i := 0; i < choices; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				anthropicResps[i], errs[i] = s.completeAnthropicRequest(ctx, provider, &req, anthropicReq)
+				if errs[i] != nil {
+					cancel()
+				}
+			}(i)
+		}
+		wg.Wait()
+		if err := firstChoiceError(errs); err != nil {
+			upstreamError(err).respond(c, typ.APIStyleOpenAI)
 			return
 		}
 
-		openaiResp := adaptor.ConvertAnthropicToOpenAIResponse(anthropicResp, responseModel)
-		c.JSON(http.StatusOK, openaiResp)
+		if choices > 1 {
+			c.JSON(http.StatusOK, adaptor.ConvertAnthropicToOpenAIChoicesResponse(anthropicResps, responseModel))
+			return
+		}
+		c.JSON(http.StatusOK, adaptor.ConvertAnthropicToOpenAIResponse(anthropicResps[0], responseModel))
 		return
 	} else {
+		req.Tools = adaptor.SanitizeOpenAITools(req.Tools, s.toolSchemaFor(provider))
 		if isStreaming {
 			s.handleStreamingRequest(c, provider, &req, responseModel)
 		} else {
@@ -274,45 +289,172 @@ func (s *Server) handleNonStreamingRequest(c *gin.Context, provider *typ.Provide
 	// Forward request to provider
 	response, err := s.forwardOpenAIRequest(provider, req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Error: ErrorDetail{
-				Message: "Failed to forward request: " + err.Error(),
-				Type:    "api_error",
-			},
-		})
+		upstreamError(err).respond(c, typ.APIStyleOpenAI)
 		return
 	}
 
 	// Convert response to JSON map for modification
 	responseJSON, err := json.Marshal(response)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Error: ErrorDetail{
-				Message: "Failed to marshal response: " + err.Error(),
-				Type:    "api_error",
-			},
-		})
+		adaptorError(http.StatusInternalServerError, "Failed to marshal response: "+err.Error()).respond(c, typ.APIStyleOpenAI)
 		return
 	}
 
 	var responseMap map[string]interface{}
 	if err := json.Unmarshal(responseJSON, &responseMap); err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Error: ErrorDetail{
-				Message: "Failed to process response: " + err.Error(),
-				Type:    "api_error",
-			},
-		})
+		adaptorError(http.StatusInternalServerError, "Failed to process response: "+err.Error()).respond(c, typ.APIStyleOpenAI)
 		return
 	}
 
 	// Update response model if configured
 	responseMap["model"] = responseModel
 
+	// Keep reasoning, under the field OpenAI clients expect
+	dialect := s.dialectFor(provider)
+	if choices, ok := responseMap["choices"].([]interface{}); ok {
+		for i, choice := range response.Choices {
+			reasoning := adaptor.ReasoningExtraFields(choice.Message.JSON.ExtraFields, dialect)
+			if i >= len(choices) || reasoning == nil {
+				continue
+			}
+			if choiceMap, ok := choices[i].(map[string]interface{}); ok {
+				if message, ok := choiceMap["message"].(map[string]interface{}); ok {
+					for key, value := range reasoning {
+						message[key] = value
+					}
+				}
+			}
+		}
+	}
+
 	// Return modified response
 	c.JSON(http.StatusOK, responseMap)
 }
 
+// requestedChoices returns the n of a request for providers that need one request per choice,
+// rejecting values above the configured maximum
+func (s *Server) requestedChoices(req *openai.ChatCompletionNewParams) (int, error) {
+	if !req.N.Valid() || req.N.Value <= 1 {
+		return 1, nil
+	}
+	maxChoices := s.config.GetMaxChoices()
+	if maxChoices <= 0 {
+		maxChoices = constant.DefaultMaxChoices
+	}
+	if req.N.Value > int64(maxChoices) {
+		return 0, fmt.Errorf("n must be at most %d for this provider", maxChoices)
+	}
+	return int(req.N.Value), nil
+}
+
+// firstChoiceError returns the failure that cancelled the other choices of a request, rather
+// than the cancellation it caused them, or nil when every choice succeeded
+func firstChoiceError(errs []error) error {
+	var cancelled error
+	for _, err := range errs {
+		switch {
+		case err == nil:
+		case errors.Is(err, context.Canceled):
+			if cancelled == nil {
+				cancelled = err
+			}
+		default:
+			return err
+		}
+	}
+	return cancelled
+}
+
+// heartbeatInterval is how long a stream upstream may be silent before the client gets a keepalive,
+// 0 when keepalives are disabled
+func (s *Server) heartbeatInterval() time.Duration {
+	seconds := s.config.GetHeartbeatSeconds()
+	if seconds == 0 {
+		seconds = constant.DefaultHeartbeatSeconds
+	}
+	if seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// completeAnthropicRequest forwards a converted request, retrying once when emulated structured
+// output does not match its schema. Only non-streaming requests are validated and retried:
+// streamed output reaches the client before it can be checked.
+func (s *Server) completeAnthropicRequest(ctx context.Context, provider *typ.Provider, req *openai.ChatCompletionNewParams, anthropicReq anthropic.MessageNewParams) (*anthropic.Message, error) {
+	anthropicResp, err := s.forwardAnthropicRequest(ctx, provider, anthropicReq)
+	if err != nil {
+		return nil, err
+	}
+
+	// Structured output is emulated with a tool call; give the model one chance to fix invalid output
+	if schema, ok := adaptor.StructuredOutputSchema(req); ok {
+		if validationErr := adaptor.ValidateStructuredOutput(anthropicResp, schema); validationErr != nil {
+			logrus.Warnf("Structured output from %s is invalid, retrying: %v", provider.Name, validationErr)
+			adaptor.AppendStructuredOutputRetry(&anthropicReq, anthropicResp, validationErr)
+			if retryResp, err := s.forwardAnthropicRequest(ctx, provider, anthropicReq); err == nil {
+				// Both attempts are billed
+				addAnthropicUsage(&retryResp.Usage, anthropicResp.Usage)
+				anthropicResp = retryResp
+			} else {
+				logrus.Errorf("Structured output retry failed: %v", err)
+			}
+		}
+	}
+	return anthropicResp, nil
+}
+
+// addAnthropicUsage adds every token and request count of usage to total
+func addAnthropicUsage(total *anthropic.Usage, usage anthropic.Usage) {
+	total.InputTokens += usage.InputTokens
+	total.OutputTokens += usage.OutputTokens
+	total.CacheReadInputTokens += usage.CacheReadInputTokens
+	total.CacheCreationInputTokens += usage.CacheCreationInputTokens
+	total.CacheCreation.Ephemeral5mInputTokens += usage.CacheCreation.Ephemeral5mInputTokens
+	total.CacheCreation.Ephemeral1hInputTokens += usage.CacheCreation.Ephemeral1hInputTokens
+	total.ServerToolUse.WebSearchRequests += usage.ServerToolUse.WebSearchRequests
+}
+
+// convertOptions returns the request conversion options configured for a provider
+func (s *Server) convertOptions(provider *typ.Provider) adaptor.ConvertOptions {
+	return adaptor.ConvertOptions{
+		DocumentFallback:    adaptor.DocumentFallback(provider.DocumentFallback),
+		ForwardCacheControl: provider.ForwardCacheControl,
+		ToolSchema:          s.toolSchemaFor(provider),
+	}
+}
+
+// toolSchemaFor returns the tool schema policy of a provider, defaulted from its dialect
+func (s *Server) toolSchemaFor(provider *typ.Provider) adaptor.SchemaPolicy {
+	if cfg := provider.ToolSchema; cfg != nil {
+		policy := adaptor.SchemaPolicy{
+			DropKeywords:         cfg.DropKeywords,
+			OneOfToAnyOf:         cfg.OneOfToAnyOf,
+			ConstToEnum:          cfg.ConstToEnum,
+			MaxDepth:             cfg.MaxDepth,
+			MaxDescriptionLength: cfg.MaxDescriptionLength,
+		}
+		if len(cfg.AllowedFormats) > 0 {
+			policy.AllowedFormats = cfg.AllowedFormats
+		}
+		return policy
+	}
+	profile, _ := s.dialectFor(provider).Profile()
+	return profile.ToolSchema
+}
+
+// applyAutoCache inserts prompt caching breakpoints when the rule enables auto cache
+func applyAutoCache(rule *typ.Rule, req *anthropic.MessageNewParams) {
+	if rule != nil && rule.AutoCache {
+		adaptor.ApplyAutoCache(req, rule.AutoCacheTurns)
+	}
+}
+
+// dialectFor returns the OpenAI-compatible dialect of a provider, defaulted from its template
+func (s *Server) dialectFor(provider *typ.Provider) adaptor.Dialect {
+	return adaptor.Dialect(s.templateManager.GetDialectForProvider(provider))
+}
+
 // forwardOpenAIRequest forwards the request to the selected provider using OpenAI library
 func (s *Server) forwardOpenAIRequest(provider *typ.Provider, req *openai.ChatCompletionNewParams) (*openai.ChatCompletion, error) {
 	// Get or create OpenAI client from pool
@@ -322,6 +464,7 @@ func (s *Server) forwardOpenAIRequest(provider *typ.Provider, req *openai.ChatCo
 	// Since  openai.ChatCompletionNewParams is a type alias to openai.ChatCompletionNewParams,
 	// we can directly use it as the request parameters
 	chatReq := *req
+	adaptor.ApplyDialect(&chatReq, s.dialectFor(provider))
 
 	// Make the request using OpenAI library
 	chatCompletion, err := client.Chat.Completions.New(context.Background(), chatReq)
@@ -342,6 +485,7 @@ func (s *Server) forwardOpenAIStreamRequest(provider *typ.Provider, req *openai.
 	// Since  openai.ChatCompletionNewParams is a type alias to openai.ChatCompletionNewParams,
 	// we can directly use it as the request parameters
 	chatReq := *req
+	adaptor.ApplyDialect(&chatReq, s.dialectFor(provider))
 
 	// Make the streaming request using OpenAI library
 	stream := client.Chat.Completions.NewStreaming(context.Background(), chatReq)
@@ -354,21 +498,16 @@ func (s *Server) handleStreamingRequest(c *gin.Context, provider *typ.Provider,
 	// Create streaming request
 	stream, err := s.forwardOpenAIStreamRequest(provider, req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Error: ErrorDetail{
-				Message: "Failed to create streaming request: " + err.Error(),
-				Type:    "api_error",
-			},
-		})
+		upstreamError(err).respond(c, typ.APIStyleOpenAI)
 		return
 	}
 
 	// Handle the streaming response
-	s.handleOpenAIStreamResponse(c, stream, responseModel)
+	s.handleOpenAIStreamResponse(c, stream, responseModel, s.dialectFor(provider))
 }
 
 // handleOpenAIStreamResponse processes the streaming response and sends it to the client
-func (s *Server) handleOpenAIStreamResponse(c *gin.Context, stream *ssestream.Stream[openai.ChatCompletionChunk], responseModel string) {
+func (s *Server) handleOpenAIStreamResponse(c *gin.Context, stream *ssestream.Stream[openai.ChatCompletionChunk], responseModel string, dialect adaptor.Dialect) {
 	defer func() {
 		if r := recover(); r != nil {
 			logrus.Errorf("Panic in streaming handler: %v", r)
@@ -399,54 +538,57 @@ func (s *Server) handleOpenAIStreamResponse(c *gin.Context, stream *ssestream.St
 	// Create a flusher to ensure immediate sending of data
 	flusher, ok := c.Writer.(http.Flusher)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Error: ErrorDetail{
-				Message: "Streaming not supported by this connection",
-				Type:    "api_error",
-				Code:    "streaming_unsupported",
-			},
-		})
+		streamError(errors.New("Streaming not supported by this connection")).respond(c, typ.APIStyleOpenAI)
 		return
 	}
 
+	// Keepalives go through the same writer as the chunks until the upstream ends
+	heartbeat := adaptor.StartHeartbeat(c.Writer, s.heartbeatInterval(), adaptor.SSEKeepalive)
+	defer heartbeat.Stop()
+
 	// Process the stream
 	for stream.Next() {
 		chatChunk := stream.Current()
+		hasUsage := chatChunk.Usage.PromptTokens != 0 || chatChunk.Usage.CompletionTokens != 0
 
-		// Check if we have choices and they're not empty
-		if len(chatChunk.Choices) == 0 {
-			continue
-		}
-
-		choice := chatChunk.Choices[0]
-
-		// Build delta map - include all fields, JSON marshaling will handle empty values
-		delta := map[string]interface{}{
-			"role":          choice.Delta.Role,
-			"content":       choice.Delta.Content,
-			"refusal":       choice.Delta.Refusal,
-			"function_call": choice.Delta.FunctionCall,
-			"tool_calls":    choice.Delta.ToolCalls,
-		}
-
-		// Prepare the chunk in OpenAI format
+		// Prepare the chunk in OpenAI format; with stream_options.include_usage the usage comes in a
+		// final chunk without choices
 		chunk := map[string]interface{}{
 			"id":      chatChunk.ID,
 			"object":  "chat.completion.chunk",
 			"created": chatChunk.Created,
 			"model":   responseModel,
-			"choices": []map[string]interface{}{
+			"choices": []map[string]interface{}{},
+		}
+		if len(chatChunk.Choices) > 0 {
+			choice := chatChunk.Choices[0]
+
+			// Build delta map - include all fields, JSON marshaling will handle empty values
+			delta := map[string]interface{}{
+				"role":          choice.Delta.Role,
+				"content":       choice.Delta.Content,
+				"refusal":       choice.Delta.Refusal,
+				"function_call": choice.Delta.FunctionCall,
+				"tool_calls":    choice.Delta.ToolCalls,
+			}
+			for key, value := range adaptor.ReasoningExtraFields(choice.Delta.JSON.ExtraFields, dialect) {
+				delta[key] = value
+			}
+
+			chunk["choices"] = []map[string]interface{}{
 				{
 					"index":         choice.Index,
 					"delta":         delta,
 					"finish_reason": choice.FinishReason,
 					"logprobs":      choice.Logprobs,
 				},
-			},
+			}
+		} else if !hasUsage {
+			continue
 		}
 
 		// Add usage if present (usually only in the last chunk)
-		if chatChunk.Usage.PromptTokens != 0 || chatChunk.Usage.CompletionTokens != 0 {
+		if hasUsage {
 			chunk["usage"] = chatChunk.Usage
 		}
 
@@ -463,31 +605,19 @@ func (s *Server) handleOpenAIStreamResponse(c *gin.Context, stream *ssestream.St
 		}
 
 		// Send the chunk
-		c.Writer.Write([]byte(fmt.Sprintf("data: %s\n\n", string(chunkJSON))))
-		flusher.Flush()
+		heartbeat.Write([]byte(fmt.Sprintf("data: %s\n\n", string(chunkJSON))))
+		heartbeat.Flush()
 	}
+	heartbeat.Stop()
 
 	// Check for stream errors
 	if err := stream.Err(); err != nil {
-		logrus.Errorf("Stream error: %v", err)
-
-		// Send error event
-		errorChunk := map[string]interface{}{
-			"error": map[string]interface{}{
-				"message": err.Error(),
-				"type":    "stream_error",
-				"code":    "stream_failed",
-			},
-		}
-
-		errorJSON, marshalErr := json.Marshal(errorChunk)
-		if marshalErr != nil {
-			logrus.Errorf("Failed to marshal error chunk: %v", marshalErr)
-			c.Writer.Write([]byte(fmt.Sprintf("data: {\"error\":{\"message\":\"Failed to marshal error\",\"type\":\"internal_error\"}}\n\n")))
-		} else {
-			c.Writer.Write([]byte(fmt.Sprintf("data: %s\n\n", string(errorJSON))))
+		if !c.Writer.Written() {
+			// Nothing was sent yet, so the client gets the status of the upstream
+			upstreamError(err).respond(c, typ.APIStyleOpenAI)
+			return
 		}
-		flusher.Flush()
+		adaptor.SendOpenAIStreamError(c, err, flusher)
 		return
 	}
 
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"context"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+
+	"tingly-box/internal/typ"
+)
+
+// OpenAIAudioTranscriptions handles OpenAI v1 audio transcription requests (multipart/form-data uploads)
+func (s *Server) OpenAIAudioTranscriptions(c *gin.Context) {
+	s.handleOpenAIAudioRequest(c, "audio/transcriptions")
+}
+
+// OpenAIAudioTranslations handles OpenAI v1 audio translation requests (multipart/form-data uploads)
+func (s *Server) OpenAIAudioTranslations(c *gin.Context) {
+	s.handleOpenAIAudioRequest(c, "audio/translations")
+}
+
+// OpenAIAudioSpeech handles OpenAI v1 text-to-speech requests.
+// The binary audio response is streamed back to the client as it arrives.
+func (s *Server) OpenAIAudioSpeech(c *gin.Context) {
+	s.handleOpenAIAudioRequest(c, "audio/speech")
+}
+
+// handleOpenAIAudioRequest routes an audio request by rule and relays it to an OpenAI-style provider.
+// Only the model name is rewritten; audio files, response formats and streamed output pass through unchanged.
+func (s *Server) handleOpenAIAudioRequest(c *gin.Context, path string) {
+	req, err := readRawRequest(c)
+	if err != nil {
+		requestError(err.Error()).respond(c, typ.APIStyleOpenAI)
+		return
+	}
+
+	provider, _, ok := s.routeRawRequest(c, req, path)
+	if !ok {
+		return
+	}
+
+	// Speech is billed per input character, which the stats middleware cannot see in the binary response
+	if input, ok := req.fields["input"]; ok {
+		c.Set("audio_characters", utf8.RuneCountInString(input))
+	}
+
+	timeout := time.Duration(provider.Timeout) * time.Second
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	defer cancel()
+
+	resp, err := s.forwardOpenAIRawRequest(ctx, provider, path, req)
+	if err != nil {
+		upstreamError(err).respond(c, typ.APIStyleOpenAI)
+		return
+	}
+
+	copyRawUpstreamResponse(c, resp)
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"tingly-box/internal/typ"
+)
+
+// OpenAIImageGenerations handles OpenAI v1 image generation requests
+func (s *Server) OpenAIImageGenerations(c *gin.Context) {
+	s.handleOpenAIImageRequest(c, "images/generations")
+}
+
+// OpenAIImageEdits handles OpenAI v1 image edit requests (multipart/form-data uploads)
+func (s *Server) OpenAIImageEdits(c *gin.Context) {
+	s.handleOpenAIImageRequest(c, "images/edits")
+}
+
+// handleOpenAIImageRequest routes an image request by rule and relays it to an OpenAI-style provider.
+// The body is forwarded as is apart from the model name, so both `url` and `b64_json`
+// response formats and multipart uploads reach the client unchanged.
+func (s *Server) handleOpenAIImageRequest(c *gin.Context, path string) {
+	req, err := readRawRequest(c)
+	if err != nil {
+		requestError(err.Error()).respond(c, typ.APIStyleOpenAI)
+		return
+	}
+
+	provider, _, ok := s.routeRawRequest(c, req, path)
+	if !ok {
+		return
+	}
+
+	timeout := time.Duration(provider.Timeout) * time.Second
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	defer cancel()
+
+	resp, err := s.forwardOpenAIRawRequest(ctx, provider, path, req)
+	if err != nil {
+		upstreamError(err).respond(c, typ.APIStyleOpenAI)
+		return
+	}
+
+	copyRawUpstreamResponse(c, resp)
+}
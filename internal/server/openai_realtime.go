@@ -0,0 +1,308 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/websocket"
+
+	"tingly-box/internal/typ"
+	"tingly-box/pkg/client"
+)
+
+const (
+	// realtimeDialTimeout bounds the upstream WebSocket handshake
+	realtimeDialTimeout = 30 * time.Second
+	// realtimeAPIKeyProtocol is the subprotocol prefix browser clients use to pass an API key
+	realtimeAPIKeyProtocol = "openai-insecure-api-key."
+)
+
+// realtimeFrame is a single WebSocket message together with its payload type (text or binary)
+type realtimeFrame struct {
+	payloadType byte
+	data        []byte
+}
+
+// realtimeFrameCodec relays messages without changing their payload type
+var realtimeFrameCodec = websocket.Codec{
+	Marshal: func(v interface{}) ([]byte, byte, error) {
+		frame := v.(*realtimeFrame)
+		return frame.data, frame.payloadType, nil
+	},
+	Unmarshal: func(data []byte, payloadType byte, v interface{}) error {
+		frame := v.(*realtimeFrame)
+		frame.data = data
+		frame.payloadType = payloadType
+		return nil
+	},
+}
+
+// RealtimeSubprotocolAuth lets browser clients, which cannot set headers on WebSocket requests,
+// authenticate with the model token through the `openai-insecure-api-key.<token>` subprotocol
+func RealtimeSubprotocolAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			for _, protocol := range strings.Split(c.GetHeader("Sec-WebSocket-Protocol"), ",") {
+				protocol = strings.TrimSpace(protocol)
+				if strings.HasPrefix(protocol, realtimeAPIKeyProtocol) {
+					c.Request.Header.Set("Authorization", "Bearer "+strings.TrimPrefix(protocol, realtimeAPIKeyProtocol))
+					break
+				}
+			}
+		}
+		c.Next()
+	}
+}
+
+// OpenAIRealtime handles OpenAI Realtime API WebSocket sessions.
+// The model query parameter selects the rule; frames are relayed in both directions
+// and `response.done` usage plus the session duration are recorded in stats.
+func (s *Server) OpenAIRealtime(c *gin.Context) {
+	model := c.Query("model")
+	if model == "" {
+		requestError("Model query parameter is required").respond(c, typ.APIStyleOpenAI)
+		return
+	}
+
+	provider, selectedService, rule, err := s.DetermineProviderAndModel(model)
+	if err != nil {
+		routingError(err).respond(c, typ.APIStyleOpenAI)
+		return
+	}
+	if !isOpenAIStyle(provider) {
+		adaptorError(http.StatusUnprocessableEntity, fmt.Sprintf("The realtime endpoint requires an OpenAI-style provider, but rule '%s' selected '%s' (%s)", model, provider.Name, provider.APIStyle)).respond(c, typ.APIStyleOpenAI)
+		return
+	}
+
+	upstream, err := s.dialRealtimeUpstream(c, provider, selectedService.Model)
+	if err != nil {
+		upstreamError(fmt.Errorf("failed to connect to upstream realtime API: %w", err)).respond(c, typ.APIStyleOpenAI)
+		return
+	}
+	defer upstream.Close()
+
+	c.Set("provider", provider.UUID)
+	c.Set("model", selectedService.Model)
+	if rule != nil {
+		c.Set("rule", rule)
+	}
+
+	server := websocket.Server{
+		Handshake: selectRealtimeProtocol,
+		Handler: func(client *websocket.Conn) {
+			s.relayRealtime(client, upstream, rule, provider.UUID, selectedService.Model)
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// dialRealtimeUpstream opens the upstream WebSocket with the provider's credentials, through
+// the provider's proxy when one is configured
+func (s *Server) dialRealtimeUpstream(c *gin.Context, provider *typ.Provider, model string) (*websocket.Conn, error) {
+	upstreamURL, err := realtimeUpstreamURL(provider.APIBase, c.Request.URL.Query(), model)
+	if provider.IsAzure() {
+		upstreamURL, err = azureRealtimeUpstreamURL(provider, c.Request.URL.Query(), model)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := websocket.NewConfig(upstreamURL, provider.APIBase)
+	if err != nil {
+		return nil, err
+	}
+
+	// Sessions outlive the refresher's check interval, so an expiring token is refreshed first
+	if provider.IsOAuthExpired() && s.oauthRefresher != nil {
+		s.oauthRefresher.RefreshProvider(provider)
+	}
+	config.Header = http.Header{}
+	if token := provider.GetAccessToken(); provider.IsAzure() {
+		client.SetAzureAuth(config.Header, token)
+	} else if token != "" {
+		config.Header.Set("Authorization", "Bearer "+token)
+	}
+	if beta := c.GetHeader("OpenAI-Beta"); beta != "" {
+		config.Header.Set("OpenAI-Beta", beta)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), realtimeDialTimeout)
+	defer cancel()
+
+	logrus.Infof("provider: %s (realtime, model: %s)", provider.Name, model)
+	conn, err := client.DialContextWithProxy(ctx, provider.ProxyURL, realtimeAuthority(config.Location))
+	if err != nil {
+		return nil, err
+	}
+	if config.Location.Scheme == "wss" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: config.Location.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+	ws, err := websocket.NewClient(config, conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	return ws, nil
+}
+
+// realtimeAuthority returns the host:port of a WebSocket URL
+func realtimeAuthority(location *url.URL) string {
+	if location.Port() != "" {
+		return location.Host
+	}
+	if location.Scheme == "wss" {
+		return net.JoinHostPort(location.Hostname(), "443")
+	}
+	return net.JoinHostPort(location.Hostname(), "80")
+}
+
+// relayRealtime copies frames in both directions until either side closes, then records the session
+func (s *Server) relayRealtime(client, upstream *websocket.Conn, rule *typ.Rule, provider, model string) {
+	start := time.Now()
+	done := make(chan struct{}, 2)
+
+	go func() {
+		relayRealtimeFrames(upstream, client, func(frame *realtimeFrame) {
+			s.recordRealtimeUsage(frame, rule, provider, model)
+		})
+		done <- struct{}{}
+	}()
+	go func() {
+		relayRealtimeFrames(client, upstream, nil)
+		done <- struct{}{}
+	}()
+
+	// Closing both connections unblocks the other direction
+	<-done
+	client.Close()
+	upstream.Close()
+	<-done
+
+	if rule != nil && s.statsMW != nil {
+		s.statsMW.RecordRealtimeSessionOnRule(rule, provider, model, time.Since(start).Seconds())
+	}
+}
+
+// relayRealtimeFrames copies messages from src to dst until an error occurs
+func relayRealtimeFrames(src, dst *websocket.Conn, onFrame func(*realtimeFrame)) {
+	for {
+		var frame realtimeFrame
+		if err := realtimeFrameCodec.Receive(src, &frame); err != nil {
+			return
+		}
+		if onFrame != nil {
+			onFrame(&frame)
+		}
+		if err := realtimeFrameCodec.Send(dst, &frame); err != nil {
+			return
+		}
+	}
+}
+
+// recordRealtimeUsage records the token usage reported by `response.done` server events
+func (s *Server) recordRealtimeUsage(frame *realtimeFrame, rule *typ.Rule, provider, model string) {
+	if rule == nil || s.statsMW == nil || frame.payloadType != websocket.TextFrame {
+		return
+	}
+	if !bytes.Contains(frame.data, []byte(`"response.done"`)) {
+		return
+	}
+
+	var event struct {
+		Type     string `json:"type"`
+		Response struct {
+			Usage struct {
+				InputTokens  int `json:"input_tokens"`
+				OutputTokens int `json:"output_tokens"`
+			} `json:"usage"`
+		} `json:"response"`
+	}
+	if err := json.Unmarshal(frame.data, &event); err != nil || event.Type != "response.done" {
+		return
+	}
+	s.statsMW.RecordUsageOnRule(rule, provider, model, event.Response.Usage.InputTokens, event.Response.Usage.OutputTokens)
+}
+
+// selectRealtimeProtocol accepts any origin (API clients send none) and answers with the
+// "realtime" subprotocol when offered. Other subprotocols are never echoed, since the API key
+// carrier protocol would send the token back in the handshake.
+func selectRealtimeProtocol(config *websocket.Config, req *http.Request) error {
+	offered := config.Protocol
+	config.Protocol = nil
+	for _, protocol := range offered {
+		if protocol == "realtime" {
+			config.Protocol = []string{protocol}
+			break
+		}
+	}
+	return nil
+}
+
+// realtimeUpstreamURL builds the upstream WebSocket URL from the provider's API base,
+// keeping the client's query parameters but replacing the model
+func realtimeUpstreamURL(apiBase string, query url.Values, model string) (string, error) {
+	u, err := url.Parse(strings.TrimSuffix(apiBase, "/") + "/realtime")
+	if err != nil {
+		return "", err
+	}
+	switch u.Scheme {
+	case "https":
+		u.Scheme = "wss"
+	case "http":
+		u.Scheme = "ws"
+	}
+
+	values := url.Values{}
+	for k, v := range query {
+		values[k] = v
+	}
+	values.Set("model", model)
+	u.RawQuery = values.Encode()
+	return u.String(), nil
+}
+
+// azureRealtimeUpstreamURL builds the Azure OpenAI realtime URL, which selects the
+// deployment instead of the model and requires an api-version
+func azureRealtimeUpstreamURL(provider *typ.Provider, query url.Values, model string) (string, error) {
+	u, err := url.Parse(client.AzureEndpoint(provider.APIBase) + "openai/realtime")
+	if err != nil {
+		return "", err
+	}
+	switch u.Scheme {
+	case "https":
+		u.Scheme = "wss"
+	case "http":
+		u.Scheme = "ws"
+	}
+
+	values := url.Values{}
+	for k, v := range query {
+		values[k] = v
+	}
+	values.Del("model")
+	values.Set("deployment", provider.AzureDeployment(model))
+	if values.Get("api-version") == "" {
+		values.Set("api-version", provider.AzureAPIVersion())
+	}
+	u.RawQuery = values.Encode()
+	return u.String(), nil
+}
@@ -0,0 +1,175 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"tingly-box/internal/constant"
+	"tingly-box/internal/typ"
+	"tingly-box/pkg/adaptor"
+	"tingly-box/pkg/client"
+)
+
+// hopByHopHeaders are connection-scoped headers that must not be forwarded by a proxy
+var hopByHopHeaders = map[string]bool{
+	"Connection":          true,
+	"Keep-Alive":          true,
+	"Proxy-Authenticate":  true,
+	"Proxy-Authorization": true,
+	"Te":                  true,
+	"Trailer":             true,
+	"Transfer-Encoding":   true,
+	"Upgrade":             true,
+}
+
+// passthroughCredentialHeaders carry the gateway's own credentials and are replaced by the provider's
+var passthroughCredentialHeaders = map[string]bool{
+	"Authorization": true,
+	"X-Api-Key":     true,
+}
+
+// UsePassthroughEndpoints registers the raw passthrough proxy
+func (s *Server) UsePassthroughEndpoints() {
+	s.engine.Any("/passthrough/:provider_uuid/*path", s.authMW.ModelAuthMiddleware(), s.Passthrough)
+}
+
+// Passthrough forwards any request to a provider unchanged, replacing only the credentials.
+// The path after the provider UUID is appended to the provider's API base, e.g.
+// /passthrough/<uuid>/files/abc -> <api_base>/files/abc. The route is opt-in via enable_passthrough.
+func (s *Server) Passthrough(c *gin.Context) {
+	if !s.config.GetEnablePassthrough() {
+		gatewayErr := requestError("Passthrough proxy is disabled, set enable_passthrough to use it")
+		gatewayErr.Status, gatewayErr.Type = http.StatusNotFound, adaptor.ErrorTypeNotFound
+		gatewayErr.respond(c, typ.APIStyleOpenAI)
+		return
+	}
+
+	provider, err := s.config.GetProviderByUUID(c.Param("provider_uuid"))
+	if err != nil || provider == nil {
+		gatewayErr := routingError(fmt.Errorf("Provider not found: %s", c.Param("provider_uuid")))
+		gatewayErr.Status, gatewayErr.Type = http.StatusNotFound, adaptor.ErrorTypeNotFound
+		gatewayErr.respond(c, typ.APIStyleOpenAI)
+		return
+	}
+	if !provider.Enabled {
+		gatewayErr := routingError(fmt.Errorf("Provider is disabled: %s", provider.Name))
+		gatewayErr.Status, gatewayErr.Type = http.StatusForbidden, adaptor.ErrorTypePermission
+		gatewayErr.respond(c, provider.APIStyle)
+		return
+	}
+
+	timeout := time.Duration(provider.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = time.Duration(constant.DefaultRequestTimeout) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	defer cancel()
+
+	apiBase := provider.APIBase
+	if provider.IsBedrock() {
+		apiBase = client.BedrockEndpoint(provider.APIBase, provider.Region)
+	} else if provider.IsVertex() {
+		apiBase = client.VertexEndpoint(provider.APIBase, provider.Region)
+	}
+	targetURL := strings.TrimSuffix(apiBase, "/") + c.Param("path")
+	rawQuery := c.Request.URL.RawQuery
+	if provider.IsAzure() && c.Query("api-version") == "" {
+		// Azure OpenAI rejects requests without an api-version
+		query := c.Request.URL.Query()
+		query.Set("api-version", provider.AzureAPIVersion())
+		rawQuery = query.Encode()
+	}
+	if rawQuery != "" {
+		targetURL += "?" + rawQuery
+	}
+
+	req, err := http.NewRequestWithContext(ctx, c.Request.Method, targetURL, c.Request.Body)
+	if err != nil {
+		requestError("Invalid passthrough request: "+err.Error()).respond(c, provider.APIStyle)
+		return
+	}
+	req.ContentLength = c.Request.ContentLength
+	copyPassthroughHeaders(req.Header, c.Request.Header, passthroughCredentialHeaders)
+	if provider.IsBedrock() {
+		err = signBedrockPassthrough(req, provider)
+	} else {
+		setPassthroughCredentials(req, provider)
+	}
+	if err != nil {
+		upstreamError(fmt.Errorf("failed to sign passthrough request: %w", err)).respond(c, provider.APIStyle)
+		return
+	}
+
+	logrus.Infof("passthrough: %s %s -> %s", c.Request.Method, c.Param("path"), provider.Name)
+	resp, err := s.clientPool.GetHTTPClient(provider).Do(req)
+	if err != nil {
+		upstreamError(err).respond(c, provider.APIStyle)
+		return
+	}
+	defer resp.Body.Close()
+
+	copyPassthroughHeaders(c.Writer.Header(), resp.Header, nil)
+	c.Status(resp.StatusCode)
+	streamResponseBody(c, resp.Body)
+}
+
+// copyPassthroughHeaders copies end-to-end headers, skipping hop-by-hop and excluded headers
+func copyPassthroughHeaders(dst, src http.Header, exclude map[string]bool) {
+	for name, values := range src {
+		name = http.CanonicalHeaderKey(name)
+		if hopByHopHeaders[name] || exclude[name] {
+			continue
+		}
+		for _, v := range values {
+			dst.Add(name, v)
+		}
+	}
+}
+
+// setPassthroughCredentials injects the provider's credentials in the header its API style expects.
+// OAuth hooks from pkg/client run afterwards in the HTTP client and may rewrite them.
+func setPassthroughCredentials(req *http.Request, provider *typ.Provider) {
+	token := provider.GetAccessToken()
+	if token == "" {
+		return
+	}
+	if provider.IsAzure() {
+		client.SetAzureAuth(req.Header, token)
+		return
+	}
+	if provider.APIStyle == typ.APIStyleAnthropic && !provider.IsVertex() {
+		req.Header.Set("X-Api-Key", token)
+		if req.Header.Get("Anthropic-Version") == "" {
+			req.Header.Set("Anthropic-Version", "2023-06-01")
+		}
+		return
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+}
+
+// signBedrockPassthrough signs a request for Amazon Bedrock. SigV4 covers the payload,
+// so the body is buffered before signing.
+func signBedrockPassthrough(req *http.Request, provider *typ.Provider) error {
+	creds, err := client.ResolveAWSCredentials(provider.AWSAccessKeyID, provider.AWSSecretAccessKey, provider.AWSSessionToken, provider.AWSProfile)
+	if err != nil {
+		return err
+	}
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.ContentLength = int64(len(body))
+	}
+	client.SignBedrockRequest(req, body, creds, provider.Region)
+	return nil
+}
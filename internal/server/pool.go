@@ -4,6 +4,7 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"net/http"
 	"strings"
 	"sync"
 
@@ -22,6 +23,7 @@ import (
 type ClientPool struct {
 	openaiClients    map[string]*openai.Client
 	anthropicClients map[string]anthropic.Client
+	httpClients      map[string]*http.Client
 	mutex            sync.RWMutex
 }
 
@@ -30,6 +32,7 @@ func NewClientPool() *ClientPool {
 	return &ClientPool{
 		openaiClients:    make(map[string]*openai.Client),
 		anthropicClients: make(map[string]anthropic.Client),
+		httpClients:      make(map[string]*http.Client),
 	}
 }
 
@@ -61,9 +64,18 @@ func (p *ClientPool) GetOpenAIClient(provider *typ.Provider) *openai.Client {
 	// Create new client with proxy support if configured
 	logrus.Infof("Creating new OpenAI client for provider: %s (API: %s)", provider.Name, provider.APIBase)
 
-	options := []openaiOption.RequestOption{
-		openaiOption.WithAPIKey(provider.GetAccessToken()),
-		openaiOption.WithBaseURL(provider.APIBase),
+	var options []openaiOption.RequestOption
+	if provider.IsAzure() {
+		options = client.WithAzureOpenAI(provider.APIBase, provider.GetAccessToken(), provider.AzureAPIVersion(), provider.AzureDeployment)
+		logrus.Infof("Using Azure OpenAI deployments (api-version: %s)", provider.AzureAPIVersion())
+	} else if provider.IsVertex() {
+		options = client.WithVertexGemini(provider.APIBase, provider.Region, provider.VertexProjectID(), provider.GetAccessToken())
+		logrus.Infof("Using Vertex AI generateContent API (project: %s, region: %s)", provider.VertexProjectID(), provider.Region)
+	} else {
+		options = []openaiOption.RequestOption{
+			openaiOption.WithAPIKey(provider.GetAccessToken()),
+			openaiOption.WithBaseURL(provider.APIBase),
+		}
 	}
 
 	// Add proxy if configured
@@ -113,9 +125,20 @@ func (p *ClientPool) GetAnthropicClient(provider *typ.Provider) anthropic.Client
 
 	logrus.Infof("Creating new Anthropic client for provider: %s (API: %s)", provider.Name, apiBase)
 
-	options := []anthropicOption.RequestOption{
-		anthropicOption.WithAPIKey(provider.GetAccessToken()),
-		anthropicOption.WithBaseURL(apiBase),
+	var options []anthropicOption.RequestOption
+	if provider.IsBedrock() {
+		options = client.WithBedrock(provider.APIBase, provider.Region, func() (client.AWSCredentials, error) {
+			return client.ResolveAWSCredentials(provider.AWSAccessKeyID, provider.AWSSecretAccessKey, provider.AWSSessionToken, provider.AWSProfile)
+		})
+		logrus.Infof("Using Amazon Bedrock InvokeModel API (region: %s)", provider.Region)
+	} else if provider.IsVertex() {
+		options = client.WithVertexAnthropic(provider.APIBase, provider.Region, provider.VertexProjectID(), provider.GetAccessToken())
+		logrus.Infof("Using Vertex AI rawPredict API (project: %s, region: %s)", provider.VertexProjectID(), provider.Region)
+	} else {
+		options = []anthropicOption.RequestOption{
+			anthropicOption.WithAPIKey(provider.GetAccessToken()),
+			anthropicOption.WithBaseURL(apiBase),
+		}
 	}
 
 	// Add proxy and/or custom headers if configured
@@ -143,10 +166,53 @@ func (p *ClientPool) GetAnthropicClient(provider *typ.Provider) anthropic.Client
 	return anthropicClient
 }
 
+// GetHTTPClient returns a plain HTTP client for the specified provider with its proxy
+// settings and OAuth hooks applied, used for requests that bypass the SDK clients
+func (p *ClientPool) GetHTTPClient(provider *typ.Provider) *http.Client {
+	key := p.generateProviderKey(provider)
+
+	p.mutex.RLock()
+	if httpClient, exists := p.httpClients[key]; exists {
+		p.mutex.RUnlock()
+		return httpClient
+	}
+	p.mutex.RUnlock()
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if httpClient, exists := p.httpClients[key]; exists {
+		return httpClient
+	}
+
+	var providerType oauth.ProviderType
+	if provider.OAuthDetail != nil {
+		providerType = oauth.ProviderType(provider.OAuthDetail.ProviderType)
+	}
+	httpClient := client.CreateHTTPClientForProvider(providerType, provider.ProxyURL, provider.AuthType == typ.AuthTypeOAuth)
+
+	p.httpClients[key] = httpClient
+	return httpClient
+}
+
 // generateProviderKey creates a unique key for a provider
 // Uses combination of name, API base, hash of the token, and proxy URL for uniqueness
 func (p *ClientPool) generateProviderKey(provider *typ.Provider) string {
-	return fmt.Sprintf("%s:%s:%s:%s", provider.Name, provider.APIBase, hashToken(provider.GetAccessToken()), hashToken(provider.ProxyURL))
+	key := fmt.Sprintf("%s:%s:%s:%s", provider.Name, provider.APIBase, hashToken(provider.GetAccessToken()), hashToken(provider.ProxyURL))
+	if provider.IsAzure() {
+		// Deployment mapping and API version are baked into Azure clients
+		key += fmt.Sprintf(":azure:%s:%v", provider.AzureAPIVersion(), provider.Deployments)
+	}
+	if provider.IsVertex() {
+		// Project and region are baked into Vertex clients
+		key += fmt.Sprintf(":vertex:%s:%s", provider.VertexProjectID(), provider.Region)
+	}
+	if provider.IsBedrock() {
+		// Region and credentials are baked into Bedrock clients
+		key += fmt.Sprintf(":bedrock:%s:%s:%s:%s", provider.Region, provider.AWSAccessKeyID,
+			hashToken(provider.AWSSecretAccessKey+provider.AWSSessionToken), provider.AWSProfile)
+	}
+	return key
 }
 
 // hashToken creates a secure hash of the token for key generation
@@ -169,6 +235,7 @@ func (p *ClientPool) Clear() {
 
 	p.openaiClients = make(map[string]*openai.Client)
 	p.anthropicClients = make(map[string]anthropic.Client)
+	p.httpClients = make(map[string]*http.Client)
 	logrus.Info("Client pools cleared")
 }
 
@@ -188,6 +255,10 @@ func (p *ClientPool) RemoveProvider(provider *typ.Provider) {
 		delete(p.anthropicClients, key)
 		removed = true
 	}
+	if _, exists := p.httpClients[key]; exists {
+		delete(p.httpClients, key)
+		removed = true
+	}
 
 	if removed {
 		logrus.Infof("Removed clients for provider: %s", provider.Name)
@@ -15,13 +15,22 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/openai/openai-go/v3"
 
+	"tingly-box/internal/helper"
 	"tingly-box/internal/obs"
 	"tingly-box/internal/typ"
+	"tingly-box/pkg/client"
 )
 
 // ClaudeCodeSystemHeader MENTION: this a special process for subscriptions
 const ClaudeCodeSystemHeader = "You are Claude Code, Anthropic's official CLI for Claude."
 
+// Widely available models used to probe connectivity of providers without a default model
+const (
+	bedrockProbeModel         = "anthropic.claude-3-haiku-20240307-v1:0"
+	vertexAnthropicProbeModel = "claude-3-5-haiku@20241022"
+	vertexGeminiProbeModel    = "gemini-2.0-flash"
+)
+
 // HandleProbeProvider tests a provider's API key and connectivity
 func (s *Server) HandleProbeProvider(c *gin.Context) {
 	var req ProbeProviderRequest
@@ -114,6 +123,17 @@ func (s *Server) testProviderConnectivity(req *ProbeProviderRequest) (bool, stri
 		APIStyle: typ.APIStyle(req.APIStyle),
 		Token:    req.Token,
 		Enabled:  true,
+
+		Flavor:      typ.ProviderFlavor(req.Flavor),
+		APIVersion:  req.APIVersion,
+		Deployments: req.Deployments,
+
+		Region:             req.Region,
+		AWSAccessKeyID:     req.AWSAccessKeyID,
+		AWSSecretAccessKey: req.AWSSecretAccessKey,
+		AWSSessionToken:    req.AWSSessionToken,
+		AWSProfile:         req.AWSProfile,
+		ProjectID:          req.ProjectID,
 	}
 
 	var lastErr error
@@ -147,6 +167,10 @@ func (s *Server) testProviderConnectivity(req *ProbeProviderRequest) (bool, stri
 
 // getProviderModelsForProbe is a simplified version of getProviderModelsFromAPI for probing
 func (s *Server) getProviderModelsForProbe(provider *typ.Provider) ([]string, error) {
+	if provider.IsAzure() || provider.IsBedrock() || provider.IsVertex() {
+		return helper.GetProviderModelsFromAPI(provider)
+	}
+
 	// Construct the models endpoint URL
 	apiBase := strings.TrimSuffix(provider.APIBase, "/")
 	if provider.APIStyle == typ.APIStyleAnthropic {
@@ -398,6 +422,16 @@ func (s *Server) probeChatEndpoint(provider *typ.Provider) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	if provider.IsAzure() {
+		return s.probeAzureChat(ctx, provider)
+	}
+	if provider.IsBedrock() {
+		return s.probeBedrockChat(ctx, provider)
+	}
+	if provider.IsVertex() {
+		return s.probeVertexChat(ctx, provider)
+	}
+
 	switch provider.APIStyle {
 	case typ.APIStyleOpenAI:
 		return s.probeOpenAIChat(ctx, provider)
@@ -419,7 +453,9 @@ func (s *Server) probeOptionsEndpoint(provider *typ.Provider) error {
 	}
 
 	// Set authentication headers
-	if provider.APIStyle == typ.APIStyleAnthropic {
+	if provider.IsAzure() {
+		client.SetAzureAuth(req.Header, provider.Token)
+	} else if provider.APIStyle == typ.APIStyleAnthropic {
 		req.Header.Set("x-api-key", provider.Token)
 		req.Header.Set("anthropic-version", "2023-06-01")
 	} else {
@@ -485,6 +521,96 @@ func (s *Server) probeOpenAIChat(ctx context.Context, provider *typ.Provider) er
 	return fmt.Errorf("chat endpoint failed with status: %d", resp.StatusCode)
 }
 
+// probeAzureChat tests an Azure OpenAI deployment with minimal message.
+// Azure has no default model, so the first configured deployment is used.
+func (s *Server) probeAzureChat(ctx context.Context, provider *typ.Provider) error {
+	var deployment string
+	for _, d := range provider.Deployments {
+		if deployment == "" || d < deployment {
+			deployment = d
+		}
+	}
+	if deployment == "" {
+		return fmt.Errorf("no Azure deployment configured to probe")
+	}
+
+	requestBody := map[string]interface{}{
+		"messages": []map[string]string{
+			{"role": "user", "content": "test"},
+		},
+		"max_tokens": 5,
+	}
+	bodyBytes, err := json.Marshal(requestBody)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	chatURL := client.AzureURL(provider.APIBase, "/deployments/"+url.PathEscape(deployment)+"/chat/completions", provider.AzureAPIVersion())
+	req, err := http.NewRequestWithContext(ctx, "POST", chatURL, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	client.SetAzureAuth(req.Header, provider.Token)
+	req.Header.Set("Content-Type", "application/json")
+
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("chat request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusTooManyRequests {
+		return nil
+	}
+
+	return fmt.Errorf("chat endpoint failed with status: %d", resp.StatusCode)
+}
+
+// probeBedrockChat tests Amazon Bedrock with a minimal message through the signing client
+func (s *Server) probeBedrockChat(ctx context.Context, provider *typ.Provider) error {
+	anthropicClient := s.clientPool.GetAnthropicClient(provider)
+	_, err := anthropicClient.Messages.New(ctx, anthropic.MessageNewParams{
+		Model: anthropic.Model(bedrockProbeModel),
+		Messages: []anthropic.MessageParam{
+			anthropic.NewUserMessage(anthropic.NewTextBlock("test")),
+		},
+		MaxTokens: 5,
+	})
+	if err != nil {
+		return fmt.Errorf("bedrock invoke failed: %w", err)
+	}
+	return nil
+}
+
+// probeVertexChat tests Vertex AI with a minimal message through the publisher's client
+func (s *Server) probeVertexChat(ctx context.Context, provider *typ.Provider) error {
+	var err error
+	if provider.APIStyle == typ.APIStyleAnthropic {
+		anthropicClient := s.clientPool.GetAnthropicClient(provider)
+		_, err = anthropicClient.Messages.New(ctx, anthropic.MessageNewParams{
+			Model: anthropic.Model(vertexAnthropicProbeModel),
+			Messages: []anthropic.MessageParam{
+				anthropic.NewUserMessage(anthropic.NewTextBlock("test")),
+			},
+			MaxTokens: 5,
+		})
+	} else {
+		openaiClient := s.clientPool.GetOpenAIClient(provider)
+		_, err = openaiClient.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
+			Model: openai.ChatModel(vertexGeminiProbeModel),
+			Messages: []openai.ChatCompletionMessageParamUnion{
+				openai.UserMessage("test"),
+			},
+			MaxTokens: openai.Int(5),
+		})
+	}
+	if err != nil {
+		return fmt.Errorf("vertex request failed: %w", err)
+	}
+	return nil
+}
+
 // probeAnthropicChat tests Anthropic messages endpoint with minimal message
 func (s *Server) probeAnthropicChat(ctx context.Context, provider *typ.Provider) error {
 	apiBase := strings.TrimSuffix(provider.APIBase, "/")
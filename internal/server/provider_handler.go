@@ -9,18 +9,32 @@ import (
 
 	"tingly-box/internal/obs"
 	"tingly-box/internal/typ"
+	"tingly-box/pkg/adaptor"
 )
 
 // maskProviderForResponse masks sensitive data and returns a safe ProviderResponse
 func maskProviderForResponse(provider *typ.Provider) ProviderResponse {
 	resp := ProviderResponse{
-		UUID:          provider.UUID,
-		Name:          provider.Name,
-		APIBase:       provider.APIBase,
-		APIStyle:      string(provider.APIStyle),
-		NoKeyRequired: provider.NoKeyRequired,
-		Enabled:       provider.Enabled,
-		AuthType:      string(provider.AuthType),
+		UUID:             provider.UUID,
+		Name:             provider.Name,
+		APIBase:          provider.APIBase,
+		APIStyle:         string(provider.APIStyle),
+		NoKeyRequired:    provider.NoKeyRequired,
+		Enabled:          provider.Enabled,
+		AuthType:         string(provider.AuthType),
+		Flavor:           string(provider.Flavor),
+		APIVersion:       provider.APIVersion,
+		Deployments:      provider.Deployments,
+		Region:           provider.Region,
+		AWSAccessKeyID:   provider.AWSAccessKeyID,
+		AWSProfile:       provider.AWSProfile,
+		ProjectID:        provider.ProjectID,
+		InlineImages:     provider.InlineImages,
+		DocumentFallback: provider.DocumentFallback,
+
+		ForwardCacheControl: provider.ForwardCacheControl,
+		Dialect:             provider.Dialect,
+		ToolSchema:          provider.ToolSchema,
 	}
 
 	switch provider.AuthType {
@@ -85,6 +99,21 @@ func (s *Server) CreateProvider(c *gin.Context) {
 		return
 	}
 
+	if err := adaptor.ValidateDialect(adaptor.Dialect(req.Dialect)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+	if err := adaptor.ValidateDocumentFallback(adaptor.DocumentFallback(req.DocumentFallback)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
 	// Backend verification: Verify provider connection before saving (skip if no key required)
 	// This is a safety measure in addition to frontend verification
 	if !req.NoKeyRequired && req.Token != "" {
@@ -93,6 +122,17 @@ func (s *Server) CreateProvider(c *gin.Context) {
 			APIBase:  req.APIBase,
 			APIStyle: req.APIStyle,
 			Token:    req.Token,
+
+			Flavor:      req.Flavor,
+			APIVersion:  req.APIVersion,
+			Deployments: req.Deployments,
+
+			Region:             req.Region,
+			AWSAccessKeyID:     req.AWSAccessKeyID,
+			AWSSecretAccessKey: req.AWSSecretAccessKey,
+			AWSSessionToken:    req.AWSSessionToken,
+			AWSProfile:         req.AWSProfile,
+			ProjectID:          req.ProjectID,
 		}
 		success, message, _, err := s.testProviderConnectivity(probeReq)
 		if err != nil || !success {
@@ -133,13 +173,32 @@ func (s *Server) CreateProvider(c *gin.Context) {
 		return
 	}
 	provider := &typ.Provider{
-		UUID:          uid.String(),
-		Name:          req.Name,
-		APIBase:       req.APIBase,
-		APIStyle:      typ.APIStyle(req.APIStyle),
-		Token:         req.Token,
-		NoKeyRequired: req.NoKeyRequired,
-		Enabled:       req.Enabled,
+		UUID:               uid.String(),
+		Name:               req.Name,
+		APIBase:            req.APIBase,
+		APIStyle:           typ.APIStyle(req.APIStyle),
+		Token:              req.Token,
+		NoKeyRequired:      req.NoKeyRequired,
+		Enabled:            req.Enabled,
+		Flavor:             typ.ProviderFlavor(req.Flavor),
+		APIVersion:         req.APIVersion,
+		Deployments:        req.Deployments,
+		Region:             req.Region,
+		AWSAccessKeyID:     req.AWSAccessKeyID,
+		AWSSecretAccessKey: req.AWSSecretAccessKey,
+		AWSSessionToken:    req.AWSSessionToken,
+		AWSProfile:         req.AWSProfile,
+		ProjectID:          req.ProjectID,
+		InlineImages:       req.InlineImages,
+		DocumentFallback:   req.DocumentFallback,
+
+		ForwardCacheControl: req.ForwardCacheControl,
+		Dialect:             req.Dialect,
+		ToolSchema:          req.ToolSchema,
+	}
+
+	if provider.ToolSchema != nil && provider.ToolSchema.IsEmpty() {
+		provider.ToolSchema = nil
 	}
 
 	err = s.config.AddProvider(provider)
@@ -281,6 +340,65 @@ func (s *Server) UpdateProvider(c *gin.Context) {
 	if req.Enabled != nil {
 		provider.Enabled = *req.Enabled
 	}
+	if req.Flavor != nil {
+		provider.Flavor = typ.ProviderFlavor(*req.Flavor)
+	}
+	if req.APIVersion != nil {
+		provider.APIVersion = *req.APIVersion
+	}
+	if req.Deployments != nil {
+		provider.Deployments = req.Deployments
+	}
+	if req.Region != nil {
+		provider.Region = *req.Region
+	}
+	if req.AWSAccessKeyID != nil {
+		provider.AWSAccessKeyID = *req.AWSAccessKeyID
+	}
+	if req.AWSSecretAccessKey != nil {
+		provider.AWSSecretAccessKey = *req.AWSSecretAccessKey
+	}
+	if req.AWSSessionToken != nil {
+		provider.AWSSessionToken = *req.AWSSessionToken
+	}
+	if req.AWSProfile != nil {
+		provider.AWSProfile = *req.AWSProfile
+	}
+	if req.ProjectID != nil {
+		provider.ProjectID = *req.ProjectID
+	}
+	if req.InlineImages != nil {
+		provider.InlineImages = *req.InlineImages
+	}
+	if req.DocumentFallback != nil {
+		if err := adaptor.ValidateDocumentFallback(adaptor.DocumentFallback(*req.DocumentFallback)); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   err.Error(),
+			})
+			return
+		}
+		provider.DocumentFallback = *req.DocumentFallback
+	}
+	if req.ForwardCacheControl != nil {
+		provider.ForwardCacheControl = *req.ForwardCacheControl
+	}
+	if req.Dialect != nil {
+		if err := adaptor.ValidateDialect(adaptor.Dialect(*req.Dialect)); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   err.Error(),
+			})
+			return
+		}
+		provider.Dialect = *req.Dialect
+	}
+	if req.ToolSchema != nil {
+		provider.ToolSchema = req.ToolSchema
+		if req.ToolSchema.IsEmpty() {
+			provider.ToolSchema = nil
+		}
+	}
 
 	err = s.config.UpdateProvider(uid, provider)
 	if err != nil {
@@ -0,0 +1,282 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	openaiOption "github.com/openai/openai-go/v3/option"
+	"github.com/sirupsen/logrus"
+
+	"tingly-box/internal/loadbalance"
+	"tingly-box/internal/typ"
+)
+
+// rawRequest holds a request body that is forwarded upstream without being decoded
+// into SDK types, e.g. image and audio uploads sent as multipart/form-data
+type rawRequest struct {
+	body        []byte
+	contentType string
+	fields      map[string]string // plain (non-file) fields, used for routing and usage accounting
+}
+
+// isMultipart reports whether the request body is multipart/form-data
+func (r *rawRequest) isMultipart() bool {
+	mediaType, _, _ := mime.ParseMediaType(r.contentType)
+	return mediaType == "multipart/form-data"
+}
+
+// readRawRequest reads the request body and extracts its plain fields from either
+// a JSON object or a multipart/form-data body
+func readRawRequest(c *gin.Context) (*rawRequest, error) {
+	body, err := c.GetRawData()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+
+	req := &rawRequest{
+		body:        body,
+		contentType: c.GetHeader("Content-Type"),
+		fields:      make(map[string]string),
+	}
+
+	if req.isMultipart() {
+		err = req.readMultipartFields()
+	} else {
+		err = req.readJSONFields()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// readJSONFields collects top-level scalar fields of a JSON body
+func (r *rawRequest) readJSONFields() error {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(r.body, &raw); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	for k, v := range raw {
+		switch tv := v.(type) {
+		case string:
+			r.fields[k] = tv
+		case float64, bool:
+			r.fields[k] = fmt.Sprintf("%v", tv)
+		}
+	}
+	return nil
+}
+
+// readMultipartFields collects the non-file parts of a multipart body
+func (r *rawRequest) readMultipartFields() error {
+	reader, err := r.multipartReader()
+	if err != nil {
+		return err
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid multipart body: %w", err)
+		}
+		if part.FileName() == "" && part.FormName() != "" {
+			value, err := io.ReadAll(part)
+			if err != nil {
+				return fmt.Errorf("invalid multipart field %s: %w", part.FormName(), err)
+			}
+			r.fields[part.FormName()] = string(value)
+		}
+		part.Close()
+	}
+}
+
+// multipartReader creates a reader over the buffered multipart body
+func (r *rawRequest) multipartReader() (*multipart.Reader, error) {
+	_, params, err := mime.ParseMediaType(r.contentType)
+	if err != nil {
+		return nil, fmt.Errorf("invalid content type: %w", err)
+	}
+	boundary := params["boundary"]
+	if boundary == "" {
+		return nil, errors.New("multipart boundary is missing")
+	}
+	return multipart.NewReader(bytes.NewReader(r.body), boundary), nil
+}
+
+// setField replaces a plain field in the body, keeping every other field and file part as is
+func (r *rawRequest) setField(name, value string) error {
+	if r.isMultipart() {
+		if err := r.setMultipartField(name, value); err != nil {
+			return err
+		}
+	} else {
+		var raw map[string]interface{}
+		if err := json.Unmarshal(r.body, &raw); err != nil {
+			return fmt.Errorf("invalid JSON: %w", err)
+		}
+		raw[name] = value
+		body, err := json.Marshal(raw)
+		if err != nil {
+			return err
+		}
+		r.body = body
+	}
+	r.fields[name] = value
+	return nil
+}
+
+// setMultipartField rebuilds the multipart body with the given field replaced
+func (r *rawRequest) setMultipartField(name, value string) error {
+	reader, err := r.multipartReader()
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	replaced := false
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("invalid multipart body: %w", err)
+		}
+
+		if part.FileName() == "" && part.FormName() == name {
+			if err := writer.WriteField(name, value); err != nil {
+				return err
+			}
+			replaced = true
+			part.Close()
+			continue
+		}
+
+		dst, err := writer.CreatePart(part.Header)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(dst, part); err != nil {
+			return err
+		}
+		part.Close()
+	}
+	if !replaced {
+		if err := writer.WriteField(name, value); err != nil {
+			return err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	r.body = buf.Bytes()
+	r.contentType = writer.FormDataContentType()
+	return nil
+}
+
+// forwardOpenAIRawRequest posts a raw body to an OpenAI-style provider using the pooled client,
+// so credentials, proxy settings and OAuth hooks are applied as for chat requests.
+// The caller owns the returned response body.
+func (s *Server) forwardOpenAIRawRequest(ctx context.Context, provider *typ.Provider, path string, req *rawRequest) (*http.Response, error) {
+	client := s.clientPool.GetOpenAIClient(provider)
+	logrus.Infof("provider: %s (%s)", provider.Name, path)
+
+	var resp *http.Response
+	err := client.Post(ctx, path, nil, &resp,
+		openaiOption.WithRequestBody(req.contentType, req.body),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// copyRawUpstreamResponse copies an upstream response to the client unchanged
+func copyRawUpstreamResponse(c *gin.Context, resp *http.Response) {
+	defer resp.Body.Close()
+
+	for _, header := range []string{"Content-Type", "Content-Length", "Content-Disposition"} {
+		if v := resp.Header.Get(header); v != "" {
+			c.Header(header, v)
+		}
+	}
+	c.Status(resp.StatusCode)
+	streamResponseBody(c, resp.Body)
+}
+
+// streamResponseBody copies a response body to the client, flushing after every read
+// so streamed and chunked responses are delivered as they arrive
+func streamResponseBody(c *gin.Context, body io.Reader) {
+	flusher, canFlush := c.Writer.(http.Flusher)
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := body.Read(buf)
+		if n > 0 {
+			if _, werr := c.Writer.Write(buf[:n]); werr != nil {
+				logrus.Debugf("Failed to write upstream response: %v", werr)
+				return
+			}
+			if canFlush {
+				flusher.Flush()
+			}
+		}
+		if err != nil {
+			if err != io.EOF {
+				logrus.Errorf("Failed to read upstream response: %v", err)
+			}
+			return
+		}
+	}
+}
+
+// isOpenAIStyle reports whether the provider speaks the OpenAI API (the default style)
+func isOpenAIStyle(provider *typ.Provider) bool {
+	return provider.APIStyle == "" || provider.APIStyle == typ.APIStyleOpenAI
+}
+
+// routeRawRequest resolves the rule for the requested model and selects an OpenAI-style
+// provider for it, writing an error response and returning ok=false on failure
+func (s *Server) routeRawRequest(c *gin.Context, req *rawRequest, endpoint string) (*typ.Provider, *loadbalance.Service, bool) {
+	model := req.fields["model"]
+	if model == "" {
+		requestError("Model is required").respond(c, typ.APIStyleOpenAI)
+		return nil, nil, false
+	}
+
+	provider, selectedService, rule, err := s.DetermineProviderAndModel(model)
+	if err != nil {
+		routingError(err).respond(c, typ.APIStyleOpenAI)
+		return nil, nil, false
+	}
+
+	if !isOpenAIStyle(provider) {
+		adaptorError(http.StatusUnprocessableEntity, fmt.Sprintf("The %s endpoint requires an OpenAI-style provider, but rule '%s' selected '%s' (%s)", endpoint, model, provider.Name, provider.APIStyle)).respond(c, typ.APIStyleOpenAI)
+		return nil, nil, false
+	}
+
+	// Set the rule and provider in context so middleware can use the same rule
+	if rule != nil {
+		c.Set("rule", rule)
+	}
+	c.Set("provider", provider.UUID)
+	c.Set("model", selectedService.Model)
+
+	if err := req.setField("model", selectedService.Model); err != nil {
+		requestError("Failed to rewrite request: "+err.Error()).respond(c, typ.APIStyleOpenAI)
+		return nil, nil, false
+	}
+
+	return provider, selectedService, true
+}
@@ -17,6 +17,7 @@ import (
 	"tingly-box/internal/config"
 	"tingly-box/internal/config/template"
 	"tingly-box/internal/constant"
+	"tingly-box/internal/db"
 	"tingly-box/internal/obs"
 	"tingly-box/internal/server/background"
 	"tingly-box/internal/server/middleware"
@@ -50,6 +51,10 @@ type Server struct {
 	// OAuth refresher for OAuth auto-refresh
 	oauthRefresher *background.OAuthRefresher
 
+	// local batch emulation (OpenAI batches, Anthropic message batches)
+	batchStore  *db.BatchStore
+	batchWorker *background.BatchWorker
+
 	// template manager for provider templates
 	templateManager *template.TemplateManager
 
@@ -228,6 +233,15 @@ func NewServer(cfg *config.Config, opts ...ServerOption) *Server {
 	server.oauthManager = oauthManager
 	server.oauthRefresher = tokenRefresher
 
+	// Initialize batch store next to the stats database and the worker executing its batches
+	batchStore, err := db.NewBatchStore(filepath.Join(cfg.ConfigDir, constant.StateDirName))
+	if err != nil {
+		log.Printf("Warning: Failed to initialize batch store, batch endpoints disabled: %v", err)
+	} else {
+		server.batchStore = batchStore
+		server.batchWorker = background.NewBatchWorker(batchStore, server.dispatchBatchRequest, cfg.GetBatchConcurrency())
+	}
+
 	// Initialize template manager with GitHub URL for template sync
 	templateManager := template.NewDefaultTemplateManager()
 	if err := templateManager.Initialize(); err != nil {
@@ -316,6 +330,8 @@ func (s *Server) setupRoutes() {
 
 	s.UseAIEndpoints()
 
+	s.UsePassthroughEndpoints()
+
 	s.UseLoadBalanceEndpoints()
 }
 
@@ -353,8 +369,29 @@ func (s *Server) SetupMixinEndpoints(group *gin.RouterGroup) {
 func (s *Server) SetupOpenAIEndpoints(group *gin.RouterGroup) {
 	// Chat completions endpoint (OpenAI compatible)
 	group.POST("/chat/completions", s.authMW.ModelAuthMiddleware(), s.OpenAIChatCompletions)
+	// Count tokens endpoint (counted locally with the upstream model's tokenizer)
+	group.POST("/chat/completions/count_tokens", s.authMW.ModelAuthMiddleware(), s.OpenAICountTokens)
 	// Models endpoint (OpenAI compatible)
 	group.GET("/models", s.authMW.ModelAuthMiddleware(), s.OpenAIListModels)
+	// Image endpoints (OpenAI compatible, forwarded to OpenAI-style providers only)
+	group.POST("/images/generations", s.authMW.ModelAuthMiddleware(), s.OpenAIImageGenerations)
+	group.POST("/images/edits", s.authMW.ModelAuthMiddleware(), s.OpenAIImageEdits)
+	// Audio endpoints (OpenAI compatible, forwarded to OpenAI-style providers only)
+	group.POST("/audio/transcriptions", s.authMW.ModelAuthMiddleware(), s.OpenAIAudioTranscriptions)
+	group.POST("/audio/translations", s.authMW.ModelAuthMiddleware(), s.OpenAIAudioTranslations)
+	group.POST("/audio/speech", s.authMW.ModelAuthMiddleware(), s.OpenAIAudioSpeech)
+	// Files and batches endpoints (OpenAI compatible, emulated locally for any provider)
+	group.POST("/files", s.authMW.ModelAuthMiddleware(), s.OpenAIUploadFile)
+	group.GET("/files", s.authMW.ModelAuthMiddleware(), s.OpenAIListFiles)
+	group.GET("/files/:file_id", s.authMW.ModelAuthMiddleware(), s.OpenAIGetFile)
+	group.GET("/files/:file_id/content", s.authMW.ModelAuthMiddleware(), s.OpenAIGetFileContent)
+	group.DELETE("/files/:file_id", s.authMW.ModelAuthMiddleware(), s.OpenAIDeleteFile)
+	group.POST("/batches", s.authMW.ModelAuthMiddleware(), s.OpenAICreateBatch)
+	group.GET("/batches", s.authMW.ModelAuthMiddleware(), s.OpenAIListBatches)
+	group.GET("/batches/:batch_id", s.authMW.ModelAuthMiddleware(), s.OpenAIGetBatch)
+	group.POST("/batches/:batch_id/cancel", s.authMW.ModelAuthMiddleware(), s.OpenAICancelBatch)
+	// Realtime endpoint (OpenAI compatible WebSocket, relayed to OpenAI-style providers only)
+	group.GET("/realtime", RealtimeSubprotocolAuth(), s.authMW.ModelAuthMiddleware(), s.OpenAIRealtime)
 }
 
 func (s *Server) SetupAnthropicEndpoints(group *gin.RouterGroup) {
@@ -364,6 +401,12 @@ func (s *Server) SetupAnthropicEndpoints(group *gin.RouterGroup) {
 	group.POST("/messages/count_tokens", s.authMW.ModelAuthMiddleware(), s.AnthropicCountTokens)
 	// Models endpoint (Anthropic compatible)
 	group.GET("/models", s.authMW.ModelAuthMiddleware(), s.AnthropicListModels)
+	// Message batches endpoints (Anthropic compatible, emulated locally for any provider)
+	group.POST("/messages/batches", s.authMW.ModelAuthMiddleware(), s.AnthropicCreateMessageBatch)
+	group.GET("/messages/batches", s.authMW.ModelAuthMiddleware(), s.AnthropicListMessageBatches)
+	group.GET("/messages/batches/:batch_id", s.authMW.ModelAuthMiddleware(), s.AnthropicGetMessageBatch)
+	group.POST("/messages/batches/:batch_id/cancel", s.authMW.ModelAuthMiddleware(), s.AnthropicCancelMessageBatch)
+	group.GET("/messages/batches/:batch_id/results", s.authMW.ModelAuthMiddleware(), s.AnthropicMessageBatchResults)
 }
 
 func (s *Server) UseLoadBalanceEndpoints() {
@@ -386,6 +429,11 @@ func (s *Server) Start(port int) error {
 		log.Println("OAuth token auto-refresh started")
 	}
 
+	if s.batchWorker != nil {
+		go s.batchWorker.Start(ctx)
+		log.Println("Batch worker started")
+	}
+
 	// Start configuration watcher
 	if s.watcher != nil {
 		if err := s.watcher.Start(); err != nil {
@@ -485,6 +533,11 @@ func (s *Server) Stop(ctx context.Context) error {
 		log.Println("OAuth token auto-refresh stopped")
 	}
 
+	// Stop batch worker; unfinished requests resume on next start
+	if s.batchWorker != nil {
+		s.batchWorker.Stop()
+	}
+
 	// Stop debug middleware
 	if s.errorMW != nil {
 		s.errorMW.Stop()
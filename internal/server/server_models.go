@@ -13,6 +13,8 @@ import (
 
 // ErrorResponse represents an error response
 type ErrorResponse struct {
+	// Type is "error" for Anthropic clients
+	Type  string      `json:"type,omitempty"`
 	Error ErrorDetail `json:"error"`
 }
 
@@ -21,6 +23,8 @@ type ErrorDetail struct {
 	Message string `json:"message"`
 	Type    string `json:"type"`
 	Code    string `json:"code,omitempty"`
+	// GatewayCode tells which part of the gateway failed the request
+	GatewayCode string `json:"gateway_code,omitempty"`
 }
 
 // =============================================
@@ -162,6 +166,18 @@ type ProbeProviderRequest struct {
 	APIBase  string `json:"api_base" binding:"required" description:"API base URL" example:"https://api.openai.com/v1"`
 	APIStyle string `json:"api_style" binding:"required,oneof=openai anthropic" description:"API style" example:"openai"`
 	Token    string `json:"token" binding:"required" description:"API token to test" example:"sk-..."`
+
+	Flavor      string            `json:"flavor,omitempty" description:"Provider flavor (azure for Azure OpenAI)" example:"azure"`
+	APIVersion  string            `json:"api_version,omitempty" description:"Azure api-version" example:"2024-10-21"`
+	Deployments map[string]string `json:"deployments,omitempty" description:"Azure model to deployment mapping"`
+
+	Region             string `json:"region,omitempty" description:"AWS region for Bedrock" example:"us-east-1"`
+	AWSAccessKeyID     string `json:"aws_access_key_id,omitempty" description:"AWS access key for Bedrock"`
+	AWSSecretAccessKey string `json:"aws_secret_access_key,omitempty" description:"AWS secret key for Bedrock"`
+	AWSSessionToken    string `json:"aws_session_token,omitempty" description:"AWS session token for Bedrock"`
+	AWSProfile         string `json:"aws_profile,omitempty" description:"AWS shared credentials profile for Bedrock"`
+
+	ProjectID string `json:"project_id,omitempty" description:"Google Cloud project for Vertex AI" example:"my-project"`
 }
 
 // ProbeProviderResponse represents the response from provider probing
@@ -185,15 +201,28 @@ type ProbeProviderResponseData struct {
 
 // ProviderResponse represents a provider configuration with masked token
 type ProviderResponse struct {
-	UUID          string           `json:"uuid" example:"0123456789ABCDEF"`
-	Name          string           `json:"name" example:"openai"`
-	APIBase       string           `json:"api_base" example:"https://api.openai.com/v1"`
-	APIStyle      string           `json:"api_style" example:"openai"`
-	Token         string           `json:"token" example:"sk-***...***"` // Only populated for api_key auth type
-	NoKeyRequired bool             `json:"no_key_required" example:"false"`
-	Enabled       bool             `json:"enabled" example:"true"`
-	AuthType      string           `json:"auth_type,omitempty" example:"api_key"` // api_key or oauth
-	OAuthDetail   *typ.OAuthDetail `json:"oauth_detail,omitempty"`                // OAuth credentials (only for oauth auth type)
+	UUID             string            `json:"uuid" example:"0123456789ABCDEF"`
+	Name             string            `json:"name" example:"openai"`
+	APIBase          string            `json:"api_base" example:"https://api.openai.com/v1"`
+	APIStyle         string            `json:"api_style" example:"openai"`
+	Token            string            `json:"token" example:"sk-***...***"` // Only populated for api_key auth type
+	NoKeyRequired    bool              `json:"no_key_required" example:"false"`
+	Enabled          bool              `json:"enabled" example:"true"`
+	AuthType         string            `json:"auth_type,omitempty" example:"api_key"` // api_key or oauth
+	OAuthDetail      *typ.OAuthDetail  `json:"oauth_detail,omitempty"`                // OAuth credentials (only for oauth auth type)
+	Flavor           string            `json:"flavor,omitempty" example:"azure"`      // azure for Azure OpenAI
+	APIVersion       string            `json:"api_version,omitempty" example:"2024-10-21"`
+	Deployments      map[string]string `json:"deployments,omitempty"` // Azure model to deployment mapping
+	Region           string            `json:"region,omitempty" example:"us-east-1"`
+	AWSAccessKeyID   string            `json:"aws_access_key_id,omitempty"` // AWS secret key and session token are never returned
+	AWSProfile       string            `json:"aws_profile,omitempty"`
+	ProjectID        string            `json:"project_id,omitempty" example:"my-project"`
+	InlineImages     bool              `json:"inline_images,omitempty"`
+	DocumentFallback string            `json:"document_fallback,omitempty" example:"text"`
+
+	ForwardCacheControl bool                  `json:"forward_cache_control,omitempty"`
+	Dialect             string                `json:"dialect,omitempty" example:"deepseek"`
+	ToolSchema          *typ.ToolSchemaConfig `json:"tool_schema,omitempty"`
 }
 
 // ProvidersResponse represents the response for listing providers
@@ -211,6 +240,10 @@ type StatusResponse struct {
 		ProvidersTotal   int  `json:"providers_total" example:"3"`
 		ProvidersEnabled int  `json:"providers_enabled" example:"2"`
 		RequestCount     int  `json:"request_count" example:"100"`
+		// How often each repair of malformed upstream tool calls occurred
+		ToolCallRepairs map[string]int64 `json:"tool_call_repairs"`
+		// How often upstream streams failed, by error type
+		StreamErrors map[string]int64 `json:"stream_errors"`
 	} `json:"data"`
 }
 
@@ -267,6 +300,25 @@ type CreateProviderRequest struct {
 	Token         string `json:"token" description:"API token" example:"sk-..."`
 	NoKeyRequired bool   `json:"no_key_required" description:"Whether provider requires no API key" example:"false"`
 	Enabled       bool   `json:"enabled" description:"Whether provider is enabled" example:"true"`
+
+	Flavor      string            `json:"flavor,omitempty" description:"Provider flavor (azure for Azure OpenAI)" example:"azure"`
+	APIVersion  string            `json:"api_version,omitempty" description:"Azure api-version" example:"2024-10-21"`
+	Deployments map[string]string `json:"deployments,omitempty" description:"Azure model to deployment mapping"`
+
+	Region             string `json:"region,omitempty" description:"AWS region for Bedrock" example:"us-east-1"`
+	AWSAccessKeyID     string `json:"aws_access_key_id,omitempty" description:"AWS access key for Bedrock"`
+	AWSSecretAccessKey string `json:"aws_secret_access_key,omitempty" description:"AWS secret key for Bedrock"`
+	AWSSessionToken    string `json:"aws_session_token,omitempty" description:"AWS session token for Bedrock"`
+	AWSProfile         string `json:"aws_profile,omitempty" description:"AWS shared credentials profile for Bedrock"`
+
+	ProjectID string `json:"project_id,omitempty" description:"Google Cloud project for Vertex AI" example:"my-project"`
+
+	InlineImages     bool   `json:"inline_images,omitempty" description:"Download remote images and send them as base64, for providers that only accept inline images"`
+	DocumentFallback string `json:"document_fallback,omitempty" description:"Handling of documents the provider cannot take natively: text, reject or text_only" example:"text"`
+
+	ForwardCacheControl bool                  `json:"forward_cache_control,omitempty" description:"Keep Anthropic cache_control on OpenAI-style requests, for gateways that accept it"`
+	Dialect             string                `json:"dialect,omitempty" description:"OpenAI-compatible dialect such as openai, deepseek or mistral; defaults from the provider template" example:"deepseek"`
+	ToolSchema          *typ.ToolSchemaConfig `json:"tool_schema,omitempty" description:"Tool schema sanitizing, replacing the dialect's defaults"`
 }
 
 // CreateProviderResponse represents the response for adding a provider
@@ -284,6 +336,25 @@ type UpdateProviderRequest struct {
 	Token         *string `json:"token,omitempty" description:"New API token"`
 	NoKeyRequired *bool   `json:"no_key_required,omitempty" description:"Whether provider requires no API key"`
 	Enabled       *bool   `json:"enabled,omitempty" description:"New enabled status"`
+
+	Flavor      *string           `json:"flavor,omitempty" description:"New provider flavor"`
+	APIVersion  *string           `json:"api_version,omitempty" description:"New Azure api-version"`
+	Deployments map[string]string `json:"deployments,omitempty" description:"New Azure model to deployment mapping"`
+
+	Region             *string `json:"region,omitempty" description:"New AWS region"`
+	AWSAccessKeyID     *string `json:"aws_access_key_id,omitempty" description:"New AWS access key"`
+	AWSSecretAccessKey *string `json:"aws_secret_access_key,omitempty" description:"New AWS secret key"`
+	AWSSessionToken    *string `json:"aws_session_token,omitempty" description:"New AWS session token"`
+	AWSProfile         *string `json:"aws_profile,omitempty" description:"New AWS shared credentials profile"`
+
+	ProjectID *string `json:"project_id,omitempty" description:"New Google Cloud project for Vertex AI"`
+
+	InlineImages     *bool   `json:"inline_images,omitempty" description:"Whether to download remote images and send them as base64"`
+	DocumentFallback *string `json:"document_fallback,omitempty" description:"New handling of documents the provider cannot take natively"`
+
+	ForwardCacheControl *bool                 `json:"forward_cache_control,omitempty" description:"Whether to keep Anthropic cache_control on OpenAI-style requests"`
+	Dialect             *string               `json:"dialect,omitempty" description:"New OpenAI-compatible dialect; empty uses the provider template"`
+	ToolSchema          *typ.ToolSchemaConfig `json:"tool_schema,omitempty" description:"New tool schema sanitizing; an empty object restores the dialect's defaults"`
 }
 
 // UpdateProviderResponse represents the response for updating a provider
@@ -0,0 +1,103 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestOpenAIAudioEndpoints tests that audio requests are routed by rule and relayed unchanged
+func TestOpenAIAudioEndpoints(t *testing.T) {
+	var lastModel string
+	var lastAudio []byte
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/audio/transcriptions", "/audio/translations":
+			require.NoError(t, r.ParseMultipartForm(1<<20))
+			lastModel = r.FormValue("model")
+			file, _, err := r.FormFile("file")
+			require.NoError(t, err)
+			lastAudio, _ = io.ReadAll(file)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"task":"transcribe","language":"english","duration":12.5,"text":"hello world"}`))
+		case "/audio/speech":
+			var body map[string]interface{}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			lastModel, _ = body["model"].(string)
+			w.Header().Set("Content-Type", "audio/mpeg")
+			w.Write([]byte("ID3 fake mp3 bytes"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer upstream.Close()
+
+	ts := NewTestServer(t)
+	defer Cleanup()
+	ts.AddTestProviderWithURL(t, "audio-provider", upstream.URL, "openai", true)
+	ts.AddTestRule(t, "tingly-whisper", "audio-provider", "whisper-1")
+	ts.AddTestRule(t, "tingly-tts", "audio-provider", "tts-1")
+	modelToken := ts.appConfig.GetGlobalConfig().GetModelToken()
+
+	for _, path := range []string{"transcriptions", "translations"} {
+		t.Run(path+"_multipart", func(t *testing.T) {
+			rule := ts.appConfig.GetGlobalConfig().GetRuleByUUID("tingly-whisper")
+			require.NotNil(t, rule)
+			before := rule.Services[0].Stats.GetStats()
+
+			var buf bytes.Buffer
+			writer := multipart.NewWriter(&buf)
+			_ = writer.WriteField("model", "tingly-whisper")
+			_ = writer.WriteField("response_format", "verbose_json")
+			part, _ := writer.CreateFormFile("file", "speech.wav")
+			part.Write([]byte("RIFF fake wav bytes"))
+			writer.Close()
+
+			req, _ := http.NewRequest("POST", "/openai/v1/audio/"+path, &buf)
+			req.Header.Set("Authorization", "Bearer "+modelToken)
+			req.Header.Set("Content-Type", writer.FormDataContentType())
+			w := httptest.NewRecorder()
+			ts.ginEngine.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "whisper-1", lastModel)
+			assert.Equal(t, "RIFF fake wav bytes", string(lastAudio))
+			assert.Contains(t, w.Body.String(), "hello world")
+
+			after := rule.Services[0].Stats.GetStats()
+			assert.InDelta(t, 12.5, after.AudioSeconds-before.AudioSeconds, 0.001)
+		})
+	}
+
+	t.Run("speech_binary", func(t *testing.T) {
+		rule := ts.appConfig.GetGlobalConfig().GetRuleByUUID("tingly-tts")
+		require.NotNil(t, rule)
+		before := rule.Services[0].Stats.GetStats()
+
+		req, _ := http.NewRequest("POST", "/openai/v1/audio/speech", CreateJSONBody(map[string]interface{}{
+			"model": "tingly-tts",
+			"input": "héllo",
+			"voice": "alloy",
+		}))
+		req.Header.Set("Authorization", "Bearer "+modelToken)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		ts.ginEngine.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "tts-1", lastModel)
+		assert.Equal(t, "audio/mpeg", w.Header().Get("Content-Type"))
+		assert.Equal(t, "ID3 fake mp3 bytes", w.Body.String())
+
+		after := rule.Services[0].Stats.GetStats()
+		assert.Equal(t, int64(5), after.AudioCharacters-before.AudioCharacters)
+	})
+}
@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tingly-box/internal/helper"
+	"tingly-box/internal/typ"
+)
+
+// TestAzureOpenAIProvider tests that azure providers use deployment URLs, api-version and the api-key header
+func TestAzureOpenAIProvider(t *testing.T) {
+	var lastPath, lastAPIVersion, lastAPIKey, lastAuthorization string
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastPath = r.URL.Path
+		lastAPIVersion = r.URL.Query().Get("api-version")
+		lastAPIKey = r.Header.Get("api-key")
+		lastAuthorization = r.Header.Get("Authorization")
+
+		if r.URL.Path == "/openai/models" {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"data":[{"id":"gpt-4o","capabilities":{"chat_completion":true}},` +
+				`{"id":"text-embedding-3-small","capabilities":{"chat_completion":false,"embeddings":true}}]}`))
+			return
+		}
+		if r.URL.Path != "/openai/deployments/prod-gpt4o/chat/completions" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"chatcmpl-1","object":"chat.completion","created":1,"model":"gpt-4o",` +
+			`"choices":[{"index":0,"message":{"role":"assistant","content":"hi from azure"},"finish_reason":"stop"}],` +
+			`"usage":{"prompt_tokens":3,"completion_tokens":4,"total_tokens":7}}`))
+	}))
+	defer upstream.Close()
+
+	ts := NewTestServer(t)
+	defer Cleanup()
+	ts.AddTestProviderWithURL(t, "azure-provider", upstream.URL+"/openai/v1", "openai", true)
+	ts.AddTestRule(t, "tingly-azure", "azure-provider", "gpt-4o")
+	cfg := ts.appConfig.GetGlobalConfig()
+
+	provider, err := cfg.GetProviderByName("azure-provider")
+	require.NoError(t, err)
+	provider.Flavor = typ.FlavorAzure
+	provider.APIVersion = "2024-06-01"
+	provider.Deployments = map[string]string{"gpt-4o": "prod-gpt4o"}
+	require.NoError(t, cfg.UpdateProvider(provider.UUID, provider))
+
+	t.Run("chat_completion", func(t *testing.T) {
+		req, _ := http.NewRequest("POST", "/openai/v1/chat/completions", CreateJSONBody(map[string]interface{}{
+			"model":    "tingly-azure",
+			"messages": []map[string]string{{"role": "user", "content": "hello"}},
+		}))
+		req.Header.Set("Authorization", "Bearer "+cfg.GetModelToken())
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		ts.ginEngine.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "/openai/deployments/prod-gpt4o/chat/completions", lastPath)
+		assert.Equal(t, "2024-06-01", lastAPIVersion)
+		assert.Equal(t, provider.Token, lastAPIKey)
+		assert.Empty(t, lastAuthorization)
+
+		var resp map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		choices := resp["choices"].([]interface{})
+		require.Len(t, choices, 1)
+		assert.Equal(t, "hi from azure", choices[0].(map[string]interface{})["message"].(map[string]interface{})["content"])
+	})
+
+	t.Run("models_from_deployments", func(t *testing.T) {
+		models, err := helper.GetProviderModelsFromAPI(provider)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"gpt-4o"}, models)
+	})
+
+	t.Run("models_from_resource", func(t *testing.T) {
+		unmapped := *provider
+		unmapped.Deployments = nil
+		models, err := helper.GetProviderModelsFromAPI(&unmapped)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"gpt-4o"}, models)
+		assert.Equal(t, "/openai/models", lastPath)
+		assert.Equal(t, "2024-06-01", lastAPIVersion)
+	})
+}
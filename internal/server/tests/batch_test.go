@@ -0,0 +1,222 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBatchEmulation tests that OpenAI and Anthropic batches are stored and executed locally
+// through the regular routing, independent of upstream batch support
+func TestBatchEmulation(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		model, _ := body["model"].(string)
+		w.Header().Set("Content-Type", "application/json")
+
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/chat/completions"):
+			if _, ok := body["stream"]; ok {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error":{"message":"stream is not allowed","type":"invalid_request_error"}}`))
+				return
+			}
+			json.NewEncoder(w).Encode(CreateMockChatCompletionResponse("chatcmpl-1", model, "hello from "+model))
+		case strings.HasSuffix(r.URL.Path, "/messages"):
+			w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","model":"` + model + `","content":[{"type":"text","text":"hi"}],"stop_reason":"end_turn","usage":{"input_tokens":3,"output_tokens":1}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer upstream.Close()
+
+	ts := NewTestServer(t)
+	defer Cleanup()
+	ts.AddTestProviderWithURL(t, "batch-openai", upstream.URL, "openai", true)
+	ts.AddTestProviderWithURL(t, "batch-anthropic", upstream.URL, "anthropic", true)
+	ts.AddTestRule(t, "tingly-batch", "batch-openai", "gpt-4o-mini")
+	ts.AddTestRule(t, "tingly-batch-claude", "batch-anthropic", "claude-haiku-4-5")
+	modelToken := ts.appConfig.GetGlobalConfig().GetModelToken()
+
+	do := func(method, path string, body *bytes.Buffer, contentType string) *httptest.ResponseRecorder {
+		if body == nil {
+			body = &bytes.Buffer{}
+		}
+		req, _ := http.NewRequest(method, path, body)
+		req.Header.Set("Authorization", "Bearer "+modelToken)
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		w := httptest.NewRecorder()
+		ts.ginEngine.ServeHTTP(w, req)
+		return w
+	}
+	decode := func(w *httptest.ResponseRecorder) map[string]interface{} {
+		var out map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &out), w.Body.String())
+		return out
+	}
+
+	t.Run("openai_files_and_batches", func(t *testing.T) {
+		input := strings.Join([]string{
+			`{"custom_id":"req-1","method":"POST","url":"/v1/chat/completions","body":{"model":"tingly-batch","stream":true,"messages":[{"role":"user","content":"one"}]}}`,
+			`{"custom_id":"req-2","method":"POST","url":"/v1/chat/completions","body":{"model":"unknown-rule","messages":[{"role":"user","content":"two"}]}}`,
+		}, "\n")
+
+		var buf bytes.Buffer
+		writer := multipart.NewWriter(&buf)
+		_ = writer.WriteField("purpose", "batch")
+		part, _ := writer.CreateFormFile("file", "input.jsonl")
+		part.Write([]byte(input))
+		writer.Close()
+
+		w := do("POST", "/openai/v1/files", &buf, writer.FormDataContentType())
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		fileID := decode(w)["id"].(string)
+
+		w = do("POST", "/openai/v1/batches", CreateJSONBody(map[string]interface{}{
+			"input_file_id":     fileID,
+			"endpoint":          "/v1/chat/completions",
+			"completion_window": "24h",
+		}), "application/json")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		batch := decode(w)
+		batchID := batch["id"].(string)
+		assert.Equal(t, "in_progress", batch["status"])
+
+		ts.server.GetBatchWorker().RunOnce(context.Background())
+
+		w = do("GET", "/openai/v1/batches/"+batchID, nil, "")
+		require.Equal(t, http.StatusOK, w.Code)
+		batch = decode(w)
+		assert.Equal(t, "completed", batch["status"])
+		counts := batch["request_counts"].(map[string]interface{})
+		assert.Equal(t, float64(2), counts["total"])
+		assert.Equal(t, float64(1), counts["completed"])
+		assert.Equal(t, float64(1), counts["failed"])
+
+		w = do("GET", "/openai/v1/files/"+batch["output_file_id"].(string)+"/content", nil, "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var line struct {
+			CustomID string `json:"custom_id"`
+			Response struct {
+				StatusCode int                    `json:"status_code"`
+				Body       map[string]interface{} `json:"body"`
+			} `json:"response"`
+		}
+		require.NoError(t, json.Unmarshal(bytes.TrimSpace(w.Body.Bytes()), &line))
+		assert.Equal(t, "req-1", line.CustomID)
+		assert.Equal(t, http.StatusOK, line.Response.StatusCode)
+		assert.Equal(t, "chat.completion", line.Response.Body["object"])
+
+		w = do("GET", "/openai/v1/files/"+batch["error_file_id"].(string)+"/content", nil, "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"custom_id":"req-2"`)
+		assert.Contains(t, w.Body.String(), `"status_code":400`)
+	})
+
+	t.Run("openai_batch_rejects_mismatched_url", func(t *testing.T) {
+		var buf bytes.Buffer
+		writer := multipart.NewWriter(&buf)
+		_ = writer.WriteField("purpose", "batch")
+		part, _ := writer.CreateFormFile("file", "input.jsonl")
+		part.Write([]byte(`{"custom_id":"a","method":"POST","url":"/v1/embeddings","body":{"model":"tingly-batch"}}`))
+		writer.Close()
+		w := do("POST", "/openai/v1/files", &buf, writer.FormDataContentType())
+		fileID := decode(w)["id"].(string)
+
+		w = do("POST", "/openai/v1/batches", CreateJSONBody(map[string]interface{}{
+			"input_file_id": fileID,
+			"endpoint":      "/v1/chat/completions",
+		}), "application/json")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("openai_batch_rejects_completion_window", func(t *testing.T) {
+		w := do("POST", "/openai/v1/batches", CreateJSONBody(map[string]interface{}{
+			"input_file_id":     "file-unused",
+			"endpoint":          "/v1/chat/completions",
+			"completion_window": "1h",
+		}), "application/json")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "completion_window")
+	})
+
+	t.Run("anthropic_message_batches", func(t *testing.T) {
+		w := do("POST", "/anthropic/v1/messages/batches", CreateJSONBody(map[string]interface{}{
+			"requests": []map[string]interface{}{
+				{"custom_id": "first", "params": map[string]interface{}{
+					"model": "tingly-batch-claude", "max_tokens": 16,
+					"messages": []map[string]interface{}{{"role": "user", "content": "hi"}},
+				}},
+			},
+		}), "application/json")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		batch := decode(w)
+		batchID := batch["id"].(string)
+		assert.Equal(t, "message_batch", batch["type"])
+		assert.Equal(t, "in_progress", batch["processing_status"])
+
+		w = do("GET", "/anthropic/v1/messages/batches/"+batchID+"/results", nil, "")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		ts.server.GetBatchWorker().RunOnce(context.Background())
+
+		w = do("GET", "/anthropic/v1/messages/batches/"+batchID, nil, "")
+		batch = decode(w)
+		assert.Equal(t, "ended", batch["processing_status"])
+		assert.NotNil(t, batch["results_url"])
+
+		w = do("GET", "/anthropic/v1/messages/batches/"+batchID+"/results", nil, "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var result struct {
+			CustomID string `json:"custom_id"`
+			Result   struct {
+				Type    string                 `json:"type"`
+				Message map[string]interface{} `json:"message"`
+			} `json:"result"`
+		}
+		require.NoError(t, json.Unmarshal(bytes.TrimSpace(w.Body.Bytes()), &result), w.Body.String())
+		assert.Equal(t, "first", result.CustomID)
+		assert.Equal(t, "succeeded", result.Result.Type, w.Body.String())
+		assert.Equal(t, "message", result.Result.Message["type"])
+	})
+
+	t.Run("anthropic_cancel_before_execution", func(t *testing.T) {
+		w := do("POST", "/anthropic/v1/messages/batches", CreateJSONBody(map[string]interface{}{
+			"requests": []map[string]interface{}{
+				{"custom_id": "never", "params": map[string]interface{}{"model": "tingly-batch-claude", "max_tokens": 16}},
+			},
+		}), "application/json")
+		require.Equal(t, http.StatusOK, w.Code)
+		batchID := decode(w)["id"].(string)
+
+		w = do("POST", "/anthropic/v1/messages/batches/"+batchID+"/cancel", nil, "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "canceling", decode(w)["processing_status"])
+
+		ts.server.GetBatchWorker().RunOnce(context.Background())
+
+		w = do("GET", "/anthropic/v1/messages/batches/"+batchID, nil, "")
+		batch := decode(w)
+		assert.Equal(t, "ended", batch["processing_status"])
+		assert.Equal(t, float64(1), batch["request_counts"].(map[string]interface{})["canceled"])
+
+		w = do("GET", "/anthropic/v1/messages/batches/"+batchID+"/results", nil, "")
+		assert.Contains(t, w.Body.String(), `"type":"canceled"`)
+	})
+
+	t.Run("batch_format_isolated", func(t *testing.T) {
+		w := do("GET", "/openai/v1/batches/msgbatch_missing", nil, "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
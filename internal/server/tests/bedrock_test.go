@@ -0,0 +1,134 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tingly-box/internal/typ"
+)
+
+// bedrockStreamChunk encodes an Anthropic event as a Bedrock event-stream chunk message
+func bedrockStreamChunk(event string) []byte {
+	payload := []byte(`{"bytes":"` + base64.StdEncoding.EncodeToString([]byte(event)) + `"}`)
+
+	var headers bytes.Buffer
+	for _, h := range [][2]string{{":message-type", "event"}, {":event-type", "chunk"}} {
+		headers.WriteByte(byte(len(h[0])))
+		headers.WriteString(h[0])
+		headers.WriteByte(7)
+		binary.Write(&headers, binary.BigEndian, uint16(len(h[1])))
+		headers.WriteString(h[1])
+	}
+
+	var msg bytes.Buffer
+	binary.Write(&msg, binary.BigEndian, uint32(16+headers.Len()+len(payload)))
+	binary.Write(&msg, binary.BigEndian, uint32(headers.Len()))
+	binary.Write(&msg, binary.BigEndian, crc32.ChecksumIEEE(msg.Bytes()))
+	msg.Write(headers.Bytes())
+	msg.Write(payload)
+	binary.Write(&msg, binary.BigEndian, crc32.ChecksumIEEE(msg.Bytes()))
+	return msg.Bytes()
+}
+
+// TestBedrockProvider tests that bedrock providers call InvokeModel with SigV4 signatures
+// and that streamed event-stream responses are relayed as Anthropic SSE
+func TestBedrockProvider(t *testing.T) {
+	var lastPath, lastAuthorization, lastSecurityToken string
+	var lastBody map[string]interface{}
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastPath = r.URL.EscapedPath()
+		lastAuthorization = r.Header.Get("Authorization")
+		lastSecurityToken = r.Header.Get("X-Amz-Security-Token")
+		lastBody = nil
+		_ = json.NewDecoder(r.Body).Decode(&lastBody)
+
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/invoke"):
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","model":"claude-3-haiku",` +
+				`"content":[{"type":"text","text":"hi from bedrock"}],"stop_reason":"end_turn",` +
+				`"usage":{"input_tokens":3,"output_tokens":4}}`))
+		case strings.HasSuffix(r.URL.Path, "/invoke-with-response-stream"):
+			w.Header().Set("Content-Type", "application/vnd.amazon.eventstream")
+			for _, event := range []string{
+				`{"type":"message_start","message":{"id":"msg_2","type":"message","role":"assistant","model":"claude-3-haiku","content":[],"usage":{"input_tokens":3,"output_tokens":0}}}`,
+				`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+				`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"streamed"}}`,
+				`{"type":"content_block_stop","index":0}`,
+				`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":2}}`,
+				`{"type":"message_stop"}`,
+			} {
+				w.Write(bedrockStreamChunk(event))
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer upstream.Close()
+
+	ts := NewTestServer(t)
+	defer Cleanup()
+	ts.AddTestProviderWithURL(t, "bedrock-provider", upstream.URL, "anthropic", true)
+	ts.AddTestRule(t, "tingly-bedrock", "bedrock-provider", "anthropic.claude-3-haiku-20240307-v1:0")
+	cfg := ts.appConfig.GetGlobalConfig()
+
+	provider, err := cfg.GetProviderByName("bedrock-provider")
+	require.NoError(t, err)
+	provider.Flavor = typ.FlavorBedrock
+	provider.Region = "us-west-2"
+	provider.AWSAccessKeyID = "AKIDEXAMPLE"
+	provider.AWSSecretAccessKey = "secret"
+	provider.AWSSessionToken = "session"
+	require.NoError(t, cfg.UpdateProvider(provider.UUID, provider))
+
+	send := func(stream bool) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/anthropic/v1/messages", CreateJSONBody(map[string]interface{}{
+			"model":      "tingly-bedrock",
+			"max_tokens": 16,
+			"stream":     stream,
+			"messages":   []map[string]string{{"role": "user", "content": "hello"}},
+		}))
+		req.Header.Set("Authorization", "Bearer "+cfg.GetModelToken())
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		ts.ginEngine.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("invoke", func(t *testing.T) {
+		w := send(false)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), "hi from bedrock")
+
+		assert.Equal(t, "/model/anthropic.claude-3-haiku-20240307-v1%3A0/invoke", lastPath)
+		assert.True(t, strings.HasPrefix(lastAuthorization, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/"))
+		assert.Contains(t, lastAuthorization, "/us-west-2/bedrock/aws4_request")
+		assert.Equal(t, "session", lastSecurityToken)
+		assert.Equal(t, "bedrock-2023-05-31", lastBody["anthropic_version"])
+		assert.NotContains(t, lastBody, "model")
+		assert.NotContains(t, lastBody, "stream")
+	})
+
+	t.Run("invoke_with_response_stream", func(t *testing.T) {
+		w := send(true)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "/model/anthropic.claude-3-haiku-20240307-v1%3A0/invoke-with-response-stream", lastPath)
+
+		body, _ := io.ReadAll(w.Body)
+		assert.Contains(t, string(body), "message_start")
+		assert.Contains(t, string(body), "streamed")
+		assert.Contains(t, string(body), "message_stop")
+	})
+}
@@ -0,0 +1,139 @@
+package tests
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAnthropicChoicesFanOut tests that n>1 requests to an Anthropic provider run their choices
+// concurrently and that a failing choice cancels the others, streaming or not
+func TestAnthropicChoicesFanOut(t *testing.T) {
+	var calls, cancelled atomic.Int32
+	var failing, streamFailing atomic.Bool
+	arrived := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The server only notices a closed connection once the body is read
+		io.Copy(io.Discard, r.Body)
+		if streamFailing.Load() {
+			w.Header().Set("Content-Type", "text/event-stream")
+			if calls.Add(1) == 1 {
+				w.Write([]byte("event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"choice overloaded\"}}\n\n"))
+				return
+			}
+			w.Write([]byte("event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_1\",\"type\":\"message\",\"role\":\"assistant\",\"model\":\"claude-haiku-4-5\",\"content\":[],\"usage\":{\"input_tokens\":3,\"output_tokens\":0}}}\n\n"))
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+				cancelled.Add(1)
+			case <-time.After(10 * time.Second):
+			}
+			return
+		}
+		if failing.Load() {
+			if calls.Add(1) == 1 {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"type":"error","error":{"type":"invalid_request_error","message":"choice rejected"}}`))
+				return
+			}
+			select {
+			case <-r.Context().Done():
+				cancelled.Add(1)
+			case <-time.After(10 * time.Second):
+			}
+			return
+		}
+
+		// Every stream must be open before any of them answers
+		if calls.Add(1) == 2 {
+			close(arrived)
+		}
+		select {
+		case <-arrived:
+		case <-time.After(5 * time.Second):
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"type":"error","error":{"type":"invalid_request_error","message":"streams opened one after another"}}`))
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range []string{
+			`{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude-haiku-4-5","content":[],"usage":{"input_tokens":3,"output_tokens":0}}}`,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"hi"}}`,
+			`{"type":"content_block_stop","index":0}`,
+			`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":1}}`,
+			`{"type":"message_stop"}`,
+		} {
+			eventType := strings.SplitN(strings.TrimPrefix(event, `{"type":"`), `"`, 2)[0]
+			w.Write([]byte("event: " + eventType + "\ndata: " + event + "\n\n"))
+		}
+	}))
+	defer upstream.Close()
+
+	ts := NewTestServerWithAdaptor(t, true)
+	defer Cleanup()
+	ts.AddTestProviderWithURL(t, "choices-anthropic", upstream.URL, "anthropic", true)
+	ts.AddTestRule(t, "tingly-choices", "choices-anthropic", "claude-haiku-4-5")
+	cfg := ts.appConfig.GetGlobalConfig()
+
+	do := func(body map[string]interface{}) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/openai/v1/chat/completions", CreateJSONBody(body))
+		req.Header.Set("Authorization", "Bearer "+cfg.GetModelToken())
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		ts.ginEngine.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("streams_open_concurrently", func(t *testing.T) {
+		w := do(map[string]interface{}{
+			"model":    "tingly-choices",
+			"n":        2,
+			"stream":   true,
+			"messages": []map[string]string{{"role": "user", "content": "hello"}},
+		})
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), `"index":1`)
+		assert.NotContains(t, w.Body.String(), "one after another")
+	})
+
+	t.Run("failure_cancels_siblings", func(t *testing.T) {
+		failing.Store(true)
+		calls.Store(0)
+		start := time.Now()
+		w := do(map[string]interface{}{
+			"model":    "tingly-choices",
+			"n":        3,
+			"messages": []map[string]string{{"role": "user", "content": "hello"}},
+		})
+		assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), "choice rejected")
+		assert.Less(t, time.Since(start), 5*time.Second)
+		assert.Eventually(t, func() bool { return cancelled.Load() == calls.Load()-1 }, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("stream_failure_cancels_siblings", func(t *testing.T) {
+		failing.Store(false)
+		streamFailing.Store(true)
+		calls.Store(0)
+		cancelled.Store(0)
+		start := time.Now()
+		w := do(map[string]interface{}{
+			"model":    "tingly-choices",
+			"n":        3,
+			"stream":   true,
+			"messages": []map[string]string{{"role": "user", "content": "hello"}},
+		})
+		assert.Contains(t, w.Body.String(), "choice overloaded")
+		assert.Less(t, time.Since(start), 5*time.Second)
+		assert.Eventually(t, func() bool { return cancelled.Load() == 2 }, 5*time.Second, 10*time.Millisecond)
+	})
+}
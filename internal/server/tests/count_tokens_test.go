@@ -0,0 +1,61 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCountTokensEndpoints tests local token counting on the OpenAI and Anthropic paths
+func TestCountTokensEndpoints(t *testing.T) {
+	ts := NewTestServer(t)
+	defer Cleanup()
+	ts.AddTestProvider(t, "count-openai", "http://127.0.0.1:1/v1", "openai", true)
+	ts.AddTestRule(t, "tingly-count", "count-openai", "gpt-4o")
+	modelToken := ts.appConfig.GetGlobalConfig().GetModelToken()
+
+	send := func(path string, body map[string]interface{}) map[string]interface{} {
+		req, _ := http.NewRequest("POST", path, CreateJSONBody(body))
+		req.Header.Set("Authorization", "Bearer "+modelToken)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		ts.ginEngine.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var resp map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+
+	t.Run("openai_chat_completions", func(t *testing.T) {
+		text := send("/openai/v1/chat/completions/count_tokens", map[string]interface{}{
+			"model":    "tingly-count",
+			"messages": []map[string]interface{}{{"role": "user", "content": "Hello there"}},
+		})
+		assert.Equal(t, "gpt-4o", text["model"])
+		textTokens := text["input_tokens"].(float64)
+		assert.Greater(t, textTokens, float64(0))
+
+		withImage := send("/openai/v1/chat/completions/count_tokens", map[string]interface{}{
+			"model": "tingly-count",
+			"messages": []map[string]interface{}{{"role": "user", "content": []map[string]interface{}{
+				{"type": "text", "text": "Hello there"},
+				{"type": "image_url", "image_url": map[string]string{"url": "https://example.com/a.png", "detail": "low"}},
+			}}},
+		})
+		assert.Equal(t, textTokens+85, withImage["input_tokens"])
+	})
+
+	t.Run("anthropic_messages_local", func(t *testing.T) {
+		resp := send("/anthropic/v1/messages/count_tokens?beta=true", map[string]interface{}{
+			"model":      "tingly-count",
+			"max_tokens": 16,
+			"messages":   []map[string]interface{}{{"role": "user", "content": "Hello there"}},
+		})
+		assert.Greater(t, resp["input_tokens"].(float64), float64(0))
+	})
+}
@@ -0,0 +1,158 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// errorBody decodes an error response
+func errorBody(t *testing.T, w *httptest.ResponseRecorder) (string, map[string]interface{}) {
+	var resp struct {
+		Type  string                 `json:"type"`
+		Error map[string]interface{} `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp), w.Body.String())
+	return resp.Type, resp.Error
+}
+
+// TestUpstreamErrorNormalization tests that upstream failures keep their status and type in the schema
+// of the client's endpoint
+func TestUpstreamErrorNormalization(t *testing.T) {
+	openaiUpstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Retry-After", "7")
+		// Keep the retries of the SDK short
+		w.Header().Set("Retry-After-Ms", "1")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"error":{"message":"Rate limit reached","type":"requests","code":"rate_limit_exceeded"}}`))
+	}))
+	defer openaiUpstream.Close()
+
+	anthropicUpstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Retry-After-Ms", "1")
+		w.WriteHeader(529)
+		w.Write([]byte(`{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`))
+	}))
+	defer anthropicUpstream.Close()
+
+	ts := NewTestServerWithAdaptor(t, true)
+	defer Cleanup()
+	ts.AddTestProviderWithURL(t, "openai-provider", openaiUpstream.URL, "openai", true)
+	ts.AddTestProviderWithURL(t, "anthropic-provider", anthropicUpstream.URL, "anthropic", true)
+	ts.AddTestRule(t, "limited", "openai-provider", "gpt-4o")
+	ts.AddTestRule(t, "overloaded", "anthropic-provider", "claude")
+	token := ts.appConfig.GetGlobalConfig().GetModelToken()
+
+	send := func(path string, body map[string]interface{}) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", path, CreateJSONBody(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		ts.ginEngine.ServeHTTP(w, req)
+		return w
+	}
+	chat := func(model string, stream bool) map[string]interface{} {
+		return map[string]interface{}{
+			"model":    model,
+			"stream":   stream,
+			"messages": []map[string]string{{"role": "user", "content": "hello"}},
+		}
+	}
+	messages := func(model string, stream bool) map[string]interface{} {
+		body := chat(model, stream)
+		body["max_tokens"] = 16
+		return body
+	}
+
+	t.Run("openai_upstream_to_openai_client", func(t *testing.T) {
+		w := send("/openai/v1/chat/completions", chat("limited", false))
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "7", w.Header().Get("Retry-After"))
+		_, detail := errorBody(t, w)
+		assert.Equal(t, "requests", detail["type"])
+		assert.Equal(t, "rate_limit_exceeded", detail["code"])
+		assert.Equal(t, "upstream", detail["gateway_code"])
+		assert.Equal(t, "Rate limit reached", detail["message"])
+	})
+
+	t.Run("openai_upstream_to_anthropic_client_streaming", func(t *testing.T) {
+		w := send("/anthropic/v1/messages", messages("limited", true))
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+		errorType, detail := errorBody(t, w)
+		assert.Equal(t, "error", errorType)
+		assert.Equal(t, "rate_limit_error", detail["type"])
+		assert.Equal(t, "upstream", detail["gateway_code"])
+	})
+
+	t.Run("anthropic_upstream_to_anthropic_client", func(t *testing.T) {
+		w := send("/anthropic/v1/messages", messages("overloaded", true))
+		assert.Equal(t, 529, w.Code)
+		errorType, detail := errorBody(t, w)
+		assert.Equal(t, "error", errorType)
+		assert.Equal(t, "overloaded_error", detail["type"])
+		assert.Equal(t, "Overloaded", detail["message"])
+	})
+
+	t.Run("anthropic_upstream_to_openai_client", func(t *testing.T) {
+		w := send("/openai/v1/chat/completions", chat("overloaded", false))
+		assert.Equal(t, 529, w.Code)
+		_, detail := errorBody(t, w)
+		assert.Equal(t, "server_error", detail["type"])
+		assert.Equal(t, "overloaded", detail["code"])
+		assert.Equal(t, "upstream", detail["gateway_code"])
+	})
+
+	t.Run("unknown_model", func(t *testing.T) {
+		w := send("/openai/v1/chat/completions", chat("missing", false))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		_, detail := errorBody(t, w)
+		assert.Equal(t, "invalid_request_error", detail["type"])
+		assert.Equal(t, "routing", detail["gateway_code"])
+	})
+
+	t.Run("raw_endpoint_upstream", func(t *testing.T) {
+		w := send("/openai/v1/images/generations", map[string]interface{}{"model": "limited", "prompt": "a cat"})
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		_, detail := errorBody(t, w)
+		assert.Equal(t, "requests", detail["type"])
+		assert.Equal(t, "upstream", detail["gateway_code"])
+	})
+
+	t.Run("raw_endpoint_unknown_model", func(t *testing.T) {
+		w := send("/openai/v1/audio/speech", map[string]interface{}{"model": "missing", "input": "hi"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		_, detail := errorBody(t, w)
+		assert.Equal(t, "routing", detail["gateway_code"])
+	})
+
+	t.Run("anthropic_batch_not_found", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/anthropic/v1/messages/batches/msgbatch_missing", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		ts.ginEngine.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		errorType, detail := errorBody(t, w)
+		assert.Equal(t, "error", errorType)
+		assert.Equal(t, "not_found_error", detail["type"])
+		assert.Equal(t, "invalid_request", detail["gateway_code"])
+	})
+
+	t.Run("bad_token", func(t *testing.T) {
+		req, _ := http.NewRequest("POST", "/anthropic/v1/messages", CreateJSONBody(messages("overloaded", false)))
+		req.Header.Set("X-Api-Key", "wrong")
+		w := httptest.NewRecorder()
+		ts.ginEngine.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		errorType, detail := errorBody(t, w)
+		assert.Equal(t, "error", errorType)
+		assert.Equal(t, "authentication_error", detail["type"])
+		assert.Equal(t, "auth", detail["gateway_code"])
+	})
+}
@@ -0,0 +1,128 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestOpenAIImageEndpoints tests that image requests are routed by rule and relayed unchanged
+func TestOpenAIImageEndpoints(t *testing.T) {
+	var lastModel string
+	var lastImage []byte
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/images/generations":
+			var body map[string]interface{}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			lastModel, _ = body["model"].(string)
+			w.Header().Set("Content-Type", "application/json")
+			if body["response_format"] == "b64_json" {
+				w.Write([]byte(`{"created":1,"data":[{"b64_json":"aGVsbG8="},{"b64_json":"d29ybGQ="}],"usage":{"input_tokens":7,"output_tokens":20}}`))
+				return
+			}
+			w.Write([]byte(`{"created":1,"data":[{"url":"https://example.com/a.png"}]}`))
+		case "/images/edits":
+			require.NoError(t, r.ParseMultipartForm(1<<20))
+			lastModel = r.FormValue("model")
+			file, _, err := r.FormFile("image")
+			require.NoError(t, err)
+			lastImage, _ = io.ReadAll(file)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"created":1,"data":[{"url":"https://example.com/edited.png"}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer upstream.Close()
+
+	ts := NewTestServer(t)
+	defer Cleanup()
+	ts.AddTestProviderWithURL(t, "image-provider", upstream.URL, "openai", true)
+	ts.AddTestProviderWithURL(t, "claude-provider", upstream.URL, "anthropic", true)
+	ts.AddTestRule(t, "tingly-image", "image-provider", "gpt-image-1")
+	ts.AddTestRule(t, "tingly-claude", "claude-provider", "claude-sonnet-4")
+	modelToken := ts.appConfig.GetGlobalConfig().GetModelToken()
+
+	t.Run("generation_url", func(t *testing.T) {
+		req, _ := http.NewRequest("POST", "/openai/v1/images/generations", CreateJSONBody(map[string]interface{}{
+			"model":  "tingly-image",
+			"prompt": "a cat",
+		}))
+		req.Header.Set("Authorization", "Bearer "+modelToken)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		ts.ginEngine.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "gpt-image-1", lastModel)
+		assert.Contains(t, w.Body.String(), "https://example.com/a.png")
+	})
+
+	t.Run("generation_b64_json_records_images", func(t *testing.T) {
+		rule := ts.appConfig.GetGlobalConfig().GetRuleByUUID("tingly-image")
+		require.NotNil(t, rule)
+		before := rule.Services[0].Stats.GetStats()
+
+		req, _ := http.NewRequest("POST", "/openai/v1/images/generations", CreateJSONBody(map[string]interface{}{
+			"model":           "tingly-image",
+			"prompt":          "two cats",
+			"n":               2,
+			"response_format": "b64_json",
+		}))
+		req.Header.Set("Authorization", "Bearer "+modelToken)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		ts.ginEngine.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "aGVsbG8=")
+
+		after := rule.Services[0].Stats.GetStats()
+		assert.Equal(t, int64(2), after.ImageCount-before.ImageCount)
+		assert.Equal(t, int64(7), after.WindowInputTokens-before.WindowInputTokens)
+		assert.Equal(t, int64(20), after.WindowOutputTokens-before.WindowOutputTokens)
+	})
+
+	t.Run("edit_multipart", func(t *testing.T) {
+		var buf bytes.Buffer
+		writer := multipart.NewWriter(&buf)
+		_ = writer.WriteField("model", "tingly-image")
+		_ = writer.WriteField("prompt", "add a hat")
+		part, _ := writer.CreateFormFile("image", "cat.png")
+		part.Write([]byte("\x89PNG fake image bytes"))
+		writer.Close()
+
+		req, _ := http.NewRequest("POST", "/openai/v1/images/edits", &buf)
+		req.Header.Set("Authorization", "Bearer "+modelToken)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		w := httptest.NewRecorder()
+		ts.ginEngine.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "gpt-image-1", lastModel)
+		assert.Equal(t, "\x89PNG fake image bytes", string(lastImage))
+		assert.Contains(t, w.Body.String(), "edited.png")
+	})
+
+	t.Run("anthropic_provider_rejected", func(t *testing.T) {
+		req, _ := http.NewRequest("POST", "/openai/v1/images/generations", CreateJSONBody(map[string]interface{}{
+			"model":  "tingly-claude",
+			"prompt": "a cat",
+		}))
+		req.Header.Set("Authorization", "Bearer "+modelToken)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		ts.ginEngine.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	})
+}
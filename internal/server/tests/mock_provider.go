@@ -474,7 +474,7 @@ func (suite *MockProviderTestSuite) TestProviderError() {
 	suite.testServer.ginEngine.ServeHTTP(w, req)
 
 	// Assertions
-	assert.Equal(suite.t, 500, w.Code) // Internal server error due to provider error
+	assert.Equal(suite.t, 401, w.Code) // The status of the provider is passed on
 
 	var errorResp server.ErrorResponse
 	err := json.Unmarshal(w.Body.Bytes(), &errorResp)
@@ -0,0 +1,95 @@
+package tests
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPassthroughProxy tests that raw requests reach the provider unchanged apart from credentials
+func TestPassthroughProxy(t *testing.T) {
+	var lastRequest *http.Request
+	var lastBody []byte
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastRequest = r
+		lastBody, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Upstream", "yes")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":"ft-job-1"}`))
+	}))
+	defer upstream.Close()
+
+	ts := NewTestServer(t)
+	defer Cleanup()
+	ts.AddTestProviderWithURL(t, "raw-openai", upstream.URL, "openai", true)
+	ts.AddTestProviderWithURL(t, "raw-anthropic", upstream.URL, "anthropic", true)
+	cfg := ts.appConfig.GetGlobalConfig()
+	modelToken := cfg.GetModelToken()
+
+	openaiProvider, err := cfg.GetProviderByName("raw-openai")
+	require.NoError(t, err)
+	anthropicProvider, err := cfg.GetProviderByName("raw-anthropic")
+	require.NoError(t, err)
+
+	send := func(method, path string, body []byte) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+modelToken)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("OpenAI-Beta", "assistants=v2")
+		w := httptest.NewRecorder()
+		ts.ginEngine.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("disabled_by_default", func(t *testing.T) {
+		w := send("GET", "/passthrough/"+openaiProvider.UUID+"/models", nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	require.NoError(t, cfg.SetEnablePassthrough(true))
+
+	t.Run("openai_credentials_and_body", func(t *testing.T) {
+		body := []byte(`{"training_file":"file-1","model":"gpt-4o-mini"}`)
+		w := send("POST", "/passthrough/"+openaiProvider.UUID+"/fine_tuning/jobs?limit=3", body)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, "yes", w.Header().Get("X-Upstream"))
+		assert.JSONEq(t, `{"id":"ft-job-1"}`, w.Body.String())
+
+		require.NotNil(t, lastRequest)
+		assert.Equal(t, "POST", lastRequest.Method)
+		assert.Equal(t, "/fine_tuning/jobs", lastRequest.URL.Path)
+		assert.Equal(t, "3", lastRequest.URL.Query().Get("limit"))
+		assert.Equal(t, "Bearer "+openaiProvider.GetAccessToken(), lastRequest.Header.Get("Authorization"))
+		assert.Equal(t, "assistants=v2", lastRequest.Header.Get("OpenAI-Beta"))
+		assert.Equal(t, body, lastBody)
+	})
+
+	t.Run("anthropic_credentials", func(t *testing.T) {
+		w := send("GET", "/passthrough/"+anthropicProvider.UUID+"/v1/models/claude-x", nil)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, anthropicProvider.GetAccessToken(), lastRequest.Header.Get("X-Api-Key"))
+		assert.Empty(t, lastRequest.Header.Get("Authorization"))
+		assert.Equal(t, "2023-06-01", lastRequest.Header.Get("Anthropic-Version"))
+	})
+
+	t.Run("unknown_provider", func(t *testing.T) {
+		w := send("GET", "/passthrough/does-not-exist/models", nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("requires_model_token", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/passthrough/"+openaiProvider.UUID+"/models", nil)
+		w := httptest.NewRecorder()
+		ts.ginEngine.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
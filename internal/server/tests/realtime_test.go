@@ -0,0 +1,178 @@
+package tests
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+// TestOpenAIRealtimeProxy tests that realtime WebSocket frames are relayed to the routed provider
+// with upstream credentials, and that usage events and session duration reach stats
+func TestOpenAIRealtimeProxy(t *testing.T) {
+	var upstreamAuth, upstreamModel string
+
+	// Echo stand-in for the provider's realtime API
+	upstream := httptest.NewServer(websocket.Server{
+		Handler: func(ws *websocket.Conn) {
+			for {
+				var payloadType byte
+				var data []byte
+				codec := websocket.Codec{Unmarshal: func(msg []byte, pt byte, _ interface{}) error {
+					payloadType, data = pt, msg
+					return nil
+				}}
+				if err := codec.Receive(ws, nil); err != nil {
+					return
+				}
+				if payloadType == websocket.BinaryFrame {
+					websocket.Message.Send(ws, data)
+					continue
+				}
+				websocket.Message.Send(ws, string(data))
+				if strings.Contains(string(data), "response.create") {
+					websocket.Message.Send(ws, `{"type":"response.done","response":{"usage":{"input_tokens":5,"output_tokens":9}}}`)
+				}
+			}
+		},
+		Handshake: func(config *websocket.Config, r *http.Request) error {
+			upstreamAuth = r.Header.Get("Authorization")
+			upstreamModel = r.URL.Query().Get("model")
+			return nil
+		},
+	})
+	defer upstream.Close()
+
+	ts := NewTestServer(t)
+	defer Cleanup()
+	ts.AddTestProviderWithURL(t, "realtime-provider", upstream.URL, "openai", true)
+	ts.AddTestRule(t, "tingly-realtime", "realtime-provider", "gpt-realtime")
+	cfg := ts.appConfig.GetGlobalConfig()
+	provider, err := cfg.GetProviderByName("realtime-provider")
+	require.NoError(t, err)
+
+	// CONNECT proxy for a second provider reaching the same upstream
+	var tunneled atomic.Value
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		tunneled.Store(r.Host)
+		target, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		conn, buf, _ := w.(http.Hijacker).Hijack()
+		conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+		go func() {
+			io.Copy(target, buf)
+			target.Close()
+		}()
+		io.Copy(conn, target)
+		conn.Close()
+	}))
+	defer proxy.Close()
+	ts.AddTestProviderWithURL(t, "realtime-proxied", upstream.URL, "openai", true)
+	ts.AddTestRule(t, "tingly-realtime-proxied", "realtime-proxied", "gpt-realtime")
+	proxied, err := cfg.GetProviderByName("realtime-proxied")
+	require.NoError(t, err)
+	proxied.ProxyURL = proxy.URL
+	require.NoError(t, cfg.UpdateProvider(proxied.UUID, proxied))
+
+	gateway := httptest.NewServer(ts.ginEngine)
+	defer gateway.Close()
+	wsURL := "ws" + strings.TrimPrefix(gateway.URL, "http") + "/openai/v1/realtime?model=tingly-realtime"
+	proxiedURL := "ws" + strings.TrimPrefix(gateway.URL, "http") + "/openai/v1/realtime?model=tingly-realtime-proxied"
+
+	rule := cfg.GetRuleByUUID("tingly-realtime")
+	require.NotNil(t, rule)
+	before := rule.Services[0].Stats.GetStats()
+
+	config, err := websocket.NewConfig(wsURL, gateway.URL)
+	require.NoError(t, err)
+	config.Header = http.Header{"Authorization": {"Bearer " + cfg.GetModelToken()}}
+	client, err := websocket.DialConfig(config)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Bearer "+provider.GetAccessToken(), upstreamAuth)
+	assert.Equal(t, "gpt-realtime", upstreamModel)
+
+	require.NoError(t, websocket.Message.Send(client, []byte{0x01, 0x02, 0x03}))
+	var binary []byte
+	require.NoError(t, websocket.Message.Receive(client, &binary))
+	assert.Equal(t, []byte{0x01, 0x02, 0x03}, binary)
+
+	require.NoError(t, websocket.Message.Send(client, `{"type":"response.create"}`))
+	var echo, done string
+	require.NoError(t, websocket.Message.Receive(client, &echo))
+	require.NoError(t, websocket.Message.Receive(client, &done))
+	assert.JSONEq(t, `{"type":"response.create"}`, echo)
+	assert.Contains(t, done, "response.done")
+
+	client.Close()
+
+	// The session is recorded once the relay notices the closed connection
+	require.Eventually(t, func() bool {
+		return rule.Services[0].Stats.GetStats().RealtimeSessions > before.RealtimeSessions
+	}, 2*time.Second, 20*time.Millisecond)
+	after := rule.Services[0].Stats.GetStats()
+	assert.Equal(t, int64(5), after.WindowInputTokens-before.WindowInputTokens)
+	assert.Equal(t, int64(9), after.WindowOutputTokens-before.WindowOutputTokens)
+	assert.Greater(t, after.RealtimeSeconds, before.RealtimeSeconds)
+
+	t.Run("requires_model_token", func(t *testing.T) {
+		config, _ := websocket.NewConfig(wsURL, gateway.URL)
+		_, err := websocket.DialConfig(config)
+		assert.Error(t, err)
+	})
+
+	t.Run("subprotocol_token", func(t *testing.T) {
+		config, _ := websocket.NewConfig(wsURL, gateway.URL)
+		config.Protocol = []string{"realtime", "openai-insecure-api-key." + cfg.GetModelToken()}
+		conn, err := websocket.DialConfig(config)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"realtime"}, conn.Config().Protocol)
+		sessions := rule.Services[0].Stats.GetStats().RealtimeSessions
+		conn.Close()
+		require.Eventually(t, func() bool {
+			return rule.Services[0].Stats.GetStats().RealtimeSessions > sessions
+		}, 2*time.Second, 20*time.Millisecond)
+
+		// The key carrier is never echoed back, even when it is the only offer
+		req, _ := http.NewRequest("GET", gateway.URL+"/openai/v1/realtime?model=tingly-realtime", nil)
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Upgrade", "websocket")
+		req.Header.Set("Sec-WebSocket-Version", "13")
+		req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+		req.Header.Set("Sec-WebSocket-Protocol", "openai-insecure-api-key."+cfg.GetModelToken())
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get("Sec-WebSocket-Protocol"))
+	})
+
+	t.Run("provider_proxy", func(t *testing.T) {
+		config, _ := websocket.NewConfig(proxiedURL, gateway.URL)
+		config.Header = http.Header{"Authorization": {"Bearer " + cfg.GetModelToken()}}
+		conn, err := websocket.DialConfig(config)
+		require.NoError(t, err)
+		defer conn.Close()
+
+		require.NoError(t, websocket.Message.Send(conn, `{"type":"ping"}`))
+		var echo string
+		require.NoError(t, websocket.Message.Receive(conn, &echo))
+		assert.JSONEq(t, `{"type":"ping"}`, echo)
+		assert.Equal(t, strings.TrimPrefix(upstream.URL, "http://"), tunneled.Load())
+	})
+}
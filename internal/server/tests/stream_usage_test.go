@@ -0,0 +1,49 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestOpenAIStreamIncludeUsage tests that the usage chunk an OpenAI-style provider sends without
+// choices reaches clients that asked for it
+func TestOpenAIStreamIncludeUsage(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range []string{
+			`{"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"gpt-4o","choices":[{"index":0,"delta":{"role":"assistant","content":"hi"},"finish_reason":"stop"}]}`,
+			`{"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"gpt-4o","choices":[],"usage":{"prompt_tokens":5,"completion_tokens":1,"total_tokens":6}}`,
+		} {
+			w.Write([]byte("data: " + chunk + "\n\n"))
+		}
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer upstream.Close()
+
+	ts := NewTestServer(t)
+	defer Cleanup()
+	ts.AddTestProviderWithURL(t, "usage-openai", upstream.URL, "openai", true)
+	ts.AddTestRule(t, "tingly-usage", "usage-openai", "gpt-4o")
+	cfg := ts.appConfig.GetGlobalConfig()
+
+	req, _ := http.NewRequest("POST", "/openai/v1/chat/completions", CreateJSONBody(map[string]interface{}{
+		"model":          "tingly-usage",
+		"stream":         true,
+		"stream_options": map[string]interface{}{"include_usage": true},
+		"messages":       []map[string]string{{"role": "user", "content": "hello"}},
+	}))
+	req.Header.Set("Authorization", "Bearer "+cfg.GetModelToken())
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	ts.ginEngine.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	assert.Contains(t, w.Body.String(), `"content":"hi"`)
+	assert.Contains(t, w.Body.String(), `"choices":[]`)
+	assert.Contains(t, w.Body.String(), `"prompt_tokens":5`)
+	assert.Contains(t, w.Body.String(), "data: [DONE]")
+}
@@ -0,0 +1,69 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStructuredOutputRetryUsage tests that an emulated structured output retry reports the
+// usage of both attempts, cache tokens included
+func TestStructuredOutputRetryUsage(t *testing.T) {
+	var calls atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		input := `{"answer":1}`
+		if calls.Add(1) > 1 {
+			input = `{"answer":"yes"}`
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","model":"claude-haiku-4-5",` +
+			`"content":[{"type":"tool_use","id":"toolu_1","name":"structured_output","input":` + input + `}],` +
+			`"stop_reason":"tool_use","usage":{"input_tokens":10,"output_tokens":4,"cache_read_input_tokens":100,"cache_creation_input_tokens":20}}`))
+	}))
+	defer upstream.Close()
+
+	ts := NewTestServerWithAdaptor(t, true)
+	defer Cleanup()
+	ts.AddTestProviderWithURL(t, "structured-anthropic", upstream.URL, "anthropic", true)
+	ts.AddTestRule(t, "tingly-structured", "structured-anthropic", "claude-haiku-4-5")
+	cfg := ts.appConfig.GetGlobalConfig()
+
+	req, _ := http.NewRequest("POST", "/openai/v1/chat/completions", CreateJSONBody(map[string]interface{}{
+		"model":    "tingly-structured",
+		"messages": []map[string]string{{"role": "user", "content": "answer"}},
+		"response_format": map[string]interface{}{
+			"type": "json_schema",
+			"json_schema": map[string]interface{}{
+				"name": "answer",
+				"schema": map[string]interface{}{
+					"type":       "object",
+					"properties": map[string]interface{}{"answer": map[string]interface{}{"type": "string"}},
+					"required":   []string{"answer"},
+				},
+			},
+		},
+	}))
+	req.Header.Set("Authorization", "Bearer "+cfg.GetModelToken())
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	ts.ginEngine.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.EqualValues(t, 2, calls.Load())
+
+	var resp struct {
+		Usage struct {
+			CompletionTokens    int `json:"completion_tokens"`
+			PromptTokensDetails struct {
+				CachedTokens int `json:"cached_tokens"`
+			} `json:"prompt_tokens_details"`
+		} `json:"usage"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 8, resp.Usage.CompletionTokens)
+	assert.Equal(t, 200, resp.Usage.PromptTokensDetails.CachedTokens)
+}
@@ -0,0 +1,137 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tingly-box/internal/typ"
+)
+
+// TestVertexProvider tests that vertex providers call rawPredict for Claude and
+// generateContent for Gemini with project- and region-scoped URLs
+func TestVertexProvider(t *testing.T) {
+	var lastPath, lastQuery, lastAuthorization string
+	var lastBody map[string]interface{}
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastPath = r.URL.Path
+		lastQuery = r.URL.RawQuery
+		lastAuthorization = r.Header.Get("Authorization")
+		lastBody = nil
+		_ = json.NewDecoder(r.Body).Decode(&lastBody)
+
+		switch {
+		case strings.HasSuffix(r.URL.Path, ":rawPredict"):
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4",` +
+				`"content":[{"type":"text","text":"hi from vertex claude"}],"stop_reason":"end_turn",` +
+				`"usage":{"input_tokens":3,"output_tokens":4}}`))
+		case strings.HasSuffix(r.URL.Path, ":generateContent"):
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"responseId":"resp-1","candidates":[{"index":0,"content":{"role":"model",` +
+				`"parts":[{"text":"hi from gemini"}]},"finishReason":"STOP"}],` +
+				`"usageMetadata":{"promptTokenCount":5,"candidatesTokenCount":3,"totalTokenCount":8}}`))
+		case strings.HasSuffix(r.URL.Path, ":streamGenerateContent"):
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Write([]byte(`data: {"candidates":[{"index":0,"content":{"role":"model","parts":[{"text":"hello "}]}}]}` + "\n\n"))
+			w.Write([]byte(`data: {"candidates":[{"index":0,"content":{"role":"model","parts":[{"text":"world"}]},"finishReason":"STOP"}],` +
+				`"usageMetadata":{"promptTokenCount":5,"candidatesTokenCount":2,"totalTokenCount":7}}` + "\n\n"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer upstream.Close()
+
+	ts := NewTestServer(t)
+	defer Cleanup()
+	ts.AddTestProviderWithURL(t, "vertex-claude", upstream.URL, "anthropic", true)
+	ts.AddTestProviderWithURL(t, "vertex-gemini", upstream.URL, "openai", true)
+	ts.AddTestRule(t, "tingly-vertex-claude", "vertex-claude", "claude-sonnet-4@20250514")
+	ts.AddTestRule(t, "tingly-vertex-gemini", "vertex-gemini", "gemini-2.0-flash")
+	cfg := ts.appConfig.GetGlobalConfig()
+
+	for _, name := range []string{"vertex-claude", "vertex-gemini"} {
+		provider, err := cfg.GetProviderByName(name)
+		require.NoError(t, err)
+		provider.Flavor = typ.FlavorVertex
+		provider.Region = "us-east5"
+		provider.ProjectID = "test-project"
+		require.NoError(t, cfg.UpdateProvider(provider.UUID, provider))
+	}
+	claudeProvider, err := cfg.GetProviderByName("vertex-claude")
+	require.NoError(t, err)
+
+	send := func(path string, body map[string]interface{}) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", path, CreateJSONBody(body))
+		req.Header.Set("Authorization", "Bearer "+cfg.GetModelToken())
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		ts.ginEngine.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("claude_raw_predict", func(t *testing.T) {
+		w := send("/anthropic/v1/messages", map[string]interface{}{
+			"model":      "tingly-vertex-claude",
+			"max_tokens": 16,
+			"messages":   []map[string]string{{"role": "user", "content": "hello"}},
+		})
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), "hi from vertex claude")
+
+		assert.Equal(t, "/v1/projects/test-project/locations/us-east5/publishers/anthropic/models/claude-sonnet-4@20250514:rawPredict", lastPath)
+		assert.Equal(t, "Bearer "+claudeProvider.GetAccessToken(), lastAuthorization)
+		assert.Equal(t, "vertex-2023-10-16", lastBody["anthropic_version"])
+		assert.NotContains(t, lastBody, "model")
+	})
+
+	t.Run("gemini_generate_content", func(t *testing.T) {
+		w := send("/openai/v1/chat/completions", map[string]interface{}{
+			"model": "tingly-vertex-gemini",
+			"messages": []map[string]string{
+				{"role": "system", "content": "be brief"},
+				{"role": "user", "content": "hello"},
+			},
+		})
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "/v1/projects/test-project/locations/us-east5/publishers/google/models/gemini-2.0-flash:generateContent", lastPath)
+		assert.Contains(t, lastBody, "systemInstruction")
+		assert.Contains(t, lastBody, "contents")
+
+		var resp struct {
+			Choices []struct {
+				Message struct {
+					Content string `json:"content"`
+				} `json:"message"`
+				FinishReason string `json:"finish_reason"`
+			} `json:"choices"`
+			Usage struct {
+				TotalTokens int `json:"total_tokens"`
+			} `json:"usage"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.Len(t, resp.Choices, 1)
+		assert.Equal(t, "hi from gemini", resp.Choices[0].Message.Content)
+		assert.Equal(t, "stop", resp.Choices[0].FinishReason)
+		assert.Equal(t, 8, resp.Usage.TotalTokens)
+	})
+
+	t.Run("gemini_stream_generate_content", func(t *testing.T) {
+		w := send("/openai/v1/chat/completions", map[string]interface{}{
+			"model":    "tingly-vertex-gemini",
+			"stream":   true,
+			"messages": []map[string]string{{"role": "user", "content": "hello"}},
+		})
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.True(t, strings.HasSuffix(lastPath, ":streamGenerateContent"))
+		assert.Equal(t, "alt=sse", lastQuery)
+		assert.Contains(t, w.Body.String(), "hello ")
+		assert.Contains(t, w.Body.String(), "world")
+	})
+}
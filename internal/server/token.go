@@ -1,68 +1,82 @@
 package server
 
 import (
-	"fmt"
+	"encoding/json"
+	"net/http"
 
-	"github.com/anthropics/anthropic-sdk-go"
-	"github.com/tiktoken-go/tokenizer"
+	"github.com/gin-gonic/gin"
+
+	"tingly-box/internal/tokencount"
 )
 
-// countTokensWithTiktoken approximates token count for OpenAI-style providers using tiktoken
-func countTokensWithTiktoken(model string, messages []anthropic.MessageParam, system []anthropic.TextBlockParam) (int, error) {
-	// Get the encoding for the model (default to O200kBase which is used by GPT-4o and above)
-	enc, err := tokenizer.Get(tokenizer.O200kBase)
+// OpenAICountTokensResponse is returned by the chat completions count_tokens endpoint
+type OpenAICountTokensResponse struct {
+	Object      string `json:"object"`
+	Model       string `json:"model"`
+	InputTokens int    `json:"input_tokens"`
+}
+
+// OpenAICountTokens counts the input tokens of a chat completion request without sending it.
+// The rule is resolved as for a real request, so the tokenizer matches the upstream model.
+func (s *Server) OpenAICountTokens(c *gin.Context) {
+	body, err := c.GetRawData()
 	if err != nil {
-		return 0, fmt.Errorf("failed to get tokenizer: %w", err)
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: ErrorDetail{
+				Message: "Failed to read request body: " + err.Error(),
+				Type:    "invalid_request_error",
+			},
+		})
+		return
 	}
 
-	totalTokens := 0
-
-	// Count tokens in system messages
-	for _, sys := range system {
-		count, err := enc.Count(sys.Text)
-		if err != nil {
-			// If counting fails, estimate with character count / 4
-			count = len(sys.Text) / 4
-		}
-		totalTokens += count
+	var req struct {
+		Model string `json:"model"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: ErrorDetail{
+				Message: "Invalid JSON: " + err.Error(),
+				Type:    "invalid_request_error",
+			},
+		})
+		return
+	}
+	if req.Model == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: ErrorDetail{
+				Message: "Model is required",
+				Type:    "invalid_request_error",
+			},
+		})
+		return
 	}
 
-	// Count tokens in regular messages
-	for _, msg := range messages {
-		// Count role
-		count, err := enc.Count(string(msg.Role))
-		if err != nil {
-			count = len(string(msg.Role)) / 4
-		}
-		totalTokens += count
-
-		// Count content blocks
-		for _, block := range msg.Content {
-			if block.OfText != nil {
-				count, err := enc.Count(block.OfText.Text)
-				if err != nil {
-					// If counting fails, estimate with character count / 4
-					count = len(block.OfText.Text) / 4
-				}
-				totalTokens += count
-			}
-
-			if block.OfThinking != nil {
-				count, err := enc.Count(block.OfThinking.Thinking)
-				if err != nil {
-					// If counting fails, estimate with character count / 4
-					count = len(block.OfThinking.Thinking) / 4
-				}
-				totalTokens += count
-			}
-			// Note: We're not counting image tokens here as they require special handling
-			// This is an approximation for text-only requests
-		}
+	_, selectedService, _, err := s.DetermineProviderAndModel(req.Model)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: ErrorDetail{
+				Message: err.Error(),
+				Type:    "invalid_request_error",
+			},
+		})
+		return
 	}
 
-	// Add some overhead for the request format (approximately)
-	// This accounts for the JSON structure and special tokens
-	totalTokens += 3 // Add a small buffer for format overhead
+	count, err := tokencount.CountOpenAIChat(selectedService.Model, body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: ErrorDetail{
+				Message: "Invalid request body: " + err.Error(),
+				Type:    "invalid_request_error",
+			},
+		})
+		return
+	}
 
-	return totalTokens, nil
+	c.JSON(http.StatusOK, OpenAICountTokensResponse{
+		Object:      "chat.completion.input_tokens",
+		Model:       selectedService.Model,
+		InputTokens: count,
+	})
 }
@@ -15,6 +15,7 @@ import (
 	assets "tingly-box/internal"
 	"tingly-box/internal/obs"
 	"tingly-box/internal/typ"
+	"tingly-box/pkg/adaptor"
 	"tingly-box/pkg/swagger"
 )
 
@@ -209,6 +210,11 @@ func (s *Server) GetStatus(c *gin.Context) {
 	response.Data.ProvidersTotal = len(providers)
 	response.Data.ProvidersEnabled = enabledCount
 	response.Data.RequestCount = 0
+	response.Data.ToolCallRepairs = make(map[string]int64)
+	for repair, count := range adaptor.ToolCallRepairCounts() {
+		response.Data.ToolCallRepairs[string(repair)] = count
+	}
+	response.Data.StreamErrors = adaptor.StreamErrorCounts()
 
 	c.JSON(http.StatusOK, response)
 }
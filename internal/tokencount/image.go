@@ -0,0 +1,107 @@
+package tokencount
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"strings"
+)
+
+// Image token formulas. Dimensions are read from inline base64 data; remote images are
+// not fetched, so their size is assumed to be defaultImageSide on both edges.
+const (
+	defaultImageSide = 1024
+
+	openAILowDetailTokens = 85
+	openAITileTokens      = 170
+
+	claudeMaxLongEdge  = 1568
+	claudeMaxImageArea = 1_150_000
+	claudeTokenDivisor = 750
+
+	geminiImageTokens    = 258
+	geminiSmallImageSide = 384
+	geminiTileSide       = 768
+)
+
+// imageSize returns the dimensions of a base64 encoded image, or the default size
+func imageSize(data string) (int, int) {
+	if data != "" {
+		decoded, err := base64.StdEncoding.DecodeString(data)
+		if err == nil {
+			if config, _, err := image.DecodeConfig(bytes.NewReader(decoded)); err == nil && config.Width > 0 && config.Height > 0 {
+				return config.Width, config.Height
+			}
+		}
+	}
+	return defaultImageSide, defaultImageSide
+}
+
+// dataURLPayload returns the base64 payload of a data URL, or "" for remote URLs
+func dataURLPayload(url string) string {
+	if !strings.HasPrefix(url, "data:") {
+		return ""
+	}
+	meta, data, ok := strings.Cut(strings.TrimPrefix(url, "data:"), ",")
+	if !ok || !strings.HasSuffix(meta, ";base64") {
+		return ""
+	}
+	return data
+}
+
+// Image counts the tokens of an image of the given base64 data (empty when unknown)
+// using the formula of the counter's model family. detail is OpenAI's image detail level.
+func (c *Counter) Image(data, detail string) int {
+	width, height := imageSize(data)
+	switch c.Family {
+	case FamilyClaude:
+		return claudeImageTokens(width, height)
+	case FamilyGemini:
+		return geminiImageTokensFor(width, height)
+	default:
+		if detail == "low" {
+			return openAILowDetailTokens
+		}
+		return openAIImageTokens(width, height)
+	}
+}
+
+// openAIImageTokens scales the image to fit 2048x2048, then its short side to 768,
+// and charges 170 tokens per 512px tile plus 85 base tokens
+func openAIImageTokens(width, height int) int {
+	w, h := float64(width), float64(height)
+	if longest := math.Max(w, h); longest > 2048 {
+		w, h = w*2048/longest, h*2048/longest
+	}
+	if shortest := math.Min(w, h); shortest > 768 {
+		w, h = w*768/shortest, h*768/shortest
+	}
+	tiles := math.Ceil(w/512) * math.Ceil(h/512)
+	return int(tiles)*openAITileTokens + openAILowDetailTokens
+}
+
+// claudeImageTokens resizes the image to Claude's limits and charges width*height/750
+func claudeImageTokens(width, height int) int {
+	w, h := float64(width), float64(height)
+	if longest := math.Max(w, h); longest > claudeMaxLongEdge {
+		w, h = w*claudeMaxLongEdge/longest, h*claudeMaxLongEdge/longest
+	}
+	if area := w * h; area > claudeMaxImageArea {
+		scale := math.Sqrt(claudeMaxImageArea / area)
+		w, h = w*scale, h*scale
+	}
+	return int(math.Ceil(w * h / claudeTokenDivisor))
+}
+
+// geminiImageTokensFor charges 258 tokens for small images and 258 per 768px tile otherwise
+func geminiImageTokensFor(width, height int) int {
+	if width <= geminiSmallImageSide && height <= geminiSmallImageSide {
+		return geminiImageTokens
+	}
+	tiles := math.Ceil(float64(width)/geminiTileSide) * math.Ceil(float64(height)/geminiTileSide)
+	return int(tiles) * geminiImageTokens
+}
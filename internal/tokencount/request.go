@@ -0,0 +1,227 @@
+package tokencount
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Structural overheads. OpenAI charges 3 tokens per message plus 3 to prime the reply;
+// tool definitions are rendered into a hidden system prompt, which for Claude is a fixed
+// 346 token preamble.
+const (
+	messageOverhead      = 3
+	replyPrimingOverhead = 3
+	nameOverhead         = 1
+	toolCallOverhead     = 3
+	toolOverhead         = 8
+	toolsOverhead        = 12
+	claudeToolsOverhead  = 346
+)
+
+// CountOpenAIChat counts the input tokens of an OpenAI chat completion request body
+func CountOpenAIChat(model string, body []byte) (int, error) {
+	var req struct {
+		Model    string `json:"model"`
+		Messages []struct {
+			Role       string          `json:"role"`
+			Name       string          `json:"name"`
+			Content    json.RawMessage `json:"content"`
+			ToolCallID string          `json:"tool_call_id"`
+			ToolCalls  []struct {
+				Function struct {
+					Name      string `json:"name"`
+					Arguments string `json:"arguments"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"messages"`
+		Tools []struct {
+			Function struct {
+				Name        string          `json:"name"`
+				Description string          `json:"description"`
+				Parameters  json.RawMessage `json:"parameters"`
+			} `json:"function"`
+		} `json:"tools"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return 0, fmt.Errorf("invalid chat completion request: %w", err)
+	}
+	if model == "" {
+		model = req.Model
+	}
+	c, err := NewCounter(model)
+	if err != nil {
+		return 0, err
+	}
+
+	total := replyPrimingOverhead
+	for _, msg := range req.Messages {
+		total += messageOverhead + c.Text(msg.Role)
+		if msg.Name != "" {
+			total += nameOverhead + c.Text(msg.Name)
+		}
+		total += c.openAIContent(msg.Content)
+		for _, call := range msg.ToolCalls {
+			total += toolCallOverhead + c.Text(call.Function.Name) + c.Text(call.Function.Arguments)
+		}
+	}
+
+	for i, tool := range req.Tools {
+		if i == 0 {
+			total += c.toolsOverhead()
+		}
+		total += toolOverhead + c.Text(tool.Function.Name) + c.Text(tool.Function.Description) + c.schema(tool.Function.Parameters)
+	}
+	return total, nil
+}
+
+// openAIContent counts string content or text and image content parts
+func (c *Counter) openAIContent(content json.RawMessage) int {
+	if len(content) == 0 || string(content) == "null" {
+		return 0
+	}
+	var text string
+	if json.Unmarshal(content, &text) == nil {
+		return c.Text(text)
+	}
+
+	var parts []struct {
+		Type     string `json:"type"`
+		Text     string `json:"text"`
+		ImageURL struct {
+			URL    string `json:"url"`
+			Detail string `json:"detail"`
+		} `json:"image_url"`
+	}
+	if json.Unmarshal(content, &parts) != nil {
+		return c.Text(string(content))
+	}
+
+	total := 0
+	for _, part := range parts {
+		switch part.Type {
+		case "text":
+			total += c.Text(part.Text)
+		case "image_url":
+			total += c.Image(dataURLPayload(part.ImageURL.URL), part.ImageURL.Detail)
+		}
+	}
+	return total
+}
+
+// CountAnthropicMessages counts the input tokens of an Anthropic messages request body
+func CountAnthropicMessages(model string, body []byte) (int, error) {
+	var req struct {
+		Model    string          `json:"model"`
+		System   json.RawMessage `json:"system"`
+		Messages []struct {
+			Role    string          `json:"role"`
+			Content json.RawMessage `json:"content"`
+		} `json:"messages"`
+		Tools []struct {
+			Name        string          `json:"name"`
+			Description string          `json:"description"`
+			InputSchema json.RawMessage `json:"input_schema"`
+		} `json:"tools"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return 0, fmt.Errorf("invalid messages request: %w", err)
+	}
+	if model == "" {
+		model = req.Model
+	}
+	c, err := NewCounter(model)
+	if err != nil {
+		return 0, err
+	}
+
+	total := replyPrimingOverhead + c.anthropicContent(req.System)
+	for _, msg := range req.Messages {
+		total += messageOverhead + c.Text(msg.Role) + c.anthropicContent(msg.Content)
+	}
+	for i, tool := range req.Tools {
+		if i == 0 {
+			total += c.toolsOverhead()
+		}
+		total += toolOverhead + c.Text(tool.Name) + c.Text(tool.Description) + c.schema(tool.InputSchema)
+	}
+	return total, nil
+}
+
+// anthropicBlock is the subset of Anthropic content blocks that carries billable content
+type anthropicBlock struct {
+	Type     string          `json:"type"`
+	Text     string          `json:"text"`
+	Thinking string          `json:"thinking"`
+	Name     string          `json:"name"`
+	Input    json.RawMessage `json:"input"`
+	Content  json.RawMessage `json:"content"`
+	Source   struct {
+		Type string `json:"type"`
+		Data string `json:"data"`
+	} `json:"source"`
+}
+
+// anthropicContent counts string content or content blocks, recursing into tool results
+func (c *Counter) anthropicContent(content json.RawMessage) int {
+	if len(content) == 0 || string(content) == "null" {
+		return 0
+	}
+	var text string
+	if json.Unmarshal(content, &text) == nil {
+		return c.Text(text)
+	}
+
+	var blocks []anthropicBlock
+	if json.Unmarshal(content, &blocks) != nil {
+		return c.Text(string(content))
+	}
+
+	total := 0
+	for _, block := range blocks {
+		switch block.Type {
+		case "text":
+			total += c.Text(block.Text)
+		case "thinking":
+			total += c.Text(block.Thinking)
+		case "image":
+			data := ""
+			if block.Source.Type == "base64" {
+				data = block.Source.Data
+			}
+			total += c.Image(data, "")
+		case "tool_use", "server_tool_use":
+			total += toolCallOverhead + c.Text(block.Name) + c.schema(block.Input)
+		case "tool_result":
+			total += toolCallOverhead + c.anthropicContent(block.Content)
+		case "document":
+			if block.Source.Type == "text" {
+				total += c.Text(block.Source.Data)
+			}
+		}
+	}
+	return total
+}
+
+// schema counts a JSON value in its compact form, as providers render it into the prompt
+func (c *Counter) schema(raw json.RawMessage) int {
+	if len(raw) == 0 || string(raw) == "null" {
+		return 0
+	}
+	var value interface{}
+	if json.Unmarshal(raw, &value) != nil {
+		return c.Text(string(raw))
+	}
+	compact, err := json.Marshal(value)
+	if err != nil {
+		return c.Text(string(raw))
+	}
+	return c.Text(string(compact))
+}
+
+// toolsOverhead is the fixed cost of declaring tools at all
+func (c *Counter) toolsOverhead() int {
+	if c.Family == FamilyClaude {
+		return claudeToolsOverhead
+	}
+	return toolsOverhead
+}
@@ -0,0 +1,118 @@
+// Package tokencount estimates the input tokens of chat requests before they are sent.
+// The tokenizer and the image and tool formulas are chosen by model family, so counts
+// approximate what the upstream provider will bill. Counting works on raw JSON request
+// bodies in either the OpenAI chat completion or the Anthropic messages format.
+package tokencount
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/tiktoken-go/tokenizer"
+)
+
+// Family groups models that share a tokenizer and billing formulas
+type Family string
+
+const (
+	FamilyOpenAI Family = "openai"
+	FamilyClaude Family = "claude"
+	FamilyGemini Family = "gemini"
+	FamilyOther  Family = "other"
+)
+
+// DetectFamily infers the model family from a model name, ignoring vendor prefixes
+// such as "anthropic." (Bedrock) or "google/" (Vertex, OpenRouter)
+func DetectFamily(model string) Family {
+	name := strings.ToLower(model)
+	switch {
+	case strings.Contains(name, "claude"):
+		return FamilyClaude
+	case strings.Contains(name, "gemini") || strings.Contains(name, "gemma"):
+		return FamilyGemini
+	case strings.Contains(name, "gpt") || strings.Contains(name, "chatgpt") ||
+		hasModelPrefix(name, "o1") || hasModelPrefix(name, "o3") || hasModelPrefix(name, "o4") ||
+		strings.Contains(name, "davinci") || strings.Contains(name, "text-embedding"):
+		return FamilyOpenAI
+	default:
+		return FamilyOther
+	}
+}
+
+// hasModelPrefix reports whether the model name, after any vendor prefix, starts with prefix
+func hasModelPrefix(name, prefix string) bool {
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	return name == prefix || strings.HasPrefix(name, prefix+"-")
+}
+
+var (
+	codecsMu sync.Mutex
+	codecs   = make(map[tokenizer.Encoding]tokenizer.Codec)
+)
+
+// encodingFor picks the tiktoken encoding closest to a model's tokenizer. Claude and
+// Gemini tokenizers are not public; cl100k and o200k are the closest approximations.
+func encodingFor(model string, family Family) tokenizer.Encoding {
+	name := strings.ToLower(model)
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	switch family {
+	case FamilyOpenAI:
+		if strings.HasPrefix(name, "gpt-4-") || name == "gpt-4" || strings.HasPrefix(name, "gpt-3.5") ||
+			strings.HasPrefix(name, "gpt-35") || strings.HasPrefix(name, "text-embedding") {
+			return tokenizer.Cl100kBase
+		}
+		return tokenizer.O200kBase
+	case FamilyClaude:
+		return tokenizer.Cl100kBase
+	default:
+		return tokenizer.O200kBase
+	}
+}
+
+// codecFor returns a cached codec for an encoding
+func codecFor(encoding tokenizer.Encoding) (tokenizer.Codec, error) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	if codec, ok := codecs[encoding]; ok {
+		return codec, nil
+	}
+	codec, err := tokenizer.Get(encoding)
+	if err != nil {
+		return nil, err
+	}
+	codecs[encoding] = codec
+	return codec, nil
+}
+
+// Counter counts tokens for one model
+type Counter struct {
+	Model  string
+	Family Family
+	codec  tokenizer.Codec
+}
+
+// NewCounter creates a counter using the tokenizer of the model's family
+func NewCounter(model string) (*Counter, error) {
+	family := DetectFamily(model)
+	codec, err := codecFor(encodingFor(model, family))
+	if err != nil {
+		return nil, err
+	}
+	return &Counter{Model: model, Family: family, codec: codec}, nil
+}
+
+// Text counts the tokens of a string, falling back to a character estimate
+func (c *Counter) Text(text string) int {
+	if text == "" {
+		return 0
+	}
+	count, err := c.codec.Count(text)
+	if err != nil {
+		return len(text) / 4
+	}
+	return count
+}
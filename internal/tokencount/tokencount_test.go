@@ -0,0 +1,108 @@
+package tokencount
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectFamily(t *testing.T) {
+	assert.Equal(t, FamilyOpenAI, DetectFamily("gpt-4o-mini"))
+	assert.Equal(t, FamilyOpenAI, DetectFamily("o3-mini"))
+	assert.Equal(t, FamilyOpenAI, DetectFamily("openai/o4-mini"))
+	assert.Equal(t, FamilyClaude, DetectFamily("claude-sonnet-4-20250514"))
+	assert.Equal(t, FamilyClaude, DetectFamily("anthropic.claude-3-haiku-20240307-v1:0"))
+	assert.Equal(t, FamilyGemini, DetectFamily("google/gemini-2.0-flash"))
+	assert.Equal(t, FamilyOther, DetectFamily("qwen3-coder"))
+}
+
+func TestCountAnthropicMessages(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantMin int
+	}{
+		{
+			name:    "simple user message",
+			body:    `{"messages":[{"role":"user","content":"Hello, world!"}]}`,
+			wantMin: 1,
+		},
+		{
+			name:    "message with system prompt",
+			body:    `{"system":"You are a helpful assistant.","messages":[{"role":"user","content":"What is the capital of France?"}]}`,
+			wantMin: 10,
+		},
+		{
+			name: "conversation with multiple messages",
+			body: `{"system":[{"type":"text","text":"You are a funny assistant."}],"messages":[
+				{"role":"user","content":[{"type":"text","text":"Hello!"}]},
+				{"role":"assistant","content":[{"type":"text","text":"Hi there! How can I help?"}]},
+				{"role":"user","content":[{"type":"text","text":"Tell me a joke."}]}]}`,
+			wantMin: 15,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count, err := CountAnthropicMessages("gpt-4o", []byte(tt.body))
+			require.NoError(t, err)
+			assert.GreaterOrEqual(t, count, tt.wantMin)
+			assert.Less(t, count, 10000)
+		})
+	}
+}
+
+func TestCountAnthropicToolsAndImages(t *testing.T) {
+	base := `{"messages":[{"role":"user","content":"weather?"}]}`
+	withTools := `{"tools":[{"name":"get_weather","description":"Get the weather","input_schema":{"type":"object","properties":{"city":{"type":"string"}}}}],
+		"messages":[{"role":"user","content":"weather?"},
+		{"role":"assistant","content":[{"type":"tool_use","id":"t1","name":"get_weather","input":{"city":"Paris"}}]},
+		{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":"sunny and 25 degrees"},
+			{"type":"image","source":{"type":"url","url":"https://example.com/a.png"}}]}]}`
+
+	plain, err := CountAnthropicMessages("claude-sonnet-4", []byte(base))
+	require.NoError(t, err)
+	full, err := CountAnthropicMessages("claude-sonnet-4", []byte(withTools))
+	require.NoError(t, err)
+
+	// Tool preamble, tool blocks and a default-sized image (1024x1024 -> 1399 tokens)
+	assert.Greater(t, full-plain, claudeToolsOverhead+1399)
+}
+
+func TestCountOpenAIChat(t *testing.T) {
+	body := `{"model":"gpt-4o","messages":[
+		{"role":"system","content":"You are helpful."},
+		{"role":"user","content":[{"type":"text","text":"Describe"},{"type":"image_url","image_url":{"url":"https://example.com/cat.jpg","detail":"low"}}]},
+		{"role":"assistant","tool_calls":[{"id":"c1","type":"function","function":{"name":"lookup","arguments":"{\"q\":\"cat\"}"}}]},
+		{"role":"tool","tool_call_id":"c1","content":"a cat"}],
+		"tools":[{"type":"function","function":{"name":"lookup","parameters":{"type":"object"}}}]}`
+
+	count, err := CountOpenAIChat("", []byte(body))
+	require.NoError(t, err)
+	assert.Greater(t, count, openAILowDetailTokens+toolsOverhead)
+	assert.Less(t, count, 200)
+}
+
+func TestImageTokens(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 200, 100))))
+	data := base64.StdEncoding.EncodeToString(buf.Bytes())
+	assert.Equal(t, 200, func() int { w, _ := imageSize(data); return w }())
+
+	openai, _ := NewCounter("gpt-4o")
+	assert.Equal(t, 255, openai.Image(data, "high"))
+	assert.Equal(t, 85, openai.Image(data, "low"))
+	assert.Equal(t, 765, openai.Image("", "auto"))
+
+	claude, _ := NewCounter("claude-sonnet-4")
+	assert.Equal(t, 27, claude.Image(data, ""))
+
+	gemini, _ := NewCounter("gemini-2.0-flash")
+	assert.Equal(t, 258, gemini.Image(data, ""))
+	assert.Equal(t, 4*258, gemini.Image("", ""))
+}
@@ -14,6 +14,22 @@ const (
 	APIStyleAnthropic APIStyle = "anthropic"
 )
 
+// ProviderFlavor identifies a vendor-specific variant of an API style
+type ProviderFlavor string
+
+const (
+	// FlavorAzure is Azure OpenAI: deployment-based URLs, an api-version query parameter and an api-key header
+	FlavorAzure ProviderFlavor = "azure"
+	// FlavorBedrock is Amazon Bedrock: Anthropic models through InvokeModel with SigV4 signing
+	FlavorBedrock ProviderFlavor = "bedrock"
+	// FlavorVertex is Google Vertex AI: Claude through rawPredict and Gemini through generateContent,
+	// authenticated with a Google OAuth access token
+	FlavorVertex ProviderFlavor = "vertex"
+)
+
+// DefaultAzureAPIVersion is used when an Azure provider has no api_version configured
+const DefaultAzureAPIVersion = "2024-10-21"
+
 // RuleScenario represents the scenario for a routing rule
 type RuleScenario string
 
@@ -72,6 +88,92 @@ type Provider struct {
 	// Auth configuration
 	AuthType    AuthType     `json:"auth_type"`              // api_key or oauth
 	OAuthDetail *OAuthDetail `json:"oauth_detail,omitempty"` // OAuth credentials (only for oauth auth type)
+
+	// Flavor configuration (only for vendor-specific variants of an API style)
+	Flavor      ProviderFlavor    `json:"flavor,omitempty"`      // "azure", "bedrock" or "vertex"; empty for the native API
+	APIVersion  string            `json:"api_version,omitempty"` // Azure api-version query parameter
+	Deployments map[string]string `json:"deployments,omitempty"` // Azure model name to deployment name mapping
+
+	// AWS configuration (only for the bedrock flavor); without keys the profile,
+	// the AWS_* environment variables and the default profile are tried in turn
+	Region             string `json:"region,omitempty"`                // AWS region, e.g. us-east-1
+	AWSAccessKeyID     string `json:"aws_access_key_id,omitempty"`     // Static access key
+	AWSSecretAccessKey string `json:"aws_secret_access_key,omitempty"` // Static secret key
+	AWSSessionToken    string `json:"aws_session_token,omitempty"`     // Session token for temporary credentials
+	AWSProfile         string `json:"aws_profile,omitempty"`           // Profile in the shared credentials file
+
+	// Google Cloud configuration (only for the vertex flavor, which uses Region as location)
+	ProjectID string `json:"project_id,omitempty"` // Falls back to the project_id discovered during OAuth
+
+	InlineImages     bool   `json:"inline_images,omitempty"`     // Download remote images and send them as base64
+	DocumentFallback string `json:"document_fallback,omitempty"` // PDFs in tool results for OpenAI-style targets: "text" (default) or "reject"; "text_only" always sends extracted text
+	// Keep Anthropic cache_control on OpenAI-style requests, for gateways such as OpenRouter that accept it
+	ForwardCacheControl bool `json:"forward_cache_control,omitempty"`
+	// OpenAI-compatible quirks to normalize requests and responses for, e.g. "deepseek"; defaults from the provider template
+	Dialect string `json:"dialect,omitempty"`
+	// Tool schema sanitizing; replaces the dialect's defaults when set
+	ToolSchema *ToolSchemaConfig `json:"tool_schema,omitempty"`
+}
+
+// ToolSchemaConfig describes the JSON Schema a provider accepts for tool parameters
+type ToolSchemaConfig struct {
+	DropKeywords         []string `json:"drop_keywords,omitempty"`          // Keywords removed wherever they appear, e.g. "$schema"
+	AllowedFormats       []string `json:"allowed_formats,omitempty"`        // String formats kept; empty keeps every format
+	OneOfToAnyOf         bool     `json:"one_of_to_any_of,omitempty"`       // Rewrite oneOf as anyOf
+	ConstToEnum          bool     `json:"const_to_enum,omitempty"`          // Rewrite const as a single value enum
+	MaxDepth             int      `json:"max_depth,omitempty"`              // Collapse schemas nested deeper than this
+	MaxDescriptionLength int      `json:"max_description_length,omitempty"` // Truncate longer descriptions
+}
+
+// IsEmpty reports whether the config sets nothing
+func (c *ToolSchemaConfig) IsEmpty() bool {
+	return len(c.DropKeywords) == 0 && len(c.AllowedFormats) == 0 && !c.OneOfToAnyOf && !c.ConstToEnum &&
+		c.MaxDepth == 0 && c.MaxDescriptionLength == 0
+}
+
+// IsAzure reports whether the provider is an Azure OpenAI resource
+func (p *Provider) IsAzure() bool {
+	return p.Flavor == FlavorAzure
+}
+
+// IsBedrock reports whether the provider is Amazon Bedrock
+func (p *Provider) IsBedrock() bool {
+	return p.Flavor == FlavorBedrock
+}
+
+// IsVertex reports whether the provider is Google Vertex AI
+func (p *Provider) IsVertex() bool {
+	return p.Flavor == FlavorVertex
+}
+
+// VertexProjectID returns the configured project ID or the one stored with the OAuth token
+func (p *Provider) VertexProjectID() string {
+	if p.ProjectID != "" {
+		return p.ProjectID
+	}
+	if p.OAuthDetail != nil {
+		if projectID, ok := p.OAuthDetail.ExtraFields["project_id"].(string); ok {
+			return projectID
+		}
+	}
+	return ""
+}
+
+// AzureAPIVersion returns the configured Azure api-version or the default one
+func (p *Provider) AzureAPIVersion() string {
+	if p.APIVersion != "" {
+		return p.APIVersion
+	}
+	return DefaultAzureAPIVersion
+}
+
+// AzureDeployment returns the deployment serving a model; unmapped models are assumed
+// to be deployed under their own name
+func (p *Provider) AzureDeployment(model string) string {
+	if deployment, ok := p.Deployments[model]; ok && deployment != "" {
+		return deployment
+	}
+	return model
 }
 
 // GetAccessToken returns the access token based on auth type
@@ -108,6 +210,9 @@ type Rule struct {
 	// Unified Tactic Configuration
 	LBTactic Tactic `json:"lb_tactic" yaml:"lb_tactic"`
 	Active   bool   `json:"active" yaml:"active"`
+	// Prompt caching for Anthropic upstreams
+	AutoCache      bool `json:"auto_cache,omitempty" yaml:"auto_cache,omitempty"`             // Insert cache_control breakpoints on system, tools and recent turns
+	AutoCacheTurns int  `json:"auto_cache_turns,omitempty" yaml:"auto_cache_turns,omitempty"` // Recent user turns to mark; defaults to 2
 }
 
 // ToJSON implementation
@@ -125,6 +230,8 @@ func (r *Rule) ToJSON() interface{} {
 		"current_service_index": r.CurrentServiceIndex,
 		"lb_tactic":             r.LBTactic,
 		"active":                r.Active,
+		"auto_cache":            r.AutoCache,
+		"auto_cache_turns":      r.AutoCacheTurns,
 	}
 
 	return jsonRule
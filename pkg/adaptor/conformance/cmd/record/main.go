@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"tingly-box/pkg/adaptor/conformance"
+)
+
+// DefaultFixtureDir is where the conformance test loads fixtures from, relative to the repository root
+const DefaultFixtureDir = "pkg/adaptor/conformance/testdata"
+
+func main() {
+	converter := flag.String("converter", "", "Converter to record, one of: "+strings.Join(conformance.ConverterNames(), ", "))
+	name := flag.String("name", "", "Fixture name, used as its directory name")
+	input := flag.String("input", "-", "Captured request, response or SSE transcript; - reads stdin")
+	dir := flag.String("dir", DefaultFixtureDir, "Fixture directory")
+	description := flag.String("description", "", "What the fixture covers")
+	model := flag.String("model", "", "Response model for response and stream converters")
+	maxTokens := flag.Int64("max-tokens", 0, "Default max_tokens for OpenAI to Anthropic requests")
+	includeUsage := flag.Bool("include-usage", false, "Ask stream converters for a usage chunk")
+	documentFallback := flag.String("document-fallback", "", "Document fallback option")
+	forwardCacheControl := flag.Bool("forward-cache-control", false, "Forward cache_control to OpenAI-style requests")
+	flag.Parse()
+
+	if *converter == "" || *name == "" {
+		fmt.Fprintf(os.Stderr, "Usage: %s -converter NAME -name FIXTURE [-input FILE]\n", os.Args[0])
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+
+	var data []byte
+	var err error
+	if *input == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(*input)
+	}
+	if err != nil {
+		log.Fatalf("Failed to read input: %v", err)
+	}
+
+	c, err := conformance.Record(*dir, *name, conformance.Case{
+		Description:  *description,
+		Converter:    *converter,
+		Model:        *model,
+		MaxTokens:    *maxTokens,
+		IncludeUsage: *includeUsage,
+		Options: conformance.Options{
+			DocumentFallback:    *documentFallback,
+			ForwardCacheControl: *forwardCacheControl,
+		},
+	}, data)
+	if err != nil {
+		log.Fatalf("Failed to record fixture: %v", err)
+	}
+	fmt.Printf("Recorded fixture %s; review the expected output before committing\n", c.Dir)
+}
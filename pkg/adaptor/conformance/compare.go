@@ -0,0 +1,278 @@
+package conformance
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// generatedIDPattern matches the IDs converters generate from the clock
+var generatedIDPattern = regexp.MustCompile(`^((msg_|chatcmpl-)\d+|toolu_[0-9a-f]{24})$`)
+
+// placeholderSignaturePrefix marks the random signatures given to unsigned thinking
+const placeholderSignaturePrefix = "thinking-"
+
+// Event is one server-sent event of a transcript
+type Event struct {
+	Event string      `json:"event,omitempty"`
+	Data  interface{} `json:"data"`
+}
+
+// ParseSSE splits an SSE transcript into events, decoding JSON data; comments are skipped
+func ParseSSE(transcript []byte) ([]Event, error) {
+	var events []Event
+	var event string
+	var data []string
+	flush := func() {
+		if event == "" && len(data) == 0 {
+			return
+		}
+		raw := strings.Join(data, "\n")
+		var value interface{}
+		if err := json.Unmarshal([]byte(raw), &value); err != nil {
+			value = raw
+		}
+		events = append(events, Event{Event: event, Data: value})
+		event, data = "", nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(transcript))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		switch {
+		case line == "":
+			flush()
+		case strings.HasPrefix(line, ":"):
+			// Comment, such as a heartbeat
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	flush()
+	return events, scanner.Err()
+}
+
+// Compare reports the first semantic difference between expected and actual output: JSON
+// documents, or SSE transcripts compared event by event. Generated IDs, timestamps, placeholder
+// signatures and empty fields are ignored.
+func Compare(expected, actual []byte, stream bool) error {
+	var want, got interface{}
+	if stream {
+		wantEvents, err := ParseSSE(expected)
+		if err != nil {
+			return fmt.Errorf("invalid expected transcript: %w", err)
+		}
+		gotEvents, err := ParseSSE(actual)
+		if err != nil {
+			return fmt.Errorf("invalid transcript: %w", err)
+		}
+		want, got = eventsToValue(wantEvents), eventsToValue(gotEvents)
+	} else {
+		if err := json.Unmarshal(expected, &want); err != nil {
+			return fmt.Errorf("invalid expected JSON: %w", err)
+		}
+		if err := json.Unmarshal(actual, &got); err != nil {
+			return fmt.Errorf("invalid JSON: %w", err)
+		}
+	}
+
+	want, _ = prune(want)
+	got, _ = prune(got)
+	if diff := firstDifference("$", normalize("", want), normalize("", got)); diff != "" {
+		return fmt.Errorf("output differs at %s", diff)
+	}
+	return nil
+}
+
+// eventsToValue renders events as plain JSON values for comparison
+func eventsToValue(events []Event) []interface{} {
+	values := make([]interface{}, len(events))
+	for i, e := range events {
+		values[i] = map[string]interface{}{"event": e.Event, "data": e.Data}
+	}
+	return values
+}
+
+// prune drops empty fields: null, "", 0, false, empty objects and arrays, and SDK unions with no
+// variant set. The SDK types marshal the zero value of every field, so a field the converter
+// never set looks the same as an empty one, and an SDK upgrade adds and removes them freely.
+// It reports whether the value itself is empty; array items are kept to preserve positions.
+func prune(value interface{}) (interface{}, bool) {
+	switch v := value.(type) {
+	case nil:
+		return nil, true
+	case map[string]interface{}:
+		if isUnsetUnion(v) {
+			return nil, true
+		}
+		out := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			if item, empty := prune(item); !empty {
+				out[k] = item
+			}
+		}
+		return out, len(out) == 0
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i], _ = prune(item)
+		}
+		return out, len(out) == 0
+	case string:
+		return v, v == ""
+	case float64:
+		return v, v == 0
+	case bool:
+		return v, !v
+	}
+	return value, false
+}
+
+// isUnsetUnion reports whether an object is an SDK union with no variant set. Union variants
+// marshal under their Go field names, such as OfWebSearchResultBlockArray, next to the zero
+// values of the shared fields.
+func isUnsetUnion(v map[string]interface{}) bool {
+	variants := 0
+	for k, item := range v {
+		if !isUnionVariant(k) {
+			continue
+		}
+		if item != nil {
+			return false
+		}
+		variants++
+	}
+	return variants > 0
+}
+
+// isUnionVariant reports whether key is the Go field name of a union variant
+func isUnionVariant(key string) bool {
+	return len(key) > 2 && strings.HasPrefix(key, "Of") && key[2] >= 'A' && key[2] <= 'Z'
+}
+
+// pruneJSON drops the empty fields of a JSON document, leaving other data untouched
+func pruneJSON(data []byte) []byte {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return data
+	}
+	value, _ = prune(value)
+	pruned, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return data
+	}
+	return append(pruned, '\n')
+}
+
+// normalize replaces the values that change from run to run
+func normalize(key string, value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			out[k] = normalize(k, item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = normalize(key, item)
+		}
+		return out
+	case string:
+		switch {
+		case key == "id" && generatedIDPattern.MatchString(v):
+			return "<generated>"
+		case key == "signature" && strings.HasPrefix(v, placeholderSignaturePrefix):
+			return "<placeholder>"
+		}
+	case float64:
+		if key == "created" {
+			return float64(0)
+		}
+	}
+	return value
+}
+
+// firstDifference describes the first difference between two JSON values, or returns ""
+func firstDifference(path string, want, got interface{}) string {
+	switch w := want.(type) {
+	case map[string]interface{}:
+		g, ok := got.(map[string]interface{})
+		if !ok {
+			return fmt.Sprintf("%s: expected object, got %s", path, compact(got))
+		}
+		keys := make([]string, 0, len(w)+len(g))
+		for k := range w {
+			keys = append(keys, k)
+		}
+		for k := range g {
+			if _, ok := w[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			wv, wok := w[k]
+			gv, gok := g[k]
+			switch {
+			case !gok:
+				return fmt.Sprintf("%s.%s: missing, expected %s", path, k, compact(wv))
+			case !wok:
+				return fmt.Sprintf("%s.%s: unexpected %s", path, k, compact(gv))
+			}
+			if diff := firstDifference(path+"."+k, wv, gv); diff != "" {
+				return diff
+			}
+		}
+		return ""
+	case []interface{}:
+		g, ok := got.([]interface{})
+		if !ok {
+			return fmt.Sprintf("%s: expected array, got %s", path, compact(got))
+		}
+		for i := 0; i < len(w) && i < len(g); i++ {
+			if diff := firstDifference(fmt.Sprintf("%s[%d]", path, i), w[i], g[i]); diff != "" {
+				return diff
+			}
+		}
+		if len(w) != len(g) {
+			return fmt.Sprintf("%s: expected %d items, got %d", path, len(w), len(g))
+		}
+		return ""
+	}
+	if !reflect.DeepEqual(want, got) {
+		return fmt.Sprintf("%s: expected %s, got %s", path, compact(want), compact(got))
+	}
+	return ""
+}
+
+// compact renders a value for difference messages
+func compact(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	if len(data) > 200 {
+		return string(data[:200]) + "..."
+	}
+	return string(data)
+}
+
+// indentJSON pretty prints a JSON document so that fixtures diff well
+func indentJSON(data []byte) []byte {
+	var out bytes.Buffer
+	if err := json.Indent(&out, bytes.TrimSpace(data), "", "  "); err != nil {
+		return data
+	}
+	out.WriteByte('\n')
+	return out.Bytes()
+}
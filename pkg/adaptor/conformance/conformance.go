@@ -0,0 +1,195 @@
+// Package conformance runs the adaptor converters against golden fixtures: recorded requests,
+// responses and SSE transcripts in each API style, compared semantically with the converted
+// output that was reviewed when the fixture was recorded.
+package conformance
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// File names inside a fixture directory
+const (
+	CaseFile         = "case.json"
+	inputJSONFile    = "input.json"
+	inputSSEFile     = "input.sse"
+	expectedJSONFile = "expected.json"
+	expectedSSEFile  = "expected.sse"
+)
+
+// Case describes one fixture: the converter to run and its settings
+type Case struct {
+	Description string `json:"description,omitempty"`
+	Converter   string `json:"converter"`
+	// Model is the response model passed to response and stream converters
+	Model string `json:"model,omitempty"`
+	// MaxTokens is the default max_tokens for OpenAI to Anthropic requests
+	MaxTokens int64 `json:"max_tokens,omitempty"`
+	// IncludeUsage asks stream converters that support it for a usage chunk
+	IncludeUsage bool `json:"include_usage,omitempty"`
+	// Options are the request conversion options, as the provider configures them
+	Options Options `json:"options,omitempty"`
+	// KnownIssues lists what is wrong with the expected output. The output is kept so the case
+	// still catches other regressions; the fix updates it and removes the issue.
+	KnownIssues []string `json:"known_issues,omitempty"`
+
+	// Name and Dir are set when the case is loaded
+	Name string `json:"-"`
+	Dir  string `json:"-"`
+}
+
+// Options mirrors adaptor.ConvertOptions in fixture files
+type Options struct {
+	DocumentFallback    string `json:"document_fallback,omitempty"`
+	ForwardCacheControl bool   `json:"forward_cache_control,omitempty"`
+}
+
+// inputFile returns the fixture input file name for the case's converter
+func (c *Case) inputFile(conv *Converter) string {
+	if conv.Stream {
+		return inputSSEFile
+	}
+	return inputJSONFile
+}
+
+// expectedFile returns the fixture expected output file name for the case's converter
+func (c *Case) expectedFile(conv *Converter) string {
+	if conv.Stream {
+		return expectedSSEFile
+	}
+	return expectedJSONFile
+}
+
+// LoadCases loads every fixture directory under dir, sorted by name
+func LoadCases(dir string) ([]*Case, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var cases []*Case
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		c, err := LoadCase(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		cases = append(cases, c)
+	}
+	sort.Slice(cases, func(i, j int) bool { return cases[i].Name < cases[j].Name })
+	return cases, nil
+}
+
+// LoadCase loads the fixture in dir
+func LoadCase(dir string) (*Case, error) {
+	data, err := os.ReadFile(filepath.Join(dir, CaseFile))
+	if err != nil {
+		return nil, err
+	}
+	var c Case
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("invalid %s in %s: %w", CaseFile, dir, err)
+	}
+	if _, ok := converters[c.Converter]; !ok {
+		return nil, fmt.Errorf("unknown converter %q in %s", c.Converter, dir)
+	}
+	c.Name = filepath.Base(dir)
+	c.Dir = dir
+	return &c, nil
+}
+
+// Input reads the fixture input
+func (c *Case) Input() ([]byte, error) {
+	return os.ReadFile(filepath.Join(c.Dir, c.inputFile(converters[c.Converter])))
+}
+
+// Run converts the fixture input with the case's converter
+func (c *Case) Run() ([]byte, error) {
+	input, err := c.Input()
+	if err != nil {
+		return nil, err
+	}
+	return converters[c.Converter].Run(c, input)
+}
+
+// Check runs the case and compares the output with the expected output
+func (c *Case) Check() error {
+	conv := converters[c.Converter]
+	expected, err := os.ReadFile(filepath.Join(c.Dir, c.expectedFile(conv)))
+	if err != nil {
+		return err
+	}
+	actual, err := c.Run()
+	if err != nil {
+		return err
+	}
+	return Compare(expected, actual, conv.Stream)
+}
+
+// Update runs the case and stores the output as the expected output, leaving expected output that
+// only differs in generated values untouched. JSON output is stored without its empty fields.
+func (c *Case) Update() error {
+	conv := converters[c.Converter]
+	actual, err := c.Run()
+	if err != nil {
+		return err
+	}
+	if !conv.Stream {
+		actual = pruneJSON(actual)
+	}
+	path := filepath.Join(c.Dir, c.expectedFile(conv))
+	if expected, err := os.ReadFile(path); err == nil && Compare(expected, actual, conv.Stream) == nil {
+		return nil
+	}
+	return os.WriteFile(path, actual, 0644)
+}
+
+// Record creates a fixture named name under dir from a captured input, storing the current
+// converter output as the expected output to review
+func Record(dir, name string, c Case, input []byte) (*Case, error) {
+	conv, ok := converters[c.Converter]
+	if !ok {
+		return nil, fmt.Errorf("unknown converter %q", c.Converter)
+	}
+	if name == "" {
+		return nil, errors.New("fixture name is required")
+	}
+	if !conv.Stream && !json.Valid(input) {
+		return nil, errors.New("input is not valid JSON")
+	}
+
+	c.Name = name
+	c.Dir = filepath.Join(dir, name)
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return nil, err
+	}
+	caseData, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(c.Dir, CaseFile), append(caseData, '\n'), 0644); err != nil {
+		return nil, err
+	}
+	if !conv.Stream {
+		input = indentJSON(input)
+	}
+	if err := os.WriteFile(filepath.Join(c.Dir, c.inputFile(conv)), input, 0644); err != nil {
+		return nil, err
+	}
+	return &c, c.Update()
+}
+
+// ConverterNames lists the converters fixtures can use
+func ConverterNames() []string {
+	names := make([]string, 0, len(converters))
+	for name := range converters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
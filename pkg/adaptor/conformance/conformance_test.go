@@ -0,0 +1,103 @@
+package conformance
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "rewrite the expected output of every fixture")
+
+func TestConformance(t *testing.T) {
+	cases, err := LoadCases("testdata")
+	require.NoError(t, err)
+	require.NotEmpty(t, cases)
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			for _, issue := range c.KnownIssues {
+				t.Logf("known issue: %s", issue)
+			}
+			if *update {
+				require.NoError(t, c.Update())
+				return
+			}
+			assert.NoError(t, c.Check())
+		})
+	}
+}
+
+func TestEveryConverterHasFixtures(t *testing.T) {
+	cases, err := LoadCases("testdata")
+	require.NoError(t, err)
+
+	covered := make(map[string]bool)
+	for _, c := range cases {
+		covered[c.Converter] = true
+	}
+	for _, name := range ConverterNames() {
+		assert.True(t, covered[name], "no fixture for converter %s", name)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	// Generated IDs and timestamps are ignored, key order does not matter
+	assert.NoError(t, Compare(
+		[]byte(`{"id":"chatcmpl-1","created":1,"choices":[{"text":"a"}]}`),
+		[]byte(`{"choices":[{"text":"a"}],"created":2,"id":"chatcmpl-2"}`), false))
+
+	// Empty fields and unset SDK unions are ignored
+	assert.NoError(t, Compare(
+		[]byte(`{"content":[{"type":"text","text":"a"}]}`),
+		[]byte(`{"content":[{"type":"text","text":"a","id":"","input":null,"content":{"OfArray":null,"type":"error"}}],"usage":{"input_tokens":0}}`), false))
+
+	err := Compare([]byte(`{"choices":[{"text":"a"}]}`), []byte(`{"choices":[{"text":"b"}]}`), false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `$.choices[0].text: expected "a", got "b"`)
+
+	err = Compare([]byte(`{"a":1}`), []byte(`{"a":1,"b":2}`), false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "$.b: unexpected 2")
+
+	// Stream events compare by data, not by formatting
+	assert.NoError(t, Compare(
+		[]byte("event: ping\ndata: {\"type\":\"ping\"}\n\ndata: [DONE]\n\n"),
+		[]byte(": heartbeat\n\nevent:ping\ndata:{\"type\": \"ping\"}\n\ndata: [DONE]\n\n"), true))
+
+	err = Compare([]byte("data: {\"a\":1}\n\n"), []byte("data: {\"a\":1}\n\ndata: [DONE]\n\n"), true)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "expected 1 items, got 2")
+}
+
+func TestParseSSE(t *testing.T) {
+	events, err := ParseSSE([]byte("event: message_start\ndata: {\"type\":\"message_start\"}\n\n: comment\n\ndata: [DONE]\n"))
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, "message_start", events[0].Event)
+	assert.Equal(t, map[string]interface{}{"type": "message_start"}, events[0].Data)
+	assert.Equal(t, "[DONE]", events[1].Data)
+}
+
+func TestRecord(t *testing.T) {
+	dir := t.TempDir()
+	c, err := Record(dir, "simple", Case{Converter: "anthropic_to_openai_request"},
+		[]byte(`{"model":"claude-sonnet-4","max_tokens":100,"messages":[{"role":"user","content":"Hi"}]}`))
+	require.NoError(t, err)
+
+	for _, name := range []string{CaseFile, inputJSONFile, expectedJSONFile} {
+		assert.FileExists(t, filepath.Join(dir, "simple", name))
+	}
+	loaded, err := LoadCase(c.Dir)
+	require.NoError(t, err)
+	assert.Equal(t, "anthropic_to_openai_request", loaded.Converter)
+	assert.NoError(t, loaded.Check())
+
+	_, err = Record(dir, "bad", Case{Converter: "unknown"}, nil)
+	assert.Error(t, err)
+	_, err = os.Stat(filepath.Join(dir, "bad"))
+	assert.True(t, os.IsNotExist(err))
+}
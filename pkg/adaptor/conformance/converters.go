@@ -0,0 +1,135 @@
+package conformance
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	anthropicOption "github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/openai/openai-go/v3"
+	openaiOption "github.com/openai/openai-go/v3/option"
+
+	"tingly-box/pkg/adaptor"
+)
+
+// defaultMaxTokens is used by OpenAI to Anthropic request cases without max_tokens
+const defaultMaxTokens = 4096
+
+// Converter runs one adaptor conversion on a fixture input
+type Converter struct {
+	// Stream marks converters whose input and output are SSE transcripts
+	Stream bool
+	Run    func(c *Case, input []byte) ([]byte, error)
+}
+
+// converters maps the converter names used in case.json to the adaptor functions they exercise
+var converters = map[string]*Converter{
+	"openai_to_anthropic_request": {Run: func(c *Case, input []byte) ([]byte, error) {
+		var req openai.ChatCompletionNewParams
+		if err := json.Unmarshal(input, &req); err != nil {
+			return nil, err
+		}
+		// The server keeps the fields the SDK types drop before converting
+		adaptor.PreserveReasoningFields(&req, input)
+		adaptor.PreserveCacheControl(&req, input)
+		maxTokens := c.MaxTokens
+		if maxTokens == 0 {
+			maxTokens = defaultMaxTokens
+		}
+		params, err := adaptor.ConvertOpenAIToAnthropicRequestWithOptions(&req, maxTokens, c.Options.convertOptions())
+		return marshalResult(params, err)
+	}},
+	"anthropic_to_openai_request": {Run: func(c *Case, input []byte) ([]byte, error) {
+		var req anthropic.MessageNewParams
+		if err := json.Unmarshal(input, &req); err != nil {
+			return nil, err
+		}
+		params, err := adaptor.ConvertAnthropicToOpenAIRequestWithOptions(&req, c.Options.convertOptions())
+		return marshalResult(params, err)
+	}},
+	"openai_to_gemini_request": {Run: func(c *Case, input []byte) ([]byte, error) {
+		return marshalResult(adaptor.ConvertOpenAIToGeminiRequest(input))
+	}},
+	"anthropic_to_openai_response": {Run: func(c *Case, input []byte) ([]byte, error) {
+		var resp anthropic.Message
+		if err := json.Unmarshal(input, &resp); err != nil {
+			return nil, err
+		}
+		return marshalResult(adaptor.ConvertAnthropicToOpenAIResponse(&resp, c.Model), nil)
+	}},
+	"openai_to_anthropic_response": {Run: func(c *Case, input []byte) ([]byte, error) {
+		var resp openai.ChatCompletion
+		if err := json.Unmarshal(input, &resp); err != nil {
+			return nil, err
+		}
+		return marshalResult(adaptor.ConvertOpenAIToAnthropicResponse(&resp, c.Model), nil)
+	}},
+	"gemini_to_openai_response": {Run: func(c *Case, input []byte) ([]byte, error) {
+		return marshalResult(adaptor.ConvertGeminiToOpenAIResponse(input, c.Model))
+	}},
+	"anthropic_to_openai_stream": {Stream: true, Run: func(c *Case, input []byte) ([]byte, error) {
+		client := anthropic.NewClient(anthropicOption.WithHTTPClient(sseClient(input)), anthropicOption.WithAPIKey("fixture"), anthropicOption.WithMaxRetries(0))
+		stream := client.Messages.NewStreaming(context.Background(), anthropic.MessageNewParams{Model: anthropic.Model(c.Model), MaxTokens: defaultMaxTokens})
+		defer stream.Close()
+		var out bytes.Buffer
+		err := adaptor.ConvertAnthropicToOpenAIStream(stream, &out, c.Model, adaptor.StreamOptions{IncludeUsage: c.IncludeUsage})
+		return out.Bytes(), err
+	}},
+	"openai_to_anthropic_stream": {Stream: true, Run: func(c *Case, input []byte) ([]byte, error) {
+		client := openai.NewClient(openaiOption.WithHTTPClient(sseClient(input)), openaiOption.WithAPIKey("fixture"), openaiOption.WithMaxRetries(0))
+		stream := client.Chat.Completions.NewStreaming(context.Background(), openai.ChatCompletionNewParams{Model: c.Model})
+		defer stream.Close()
+		var out bytes.Buffer
+		err := adaptor.ConvertOpenAIToAnthropicStream(stream, &out, c.Model, adaptor.StreamOptions{})
+		return out.Bytes(), err
+	}},
+	"gemini_to_openai_stream": {Stream: true, Run: func(c *Case, input []byte) ([]byte, error) {
+		var out bytes.Buffer
+		err := adaptor.ConvertGeminiToOpenAIStream(bytes.NewReader(input), &out, c.Model, c.IncludeUsage)
+		return out.Bytes(), err
+	}},
+}
+
+// convertOptions converts fixture options into adaptor options
+func (o Options) convertOptions() adaptor.ConvertOptions {
+	return adaptor.ConvertOptions{
+		DocumentFallback:    adaptor.DocumentFallback(o.DocumentFallback),
+		ForwardCacheControl: o.ForwardCacheControl,
+	}
+}
+
+// marshalResult renders a converter result; conversion errors are part of the expected
+// output, recorded next to the partially converted value
+func marshalResult(v interface{}, err error) ([]byte, error) {
+	result := v
+	if err != nil {
+		result = map[string]interface{}{"error": err.Error(), "result": v}
+	}
+	data, marshalErr := json.MarshalIndent(result, "", "  ")
+	if marshalErr != nil {
+		return nil, marshalErr
+	}
+	return append(data, '\n'), nil
+}
+
+// roundTripFunc serves HTTP requests from a function
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// sseClient returns an HTTP client answering every request with an SSE transcript
+func sseClient(transcript []byte) *http.Client {
+	return &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"text/event-stream"}},
+			Body:       io.NopCloser(bytes.NewReader(transcript)),
+			Request:    req,
+		}, nil
+	})}
+}
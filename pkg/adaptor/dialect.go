@@ -0,0 +1,289 @@
+package adaptor
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/packages/param"
+	"github.com/openai/openai-go/v3/packages/respjson"
+)
+
+// Dialect names the quirks of an OpenAI-compatible API. The empty dialect sends requests as they are.
+type Dialect string
+
+const (
+	DialectNone       Dialect = ""
+	DialectOpenAI     Dialect = "openai"
+	DialectDeepSeek   Dialect = "deepseek"
+	DialectQwen       Dialect = "qwen"
+	DialectZhipu      Dialect = "zhipu"
+	DialectMoonshot   Dialect = "moonshot"
+	DialectMiniMax    Dialect = "minimax"
+	DialectMistral    Dialect = "mistral"
+	DialectGemini     Dialect = "gemini"
+	DialectXAI        Dialect = "xai"
+	DialectOpenRouter Dialect = "openrouter"
+)
+
+// DialectProfile describes what an OpenAI-compatible API accepts and how it deviates from OpenAI
+type DialectProfile struct {
+	// MaxCompletionTokens sends the output limit as max_completion_tokens instead of max_tokens
+	MaxCompletionTokens bool
+	// DeveloperRole accepts developer messages; otherwise they are sent as system messages
+	DeveloperRole bool
+	// RequiredToolChoice is the tool_choice value forcing a tool call, empty when unsupported
+	RequiredToolChoice string
+	// NamedToolChoice accepts tool_choice naming a function; otherwise any tool call is forced
+	NamedToolChoice bool
+	// StreamOptions accepts stream_options
+	StreamOptions bool
+	// StrictSchema accepts strict on function and json_schema definitions
+	StrictSchema bool
+	// ReasoningEffort accepts reasoning_effort
+	ReasoningEffort bool
+	// ReasoningField is the response field carrying reasoning, renamed to reasoning_content inbound
+	ReasoningField string
+	// ToolSchema is the tool schema the dialect accepts
+	ToolSchema SchemaPolicy
+}
+
+// draftKeywords are schema annotations many OpenAI-compatible APIs reject
+var draftKeywords = []string{"$schema", "$id", "$comment"}
+
+// dialectProfiles are the profiles of the known dialects
+var dialectProfiles = map[Dialect]DialectProfile{
+	DialectOpenAI: {
+		MaxCompletionTokens: true,
+		DeveloperRole:       true,
+		RequiredToolChoice:  "required",
+		NamedToolChoice:     true,
+		StreamOptions:       true,
+		StrictSchema:        true,
+		ReasoningEffort:     true,
+	},
+	DialectDeepSeek: {
+		RequiredToolChoice: "required",
+		NamedToolChoice:    true,
+		StreamOptions:      true,
+		ReasoningField:     openaiFieldReasoningContent,
+		ToolSchema:         SchemaPolicy{DropKeywords: draftKeywords},
+	},
+	DialectQwen: {
+		NamedToolChoice: true,
+		StreamOptions:   true,
+		ReasoningField:  openaiFieldReasoningContent,
+		ToolSchema:      SchemaPolicy{DropKeywords: draftKeywords},
+	},
+	DialectZhipu: {
+		StreamOptions:  true,
+		ReasoningField: openaiFieldReasoningContent,
+		ToolSchema:     SchemaPolicy{DropKeywords: draftKeywords},
+	},
+	DialectMoonshot: {
+		StreamOptions:  true,
+		ReasoningField: openaiFieldReasoningContent,
+		ToolSchema:     SchemaPolicy{DropKeywords: draftKeywords},
+	},
+	DialectMiniMax: {
+		RequiredToolChoice: "required",
+		NamedToolChoice:    true,
+		StreamOptions:      true,
+		ReasoningField:     openaiFieldReasoningContent,
+		ToolSchema:         SchemaPolicy{DropKeywords: draftKeywords},
+	},
+	DialectMistral: {
+		RequiredToolChoice: "any",
+		NamedToolChoice:    true,
+		ToolSchema:         SchemaPolicy{DropKeywords: draftKeywords},
+	},
+	DialectGemini: {
+		RequiredToolChoice: "required",
+		StreamOptions:      true,
+		ReasoningEffort:    true,
+		ToolSchema: SchemaPolicy{
+			DropKeywords: append([]string{"additionalProperties", "patternProperties", "examples",
+				"exclusiveMinimum", "exclusiveMaximum"}, draftKeywords...),
+			AllowedFormats: []string{"enum", "date-time"},
+			OneOfToAnyOf:   true,
+			ConstToEnum:    true,
+		},
+	},
+	DialectXAI: {
+		RequiredToolChoice: "required",
+		NamedToolChoice:    true,
+		StreamOptions:      true,
+		ReasoningEffort:    true,
+		ReasoningField:     openaiFieldReasoningContent,
+		ToolSchema:         SchemaPolicy{DropKeywords: draftKeywords},
+	},
+	DialectOpenRouter: {
+		RequiredToolChoice: "required",
+		NamedToolChoice:    true,
+		StreamOptions:      true,
+		StrictSchema:       true,
+		ReasoningEffort:    true,
+		ReasoningField:     openaiFieldReasoning,
+	},
+}
+
+// Dialects lists the known dialects
+func Dialects() []Dialect {
+	dialects := make([]Dialect, 0, len(dialectProfiles))
+	for d := range dialectProfiles {
+		dialects = append(dialects, d)
+	}
+	sort.Slice(dialects, func(i, j int) bool { return dialects[i] < dialects[j] })
+	return dialects
+}
+
+// ValidateDialect reports an error for dialects that are neither empty nor known
+func ValidateDialect(d Dialect) error {
+	if _, ok := dialectProfiles[d]; ok || d == DialectNone {
+		return nil
+	}
+	return fmt.Errorf("unknown dialect %q, expected one of %v", d, Dialects())
+}
+
+// Profile returns the profile of the dialect; ok is false for the empty and unknown dialects
+func (d Dialect) Profile() (DialectProfile, bool) {
+	profile, ok := dialectProfiles[d]
+	return profile, ok
+}
+
+// ApplyDialect rewrites an outbound request into what the dialect accepts. Messages and tools are
+// copied before they are changed, so slices shared with the caller are left alone.
+func ApplyDialect(req *openai.ChatCompletionNewParams, d Dialect) {
+	profile, ok := d.Profile()
+	if !ok {
+		return
+	}
+
+	// Output limit
+	if profile.MaxCompletionTokens && req.MaxTokens.Valid() && !req.MaxCompletionTokens.Valid() {
+		req.MaxCompletionTokens = req.MaxTokens
+		req.MaxTokens = param.Opt[int64]{}
+	} else if !profile.MaxCompletionTokens && req.MaxCompletionTokens.Valid() {
+		if !req.MaxTokens.Valid() {
+			req.MaxTokens = req.MaxCompletionTokens
+		}
+		req.MaxCompletionTokens = param.Opt[int64]{}
+	}
+
+	// Instructions role
+	if !profile.DeveloperRole {
+		var messages []openai.ChatCompletionMessageParamUnion
+		for i, msg := range req.Messages {
+			if msg.OfDeveloper == nil {
+				continue
+			}
+			if messages == nil {
+				messages = append([]openai.ChatCompletionMessageParamUnion(nil), req.Messages...)
+			}
+			messages[i] = developerToSystem(msg.OfDeveloper)
+		}
+		if messages != nil {
+			req.Messages = messages
+		}
+	}
+
+	// Tool choice
+	switch {
+	case req.ToolChoice.OfAuto.Value == "required" && profile.RequiredToolChoice != "required":
+		req.ToolChoice.OfAuto = openai.Opt(toolChoiceOrAuto(profile.RequiredToolChoice))
+	case req.ToolChoice.OfFunctionToolChoice != nil && !profile.NamedToolChoice:
+		req.ToolChoice = openai.ChatCompletionToolChoiceOptionUnionParam{OfAuto: openai.Opt(toolChoiceOrAuto(profile.RequiredToolChoice))}
+	}
+
+	if !profile.StreamOptions {
+		req.StreamOptions = openai.ChatCompletionStreamOptionsParam{}
+	}
+	if !profile.ReasoningEffort {
+		req.ReasoningEffort = ""
+	}
+
+	// Strict schemas
+	if !profile.StrictSchema {
+		if req.ResponseFormat.OfJSONSchema != nil && req.ResponseFormat.OfJSONSchema.JSONSchema.Strict.Valid() {
+			format := *req.ResponseFormat.OfJSONSchema
+			format.JSONSchema.Strict = param.Opt[bool]{}
+			req.ResponseFormat.OfJSONSchema = &format
+		}
+		var tools []openai.ChatCompletionToolUnionParam
+		for i, tool := range req.Tools {
+			if tool.OfFunction == nil || !tool.OfFunction.Function.Strict.Valid() {
+				continue
+			}
+			if tools == nil {
+				tools = append([]openai.ChatCompletionToolUnionParam(nil), req.Tools...)
+			}
+			fn := *tool.OfFunction
+			fn.Function.Strict = param.Opt[bool]{}
+			tools[i].OfFunction = &fn
+		}
+		if tools != nil {
+			req.Tools = tools
+		}
+	}
+}
+
+// toolChoiceOrAuto returns the tool_choice forcing a tool call, or auto when the dialect has none
+func toolChoiceOrAuto(required string) string {
+	if required == "" {
+		return "auto"
+	}
+	return required
+}
+
+// developerToSystem converts a developer message into a system message with the same content
+func developerToSystem(dev *openai.ChatCompletionDeveloperMessageParam) openai.ChatCompletionMessageParamUnion {
+	system := openai.ChatCompletionSystemMessageParam{
+		Content: openai.ChatCompletionSystemMessageParamContentUnion{
+			OfString:              dev.Content.OfString,
+			OfArrayOfContentParts: dev.Content.OfArrayOfContentParts,
+		},
+		Name: dev.Name,
+	}
+	if extra := dev.ExtraFields(); len(extra) > 0 {
+		system.SetExtraFields(extra)
+	}
+	return openai.ChatCompletionMessageParamUnion{OfSystem: &system}
+}
+
+// NormalizeDialectFields renames the reasoning field of a response message or stream delta to
+// reasoning_content, the field the gateway uses towards OpenAI clients
+func NormalizeDialectFields(fields map[string]interface{}, d Dialect) {
+	profile, ok := d.Profile()
+	if !ok || profile.ReasoningField == "" || profile.ReasoningField == openaiFieldReasoningContent {
+		return
+	}
+	value, ok := fields[profile.ReasoningField]
+	if !ok {
+		return
+	}
+	if _, exists := fields[openaiFieldReasoningContent]; !exists {
+		fields[openaiFieldReasoningContent] = value
+	}
+	delete(fields, profile.ReasoningField)
+}
+
+// ReasoningExtraFields decodes the reasoning fields among the extra fields of a response message or
+// stream delta, normalized for the dialect. It returns nil when there are none.
+func ReasoningExtraFields(extra map[string]respjson.Field, d Dialect) map[string]interface{} {
+	var fields map[string]interface{}
+	for key, field := range extra {
+		var value interface{}
+		if !isReasoningField(key) || json.Unmarshal([]byte(field.Raw()), &value) != nil || value == nil {
+			continue
+		}
+		if fields == nil {
+			fields = make(map[string]interface{})
+		}
+		fields[key] = value
+	}
+	if fields != nil {
+		NormalizeDialectFields(fields, d)
+	}
+	return fields
+}
@@ -0,0 +1,93 @@
+package adaptor
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const dialectRequest = `{"model":"m","max_completion_tokens":500,"reasoning_effort":"high",
+	"stream_options":{"include_usage":true},"tool_choice":"required",
+	"messages":[{"role":"developer","content":"Be brief."},{"role":"user","content":"hi"}],
+	"tools":[{"type":"function","function":{"name":"lookup","strict":true,"parameters":{"type":"object"}}}],
+	"response_format":{"type":"json_schema","json_schema":{"name":"out","strict":true,"schema":{"type":"object"}}}}`
+
+// marshalDialectRequest applies a dialect and returns the request as it goes on the wire
+func marshalDialectRequest(t *testing.T, req *openai.ChatCompletionNewParams, d Dialect) map[string]interface{} {
+	ApplyDialect(req, d)
+	data, err := json.Marshal(req)
+	require.NoError(t, err)
+	var wire map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &wire))
+	return wire
+}
+
+func TestApplyDialect(t *testing.T) {
+	// OpenAI keeps everything and prefers max_completion_tokens
+	req := parseOpenAIRequest(t, `{"model":"o3","max_tokens":500,"messages":[{"role":"developer","content":"Be brief."}]}`)
+	wire := marshalDialectRequest(t, req, DialectOpenAI)
+	assert.Equal(t, 500.0, wire["max_completion_tokens"])
+	assert.NotContains(t, wire, "max_tokens")
+	assert.Equal(t, "developer", wire["messages"].([]interface{})[0].(map[string]interface{})["role"])
+
+	// Zhipu takes max_tokens, system instructions and no forced or strict tools
+	original := parseOpenAIRequest(t, dialectRequest)
+	shallow := *original
+	wire = marshalDialectRequest(t, &shallow, DialectZhipu)
+	assert.Equal(t, 500.0, wire["max_tokens"])
+	assert.NotContains(t, wire, "max_completion_tokens")
+	assert.NotContains(t, wire, "reasoning_effort")
+	assert.Equal(t, "auto", wire["tool_choice"])
+	assert.Contains(t, wire, "stream_options")
+	system := wire["messages"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "system", system["role"])
+	assert.Equal(t, "Be brief.", system["content"])
+	assert.NotContains(t, wire["tools"].([]interface{})[0].(map[string]interface{})["function"], "strict")
+	assert.NotContains(t, wire["response_format"].(map[string]interface{})["json_schema"], "strict")
+
+	// Messages and tools shared with the caller are left alone
+	assert.NotNil(t, original.Messages[0].OfDeveloper)
+	assert.True(t, original.Tools[0].OfFunction.Function.Strict.Valid())
+
+	// Mistral forces tools with "any" and has no stream_options
+	wire = marshalDialectRequest(t, parseOpenAIRequest(t, dialectRequest), DialectMistral)
+	assert.Equal(t, "any", wire["tool_choice"])
+	assert.NotContains(t, wire, "stream_options")
+
+	// Gemini cannot name a function, so any tool call is forced
+	req = parseOpenAIRequest(t, `{"model":"m","messages":[{"role":"user","content":"hi"}]}`)
+	req.ToolChoice = openai.ToolChoiceOptionFunctionToolChoice(openai.ChatCompletionNamedToolChoiceFunctionParam{Name: "lookup"})
+	wire = marshalDialectRequest(t, req, DialectGemini)
+	assert.Equal(t, "required", wire["tool_choice"])
+
+	// Without a dialect the request is sent as it is
+	req = parseOpenAIRequest(t, dialectRequest)
+	wire = marshalDialectRequest(t, req, DialectNone)
+	assert.Equal(t, 500.0, wire["max_completion_tokens"])
+	assert.Equal(t, "required", wire["tool_choice"])
+}
+
+func TestValidateDialect(t *testing.T) {
+	assert.NoError(t, ValidateDialect(DialectNone))
+	assert.NoError(t, ValidateDialect(DialectDeepSeek))
+	assert.Error(t, ValidateDialect("llama"))
+	assert.Contains(t, Dialects(), DialectOpenRouter)
+}
+
+func TestReasoningExtraFields(t *testing.T) {
+	var msg openai.ChatCompletionMessage
+	require.NoError(t, json.Unmarshal([]byte(`{"role":"assistant","content":"4","reasoning":"2+2"}`), &msg))
+
+	// OpenRouter's reasoning field is renamed for OpenAI clients
+	assert.Equal(t, map[string]interface{}{"reasoning_content": "2+2"},
+		ReasoningExtraFields(msg.JSON.ExtraFields, DialectOpenRouter))
+	assert.Equal(t, map[string]interface{}{"reasoning": "2+2"},
+		ReasoningExtraFields(msg.JSON.ExtraFields, DialectNone))
+
+	var plain openai.ChatCompletionMessage
+	require.NoError(t, json.Unmarshal([]byte(`{"role":"assistant","content":"4"}`), &plain))
+	assert.Nil(t, ReasoningExtraFields(plain.JSON.ExtraFields, DialectDeepSeek))
+}
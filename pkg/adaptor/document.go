@@ -0,0 +1,327 @@
+package adaptor
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/openai/openai-go/v3"
+)
+
+// DocumentFallback selects what happens to documents the target API cannot take natively. The
+// only such documents with a text fallback are PDFs in tool results for OpenAI-style providers,
+// whose tool messages carry text only. Documents that cannot be converted at all (PDF URLs for
+// OpenAI, uploaded file IDs for Anthropic) fail with ErrUnsupportedDocument under every fallback.
+type DocumentFallback string
+
+const (
+	DocumentFallbackText     DocumentFallback = "text"      // Send PDFs in tool results as their extracted text (default)
+	DocumentFallbackReject   DocumentFallback = "reject"    // Fail requests with PDFs in tool results with ErrUnsupportedDocument
+	DocumentFallbackTextOnly DocumentFallback = "text_only" // Always send extracted text, for targets without file support
+)
+
+// ValidateDocumentFallback returns an error for values other than the empty default and the
+// known fallbacks
+func ValidateDocumentFallback(f DocumentFallback) error {
+	switch f {
+	case "", DocumentFallbackText, DocumentFallbackReject, DocumentFallbackTextOnly:
+		return nil
+	}
+	return fmt.Errorf("unknown document fallback %q, expected one of %v", f,
+		[]DocumentFallback{DocumentFallbackText, DocumentFallbackReject, DocumentFallbackTextOnly})
+}
+
+// ErrUnsupportedDocument reports a document that cannot be converted for the target API
+var ErrUnsupportedDocument = errors.New("unsupported document")
+
+// ConvertOptions tunes request conversion between API styles
+type ConvertOptions struct {
+	DocumentFallback DocumentFallback
+	// ForwardCacheControl keeps Anthropic cache_control as extra fields of OpenAI requests
+	ForwardCacheControl bool
+	// ToolSchema sanitizes tool schemas for what the target accepts
+	ToolSchema SchemaPolicy
+}
+
+// defaultDocumentFilename names PDFs sent as OpenAI file parts without a title
+const defaultDocumentFilename = "document.pdf"
+
+// unsupportedDocument wraps ErrUnsupportedDocument with the reason
+func unsupportedDocument(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrUnsupportedDocument, fmt.Sprintf(format, args...))
+}
+
+// documentText renders a document as text, headed by its title
+func documentText(title, text string) string {
+	if title == "" {
+		return text
+	}
+	return fmt.Sprintf("Document: %s\n\n%s", title, text)
+}
+
+// pdfText extracts the text of a base64 encoded PDF for the text fallback
+func pdfText(title, data string) (string, error) {
+	decoded, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return "", unsupportedDocument("invalid base64 PDF data: %v", err)
+	}
+	text := ExtractPDFText(decoded)
+	if strings.TrimSpace(text) == "" {
+		return "", unsupportedDocument("no text could be extracted from PDF %q", title)
+	}
+	return documentText(title, text), nil
+}
+
+// anthropicDocumentToPart converts an Anthropic document block
+func anthropicDocumentToPart(doc *anthropic.DocumentBlockParam) Part {
+	part := Part{Type: PartDocument, Title: doc.Title.Value, CacheControl: cacheControlFromAnthropic(doc.CacheControl)}
+	switch {
+	case doc.Source.OfBase64 != nil:
+		part.MediaType, part.Data = "application/pdf", doc.Source.OfBase64.Data
+	case doc.Source.OfText != nil:
+		part.MediaType, part.Text = "text/plain", doc.Source.OfText.Data
+	case doc.Source.OfContent != nil:
+		content := doc.Source.OfContent.Content
+		if content.OfString.Valid() {
+			part.Content = []Part{{Type: PartText, Text: content.OfString.Value}}
+		}
+		for _, item := range content.OfContentBlockSourceContent {
+			if item.OfText != nil {
+				part.Content = append(part.Content, Part{Type: PartText, Text: item.OfText.Text})
+			} else if item.OfImage != nil {
+				if image, ok := anthropicImageToPart(item.OfImage); ok {
+					part.Content = append(part.Content, image)
+				}
+			}
+		}
+	case doc.Source.OfURL != nil:
+		part.URL = doc.Source.OfURL.URL
+	}
+	return part
+}
+
+// documentPartFromOpenAIFile converts an OpenAI file part (as decoded JSON). Bare base64 data is
+// taken as a PDF.
+func documentPartFromOpenAIFile(file map[string]interface{}) Part {
+	part := Part{Type: PartDocument}
+	part.Title, _ = file["filename"].(string)
+	fileData, _ := file["file_data"].(string)
+	if fileData == "" {
+		part.FileID, _ = file["file_id"].(string)
+		return part
+	}
+	var ok bool
+	if part.MediaType, part.Data, ok = parseDataURL(fileData); !ok {
+		part.MediaType, part.Data = "application/pdf", fileData
+	}
+	return part
+}
+
+// documentToOpenAIParts converts a document into OpenAI content parts. Base64 PDFs become file
+// parts, text and content documents become text and image parts.
+func (o ConvertOptions) documentToOpenAIParts(doc Part) ([]openai.ChatCompletionContentPartUnionParam, error) {
+	switch {
+	case doc.Data != "":
+		if o.DocumentFallback == DocumentFallbackTextOnly {
+			text, err := pdfText(doc.Title, doc.Data)
+			if err != nil {
+				return nil, err
+			}
+			return []openai.ChatCompletionContentPartUnionParam{openai.TextContentPart(text)}, nil
+		}
+		filename := doc.Title
+		if filename == "" {
+			filename = defaultDocumentFilename
+		}
+		return []openai.ChatCompletionContentPartUnionParam{openai.FileContentPart(openai.ChatCompletionContentPartFileFileParam{
+			FileData: openai.String("data:" + doc.MediaType + ";base64," + doc.Data),
+			Filename: openai.String(filename),
+		})}, nil
+
+	case doc.FileID != "":
+		return []openai.ChatCompletionContentPartUnionParam{openai.FileContentPart(openai.ChatCompletionContentPartFileFileParam{
+			FileID: openai.String(doc.FileID),
+		})}, nil
+
+	case doc.URL != "":
+		return nil, unsupportedDocument("PDF documents by URL (%s) cannot be sent to OpenAI-style providers, send them as base64", doc.URL)
+
+	case doc.Content != nil:
+		var parts []openai.ChatCompletionContentPartUnionParam
+		var texts []string
+		for _, item := range doc.Content {
+			if item.Type == PartText {
+				texts = append(texts, item.Text)
+			} else if part, ok := openAIImagePart(item); ok {
+				parts = append(parts, part)
+			}
+		}
+		return append([]openai.ChatCompletionContentPartUnionParam{openai.TextContentPart(documentText(doc.Title, strings.Join(texts, "\n")))}, parts...), nil
+
+	case doc.MediaType != "":
+		return []openai.ChatCompletionContentPartUnionParam{openai.TextContentPart(documentText(doc.Title, doc.Text))}, nil
+	}
+	return nil, unsupportedDocument("document block without a source")
+}
+
+// documentToText renders a document as text, for tool messages that only carry text
+func (o ConvertOptions) documentToText(doc Part) (string, error) {
+	if doc.Data != "" {
+		if o.DocumentFallback == DocumentFallbackReject {
+			return "", unsupportedDocument("PDF documents in tool results cannot be sent to OpenAI-style providers")
+		}
+		return pdfText(doc.Title, doc.Data)
+	}
+	parts, err := o.documentToOpenAIParts(doc)
+	if err != nil {
+		return "", err
+	}
+	var texts []string
+	for _, part := range parts {
+		if part.OfText != nil {
+			texts = append(texts, part.OfText.Text)
+		}
+	}
+	return strings.Join(texts, "\n"), nil
+}
+
+// documentToAnthropic converts a document into an Anthropic document block. PDFs and text files
+// with inline data convert natively; uploaded file IDs cannot be resolved.
+func (o ConvertOptions) documentToAnthropic(doc Part) (anthropic.ContentBlockParamUnion, error) {
+	var block anthropic.ContentBlockParamUnion
+	switch {
+	case doc.FileID != "":
+		return block, unsupportedDocument("uploaded file %q cannot be sent to Anthropic-style providers, send it as file_data", doc.FileID)
+
+	case doc.Data != "" && doc.MediaType == "application/pdf":
+		if o.DocumentFallback == DocumentFallbackTextOnly {
+			text, err := pdfText(doc.Title, doc.Data)
+			if err != nil {
+				return block, err
+			}
+			return anthropic.NewTextBlock(text), nil
+		}
+		block = anthropic.NewDocumentBlock(anthropic.Base64PDFSourceParam{Data: doc.Data})
+
+	case doc.Data != "" && (strings.HasPrefix(doc.MediaType, "text/") || doc.MediaType == "application/json"):
+		decoded, err := base64.StdEncoding.DecodeString(doc.Data)
+		if err != nil {
+			return block, unsupportedDocument("invalid base64 data in file %q: %v", doc.Title, err)
+		}
+		block = anthropic.NewDocumentBlock(anthropic.PlainTextSourceParam{Data: string(decoded)})
+
+	case doc.Data != "":
+		return block, unsupportedDocument("file %q of type %s is not supported by Anthropic-style providers", doc.Title, doc.MediaType)
+
+	case doc.URL != "":
+		block = anthropic.NewDocumentBlock(anthropic.URLPDFSourceParam{URL: doc.URL})
+
+	case doc.Content != nil:
+		var content []anthropic.ContentBlockSourceContentItemUnionParam
+		for _, item := range doc.Content {
+			if item.Type == PartText {
+				content = append(content, anthropic.ContentBlockSourceContentItemUnionParam{OfText: &anthropic.TextBlockParam{Text: item.Text}})
+			} else if image, ok := anthropicImage(item); ok {
+				content = append(content, anthropic.ContentBlockSourceContentItemUnionParam{OfImage: image})
+			}
+		}
+		block = anthropic.NewDocumentBlock(anthropic.ContentBlockSourceParam{Content: anthropic.ContentBlockSourceContentUnionParam{OfContentBlockSourceContent: content}})
+
+	default:
+		block = anthropic.NewDocumentBlock(anthropic.PlainTextSourceParam{Data: doc.Text})
+	}
+	if doc.Title != "" {
+		block.OfDocument.Title = anthropic.String(doc.Title)
+	}
+	return block, nil
+}
+
+// Citation locates the source of cited text, with the fields of Anthropic response and stream citations
+type Citation struct {
+	Type              string
+	CitedText         string
+	URL               string
+	Title             string
+	DocumentIndex     int64
+	DocumentTitle     string
+	StartCharIndex    int64
+	EndCharIndex      int64
+	StartPageNumber   int64
+	EndPageNumber     int64
+	StartBlockIndex   int64
+	EndBlockIndex     int64
+	SearchResultIndex int64
+	Source            string
+}
+
+// citationFromText extracts a citation attached to a response text block
+func citationFromText(c anthropic.TextCitationUnion) Citation {
+	return Citation{
+		Type: c.Type, CitedText: c.CitedText, URL: c.URL, Title: c.Title,
+		DocumentIndex: c.DocumentIndex, DocumentTitle: c.DocumentTitle,
+		StartCharIndex: c.StartCharIndex, EndCharIndex: c.EndCharIndex,
+		StartPageNumber: c.StartPageNumber, EndPageNumber: c.EndPageNumber,
+		StartBlockIndex: c.StartBlockIndex, EndBlockIndex: c.EndBlockIndex,
+		SearchResultIndex: c.SearchResultIndex, Source: c.Source,
+	}
+}
+
+// citationFromDelta extracts a citation streamed in a citations_delta
+func citationFromDelta(c anthropic.CitationsDeltaCitationUnion) Citation {
+	return Citation{
+		Type: c.Type, CitedText: c.CitedText, URL: c.URL, Title: c.Title,
+		DocumentIndex: c.DocumentIndex, DocumentTitle: c.DocumentTitle,
+		StartCharIndex: c.StartCharIndex, EndCharIndex: c.EndCharIndex,
+		StartPageNumber: c.StartPageNumber, EndPageNumber: c.EndPageNumber,
+		StartBlockIndex: c.StartBlockIndex, EndBlockIndex: c.EndBlockIndex,
+		SearchResultIndex: c.SearchResultIndex, Source: c.Source,
+	}
+}
+
+// citationAnnotation converts a citation into an OpenAI message annotation spanning the
+// cited text block. Web results become url_citation; document locations, which OpenAI
+// chat completions have no type for, become file_citation with the Anthropic location fields.
+func citationAnnotation(c Citation, start, end int) map[string]interface{} {
+	if c.Type == "web_search_result_location" {
+		return map[string]interface{}{
+			"type": "url_citation",
+			"url_citation": map[string]interface{}{
+				"start_index": start,
+				"end_index":   end,
+				"url":         c.URL,
+				"title":       c.Title,
+			},
+		}
+	}
+
+	detail := map[string]interface{}{
+		"start_index":    start,
+		"end_index":      end,
+		"location_type":  c.Type,
+		"document_index": c.DocumentIndex,
+		"cited_text":     c.CitedText,
+	}
+	if c.DocumentTitle != "" {
+		detail["title"] = c.DocumentTitle
+	}
+	switch c.Type {
+	case "char_location":
+		detail["start_char_index"], detail["end_char_index"] = c.StartCharIndex, c.EndCharIndex
+	case "page_location":
+		detail["start_page_number"], detail["end_page_number"] = c.StartPageNumber, c.EndPageNumber
+	case "content_block_location":
+		detail["start_block_index"], detail["end_block_index"] = c.StartBlockIndex, c.EndBlockIndex
+	case "search_result_location":
+		detail["start_block_index"], detail["end_block_index"] = c.StartBlockIndex, c.EndBlockIndex
+		detail["search_result_index"], detail["source"] = c.SearchResultIndex, c.Source
+		if c.Title != "" {
+			detail["title"] = c.Title
+		}
+	}
+	return map[string]interface{}{
+		"type":          "file_citation",
+		"file_citation": detail,
+	}
+}
@@ -0,0 +1,154 @@
+package adaptor
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testPDF builds a minimal PDF with one FlateDecode content stream
+func testPDF(t *testing.T) string {
+	var stream bytes.Buffer
+	w := zlib.NewWriter(&stream)
+	_, err := w.Write([]byte("BT /F1 12 Tf 72 712 Td (Quarterly report) Tj 0 -14 Td [(Revenue \\(net\\) grew ) -250 (12%)] TJ ET"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n1 0 obj\n<< /Type /Catalog >>\nendobj\n")
+	fmt.Fprintf(&pdf, "4 0 obj\n<< /Length %d /Filter /FlateDecode >>\nstream\n", stream.Len())
+	pdf.Write(stream.Bytes())
+	pdf.WriteString("\nendstream\nendobj\n%%EOF\n")
+	return base64.StdEncoding.EncodeToString(pdf.Bytes())
+}
+
+func TestExtractPDFText(t *testing.T) {
+	data, err := base64.StdEncoding.DecodeString(testPDF(t))
+	require.NoError(t, err)
+	assert.Equal(t, "Quarterly report\nRevenue (net) grew 12%", ExtractPDFText(data))
+	assert.Empty(t, ExtractPDFText([]byte("not a pdf")))
+}
+
+func TestConvertAnthropicDocumentsToOpenAI(t *testing.T) {
+	pdf := testPDF(t)
+	pdfBlock := anthropic.NewDocumentBlock(anthropic.Base64PDFSourceParam{Data: pdf})
+	pdfBlock.OfDocument.Title = anthropic.String("report.pdf")
+	req := &anthropic.MessageNewParams{
+		Model:     "claude-sonnet-4",
+		MaxTokens: 100,
+		Messages: []anthropic.MessageParam{anthropic.NewUserMessage(
+			pdfBlock,
+			anthropic.NewDocumentBlock(anthropic.PlainTextSourceParam{Data: "plain notes"}),
+			anthropic.NewTextBlock("Summarize"),
+		)},
+	}
+
+	openaiReq, err := ConvertAnthropicToOpenAIRequestWithOptions(req, ConvertOptions{})
+	require.NoError(t, err)
+	parts := openaiReq.Messages[0].OfUser.Content.OfArrayOfContentParts
+	require.Len(t, parts, 3)
+	require.NotNil(t, parts[0].OfFile)
+	assert.Equal(t, "data:application/pdf;base64,"+pdf, parts[0].OfFile.File.FileData.Value)
+	assert.Equal(t, "report.pdf", parts[0].OfFile.File.Filename.Value)
+	assert.Equal(t, "plain notes", parts[1].OfText.Text)
+
+	openaiReq, err = ConvertAnthropicToOpenAIRequestWithOptions(req, ConvertOptions{DocumentFallback: DocumentFallbackTextOnly})
+	require.NoError(t, err)
+	parts = openaiReq.Messages[0].OfUser.Content.OfArrayOfContentParts
+	assert.Equal(t, "Document: report.pdf\n\nQuarterly report\nRevenue (net) grew 12%", parts[0].OfText.Text)
+
+	// PDFs by URL have no OpenAI equivalent
+	req.Messages = append(req.Messages, anthropic.NewUserMessage(anthropic.NewDocumentBlock(anthropic.URLPDFSourceParam{URL: "https://example.com/a.pdf"})))
+	_, err = ConvertAnthropicToOpenAIRequestWithOptions(req, ConvertOptions{})
+	assert.ErrorIs(t, err, ErrUnsupportedDocument)
+}
+
+func TestConvertAnthropicToolResultDocument(t *testing.T) {
+	req := &anthropic.MessageNewParams{Messages: []anthropic.MessageParam{
+		anthropic.NewUserMessage(anthropic.ContentBlockParamUnion{OfToolResult: &anthropic.ToolResultBlockParam{
+			ToolUseID: "toolu_1",
+			Content: []anthropic.ToolResultBlockParamContentUnion{
+				{OfDocument: anthropic.NewDocumentBlock(anthropic.Base64PDFSourceParam{Data: testPDF(t)}).OfDocument},
+			},
+		}}),
+	}}
+
+	openaiReq, err := ConvertAnthropicToOpenAIRequestWithOptions(req, ConvertOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "Quarterly report\nRevenue (net) grew 12%", openaiReq.Messages[0].OfTool.Content.OfString.Value)
+
+	_, err = ConvertAnthropicToOpenAIRequestWithOptions(req, ConvertOptions{DocumentFallback: DocumentFallbackReject})
+	assert.ErrorIs(t, err, ErrUnsupportedDocument)
+}
+
+func TestConvertOpenAIFilesToAnthropic(t *testing.T) {
+	notes := base64.StdEncoding.EncodeToString([]byte("meeting notes"))
+	var req openai.ChatCompletionNewParams
+	require.NoError(t, json.Unmarshal([]byte(`{"model":"gpt-4o","messages":[{"role":"user","content":[
+		{"type":"file","file":{"filename":"report.pdf","file_data":"data:application/pdf;base64,JVBERi0="}},
+		{"type":"file","file":{"filename":"notes.txt","file_data":"data:text/plain;base64,`+notes+`"}},
+		{"type":"text","text":"Summarize"}]}]}`), &req))
+
+	anthropicReq, err := ConvertOpenAIToAnthropicRequestWithOptions(&req, 1024, ConvertOptions{})
+	require.NoError(t, err)
+	blocks := anthropicReq.Messages[0].Content
+	require.Len(t, blocks, 3)
+	require.NotNil(t, blocks[0].OfDocument.Source.OfBase64)
+	assert.Equal(t, "JVBERi0=", blocks[0].OfDocument.Source.OfBase64.Data)
+	assert.Equal(t, "report.pdf", blocks[0].OfDocument.Title.Value)
+	require.NotNil(t, blocks[1].OfDocument.Source.OfText)
+	assert.Equal(t, "meeting notes", blocks[1].OfDocument.Source.OfText.Data)
+
+	var uploaded openai.ChatCompletionNewParams
+	require.NoError(t, json.Unmarshal([]byte(`{"model":"gpt-4o","messages":[{"role":"user","content":[
+		{"type":"file","file":{"file_id":"file-abc"}}]}]}`), &uploaded))
+	_, err = ConvertOpenAIToAnthropicRequestWithOptions(&uploaded, 1024, ConvertOptions{})
+	assert.ErrorIs(t, err, ErrUnsupportedDocument)
+}
+
+func TestCitationsToAnnotations(t *testing.T) {
+	var resp anthropic.Message
+	require.NoError(t, json.Unmarshal([]byte(`{"id":"msg_1","type":"message","role":"assistant","model":"claude",
+		"stop_reason":"end_turn","usage":{"input_tokens":10,"output_tokens":5},
+		"content":[
+			{"type":"text","text":"According to the report, "},
+			{"type":"text","text":"revenue grew 12%","citations":[{"type":"page_location","cited_text":"Revenue grew 12%",
+				"document_index":0,"document_title":"report.pdf","start_page_number":1,"end_page_number":2}]},
+			{"type":"text","text":" and ","citations":[]},
+			{"type":"text","text":"it rained","citations":[{"type":"web_search_result_location","cited_text":"rain",
+				"url":"https://example.com/weather","title":"Weather","encrypted_index":"x"}]}]}`), &resp))
+
+	result := ConvertAnthropicToOpenAIResponse(&resp, "claude")
+	message := result["choices"].([]map[string]interface{})[0]["message"].(map[string]interface{})
+	assert.Equal(t, "According to the report, revenue grew 12% and it rained", message["content"])
+
+	annotations := message["annotations"].([]map[string]interface{})
+	require.Len(t, annotations, 2)
+	fileCitation := annotations[0]["file_citation"].(map[string]interface{})
+	assert.Equal(t, "file_citation", annotations[0]["type"])
+	assert.Equal(t, 25, fileCitation["start_index"])
+	assert.Equal(t, 41, fileCitation["end_index"])
+	assert.Equal(t, "report.pdf", fileCitation["title"])
+	assert.Equal(t, int64(1), fileCitation["start_page_number"])
+
+	urlCitation := annotations[1]["url_citation"].(map[string]interface{})
+	assert.Equal(t, "url_citation", annotations[1]["type"])
+	assert.Equal(t, 46, urlCitation["start_index"])
+	assert.Equal(t, 55, urlCitation["end_index"])
+	assert.Equal(t, "https://example.com/weather", urlCitation["url"])
+}
+
+func TestValidateDocumentFallback(t *testing.T) {
+	assert.NoError(t, ValidateDocumentFallback(""))
+	assert.NoError(t, ValidateDocumentFallback(DocumentFallbackReject))
+	assert.NoError(t, ValidateDocumentFallback(DocumentFallbackTextOnly))
+	assert.Error(t, ValidateDocumentFallback("drop"))
+}
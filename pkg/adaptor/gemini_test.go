@@ -0,0 +1,99 @@
+package adaptor
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertOpenAIToGeminiRequest(t *testing.T) {
+	body := []byte(`{
+		"model": "google/gemini-2.0-flash",
+		"max_tokens": 64,
+		"temperature": 0.2,
+		"stop": "END",
+		"messages": [
+			{"role": "system", "content": "be brief"},
+			{"role": "user", "content": [
+				{"type": "text", "text": "what is this?"},
+				{"type": "image_url", "image_url": {"url": "data:image/png;base64,aGVsbG8="}}
+			]},
+			{"role": "assistant", "content": null, "tool_calls": [
+				{"id": "call_1", "type": "function", "function": {"name": "lookup", "arguments": "{\"q\":\"cat\"}"}}
+			]},
+			{"role": "tool", "tool_call_id": "call_1", "content": "a cat"}
+		],
+		"tools": [{"type": "function", "function": {"name": "lookup", "parameters": {"type": "object"}}}],
+		"tool_choice": "required"
+	}`)
+
+	req, err := ConvertOpenAIToGeminiRequest(body)
+	require.NoError(t, err)
+	assert.Equal(t, "google/gemini-2.0-flash", req.Model)
+
+	gemini := req.Body
+	require.NotNil(t, gemini.SystemInstruction)
+	assert.Equal(t, "be brief", gemini.SystemInstruction.Parts[0].Text)
+
+	require.Len(t, gemini.Contents, 3)
+	assert.Equal(t, "user", gemini.Contents[0].Role)
+	assert.Equal(t, "image/png", gemini.Contents[0].Parts[1].InlineData.MimeType)
+	assert.Equal(t, "model", gemini.Contents[1].Role)
+	assert.Equal(t, "lookup", gemini.Contents[1].Parts[0].FunctionCall.Name)
+	assert.Equal(t, "cat", gemini.Contents[1].Parts[0].FunctionCall.Args["q"])
+	assert.Equal(t, "lookup", gemini.Contents[2].Parts[0].FunctionResponse.Name)
+	assert.Equal(t, "a cat", gemini.Contents[2].Parts[0].FunctionResponse.Response["content"])
+
+	require.Len(t, gemini.Tools, 1)
+	assert.Equal(t, "lookup", gemini.Tools[0].FunctionDeclarations[0].Name)
+	assert.Equal(t, "ANY", gemini.ToolConfig.FunctionCallingConfig.Mode)
+	assert.Equal(t, int64(64), *gemini.GenerationConfig.MaxOutputTokens)
+	assert.Equal(t, []string{"END"}, gemini.GenerationConfig.StopSequences)
+}
+
+func TestConvertGeminiToOpenAIResponse(t *testing.T) {
+	body := []byte(`{"candidates":[{"index":0,"content":{"role":"model","parts":[
+		{"text":"thinking...","thought":true},
+		{"functionCall":{"name":"lookup","args":{"q":"cat"}}}
+	]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":4,"candidatesTokenCount":6,"thoughtsTokenCount":2}}`)
+
+	resp, err := ConvertGeminiToOpenAIResponse(body, "gemini")
+	require.NoError(t, err)
+
+	choice := resp["choices"].([]map[string]interface{})[0]
+	assert.Equal(t, "tool_calls", choice["finish_reason"])
+	message := choice["message"].(map[string]interface{})
+	assert.Equal(t, "thinking...", message["reasoning_content"])
+	call := message["tool_calls"].([]map[string]interface{})[0]["function"].(map[string]interface{})
+	assert.Equal(t, "lookup", call["name"])
+	assert.JSONEq(t, `{"q":"cat"}`, call["arguments"].(string))
+	assert.Equal(t, int64(12), resp["usage"].(map[string]interface{})["total_tokens"])
+}
+
+func TestConvertGeminiToOpenAIStream(t *testing.T) {
+	stream := "data: {\"candidates\":[{\"index\":0,\"content\":{\"parts\":[{\"text\":\"Hel\"}]}}]}\n\n" +
+		"data: {\"candidates\":[{\"index\":0,\"content\":{\"parts\":[{\"text\":\"lo\"}]},\"finishReason\":\"MAX_TOKENS\"}]," +
+		"\"usageMetadata\":{\"promptTokenCount\":1,\"candidatesTokenCount\":2}}\n\n"
+
+	var out bytes.Buffer
+	require.NoError(t, ConvertGeminiToOpenAIStream(strings.NewReader(stream), &out, "gemini", true))
+
+	events := strings.Split(strings.TrimSpace(out.String()), "\n\n")
+	require.Len(t, events, 4)
+	assert.Equal(t, "data: [DONE]", events[3])
+
+	var first, second, usage map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(events[0], "data: ")), &first))
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(events[1], "data: ")), &second))
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(events[2], "data: ")), &usage))
+
+	delta := first["choices"].([]interface{})[0].(map[string]interface{})["delta"].(map[string]interface{})
+	assert.Equal(t, "assistant", delta["role"])
+	assert.Equal(t, "Hel", delta["content"])
+	assert.Equal(t, "length", second["choices"].([]interface{})[0].(map[string]interface{})["finish_reason"])
+	assert.Equal(t, float64(3), usage["usage"].(map[string]interface{})["total_tokens"])
+}
@@ -0,0 +1,107 @@
+package adaptor
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// Keepalives written by a Heartbeat
+const (
+	// AnthropicPing is the ping event Anthropic sends while a stream is idle
+	AnthropicPing = "event: ping\ndata: {\"type\": \"ping\"}\n\n"
+	// SSEKeepalive is an SSE comment, which OpenAI clients ignore
+	SSEKeepalive = ": keepalive\n\n"
+)
+
+// Heartbeat passes writes on to a client stream and writes a keepalive whenever nothing was
+// written for its interval, so that clients and proxies do not drop the connection while the
+// upstream is thinking. A keepalive commits the response, so upstream errors that follow have to
+// be sent in the stream. Stop must be called before the stream is released.
+type Heartbeat struct {
+	w         io.Writer
+	keepalive string
+	interval  time.Duration
+
+	mu      sync.Mutex
+	last    time.Time
+	written bool
+
+	stop     chan struct{}
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+// StartHeartbeat starts writing keepalive to w after every interval without writes. With an
+// interval of 0 or less no keepalive is written.
+func StartHeartbeat(w io.Writer, interval time.Duration, keepalive string) *Heartbeat {
+	h := &Heartbeat{
+		w:         w,
+		keepalive: keepalive,
+		interval:  interval,
+		last:      time.Now(),
+		stop:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+	}
+	if interval > 0 {
+		go h.run()
+	} else {
+		close(h.stopped)
+	}
+	return h
+}
+
+func (h *Heartbeat) run() {
+	defer close(h.stopped)
+	timer := time.NewTimer(h.interval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-h.stop:
+			return
+		case <-timer.C:
+		}
+
+		h.mu.Lock()
+		idle := time.Since(h.last)
+		if idle >= h.interval {
+			// A failed keepalive means the client is gone, which the next write reports
+			if _, err := io.WriteString(h.w, h.keepalive); err == nil {
+				flush(h.w)
+				h.written = true
+			}
+			h.last, idle = time.Now(), 0
+		}
+		h.mu.Unlock()
+		timer.Reset(h.interval - idle)
+	}
+}
+
+// Write writes p to the stream
+func (h *Heartbeat) Write(p []byte) (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.last = time.Now()
+	h.written = true
+	return h.w.Write(p)
+}
+
+// Flush sends buffered data to the client when the stream supports it
+func (h *Heartbeat) Flush() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	flush(h.w)
+}
+
+// Written reports whether anything, keepalives included, was written
+func (h *Heartbeat) Written() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.written
+}
+
+// Stop stops the keepalives and waits until none is being written
+func (h *Heartbeat) Stop() {
+	h.stopOnce.Do(func() { close(h.stop) })
+	<-h.stopped
+}
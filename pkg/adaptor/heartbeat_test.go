@@ -0,0 +1,66 @@
+package adaptor
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeartbeatWritesKeepaliveWhileIdle(t *testing.T) {
+	var buf bytes.Buffer
+	heartbeat := StartHeartbeat(&buf, 10*time.Millisecond, SSEKeepalive)
+	time.Sleep(35 * time.Millisecond)
+	heartbeat.Stop()
+
+	assert.True(t, heartbeat.Written())
+	assert.GreaterOrEqual(t, strings.Count(buf.String(), SSEKeepalive), 2)
+
+	// Nothing is written once stopped
+	written := buf.Len()
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, written, buf.Len())
+}
+
+func TestHeartbeatDisabled(t *testing.T) {
+	var buf bytes.Buffer
+	heartbeat := StartHeartbeat(&buf, 0, AnthropicPing)
+	assert.False(t, heartbeat.Written())
+
+	_, err := heartbeat.Write([]byte("data: {}\n\n"))
+	require.NoError(t, err)
+	heartbeat.Stop()
+	heartbeat.Stop()
+
+	assert.True(t, heartbeat.Written())
+	assert.Equal(t, "data: {}\n\n", buf.String())
+}
+
+// slowStream yields its chunks after delay
+type slowStream struct {
+	sliceStream[openai.ChatCompletionChunk]
+	delay time.Duration
+}
+
+func (s *slowStream) Next() bool {
+	time.Sleep(s.delay)
+	return s.sliceStream.Next()
+}
+
+func TestConvertOpenAIToAnthropicStreamPings(t *testing.T) {
+	var chunk openai.ChatCompletionChunk
+	require.NoError(t, chunk.UnmarshalJSON([]byte(`{"id":"c1","choices":[{"index":0,"delta":{"content":"Hi"},"finish_reason":"stop"}]}`)))
+	stream := &slowStream{sliceStream: sliceStream[openai.ChatCompletionChunk]{events: []openai.ChatCompletionChunk{chunk}}, delay: 30 * time.Millisecond}
+
+	var buf bytes.Buffer
+	err := ConvertOpenAIToAnthropicStream(stream, &buf, "claude", StreamOptions{Heartbeat: 10 * time.Millisecond})
+	require.NoError(t, err)
+
+	out := buf.String()
+	assert.True(t, strings.HasPrefix(out, AnthropicPing))
+	assert.Contains(t, out, "event: message_stop\n")
+}
@@ -0,0 +1,214 @@
+package adaptor
+
+// The canonical representation that every API style is decoded into and encoded from. A converter
+// between two styles is the decoder of one composed with the encoder of the other, so supporting a
+// new style takes one decoder and one encoder rather than a converter per pair.
+
+// Role is the author of a message; tool results are parts of user messages
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleAssistant Role = "assistant"
+)
+
+// PartType selects which fields of a Part are set
+type PartType string
+
+const (
+	PartText             PartType = "text"              // Text, Citations
+	PartImage            PartType = "image"             // MediaType and Data, or URL
+	PartDocument         PartType = "document"          // MediaType and Data, Text, Content, URL or FileID; Title
+	PartThinking         PartType = "thinking"          // Text, Signature
+	PartRedactedThinking PartType = "redacted_thinking" // Data
+	PartToolCall         PartType = "tool_call"         // ToolCallID, ToolName, Arguments
+	PartToolResult       PartType = "tool_result"       // ToolCallID, Content, IsError
+)
+
+// Part is one piece of message content
+type Part struct {
+	Type PartType
+	Text string
+	// MediaType and base64 Data of inline images and documents, or the data of redacted thinking
+	MediaType string
+	Data      string
+	URL       string
+	FileID    string
+	Title     string
+	// Signature is the upstream signature of thinking, which may be missing or a placeholder
+	Signature  string
+	ToolCallID string
+	ToolName   string
+	// Arguments is the JSON object of a tool call as a string
+	Arguments string
+	// Content of tool results and of documents made of blocks
+	Content      []Part
+	IsError      bool
+	Citations    []Citation
+	CacheControl *CacheControl
+}
+
+// CacheControl is a prompt cache breakpoint; an empty TTL keeps the default lifetime
+type CacheControl struct {
+	TTL string
+}
+
+// Message is one turn of a conversation
+type Message struct {
+	Role  Role
+	Parts []Part
+}
+
+// Tool is a function the model may call
+type Tool struct {
+	Name        string
+	Description string
+	// Parameters is the JSON schema of the arguments, nil when the tool takes none
+	Parameters   map[string]interface{}
+	CacheControl *CacheControl
+}
+
+// ToolChoiceMode selects how the model uses tools
+type ToolChoiceMode string
+
+const (
+	ToolChoiceAuto ToolChoiceMode = "auto"
+	ToolChoiceAny  ToolChoiceMode = "any" // OpenAI "required"
+	ToolChoiceNone ToolChoiceMode = "none"
+	ToolChoiceTool ToolChoiceMode = "tool" // the tool named in Name
+)
+
+// ToolChoice selects how the model uses tools
+type ToolChoice struct {
+	Mode ToolChoiceMode
+	Name string
+}
+
+// ResponseFormat asks for JSON output; Type is "json_object" or "json_schema"
+type ResponseFormat struct {
+	Type        string
+	Name        string
+	Description string
+	// Schema is the schema the output must match, an object schema for json_object
+	Schema map[string]interface{}
+}
+
+// Request is a chat request. Encoders send what their target accepts, the sampling parameters
+// only going to targets that take them with the same meaning: all of them to OpenAI and Gemini,
+// top_p and stop sequences to Anthropic.
+type Request struct {
+	Model    string
+	System   []Part
+	Messages []Message
+	Tools    []Tool
+	// ToolChoice is nil when the client left it to the target
+	ToolChoice *ToolChoice
+	// MaxTokens is 0 when unset
+	MaxTokens int64
+	// ThinkingBudget enables extended thinking with a token budget; 0 leaves it off
+	ThinkingBudget int64
+	// ResponseFormat is nil for free text
+	ResponseFormat *ResponseFormat
+
+	Temperature      *float64
+	TopP             *float64
+	Stop             []string
+	N                *int64
+	Seed             *int64
+	PresencePenalty  *float64
+	FrequencyPenalty *float64
+}
+
+// StopReason is why the model stopped generating
+type StopReason string
+
+const (
+	StopEndTurn       StopReason = "end_turn"
+	StopMaxTokens     StopReason = "max_tokens"
+	StopToolUse       StopReason = "tool_use"
+	StopSequence      StopReason = "stop_sequence"
+	StopContentFilter StopReason = "content_filter"
+)
+
+// Usage counts tokens; InputTokens excludes the cached tokens
+type Usage struct {
+	InputTokens         int64
+	OutputTokens        int64
+	CacheReadTokens     int64
+	CacheCreationTokens int64
+}
+
+// Choice is one generated message
+type Choice struct {
+	Parts      []Part
+	StopReason StopReason
+}
+
+// Response is a complete chat response
+type Response struct {
+	ID      string
+	Model   string
+	Choices []Choice
+	// Usage is nil when the upstream did not report it
+	Usage *Usage
+}
+
+// StreamEventType selects which fields of a StreamEvent are set
+type StreamEventType string
+
+const (
+	EventStart             StreamEventType = "start"              // Usage, when known up front
+	EventText              StreamEventType = "text"               // Text, Extra
+	EventThinking          StreamEventType = "thinking"           // Text
+	EventThinkingSignature StreamEventType = "thinking_signature" // Index, Signature
+	EventRedactedThinking  StreamEventType = "redacted_thinking"  // Index, Data
+	EventToolCallStart     StreamEventType = "tool_call_start"    // Index, ToolCallID, ToolName
+	EventToolCallDelta     StreamEventType = "tool_call_delta"    // Index, Text holding arguments
+	EventCitations         StreamEventType = "citations"          // Citations, SpanStart, SpanEnd
+	EventUsage             StreamEventType = "usage"              // Usage, cumulative
+	EventStop              StreamEventType = "stop"               // StopReason
+)
+
+// StreamEvent is one step of a streamed response
+type StreamEvent struct {
+	Type StreamEventType
+	// Choice is the index of the choice the event belongs to
+	Choice int
+	// Index identifies the tool call or content block within the choice
+	Index      int
+	Text       string
+	Signature  string
+	Data       string
+	ToolCallID string
+	ToolName   string
+	Citations  []Citation
+	// SpanStart and SpanEnd delimit the cited text, in characters of the choice's text
+	SpanStart  int
+	SpanEnd    int
+	Usage      *Usage
+	StopReason StopReason
+	// Extra holds upstream fields without a canonical equivalent, passed on to clients that accept them
+	Extra map[string]interface{}
+}
+
+// hasToolCalls reports whether parts hold a tool call other than the structured output tool
+func hasToolCalls(parts []Part) bool {
+	for _, part := range parts {
+		if part.Type == PartToolCall && part.ToolName != StructuredOutputToolName {
+			return true
+		}
+	}
+	return false
+}
+
+// hasThinking reports whether any message holds thinking
+func (r *Request) hasThinking() bool {
+	for _, msg := range r.Messages {
+		for _, part := range msg.Parts {
+			if part.Type == PartThinking || part.Type == PartRedactedThinking {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,797 @@
+package adaptor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+// DecodeAnthropicRequest converts an Anthropic messages request into the canonical form
+func DecodeAnthropicRequest(req *anthropic.MessageNewParams) *Request {
+	out := &Request{
+		Model:     string(req.Model),
+		MaxTokens: req.MaxTokens,
+		Stop:      req.StopSequences,
+	}
+	if budget := req.Thinking.GetBudgetTokens(); budget != nil {
+		out.ThinkingBudget = *budget
+	}
+	if req.Temperature.Valid() {
+		out.Temperature = &req.Temperature.Value
+	}
+	if req.TopP.Valid() {
+		out.TopP = &req.TopP.Value
+	}
+
+	for _, block := range req.System {
+		out.System = append(out.System, Part{Type: PartText, Text: block.Text, CacheControl: cacheControlFromAnthropic(block.CacheControl)})
+	}
+	for _, msg := range req.Messages {
+		switch msg.Role {
+		case anthropic.MessageParamRoleUser:
+			out.Messages = append(out.Messages, Message{Role: RoleUser, Parts: anthropicBlocksToParts(msg.Content)})
+		case anthropic.MessageParamRoleAssistant:
+			out.Messages = append(out.Messages, Message{Role: RoleAssistant, Parts: anthropicBlocksToParts(msg.Content)})
+		}
+	}
+
+	out.Tools = decodeAnthropicTools(req.Tools)
+	out.ToolChoice = decodeAnthropicToolChoice(&req.ToolChoice)
+	return out
+}
+
+// decodeAnthropicTools converts custom tools; server tools run on Anthropic's side and have no
+// equivalent elsewhere
+func decodeAnthropicTools(tools []anthropic.ToolUnionParam) []Tool {
+	var out []Tool
+	for _, t := range tools {
+		if t.OfTool == nil {
+			continue
+		}
+		tool := Tool{
+			Name:         t.OfTool.Name,
+			Description:  t.OfTool.Description.Value,
+			CacheControl: cacheControlFromAnthropic(t.OfTool.CacheControl),
+		}
+		if t.OfTool.InputSchema.Properties != nil {
+			if raw, err := json.Marshal(t.OfTool.InputSchema); err == nil {
+				_ = json.Unmarshal(raw, &tool.Parameters)
+			}
+		}
+		out = append(out, tool)
+	}
+	return out
+}
+
+// decodeAnthropicToolChoice converts an Anthropic tool_choice, returning nil when it is not set
+func decodeAnthropicToolChoice(tc *anthropic.ToolChoiceUnionParam) *ToolChoice {
+	switch {
+	case tc.OfAuto != nil:
+		return &ToolChoice{Mode: ToolChoiceAuto}
+	case tc.OfAny != nil:
+		return &ToolChoice{Mode: ToolChoiceAny}
+	case tc.OfNone != nil:
+		return &ToolChoice{Mode: ToolChoiceNone}
+	case tc.OfTool != nil:
+		return &ToolChoice{Mode: ToolChoiceTool, Name: tc.OfTool.Name}
+	}
+	return nil
+}
+
+// anthropicBlocksToParts converts the content blocks of a request message
+func anthropicBlocksToParts(blocks []anthropic.ContentBlockParamUnion) []Part {
+	var parts []Part
+	for _, block := range blocks {
+		switch {
+		case block.OfText != nil:
+			parts = append(parts, Part{Type: PartText, Text: block.OfText.Text, CacheControl: cacheControlFromAnthropic(block.OfText.CacheControl)})
+		case block.OfImage != nil:
+			if part, ok := anthropicImageToPart(block.OfImage); ok {
+				parts = append(parts, part)
+			}
+		case block.OfDocument != nil:
+			parts = append(parts, anthropicDocumentToPart(block.OfDocument))
+		case block.OfThinking != nil:
+			parts = append(parts, Part{Type: PartThinking, Text: block.OfThinking.Thinking, Signature: block.OfThinking.Signature})
+		case block.OfRedactedThinking != nil:
+			parts = append(parts, Part{Type: PartRedactedThinking, Data: block.OfRedactedThinking.Data})
+		case block.OfToolUse != nil:
+			args, _ := json.Marshal(block.OfToolUse.Input)
+			parts = append(parts, Part{
+				Type:         PartToolCall,
+				ToolCallID:   block.OfToolUse.ID,
+				ToolName:     block.OfToolUse.Name,
+				Arguments:    string(args),
+				CacheControl: cacheControlFromAnthropic(block.OfToolUse.CacheControl),
+			})
+		case block.OfToolResult != nil:
+			result := Part{
+				Type:         PartToolResult,
+				ToolCallID:   block.OfToolResult.ToolUseID,
+				IsError:      block.OfToolResult.IsError.Value,
+				CacheControl: cacheControlFromAnthropic(block.OfToolResult.CacheControl),
+			}
+			for _, c := range block.OfToolResult.Content {
+				switch {
+				case c.OfText != nil:
+					result.Content = append(result.Content, Part{Type: PartText, Text: c.OfText.Text, CacheControl: cacheControlFromAnthropic(c.OfText.CacheControl)})
+				case c.OfImage != nil:
+					if part, ok := anthropicImageToPart(c.OfImage); ok {
+						result.Content = append(result.Content, part)
+					}
+				case c.OfDocument != nil:
+					result.Content = append(result.Content, anthropicDocumentToPart(c.OfDocument))
+				}
+			}
+			parts = append(parts, result)
+		}
+	}
+	return parts
+}
+
+// EncodeAnthropicRequest converts a canonical request into an Anthropic messages request, using
+// defaultMaxTokens when it sets none. Thinking without an Anthropic signature is left out, as
+// Anthropic rejects it. It returns the first file conversion error along with the request
+// converted without it.
+func EncodeAnthropicRequest(r *Request, defaultMaxTokens int64, opts ConvertOptions) (anthropic.MessageNewParams, error) {
+	var convErr error
+	setErr := func(err error) {
+		if err != nil && convErr == nil {
+			convErr = err
+		}
+	}
+
+	maxTokens := r.MaxTokens
+	if maxTokens == 0 {
+		maxTokens = defaultMaxTokens
+	}
+	params := anthropic.MessageNewParams{
+		Model:     anthropic.Model(r.Model),
+		Messages:  make([]anthropic.MessageParam, 0, len(r.Messages)),
+		MaxTokens: maxTokens,
+	}
+
+	for _, part := range r.System {
+		if part.Type == PartText {
+			params.System = append(params.System, anthropic.TextBlockParam{Text: part.Text, CacheControl: part.CacheControl.anthropic()})
+		}
+	}
+
+	for _, msg := range r.Messages {
+		var blocks []anthropic.ContentBlockParamUnion
+		for _, part := range msg.Parts {
+			block, ok, err := opts.anthropicBlock(part)
+			setErr(err)
+			if ok {
+				blocks = append(blocks, block)
+			} else if part.CacheControl != nil && len(blocks) > 0 {
+				// The breakpoint of a part left out moves to the block before it
+				setBlockCacheControl(&blocks[len(blocks)-1], part.CacheControl.anthropic())
+			}
+		}
+		if len(blocks) == 0 {
+			continue
+		}
+		if msg.Role == RoleAssistant {
+			params.Messages = append(params.Messages, anthropic.NewAssistantMessage(blocks...))
+		} else {
+			params.Messages = append(params.Messages, anthropic.NewUserMessage(blocks...))
+		}
+	}
+
+	if len(r.Stop) > 0 {
+		params.StopSequences = r.Stop
+	}
+	// Temperature is left out, OpenAI's scale going up to 2 where Anthropic's stops at 1
+	if budget := clampThinkingBudget(r.ThinkingBudget, maxTokens); budget > 0 {
+		params.Thinking = anthropic.ThinkingConfigParamOfEnabled(budget)
+	} else if r.TopP != nil {
+		// Thinking only takes a top_p close to 1, so it is left to the default there
+		params.TopP = anthropic.Float(*r.TopP)
+	}
+	if len(r.Tools) > 0 {
+		params.Tools = opts.encodeAnthropicTools(r.Tools)
+	}
+	if r.ToolChoice != nil {
+		params.ToolChoice = encodeAnthropicToolChoice(r.ToolChoice)
+	}
+
+	// Emulate response_format with a synthetic tool the model is made to call
+	applyStructuredOutput(r.ResponseFormat, &params)
+
+	return params, convErr
+}
+
+// anthropicBlock converts a part into a content block, reporting whether it has one
+func (o ConvertOptions) anthropicBlock(part Part) (anthropic.ContentBlockParamUnion, bool, error) {
+	var block anthropic.ContentBlockParamUnion
+	switch part.Type {
+	case PartText:
+		block = anthropic.NewTextBlock(part.Text)
+	case PartImage:
+		image, ok := anthropicImage(part)
+		if !ok {
+			return block, false, nil
+		}
+		block = anthropic.ContentBlockParamUnion{OfImage: image}
+	case PartDocument:
+		var err error
+		if block, err = o.documentToAnthropic(part); err != nil {
+			return block, false, err
+		}
+	case PartThinking:
+		if !isRealSignature(part.Signature) {
+			return block, false, nil
+		}
+		block = anthropic.NewThinkingBlock(part.Signature, part.Text)
+	case PartRedactedThinking:
+		block = anthropic.NewRedactedThinkingBlock(part.Data)
+	case PartToolCall:
+		// Invalid arguments leave the input out
+		var input interface{}
+		_ = json.Unmarshal([]byte(part.Arguments), &input)
+		block = anthropic.NewToolUseBlock(part.ToolCallID, input, part.ToolName)
+	case PartToolResult:
+		var convErr error
+		block = anthropic.NewToolResultBlock(part.ToolCallID, "", part.IsError)
+		block.OfToolResult.Content = nil
+		for _, c := range part.Content {
+			content, ok, err := o.anthropicBlock(c)
+			if err != nil && convErr == nil {
+				convErr = err
+			}
+			if ok {
+				block.OfToolResult.Content = append(block.OfToolResult.Content,
+					anthropic.ToolResultBlockParamContentUnion{OfText: content.OfText, OfImage: content.OfImage, OfDocument: content.OfDocument})
+			}
+		}
+		if part.CacheControl != nil {
+			setBlockCacheControl(&block, part.CacheControl.anthropic())
+		}
+		return block, true, convErr
+	default:
+		return block, false, nil
+	}
+	if part.CacheControl != nil {
+		setBlockCacheControl(&block, part.CacheControl.anthropic())
+	}
+	return block, true, nil
+}
+
+// encodeAnthropicTools converts tools to Anthropic tools, sanitizing their schemas with o.ToolSchema
+func (o ConvertOptions) encodeAnthropicTools(tools []Tool) []anthropic.ToolUnionParam {
+	out := make([]anthropic.ToolUnionParam, 0, len(tools))
+	for _, t := range tools {
+		inputSchema, changes := SanitizeSchema(t.Parameters, o.ToolSchema)
+		logSchemaChanges(t.Name, changes)
+
+		tool := &anthropic.ToolParam{Name: t.Name, CacheControl: t.CacheControl.anthropic()}
+		if inputSchema != nil {
+			// Keywords without a field, such as $defs, are kept as extra fields
+			tool.InputSchema = anthropicInputSchema(inputSchema)
+		}
+		if t.Description != "" {
+			tool.Description = anthropic.Opt(sanitizeToolDescription(t.Name, t.Description, o.ToolSchema))
+		}
+		out = append(out, anthropic.ToolUnionParam{OfTool: tool})
+	}
+	return out
+}
+
+// encodeAnthropicToolChoice converts a tool choice to Anthropic tool_choice
+func encodeAnthropicToolChoice(tc *ToolChoice) anthropic.ToolChoiceUnionParam {
+	switch tc.Mode {
+	case ToolChoiceTool:
+		return anthropic.ToolChoiceParamOfTool(tc.Name)
+	case ToolChoiceAny:
+		return anthropic.ToolChoiceUnionParam{OfAny: &anthropic.ToolChoiceAnyParam{}}
+	case ToolChoiceNone:
+		return anthropic.ToolChoiceUnionParam{OfNone: &anthropic.ToolChoiceNoneParam{}}
+	default:
+		return anthropic.ToolChoiceUnionParam{OfAuto: &anthropic.ToolChoiceAutoParam{}}
+	}
+}
+
+// DecodeAnthropicResponse converts an Anthropic message into the canonical form
+func DecodeAnthropicResponse(msg *anthropic.Message) *Response {
+	var parts []Part
+	for _, block := range msg.Content {
+		switch block.Type {
+		case "text":
+			part := Part{Type: PartText, Text: block.Text}
+			for _, c := range block.Citations {
+				part.Citations = append(part.Citations, citationFromText(c))
+			}
+			parts = append(parts, part)
+		case "thinking":
+			parts = append(parts, Part{Type: PartThinking, Text: block.Thinking, Signature: block.Signature})
+		case "redacted_thinking":
+			parts = append(parts, Part{Type: PartRedactedThinking, Data: block.Data})
+		case "tool_use":
+			args := bytes.Buffer{}
+			if err := json.Compact(&args, block.Input); err != nil {
+				args.Reset()
+				args.WriteString("{}")
+			}
+			parts = append(parts, Part{Type: PartToolCall, ToolCallID: block.ID, ToolName: block.Name, Arguments: args.String()})
+		}
+	}
+	return &Response{
+		ID:      msg.ID,
+		Model:   string(msg.Model),
+		Choices: []Choice{{Parts: parts, StopReason: stopReasonFromAnthropic(string(msg.StopReason))}},
+		Usage: &Usage{
+			InputTokens:         msg.Usage.InputTokens,
+			OutputTokens:        msg.Usage.OutputTokens,
+			CacheReadTokens:     msg.Usage.CacheReadInputTokens,
+			CacheCreationTokens: msg.Usage.CacheCreationInputTokens,
+		},
+	}
+}
+
+// EncodeAnthropicResponse converts the first choice of a canonical response into an Anthropic
+// message. Thinking without a signature gets a placeholder, since Anthropic clients expect every
+// thinking block to be signed.
+func EncodeAnthropicResponse(r *Response, responseModel string) anthropic.Message {
+	var parts []Part
+	stopReason := StopEndTurn
+	if len(r.Choices) > 0 {
+		parts, stopReason = r.Choices[0].Parts, r.Choices[0].StopReason
+	}
+
+	var blocks []anthropic.ContentBlockParamUnion
+	toolUse := false
+	for _, part := range parts {
+		switch part.Type {
+		case PartThinking:
+			signature := part.Signature
+			if !isRealSignature(signature) {
+				signature = placeholderSignature()
+			}
+			blocks = append(blocks, anthropic.NewThinkingBlock(signature, part.Text))
+		case PartRedactedThinking:
+			blocks = append(blocks, anthropic.NewRedactedThinkingBlock(part.Data))
+		case PartText:
+			if part.Text != "" {
+				blocks = append(blocks, anthropic.NewTextBlock(part.Text))
+			}
+		case PartToolCall:
+			// tool_use input is an object, not the JSON string of the arguments
+			input := map[string]interface{}{}
+			_ = json.Unmarshal([]byte(part.Arguments), &input)
+			blocks = append(blocks, anthropic.NewToolUseBlock(part.ToolCallID, input, part.ToolName))
+			toolUse = true
+		}
+	}
+	if stopReason == StopToolUse && !toolUse {
+		stopReason = StopEndTurn
+	}
+
+	usage := Usage{}
+	if r.Usage != nil {
+		usage = *r.Usage
+	}
+	// The content blocks are unions, so the message is built through JSON
+	responseJSON, _ := json.Marshal(map[string]interface{}{
+		"id":            fmt.Sprintf("msg_%d", time.Now().Unix()),
+		"type":          "message",
+		"role":          "assistant",
+		"content":       blocks,
+		"model":         responseModel,
+		"stop_reason":   anthropicStopReason(stopReason),
+		"stop_sequence": "",
+		"usage":         anthropicUsage(usage),
+	})
+	var msg anthropic.Message
+	_ = json.Unmarshal(responseJSON, &msg)
+	return msg
+}
+
+// stopReasonFromAnthropic maps an Anthropic stop_reason
+func stopReasonFromAnthropic(stopReason string) StopReason {
+	switch stopReason {
+	case anthropicStopReasonMaxTokens, anthropicStopReasonToolUse, string(StopSequence):
+		return StopReason(stopReason)
+	case anthropicStopReasonContentFilter:
+		return StopContentFilter
+	default:
+		return StopEndTurn
+	}
+}
+
+// anthropicStopReason maps a stop reason to an Anthropic stop_reason
+func anthropicStopReason(reason StopReason) string {
+	switch reason {
+	case StopContentFilter:
+		return anthropicStopReasonContentFilter
+	case "":
+		return anthropicStopReasonEndTurn
+	default:
+		return string(reason)
+	}
+}
+
+// anthropicStreamDecoder converts the events of one Anthropic stream into stream events of the
+// given choice
+type anthropicStreamDecoder struct {
+	choice     int
+	startUsage Usage
+	stopReason StopReason
+	// Citations are reported once their text block, and so its span, is complete
+	textOffset int
+	blockStart int
+	citations  []Citation
+}
+
+func newAnthropicStreamDecoder(choice int) *anthropicStreamDecoder {
+	return &anthropicStreamDecoder{choice: choice}
+}
+
+// decode converts one event; the message is complete with the stop event
+func (d *anthropicStreamDecoder) decode(event anthropic.MessageStreamEventUnion) []StreamEvent {
+	ev := StreamEvent{Choice: d.choice, Index: int(event.Index)}
+	switch event.Type {
+	case "message_start":
+		usage := event.Message.Usage
+		d.startUsage = Usage{
+			InputTokens:         usage.InputTokens,
+			CacheReadTokens:     usage.CacheReadInputTokens,
+			CacheCreationTokens: usage.CacheCreationInputTokens,
+		}
+		ev.Type, ev.Usage = EventStart, &d.startUsage
+
+	case "content_block_start":
+		switch event.ContentBlock.Type {
+		case "text":
+			d.blockStart = d.textOffset
+			d.citations = d.citations[:0]
+			return nil
+		case "redacted_thinking":
+			ev.Type, ev.Data = EventRedactedThinking, event.ContentBlock.Data
+		case "tool_use":
+			ev.Type, ev.ToolCallID, ev.ToolName = EventToolCallStart, event.ContentBlock.ID, event.ContentBlock.Name
+		default:
+			return nil
+		}
+
+	case "content_block_delta":
+		delta := event.Delta
+		switch {
+		case delta.Type == "text_delta" && delta.Text != "":
+			d.textOffset += utf8.RuneCountInString(delta.Text)
+			ev.Type, ev.Text = EventText, delta.Text
+		case delta.Type == "input_json_delta" && delta.PartialJSON != "":
+			ev.Type, ev.Text = EventToolCallDelta, delta.PartialJSON
+		case delta.Type == "citations_delta":
+			d.citations = append(d.citations, citationFromDelta(delta.Citation))
+			return nil
+		case delta.Type == "thinking_delta" && delta.Thinking != "":
+			ev.Type, ev.Text = EventThinking, delta.Thinking
+		case delta.Type == "signature_delta" && delta.Signature != "":
+			ev.Type, ev.Signature = EventThinkingSignature, delta.Signature
+		default:
+			return nil
+		}
+
+	case "content_block_stop":
+		if len(d.citations) == 0 {
+			return nil
+		}
+		ev.Type, ev.SpanStart, ev.SpanEnd = EventCitations, d.blockStart, d.textOffset
+		ev.Citations = append([]Citation(nil), d.citations...)
+		d.citations = d.citations[:0]
+
+	case "message_delta":
+		// Input and cache usage arrive with message_start, unless repeated here
+		usage := Usage{
+			InputTokens:         event.Usage.InputTokens,
+			OutputTokens:        event.Usage.OutputTokens,
+			CacheReadTokens:     event.Usage.CacheReadInputTokens,
+			CacheCreationTokens: event.Usage.CacheCreationInputTokens,
+		}
+		if usage.InputTokens == 0 && usage.CacheReadTokens == 0 && usage.CacheCreationTokens == 0 {
+			usage.InputTokens, usage.CacheReadTokens, usage.CacheCreationTokens = d.startUsage.InputTokens, d.startUsage.CacheReadTokens, d.startUsage.CacheCreationTokens
+		}
+		d.stopReason = stopReasonFromAnthropic(string(event.Delta.StopReason))
+		ev.Type, ev.Usage = EventUsage, &usage
+
+	case "message_stop":
+		ev.Type, ev.StopReason = EventStop, d.stopReason
+		if ev.StopReason == "" {
+			ev.StopReason = StopEndTurn
+		}
+
+	default:
+		return nil
+	}
+	return []StreamEvent{ev}
+}
+
+// anthropicToolBlock is a tool_use block whose arguments are buffered until it stops
+type anthropicToolBlock struct {
+	index int
+	id    string
+	name  string
+	input string
+}
+
+// anthropicStreamEncoder converts stream events of the first choice into Anthropic events. Tool
+// arguments are buffered and sent repaired when their block stops, and thinking without an
+// upstream signature gets a placeholder.
+type anthropicStreamEncoder struct {
+	messageID string
+	model     string
+	started   bool
+	// stopReason is set once the choice stopped; the message ends with the usage that follows
+	stopReason string
+
+	textBlockIndex     int
+	thinkingBlockIndex int
+	thinkingSignature  string
+	redactedThinking   []string
+	hasTextContent     bool
+	nextBlockIndex     int
+	toolBlocks         map[int]*anthropicToolBlock
+	deltaExtras        map[string]interface{}
+	usage              Usage
+
+	events []SSEEvent
+}
+
+// newAnthropicStreamEncoder creates an encoder reporting inputTokens until the upstream reports usage
+func newAnthropicStreamEncoder(messageID, responseModel string, inputTokens int64) *anthropicStreamEncoder {
+	return &anthropicStreamEncoder{
+		messageID:          messageID,
+		model:              responseModel,
+		textBlockIndex:     -1,
+		thinkingBlockIndex: -1,
+		toolBlocks:         make(map[int]*anthropicToolBlock),
+		deltaExtras:        make(map[string]interface{}),
+		usage:              Usage{InputTokens: inputTokens},
+	}
+}
+
+// encode converts events into Anthropic events
+func (e *anthropicStreamEncoder) encode(events []StreamEvent) []SSEEvent {
+	e.events = nil
+	for _, ev := range events {
+		if ev.Choice != 0 {
+			continue
+		}
+		for k, v := range ev.Extra {
+			e.deltaExtras[k] = v
+		}
+		switch ev.Type {
+		case EventStart:
+			if ev.Usage != nil {
+				e.usage = *ev.Usage
+			}
+			e.start()
+		case EventText:
+			e.text(ev)
+		case EventThinking:
+			if e.thinkingBlockIndex == -1 {
+				e.thinkingBlockIndex = e.nextBlockIndex
+				e.nextBlockIndex++
+				e.blockStart(e.thinkingBlockIndex, blockTypeThinking, map[string]interface{}{"thinking": ""})
+			}
+			e.blockDelta(e.thinkingBlockIndex, map[string]interface{}{
+				"type":     deltaTypeThinkingDelta,
+				"thinking": ev.Text,
+			})
+		case EventThinkingSignature:
+			e.thinkingSignature = ev.Signature
+		case EventRedactedThinking:
+			e.redactedThinking = append(e.redactedThinking, ev.Data)
+		case EventToolCallStart:
+			e.finishThinkingBlock()
+			index := e.nextBlockIndex
+			e.nextBlockIndex++
+			e.toolBlocks[ev.Index] = &anthropicToolBlock{index: index, id: ev.ToolCallID, name: ev.ToolName}
+			e.blockStart(index, blockTypeToolUse, map[string]interface{}{"id": ev.ToolCallID, "name": ev.ToolName})
+		case EventToolCallDelta:
+			if block, ok := e.toolBlocks[ev.Index]; ok {
+				block.input += ev.Text
+			}
+		case EventUsage:
+			e.usage = *ev.Usage
+		case EventStop:
+			if e.stopReason == "" {
+				e.stopBlocks()
+				e.stopReason = anthropicStopReason(ev.StopReason)
+			}
+		}
+	}
+	return e.events
+}
+
+// finish ends the message once the choice stopped, with the usage reported so far
+func (e *anthropicStreamEncoder) finish() []SSEEvent {
+	e.events = nil
+	e.start()
+	if e.stopReason != "" {
+		e.messageDelta()
+		e.messageStop()
+	}
+	return e.events
+}
+
+func (e *anthropicStreamEncoder) emit(eventType string, data map[string]interface{}) {
+	e.events = append(e.events, SSEEvent{Event: eventType, Data: data})
+}
+
+// start sends message_start unless it was sent
+func (e *anthropicStreamEncoder) start() {
+	if e.started {
+		return
+	}
+	e.started = true
+	e.emit(eventTypeMessageStart, map[string]interface{}{
+		"type": eventTypeMessageStart,
+		"message": map[string]interface{}{
+			"id":            e.messageID,
+			"type":          "message",
+			"role":          "assistant",
+			"content":       []interface{}{},
+			"model":         e.model,
+			"stop_reason":   nil,
+			"stop_sequence": nil,
+			"usage":         anthropicUsage(e.usage),
+		},
+	})
+}
+
+// text sends text, with the extra fields of its chunk. Empty text keeps the client informed while
+// a text block is open.
+func (e *anthropicStreamEncoder) text(ev StreamEvent) {
+	if ev.Text == "" && e.textBlockIndex == -1 {
+		return
+	}
+	if e.textBlockIndex == -1 {
+		e.finishThinkingBlock()
+		e.textBlockIndex = e.nextBlockIndex
+		e.nextBlockIndex++
+		e.blockStart(e.textBlockIndex, blockTypeText, map[string]interface{}{"text": ""})
+	}
+	if ev.Text != "" {
+		e.hasTextContent = true
+	}
+	delta := map[string]interface{}{
+		"type": deltaTypeTextDelta,
+		"text": ev.Text,
+	}
+	for k, v := range ev.Extra {
+		delta[k] = v
+	}
+	e.blockDelta(e.textBlockIndex, delta)
+}
+
+func (e *anthropicStreamEncoder) blockStart(index int, blockType string, initialContent map[string]interface{}) {
+	contentBlock := map[string]interface{}{
+		"type": blockType,
+	}
+	for k, v := range initialContent {
+		contentBlock[k] = v
+	}
+	e.emit(eventTypeContentBlockStart, map[string]interface{}{
+		"type":          eventTypeContentBlockStart,
+		"index":         index,
+		"content_block": contentBlock,
+	})
+}
+
+func (e *anthropicStreamEncoder) blockDelta(index int, delta map[string]interface{}) {
+	e.emit(eventTypeContentBlockDelta, map[string]interface{}{
+		"type":  eventTypeContentBlockDelta,
+		"index": index,
+		"delta": delta,
+	})
+}
+
+func (e *anthropicStreamEncoder) blockStop(index int) {
+	e.emit(eventTypeContentBlockStop, map[string]interface{}{
+		"type":  eventTypeContentBlockStop,
+		"index": index,
+	})
+}
+
+// finishThinkingBlock signs and stops the open thinking block, then sends collected redacted thinking
+func (e *anthropicStreamEncoder) finishThinkingBlock() {
+	if e.thinkingBlockIndex != -1 {
+		signature := e.thinkingSignature
+		if signature == "" {
+			signature = placeholderSignature()
+		}
+		e.blockDelta(e.thinkingBlockIndex, map[string]interface{}{
+			"type":      deltaTypeSignatureDelta,
+			"signature": signature,
+		})
+		e.blockStop(e.thinkingBlockIndex)
+		e.thinkingBlockIndex = -1
+		e.thinkingSignature = ""
+	}
+
+	for _, data := range e.redactedThinking {
+		index := e.nextBlockIndex
+		e.nextBlockIndex++
+		e.blockStart(index, blockTypeRedactedThinking, map[string]interface{}{"data": data})
+		e.blockStop(index)
+	}
+	e.redactedThinking = nil
+}
+
+// stopBlocks stops all open blocks in index order, each tool call with its repaired arguments
+func (e *anthropicStreamEncoder) stopBlocks() {
+	// Thinking is signed and stopped first, as it precedes all other blocks
+	e.finishThinkingBlock()
+
+	toolBlocks := make(map[int]*anthropicToolBlock, len(e.toolBlocks))
+	var indexes []int
+	if e.hasTextContent {
+		indexes = append(indexes, e.textBlockIndex)
+	}
+	for _, block := range e.toolBlocks {
+		toolBlocks[block.index] = block
+		indexes = append(indexes, block.index)
+	}
+	sort.Ints(indexes)
+
+	for _, index := range indexes {
+		if block, ok := toolBlocks[index]; ok {
+			args, repair := RepairToolArguments(block.input)
+			if repair != "" {
+				countToolCallRepair(repair, block.name)
+			}
+			if args != "" {
+				e.blockDelta(index, map[string]interface{}{
+					"type":         deltaTypeInputJSONDelta,
+					"partial_json": args,
+				})
+			}
+		}
+		e.blockStop(index)
+	}
+}
+
+func (e *anthropicStreamEncoder) messageDelta() {
+	delta := map[string]interface{}{
+		"stop_reason":   e.stopReason,
+		"stop_sequence": nil,
+	}
+	for k, v := range e.deltaExtras {
+		delta[k] = v
+	}
+	e.emit(eventTypeMessageDelta, map[string]interface{}{
+		"type":  eventTypeMessageDelta,
+		"delta": delta,
+		"usage": map[string]interface{}{
+			"output_tokens":               e.usage.OutputTokens,
+			"input_tokens":                e.usage.InputTokens,
+			"cache_read_input_tokens":     e.usage.CacheReadTokens,
+			"cache_creation_input_tokens": e.usage.CacheCreationTokens,
+		},
+	})
+}
+
+func (e *anthropicStreamEncoder) messageStop() {
+	e.emit(eventTypeMessageStop, map[string]interface{}{
+		"type": eventTypeMessageStop,
+		"message": map[string]interface{}{
+			"id":            e.messageID,
+			"type":          "message",
+			"role":          "assistant",
+			"content":       []interface{}{},
+			"model":         e.model,
+			"stop_reason":   e.stopReason,
+			"stop_sequence": nil,
+			"usage":         anthropicUsage(e.usage),
+		},
+	})
+	// A final data only event, without a name
+	e.emit("", map[string]interface{}{"type": eventTypeMessageStop})
+}
@@ -0,0 +1,266 @@
+package adaptor
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// EncodeGeminiRequest converts a canonical request into a Gemini generateContent request. The
+// system prompt becomes the system instruction, tool calls and results become function calls and
+// responses. Thinking is left out, Gemini having no way to replay it.
+func EncodeGeminiRequest(r *Request) (*GeminiRequest, error) {
+	out := &GeminiRequest{}
+	if len(r.System) > 0 {
+		parts, err := geminiParts(r.System)
+		if err != nil {
+			return nil, err
+		}
+		out.SystemInstruction = &GeminiContent{Parts: parts}
+	}
+
+	// Function responses carry the function name, which other styles only have on the call
+	toolNames := make(map[string]string)
+	for _, msg := range r.Messages {
+		var parts []GeminiPart
+		for _, part := range msg.Parts {
+			switch part.Type {
+			case PartToolCall:
+				toolNames[part.ToolCallID] = part.ToolName
+				var args map[string]interface{}
+				if part.Arguments != "" {
+					if err := json.Unmarshal([]byte(part.Arguments), &args); err != nil {
+						args = map[string]interface{}{"arguments": part.Arguments}
+					}
+				}
+				parts = append(parts, GeminiPart{FunctionCall: &GeminiFunctionCall{Name: part.ToolName, Args: args}})
+			case PartToolResult:
+				var text strings.Builder
+				for _, c := range part.Content {
+					text.WriteString(c.Text)
+				}
+				var response map[string]interface{}
+				if err := json.Unmarshal([]byte(text.String()), &response); err != nil || response == nil {
+					response = map[string]interface{}{"content": text.String()}
+				}
+				parts = append(parts, GeminiPart{FunctionResponse: &GeminiFunctionResponse{Name: toolNames[part.ToolCallID], Response: response}})
+			default:
+				converted, err := geminiParts([]Part{part})
+				if err != nil {
+					return nil, err
+				}
+				parts = append(parts, converted...)
+			}
+		}
+		role := "user"
+		if msg.Role == RoleAssistant {
+			role = "model"
+		}
+		out.Contents = appendGeminiContent(out.Contents, role, parts)
+	}
+
+	var declarations []GeminiFunctionDeclaration
+	for _, tool := range r.Tools {
+		declaration := GeminiFunctionDeclaration{Name: tool.Name, Description: tool.Description}
+		if tool.Parameters != nil {
+			declaration.Parameters, _ = json.Marshal(tool.Parameters)
+		}
+		declarations = append(declarations, declaration)
+	}
+	if len(declarations) > 0 {
+		out.Tools = []GeminiTool{{FunctionDeclarations: declarations}}
+	}
+	if r.ToolChoice != nil {
+		out.ToolConfig = encodeGeminiToolChoice(r.ToolChoice)
+	}
+
+	config := GeminiGenerationConfig{
+		Temperature:      r.Temperature,
+		TopP:             r.TopP,
+		StopSequences:    r.Stop,
+		CandidateCount:   r.N,
+		Seed:             r.Seed,
+		PresencePenalty:  r.PresencePenalty,
+		FrequencyPenalty: r.FrequencyPenalty,
+	}
+	if r.MaxTokens > 0 {
+		maxTokens := r.MaxTokens
+		config.MaxOutputTokens = &maxTokens
+	}
+	if r.ResponseFormat != nil {
+		config.ResponseMimeType = "application/json"
+	}
+	out.GenerationConfig = &config
+	return out, nil
+}
+
+// geminiParts converts text, image and document parts; thinking is left out
+func geminiParts(parts []Part) ([]GeminiPart, error) {
+	var out []GeminiPart
+	for _, part := range parts {
+		switch part.Type {
+		case PartText:
+			out = append(out, GeminiPart{Text: part.Text})
+		case PartImage:
+			if part.Data != "" {
+				out = append(out, GeminiPart{InlineData: &GeminiBlob{MimeType: part.MediaType, Data: part.Data}})
+			} else if part.URL != "" {
+				out = append(out, GeminiPart{FileData: &GeminiFileData{MimeType: guessImageMimeType(part.URL), FileURI: part.URL}})
+			}
+		case PartDocument:
+			// Gemini reads PDFs and text files natively as inline data
+			switch {
+			case part.Data != "":
+				out = append(out, GeminiPart{InlineData: &GeminiBlob{MimeType: part.MediaType, Data: part.Data}})
+			case part.URL != "":
+				out = append(out, GeminiPart{FileData: &GeminiFileData{MimeType: "application/pdf", FileURI: part.URL}})
+			case part.FileID != "":
+				return nil, unsupportedDocument("uploaded file %q cannot be sent to Gemini, send it as file_data", part.FileID)
+			default:
+				var text strings.Builder
+				text.WriteString(part.Text)
+				for _, c := range part.Content {
+					text.WriteString(c.Text)
+				}
+				out = append(out, GeminiPart{Text: documentText(part.Title, text.String())})
+			}
+		}
+	}
+	return out, nil
+}
+
+// encodeGeminiToolChoice converts a tool choice to a function calling mode
+func encodeGeminiToolChoice(tc *ToolChoice) *GeminiToolConfig {
+	config := GeminiFunctionCallingConfig{Mode: "AUTO"}
+	switch tc.Mode {
+	case ToolChoiceNone:
+		config.Mode = "NONE"
+	case ToolChoiceAny:
+		config.Mode = "ANY"
+	case ToolChoiceTool:
+		config.Mode, config.AllowedFunctionNames = "ANY", []string{tc.Name}
+	}
+	return &GeminiToolConfig{FunctionCallingConfig: config}
+}
+
+// DecodeGeminiResponse converts a Gemini generateContent response into the canonical form, one
+// choice per candidate
+func DecodeGeminiResponse(resp *GeminiResponse) *Response {
+	out := &Response{ID: resp.ResponseID, Usage: resp.usage()}
+	for i, candidate := range resp.Candidates {
+		var thinking, text string
+		var calls []Part
+		for _, part := range candidate.Content.Parts {
+			switch {
+			case part.FunctionCall != nil:
+				calls = append(calls, geminiToolCall(part.FunctionCall, fmt.Sprintf("call_%d_%d", i, len(calls))))
+			case part.Thought:
+				thinking += part.Text
+			default:
+				text += part.Text
+			}
+		}
+
+		var parts []Part
+		if thinking != "" {
+			parts = append(parts, Part{Type: PartThinking, Text: thinking})
+		}
+		if text != "" {
+			parts = append(parts, Part{Type: PartText, Text: text})
+		}
+		parts = append(parts, calls...)
+		out.Choices = append(out.Choices, Choice{Parts: parts, StopReason: geminiStopReason(candidate.FinishReason, len(calls) > 0)})
+	}
+	return out
+}
+
+// geminiToolCall converts a function call; Gemini has no call IDs, so the caller names it
+func geminiToolCall(call *GeminiFunctionCall, id string) Part {
+	args := []byte("{}")
+	if call.Args != nil {
+		args, _ = json.Marshal(call.Args)
+	}
+	return Part{Type: PartToolCall, ToolCallID: id, ToolName: call.Name, Arguments: string(args)}
+}
+
+// usage converts the usage metadata, returning nil when it is missing; thoughts count as output
+func (r *GeminiResponse) usage() *Usage {
+	if r.UsageMetadata == nil {
+		return nil
+	}
+	return &Usage{
+		InputTokens:  r.UsageMetadata.PromptTokenCount,
+		OutputTokens: r.UsageMetadata.CandidatesTokenCount + r.UsageMetadata.ThoughtsTokenCount,
+	}
+}
+
+// geminiStopReason maps a Gemini finish reason; a candidate that called functions stops for them
+func geminiStopReason(reason string, hasToolCalls bool) StopReason {
+	if hasToolCalls {
+		return StopToolUse
+	}
+	switch reason {
+	case "MAX_TOKENS":
+		return StopMaxTokens
+	case "SAFETY", "RECITATION", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII", "IMAGE_SAFETY":
+		return StopContentFilter
+	default:
+		return StopEndTurn
+	}
+}
+
+// geminiStreamDecoder converts the chunks of a Gemini stream into stream events, a choice per
+// candidate
+type geminiStreamDecoder struct {
+	started   map[int]bool
+	toolCalls map[int]int
+}
+
+func newGeminiStreamDecoder() *geminiStreamDecoder {
+	return &geminiStreamDecoder{started: make(map[int]bool), toolCalls: make(map[int]int)}
+}
+
+// decode converts one chunk. Usage covers all candidates, so it is reported on the first choice.
+func (d *geminiStreamDecoder) decode(chunk *GeminiResponse) []StreamEvent {
+	var events []StreamEvent
+	if usage := chunk.usage(); usage != nil {
+		events = append(events, StreamEvent{Type: EventUsage, Usage: usage})
+	}
+
+	for _, candidate := range chunk.Candidates {
+		choice := candidate.Index
+		if !d.started[choice] {
+			d.started[choice] = true
+			events = append(events, StreamEvent{Type: EventStart, Choice: choice})
+		}
+
+		var thinking, text string
+		var calls []StreamEvent
+		for _, part := range candidate.Content.Parts {
+			switch {
+			case part.FunctionCall != nil:
+				index := d.toolCalls[choice]
+				d.toolCalls[choice]++
+				call := geminiToolCall(part.FunctionCall, fmt.Sprintf("call_%d_%d", choice, index))
+				calls = append(calls,
+					StreamEvent{Type: EventToolCallStart, Choice: choice, Index: index, ToolCallID: call.ToolCallID, ToolName: call.ToolName},
+					StreamEvent{Type: EventToolCallDelta, Choice: choice, Index: index, Text: call.Arguments})
+			case part.Thought:
+				thinking += part.Text
+			default:
+				text += part.Text
+			}
+		}
+		if thinking != "" {
+			events = append(events, StreamEvent{Type: EventThinking, Choice: choice, Text: thinking})
+		}
+		if text != "" {
+			events = append(events, StreamEvent{Type: EventText, Choice: choice, Text: text})
+		}
+		events = append(events, calls...)
+		if candidate.FinishReason != "" {
+			events = append(events, StreamEvent{Type: EventStop, Choice: choice, StopReason: geminiStopReason(candidate.FinishReason, d.toolCalls[choice] > 0)})
+		}
+	}
+	return events
+}
@@ -0,0 +1,1006 @@
+package adaptor
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/packages/param"
+	"github.com/openai/openai-go/v3/shared"
+)
+
+// openaiDeltaFields are the standard fields of a chunk delta, which are not passed on as extras
+var openaiDeltaFields = map[string]bool{"role": true, "content": true, "refusal": true, "tool_calls": true, "function_call": true}
+
+// DecodeOpenAIRequest converts an OpenAI chat completion request into the canonical form.
+// System and developer messages become the system prompt and tool messages become tool results.
+func DecodeOpenAIRequest(req *openai.ChatCompletionNewParams) *Request {
+	out := &Request{
+		Model:          string(req.Model),
+		Tools:          decodeOpenAITools(req.Tools),
+		ToolChoice:     decodeOpenAIToolChoice(&req.ToolChoice),
+		ThinkingBudget: openAIThinkingBudget(req),
+		MaxTokens:      req.MaxCompletionTokens.Or(req.MaxTokens.Value),
+	}
+
+	for _, msg := range req.Messages {
+		// Union types only expose their fields, extra fields included, through JSON
+		raw, _ := json.Marshal(msg)
+		var m map[string]interface{}
+		if err := json.Unmarshal(raw, &m); err != nil {
+			continue
+		}
+
+		role, _ := m["role"].(string)
+		parts := openAIContentToParts(m["content"])
+		// A message level cache_control applies to its last part
+		messageCache := cacheControlFromValue(m[openaiFieldCacheControl])
+
+		switch role {
+		case "system", "developer":
+			setLastCacheControl(parts, messageCache)
+			out.System = append(out.System, parts...)
+
+		case "user":
+			setLastCacheControl(parts, messageCache)
+			if len(parts) > 0 {
+				out.Messages = append(out.Messages, Message{Role: RoleUser, Parts: parts})
+			}
+
+		case "assistant":
+			// Thinking comes first, as it precedes the answer
+			thinking := thinkingPartsFromReasoning(reasoningFromFields(m))
+			parts = append(thinking, parts...)
+			if toolCalls, ok := m["tool_calls"].([]interface{}); ok {
+				for _, tc := range toolCalls {
+					call, _ := tc.(map[string]interface{})
+					fn, ok := call["function"].(map[string]interface{})
+					if !ok {
+						continue
+					}
+					id, _ := call["id"].(string)
+					name, _ := fn["name"].(string)
+					args, _ := fn["arguments"].(string)
+					parts = append(parts, Part{Type: PartToolCall, ToolCallID: id, ToolName: name, Arguments: args})
+				}
+			}
+			setLastCacheControl(parts, messageCache)
+			if len(parts) > 0 {
+				out.Messages = append(out.Messages, Message{Role: RoleAssistant, Parts: parts})
+			}
+
+		case "tool":
+			toolCallID, _ := m["tool_call_id"].(string)
+			if content, ok := m["content"].(string); ok {
+				parts = []Part{{Type: PartText, Text: content}}
+			}
+			out.Messages = append(out.Messages, Message{Role: RoleUser, Parts: []Part{{
+				Type:         PartToolResult,
+				ToolCallID:   toolCallID,
+				Content:      parts,
+				CacheControl: messageCache,
+			}}})
+		}
+	}
+
+	if schema, ok := StructuredOutputSchema(req); ok {
+		out.ResponseFormat = &ResponseFormat{Type: "json_object", Schema: schema}
+		if rf := req.ResponseFormat.OfJSONSchema; rf != nil {
+			out.ResponseFormat.Type = "json_schema"
+			out.ResponseFormat.Name = rf.JSONSchema.Name
+			out.ResponseFormat.Description = rf.JSONSchema.Description.Value
+		}
+	}
+
+	if req.Temperature.Valid() {
+		out.Temperature = &req.Temperature.Value
+	}
+	if req.TopP.Valid() {
+		out.TopP = &req.TopP.Value
+	}
+	if req.N.Valid() {
+		out.N = &req.N.Value
+	}
+	if req.Seed.Valid() {
+		out.Seed = &req.Seed.Value
+	}
+	if req.PresencePenalty.Valid() {
+		out.PresencePenalty = &req.PresencePenalty.Value
+	}
+	if req.FrequencyPenalty.Valid() {
+		out.FrequencyPenalty = &req.FrequencyPenalty.Value
+	}
+	if req.Stop.OfString.Value != "" {
+		out.Stop = []string{req.Stop.OfString.Value}
+	} else if len(req.Stop.OfStringArray) > 0 {
+		out.Stop = req.Stop.OfStringArray
+	}
+	return out
+}
+
+// setLastCacheControl sets a breakpoint on the last part
+func setLastCacheControl(parts []Part, cc *CacheControl) {
+	if cc != nil && len(parts) > 0 {
+		parts[len(parts)-1].CacheControl = cc
+	}
+}
+
+// openAIContentToParts converts string or content-part message content (as decoded JSON)
+// into text, image and document parts with their cache_control
+func openAIContentToParts(content interface{}) []Part {
+	switch content := content.(type) {
+	case string:
+		if content == "" {
+			return nil
+		}
+		return []Part{{Type: PartText, Text: content}}
+	case []interface{}:
+		var parts []Part
+		for _, item := range content {
+			partMap, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			var part Part
+			switch partMap["type"] {
+			case "image_url":
+				imageURL, _ := partMap["image_url"].(map[string]interface{})
+				url, _ := imageURL["url"].(string)
+				if url == "" {
+					continue
+				}
+				part = imagePartFromURL(url)
+			case "file":
+				file, _ := partMap["file"].(map[string]interface{})
+				part = documentPartFromOpenAIFile(file)
+			default:
+				text, ok := partMap["text"].(string)
+				if !ok {
+					continue
+				}
+				part = Part{Type: PartText, Text: text}
+			}
+			part.CacheControl = cacheControlFromValue(partMap[openaiFieldCacheControl])
+			parts = append(parts, part)
+		}
+		return parts
+	}
+	return nil
+}
+
+// decodeOpenAITools converts OpenAI function tools
+func decodeOpenAITools(tools []openai.ChatCompletionToolUnionParam) []Tool {
+	var out []Tool
+	for _, t := range tools {
+		fn := t.GetFunction()
+		if fn == nil {
+			continue
+		}
+		tool := Tool{
+			Name:         fn.Name,
+			Description:  fn.Description.Value,
+			CacheControl: cacheControlFromValue(t.OfFunction.ExtraFields()[openaiFieldCacheControl]),
+		}
+		if fn.Parameters != nil {
+			if raw, err := json.Marshal(fn.Parameters); err == nil {
+				_ = json.Unmarshal(raw, &tool.Parameters)
+			}
+		}
+		out = append(out, tool)
+	}
+	return out
+}
+
+// decodeOpenAIToolChoice converts an OpenAI tool_choice, returning nil when it is not set.
+// Allowed and custom tool choices leave the choice to the model.
+func decodeOpenAIToolChoice(tc *openai.ChatCompletionToolChoiceOptionUnionParam) *ToolChoice {
+	switch {
+	case tc.OfAuto.Value != "":
+		switch tc.OfAuto.Value {
+		case "required":
+			return &ToolChoice{Mode: ToolChoiceAny}
+		case "none":
+			return &ToolChoice{Mode: ToolChoiceNone}
+		}
+	case tc.OfFunctionToolChoice != nil:
+		if name := tc.OfFunctionToolChoice.Function.Name; name != "" {
+			return &ToolChoice{Mode: ToolChoiceTool, Name: name}
+		}
+	case tc.OfAllowedTools == nil && tc.OfCustomToolChoice == nil:
+		return nil
+	}
+	return &ToolChoice{Mode: ToolChoiceAuto}
+}
+
+// EncodeOpenAIRequest converts a canonical request into an OpenAI chat completion request.
+// It returns the first document conversion error along with the request converted without it.
+func EncodeOpenAIRequest(r *Request, opts ConvertOptions) (*openai.ChatCompletionNewParams, error) {
+	var convErr error
+	out := &openai.ChatCompletionNewParams{
+		Model: openai.ChatModel(r.Model),
+	}
+
+	// Thinking providers take an Anthropic-style thinking field, reasoning models the effort
+	isThinking := r.ThinkingBudget > 0 || r.hasThinking()
+	if isThinking {
+		thinking := map[string]interface{}{
+			"type": "enabled",
+		}
+		if r.ThinkingBudget > 0 {
+			thinking["budget_tokens"] = r.ThinkingBudget
+			out.ReasoningEffort = shared.ReasoningEffort(ReasoningEffortForBudget(r.ThinkingBudget))
+		}
+		out.SetExtraFields(map[string]interface{}{
+			"thinking": thinking,
+		})
+	}
+
+	if r.MaxTokens > 0 {
+		out.MaxTokens = openai.Opt(r.MaxTokens)
+	}
+	encodeOpenAISampling(r, out)
+
+	if len(r.System) > 0 {
+		out.Messages = append(out.Messages, opts.openAISystemMessage(r.System))
+	}
+	for _, msg := range r.Messages {
+		switch msg.Role {
+		case RoleUser:
+			// Tool results become separate tool messages
+			messages, err := opts.openAIUserMessages(msg)
+			if err != nil && convErr == nil {
+				convErr = err
+			}
+			out.Messages = append(out.Messages, messages...)
+		case RoleAssistant:
+			out.Messages = append(out.Messages, openAIAssistantMessage(msg, isThinking))
+		}
+	}
+
+	if len(r.Tools) > 0 {
+		out.Tools = opts.encodeOpenAITools(r.Tools)
+	}
+	if r.ToolChoice != nil {
+		out.ToolChoice = encodeOpenAIToolChoice(r.ToolChoice)
+	}
+
+	switch {
+	case r.ResponseFormat == nil:
+	case r.ResponseFormat.Type == "json_schema":
+		schema := shared.ResponseFormatJSONSchemaJSONSchemaParam{Name: r.ResponseFormat.Name, Schema: r.ResponseFormat.Schema}
+		if r.ResponseFormat.Description != "" {
+			schema.Description = openai.String(r.ResponseFormat.Description)
+		}
+		out.ResponseFormat.OfJSONSchema = &shared.ResponseFormatJSONSchemaParam{JSONSchema: schema}
+	default:
+		out.ResponseFormat.OfJSONObject = &shared.ResponseFormatJSONObjectParam{}
+	}
+
+	return out, convErr
+}
+
+// encodeOpenAISampling sets the sampling parameters of the request, all of which OpenAI takes
+func encodeOpenAISampling(r *Request, out *openai.ChatCompletionNewParams) {
+	if r.Temperature != nil {
+		out.Temperature = openai.Float(*r.Temperature)
+	}
+	if r.TopP != nil {
+		out.TopP = openai.Float(*r.TopP)
+	}
+	if len(r.Stop) > 0 {
+		out.Stop.OfStringArray = r.Stop
+	}
+	if r.N != nil {
+		out.N = openai.Int(*r.N)
+	}
+	if r.Seed != nil {
+		out.Seed = openai.Int(*r.Seed)
+	}
+	if r.PresencePenalty != nil {
+		out.PresencePenalty = openai.Float(*r.PresencePenalty)
+	}
+	if r.FrequencyPenalty != nil {
+		out.FrequencyPenalty = openai.Float(*r.FrequencyPenalty)
+	}
+}
+
+// openAISystemMessage joins the system prompt into one system message. Breakpoints being
+// forwarded keep the parts apart, so that each one stays where the client put it.
+func (o ConvertOptions) openAISystemMessage(system []Part) openai.ChatCompletionMessageParamUnion {
+	var text strings.Builder
+	cached := false
+	for _, part := range system {
+		text.WriteString(part.Text)
+		cached = cached || part.CacheControl != nil
+	}
+	if !o.ForwardCacheControl || !cached {
+		return openai.SystemMessage(text.String())
+	}
+
+	parts := make([]openai.ChatCompletionContentPartTextParam, 0, len(system))
+	for _, part := range system {
+		textPart := openai.ChatCompletionContentPartTextParam{Text: part.Text}
+		if part.CacheControl != nil {
+			textPart.SetExtraFields(map[string]any{openaiFieldCacheControl: cacheControlToValue(part.CacheControl)})
+		}
+		parts = append(parts, textPart)
+	}
+	return openai.SystemMessage(parts)
+}
+
+// openAIUserMessages converts a user message, whose tool results become tool messages ahead of
+// the rest of its content
+func (o ConvertOptions) openAIUserMessages(msg Message) ([]openai.ChatCompletionMessageParamUnion, error) {
+	var result []openai.ChatCompletionMessageParamUnion
+	var parts []openai.ChatCompletionContentPartUnionParam
+	var textContent string
+	var multimodal bool
+	var convErr error
+	setErr := func(err error) {
+		if convErr == nil {
+			convErr = err
+		}
+	}
+
+	for _, part := range msg.Parts {
+		switch part.Type {
+		case PartText:
+			textContent += part.Text
+			textPart := openai.TextContentPart(part.Text)
+			if o.ForwardCacheControl && part.CacheControl != nil {
+				// Breakpoints need content parts to attach to
+				textPart.OfText.SetExtraFields(map[string]any{openaiFieldCacheControl: cacheControlToValue(part.CacheControl)})
+				multimodal = true
+			}
+			parts = append(parts, textPart)
+
+		case PartImage:
+			if imagePart, ok := openAIImagePart(part); ok {
+				if o.ForwardCacheControl && part.CacheControl != nil {
+					imagePart.OfImageURL.SetExtraFields(map[string]any{openaiFieldCacheControl: cacheControlToValue(part.CacheControl)})
+				}
+				parts = append(parts, imagePart)
+				multimodal = true
+			}
+
+		case PartDocument:
+			docParts, err := o.documentToOpenAIParts(part)
+			if err != nil {
+				setErr(err)
+				continue
+			}
+			parts = append(parts, docParts...)
+			multimodal = true
+
+		case PartToolResult:
+			content, err := o.toolResultText(part.Content)
+			if err != nil {
+				setErr(err)
+			}
+			toolMsg := openai.ToolMessage(content, part.ToolCallID)
+			if cc := toolResultCacheControl(part); o.ForwardCacheControl && cc != nil {
+				textPart := openai.ChatCompletionContentPartTextParam{Text: content}
+				textPart.SetExtraFields(map[string]any{openaiFieldCacheControl: cacheControlToValue(cc)})
+				toolMsg = openai.ToolMessage([]openai.ChatCompletionContentPartTextParam{textPart}, part.ToolCallID)
+			}
+			result = append(result, toolMsg)
+
+			// Tool messages only carry text, so images in the result follow in a user message
+			for _, c := range part.Content {
+				if c.Type != PartImage {
+					continue
+				}
+				if imagePart, ok := openAIImagePart(c); ok {
+					parts = append(parts, imagePart)
+					multimodal = true
+				}
+			}
+		}
+	}
+
+	// Text-only content stays a plain string; images and documents need content parts
+	if multimodal {
+		result = append(result, openai.UserMessage(parts))
+	} else if textContent != "" {
+		result = append(result, openai.UserMessage(textContent))
+	}
+	return result, convErr
+}
+
+// toolResultText renders tool result content as text; documents are rendered as text since tool
+// messages only carry text
+func (o ConvertOptions) toolResultText(content []Part) (string, error) {
+	var result strings.Builder
+	var convErr error
+	for _, c := range content {
+		switch c.Type {
+		case PartText:
+			result.WriteString(c.Text)
+		case PartDocument:
+			text, err := o.documentToText(c)
+			if err != nil {
+				if convErr == nil {
+					convErr = err
+				}
+				continue
+			}
+			if result.Len() > 0 {
+				result.WriteString("\n\n")
+			}
+			result.WriteString(text)
+		}
+	}
+	return result.String(), convErr
+}
+
+// openAIAssistantMessage converts an assistant message. Thinking becomes reasoning_content, and
+// signed or redacted thinking is also kept in reasoning_details so it survives the round trip.
+// With thinking on, reasoning_content is set on every assistant turn as thinking providers expect.
+func openAIAssistantMessage(msg Message, isThinking bool) openai.ChatCompletionMessageParamUnion {
+	var textContent string
+	var toolCalls []map[string]interface{}
+	for _, part := range msg.Parts {
+		switch part.Type {
+		case PartText:
+			textContent += part.Text
+		case PartToolCall:
+			toolCalls = append(toolCalls, map[string]interface{}{
+				"id":   part.ToolCallID,
+				"type": "function",
+				"function": map[string]interface{}{
+					"name":      part.ToolName,
+					"arguments": part.Arguments,
+				},
+			})
+		}
+	}
+	thinking, reasoningDetails := reasoningFromThinking(reasoningDetailsFromParts(msg.Parts))
+
+	extra := map[string]any{}
+	if thinking != "" || isThinking {
+		extra[openaiFieldReasoningContent] = thinking
+	}
+	if len(reasoningDetails) > 0 {
+		extra[openaiFieldReasoningDetails] = reasoningDetails
+	}
+
+	if len(toolCalls) == 0 {
+		result := openai.AssistantMessage(textContent)
+		if len(extra) > 0 {
+			result.OfAssistant.SetExtraFields(extra)
+		}
+		return result
+	}
+
+	// Tool calls are built through JSON, the SDK having no constructor for them
+	msgBytes, _ := json.Marshal(map[string]interface{}{
+		"role":       "assistant",
+		"content":    textContent,
+		"tool_calls": toolCalls,
+	})
+	var result openai.ChatCompletionMessageParamUnion
+	_ = json.Unmarshal(msgBytes, &result)
+	// Extra fields only marshal from the variant, not from the union
+	if result.OfAssistant != nil && len(extra) > 0 {
+		result.OfAssistant.SetExtraFields(extra)
+	}
+	return result
+}
+
+// encodeOpenAITools converts tools to OpenAI function tools, sanitizing their schemas with
+// o.ToolSchema
+func (o ConvertOptions) encodeOpenAITools(tools []Tool) []openai.ChatCompletionToolUnionParam {
+	out := make([]openai.ChatCompletionToolUnionParam, 0, len(tools))
+	for _, tool := range tools {
+		parameters, changes := SanitizeSchema(tool.Parameters, o.ToolSchema)
+		logSchemaChanges(tool.Name, changes)
+
+		fn := openai.ChatCompletionFunctionTool(shared.FunctionDefinitionParam{
+			Name:        tool.Name,
+			Description: param.Opt[string]{Value: sanitizeToolDescription(tool.Name, tool.Description, o.ToolSchema)},
+			Parameters:  parameters,
+		})
+		if o.ForwardCacheControl && tool.CacheControl != nil {
+			fn.OfFunction.SetExtraFields(map[string]any{openaiFieldCacheControl: cacheControlToValue(tool.CacheControl)})
+		}
+		out = append(out, fn)
+	}
+	return out
+}
+
+// encodeOpenAIToolChoice converts a tool choice to OpenAI tool_choice
+func encodeOpenAIToolChoice(tc *ToolChoice) openai.ChatCompletionToolChoiceOptionUnionParam {
+	switch tc.Mode {
+	case ToolChoiceTool:
+		return openai.ToolChoiceOptionFunctionToolChoice(openai.ChatCompletionNamedToolChoiceFunctionParam{Name: tc.Name})
+	case ToolChoiceAny:
+		// Both make the model call at least one of the tools
+		return openai.ChatCompletionToolChoiceOptionUnionParam{OfAuto: openai.Opt("required")}
+	case ToolChoiceNone:
+		return openai.ChatCompletionToolChoiceOptionUnionParam{OfAuto: openai.Opt("none")}
+	default:
+		return openai.ChatCompletionToolChoiceOptionUnionParam{OfAuto: openai.Opt("auto")}
+	}
+}
+
+// DecodeOpenAIResponse converts an OpenAI chat completion into the canonical form. Tool call
+// arguments are repaired into JSON objects.
+func DecodeOpenAIResponse(resp *openai.ChatCompletion) *Response {
+	usage := usageFromOpenAI(resp.Usage)
+	out := &Response{ID: resp.ID, Model: resp.Model, Usage: &usage}
+	for _, choice := range resp.Choices {
+		// Reasoning arrives in fields unknown to the SDK
+		fields := make(map[string]interface{})
+		for key, field := range choice.Message.JSON.ExtraFields {
+			var value interface{}
+			if isReasoningField(key) && json.Unmarshal([]byte(field.Raw()), &value) == nil {
+				fields[key] = value
+			}
+		}
+		parts := thinkingPartsFromReasoning(reasoningFromFields(fields))
+
+		if choice.Message.Content != "" {
+			parts = append(parts, Part{Type: PartText, Text: choice.Message.Content})
+		}
+		if choice.Message.Refusal != "" {
+			parts = append(parts, Part{Type: PartText, Text: choice.Message.Refusal})
+		}
+		for _, toolCall := range choice.Message.ToolCalls {
+			args, repair := RepairToolArguments(toolCall.Function.Arguments)
+			if repair != "" {
+				countToolCallRepair(repair, toolCall.Function.Name)
+			}
+			parts = append(parts, Part{Type: PartToolCall, ToolCallID: toolCall.ID, ToolName: toolCall.Function.Name, Arguments: args})
+		}
+		out.Choices = append(out.Choices, Choice{Parts: parts, StopReason: stopReasonFromOpenAI(string(choice.FinishReason))})
+	}
+	return out
+}
+
+// EncodeOpenAIResponse converts a canonical response into an OpenAI chat completion. The input
+// of the synthetic structured output tool becomes the message content.
+func EncodeOpenAIResponse(r *Response, responseModel string) map[string]interface{} {
+	choices := make([]map[string]interface{}, 0, len(r.Choices))
+	for i, choice := range r.Choices {
+		message := map[string]interface{}{"role": "assistant"}
+		var textContent string
+		var annotations []map[string]interface{}
+		var toolCalls []map[string]interface{}
+		structuredOutput := ""
+
+		for _, part := range choice.Parts {
+			switch part.Type {
+			case PartText:
+				// Citations annotate the span of the text part they are attached to
+				start := utf8.RuneCountInString(textContent)
+				textContent += part.Text
+				end := utf8.RuneCountInString(textContent)
+				for _, c := range part.Citations {
+					annotations = append(annotations, citationAnnotation(c, start, end))
+				}
+			case PartToolCall:
+				if part.ToolName == StructuredOutputToolName {
+					structuredOutput = part.Arguments
+					continue
+				}
+				toolCalls = append(toolCalls, map[string]interface{}{
+					"id":   part.ToolCallID,
+					"type": "function",
+					"function": map[string]interface{}{
+						"name":      part.ToolName,
+						"arguments": part.Arguments,
+					},
+				})
+			}
+		}
+
+		switch {
+		case structuredOutput != "":
+			message["content"] = structuredOutput
+		case textContent != "":
+			message["content"] = textContent
+			if len(annotations) > 0 {
+				message["annotations"] = annotations
+			}
+		default:
+			message["content"] = nil
+		}
+		if len(toolCalls) > 0 {
+			message["tool_calls"] = toolCalls
+		}
+		thinking, reasoningDetails := reasoningFromThinking(reasoningDetailsFromParts(choice.Parts))
+		if thinking != "" {
+			message[openaiFieldReasoningContent] = thinking
+		}
+		if len(reasoningDetails) > 0 {
+			message[openaiFieldReasoningDetails] = reasoningDetails
+		}
+
+		choices = append(choices, map[string]interface{}{
+			"index":         i,
+			"message":       message,
+			"finish_reason": openAIFinishReason(choice.StopReason, len(toolCalls) > 0),
+		})
+	}
+
+	id := r.ID
+	if id == "" {
+		id = fmt.Sprintf("chatcmpl-%d", time.Now().UnixNano())
+	}
+	response := map[string]interface{}{
+		"id":      id,
+		"object":  "chat.completion",
+		"created": time.Now().Unix(),
+		"model":   responseModel,
+		"choices": choices,
+	}
+	if r.Usage != nil {
+		response["usage"] = openAIUsage(*r.Usage)
+	}
+	return response
+}
+
+// stopReasonFromOpenAI maps an OpenAI finish_reason
+func stopReasonFromOpenAI(finishReason string) StopReason {
+	switch finishReason {
+	case "length":
+		return StopMaxTokens
+	case openaiFinishReasonToolCalls, "function_call":
+		return StopToolUse
+	case "content_filter":
+		return StopContentFilter
+	default:
+		return StopEndTurn
+	}
+}
+
+// openAIFinishReason maps a stop reason to an OpenAI finish_reason; tool_calls needs tool calls
+// to go with it
+func openAIFinishReason(reason StopReason, toolCalls bool) string {
+	switch reason {
+	case StopToolUse:
+		if toolCalls {
+			return openaiFinishReasonToolCalls
+		}
+	case StopMaxTokens:
+		return "length"
+	case StopContentFilter:
+		return "content_filter"
+	}
+	return "stop"
+}
+
+// openAIStreamCall is a tool call seen in an OpenAI stream
+type openAIStreamCall struct {
+	upstreamID string
+	name       string
+}
+
+// openAIStreamDecoder converts OpenAI chunks into stream events. Only the first choice is
+// decoded, and nothing after its finish_reason but the usage. Tool calls split over several
+// indexes are joined and missing or duplicated IDs are replaced.
+type openAIStreamDecoder struct {
+	started   bool
+	stopped   bool
+	toolIndex map[int64]int
+	calls     []openAIStreamCall
+	toolIDs   map[string]bool
+}
+
+func newOpenAIStreamDecoder() *openAIStreamDecoder {
+	return &openAIStreamDecoder{
+		toolIndex: make(map[int64]int),
+		toolIDs:   make(map[string]bool),
+	}
+}
+
+// decode converts one chunk
+func (d *openAIStreamDecoder) decode(chunk openai.ChatCompletionChunk) []StreamEvent {
+	var events []StreamEvent
+	if !d.started {
+		d.started = true
+		events = append(events, StreamEvent{Type: EventStart})
+	}
+
+	// Usage comes with the last choice chunk or, with include_usage, in a chunk of its own
+	if chunk.Usage.PromptTokens > 0 || chunk.Usage.CompletionTokens > 0 {
+		usage := usageFromOpenAI(chunk.Usage)
+		events = append(events, StreamEvent{Type: EventUsage, Usage: &usage})
+	}
+	if len(chunk.Choices) == 0 || d.stopped {
+		return events
+	}
+
+	choice := chunk.Choices[0]
+	delta := choice.Delta
+	fields := parseRawJSON(delta.RawJSON())
+
+	// Reasoning, with the signature and redacted thinking carried in reasoning_details
+	thinkingText, details := reasoningFromFields(fields)
+	detailText := ""
+	for _, detail := range details {
+		switch detail.Type {
+		case ReasoningDetailText:
+			detailText += detail.Text
+			if detail.Signature != "" {
+				events = append(events, StreamEvent{Type: EventThinkingSignature, Signature: detail.Signature})
+			}
+		case ReasoningDetailEncrypted:
+			if detail.Data != "" {
+				events = append(events, StreamEvent{Type: EventRedactedThinking, Data: detail.Data})
+			}
+		}
+	}
+	// Providers sending both fields repeat the text in reasoning_details
+	if thinkingText == "" {
+		thinkingText = detailText
+	}
+	if thinkingText != "" {
+		events = append(events, StreamEvent{Type: EventThinking, Text: thinkingText})
+	}
+
+	// Other non-standard fields are passed on
+	var extra map[string]interface{}
+	for key, value := range fields {
+		if isReasoningField(key) || openaiDeltaFields[key] {
+			continue
+		}
+		if extra == nil {
+			extra = make(map[string]interface{})
+		}
+		extra[key] = value
+	}
+
+	// A refusal is sent as text; chunks without text still send an empty one, keeping the
+	// client informed
+	text := delta.Refusal + delta.Content
+	if text != "" || choice.FinishReason == "" {
+		events = append(events, StreamEvent{Type: EventText, Text: text, Extra: extra})
+		extra = nil
+	}
+
+	for _, toolCall := range delta.ToolCalls {
+		index, start := d.toolCall(toolCall)
+		if start != nil {
+			events = append(events, *start)
+		}
+		if toolCall.Function.Arguments != "" {
+			events = append(events, StreamEvent{Type: EventToolCallDelta, Index: index, Text: toolCall.Function.Arguments})
+		}
+	}
+
+	if choice.FinishReason != "" {
+		d.stopped = true
+		events = append(events, StreamEvent{Type: EventStop, StopReason: stopReasonFromOpenAI(choice.FinishReason), Extra: extra})
+	}
+	return events
+}
+
+// toolCall returns the index of the call a tool call delta belongs to, and the start event of a
+// new call. Arguments arriving under a new index without an ID or name continue the previous call.
+func (d *openAIStreamDecoder) toolCall(toolCall openai.ChatCompletionChunkChoiceDeltaToolCall) (int, *StreamEvent) {
+	name := toolCall.Function.Name
+	if index, exists := d.toolIndex[toolCall.Index]; exists {
+		// Providers reusing one index for every call announce the next call with a new ID and name
+		if toolCall.ID == "" || toolCall.ID == d.calls[index].upstreamID || name == "" {
+			return index, nil
+		}
+	} else if toolCall.ID == "" && name == "" && len(d.calls) > 0 {
+		last := len(d.calls) - 1
+		countToolCallRepair(RepairSplitArguments, d.calls[last].name)
+		d.toolIndex[toolCall.Index] = last
+		return last, nil
+	}
+
+	id := toolCall.ID
+	switch {
+	case id == "":
+		countToolCallRepair(RepairMissingID, name)
+		id = newToolUseID()
+	case d.toolIDs[id]:
+		countToolCallRepair(RepairDuplicateID, name)
+		id = newToolUseID()
+	}
+	d.toolIDs[id] = true
+
+	index := len(d.calls)
+	d.calls = append(d.calls, openAIStreamCall{upstreamID: toolCall.ID, name: name})
+	d.toolIndex[toolCall.Index] = index
+	return index, &StreamEvent{Type: EventToolCallStart, Index: index, ToolCallID: id, ToolName: name}
+}
+
+// openAIChoiceState tracks one choice of an OpenAI stream being encoded
+type openAIChoiceState struct {
+	// toolIndex maps event indexes to tool_calls indexes
+	toolIndex map[int]int
+	// structuredIndex is the index of the synthetic structured output tool, whose input streams as content
+	structuredIndex int
+	usage           *Usage
+}
+
+// openAIStreamEncoder converts stream events into OpenAI chat completion chunks
+type openAIStreamEncoder struct {
+	chatID  string
+	created int64
+	model   string
+	choices map[int]*openAIChoiceState
+}
+
+func newOpenAIStreamEncoder(chatID string, created int64, responseModel string) *openAIStreamEncoder {
+	return &openAIStreamEncoder{
+		chatID:  chatID,
+		created: created,
+		model:   responseModel,
+		choices: make(map[int]*openAIChoiceState),
+	}
+}
+
+// choice returns the state of a choice
+func (e *openAIStreamEncoder) choice(index int) *openAIChoiceState {
+	state, ok := e.choices[index]
+	if !ok {
+		state = &openAIChoiceState{toolIndex: make(map[int]int), structuredIndex: -1}
+		e.choices[index] = state
+	}
+	return state
+}
+
+// encode converts the events decoded from one upstream chunk into a chunk per choice
+func (e *openAIStreamEncoder) encode(events []StreamEvent) []map[string]interface{} {
+	var order []int
+	byChoice := make(map[int][]StreamEvent)
+	for _, ev := range events {
+		if _, ok := byChoice[ev.Choice]; !ok {
+			order = append(order, ev.Choice)
+		}
+		byChoice[ev.Choice] = append(byChoice[ev.Choice], ev)
+	}
+
+	var chunks []map[string]interface{}
+	for _, index := range order {
+		if chunk := e.encodeChoice(index, byChoice[index]); chunk != nil {
+			chunks = append(chunks, chunk)
+		}
+	}
+	return chunks
+}
+
+// encodeChoice merges the events of one choice into a single delta
+func (e *openAIStreamEncoder) encodeChoice(index int, events []StreamEvent) map[string]interface{} {
+	state := e.choice(index)
+	delta := map[string]interface{}{}
+	var content, reasoning string
+	var details []ReasoningDetail
+	var toolCalls []map[string]interface{}
+	var annotations []map[string]interface{}
+	var finishReason interface{}
+
+	for _, ev := range events {
+		switch ev.Type {
+		case EventStart:
+			delta["role"] = "assistant"
+			if ev.Usage != nil {
+				state.usage = ev.Usage
+			}
+		case EventText:
+			content += ev.Text
+		case EventThinking:
+			reasoning += ev.Text
+		case EventThinkingSignature:
+			// The signature closes a thinking block and must be sent back with it on the next turn
+			details = append(details, ReasoningDetail{Type: ReasoningDetailText, Signature: ev.Signature, Format: reasoningDetailFormat, Index: ev.Index})
+		case EventRedactedThinking:
+			details = append(details, ReasoningDetail{Type: ReasoningDetailEncrypted, Data: ev.Data, Format: reasoningDetailFormat, Index: ev.Index})
+		case EventToolCallStart:
+			if ev.ToolName == StructuredOutputToolName {
+				state.structuredIndex = ev.Index
+				continue
+			}
+			state.toolIndex[ev.Index] = len(state.toolIndex)
+			toolCalls = append(toolCalls, map[string]interface{}{
+				"index": state.toolIndex[ev.Index],
+				"id":    ev.ToolCallID,
+				"type":  "function",
+				"function": map[string]interface{}{
+					"name":      ev.ToolName,
+					"arguments": "",
+				},
+			})
+		case EventToolCallDelta:
+			if ev.Index == state.structuredIndex {
+				content += ev.Text
+				continue
+			}
+			toolIndex, ok := state.toolIndex[ev.Index]
+			if !ok {
+				continue
+			}
+			// Arguments join the start of their call when it is in the same chunk
+			merged := false
+			for _, call := range toolCalls {
+				if call["index"] == toolIndex {
+					fn := call["function"].(map[string]interface{})
+					fn["arguments"] = fn["arguments"].(string) + ev.Text
+					merged = true
+				}
+			}
+			if !merged {
+				toolCalls = append(toolCalls, map[string]interface{}{
+					"index":    toolIndex,
+					"function": map[string]interface{}{"arguments": ev.Text},
+				})
+			}
+		case EventCitations:
+			for _, c := range ev.Citations {
+				annotations = append(annotations, citationAnnotation(c, ev.SpanStart, ev.SpanEnd))
+			}
+		case EventUsage:
+			state.usage = ev.Usage
+		case EventStop:
+			finishReason = openAIFinishReason(ev.StopReason, len(state.toolIndex) > 0)
+		}
+	}
+
+	if content != "" {
+		delta["content"] = content
+	}
+	if reasoning != "" {
+		delta[openaiFieldReasoningContent] = reasoning
+	}
+	if len(details) > 0 {
+		delta[openaiFieldReasoningDetails] = details
+	}
+	if len(toolCalls) > 0 {
+		delta["tool_calls"] = toolCalls
+	}
+	if len(annotations) > 0 {
+		delta["annotations"] = annotations
+	}
+	if len(delta) == 0 && finishReason == nil {
+		return nil
+	}
+	return map[string]interface{}{
+		"id":      e.chatID,
+		"object":  "chat.completion.chunk",
+		"created": e.created,
+		"model":   e.model,
+		"choices": []map[string]interface{}{
+			{
+				"index":         index,
+				"delta":         delta,
+				"finish_reason": finishReason,
+			},
+		},
+	}
+}
+
+// usageChunk builds the final chunk of a stream, which has no choices and carries the usage
+// summed over all choices; it is nil when the upstream reported no usage
+func (e *openAIStreamEncoder) usageChunk() map[string]interface{} {
+	var total Usage
+	reported := false
+	for _, state := range e.choices {
+		if state.usage == nil {
+			continue
+		}
+		reported = true
+		total.InputTokens += state.usage.InputTokens
+		total.OutputTokens += state.usage.OutputTokens
+		total.CacheReadTokens += state.usage.CacheReadTokens
+		total.CacheCreationTokens += state.usage.CacheCreationTokens
+	}
+	if !reported {
+		return nil
+	}
+	return map[string]interface{}{
+		"id":      e.chatID,
+		"object":  "chat.completion.chunk",
+		"created": e.created,
+		"model":   e.model,
+		"choices": []map[string]interface{}{},
+		"usage":   openAIUsage(total),
+	}
+}
@@ -0,0 +1,167 @@
+package adaptor
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeOpenAIRequest(t *testing.T) {
+	var req openai.ChatCompletionNewParams
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"model": "gpt-4o",
+		"max_completion_tokens": 300,
+		"messages": [
+			{"role": "system", "content": "Be brief."},
+			{"role": "developer", "content": "Use metric units."},
+			{"role": "user", "content": "Weather?"},
+			{"role": "assistant", "content": "", "tool_calls": [{"id": "call_1", "type": "function", "function": {"name": "get_weather", "arguments": "{\"city\":\"Paris\"}"}}]},
+			{"role": "tool", "tool_call_id": "call_1", "content": "sunny"}
+		],
+		"tools": [{"type": "function", "function": {"name": "get_weather"}}],
+		"tool_choice": "required"
+	}`), &req))
+
+	r := DecodeOpenAIRequest(&req)
+	assert.Equal(t, int64(300), r.MaxTokens)
+	require.Len(t, r.System, 2)
+	assert.Equal(t, "Use metric units.", r.System[1].Text)
+	require.Len(t, r.Messages, 3)
+	assert.Equal(t, RoleAssistant, r.Messages[1].Role)
+	assert.Equal(t, []Part{{Type: PartToolCall, ToolCallID: "call_1", ToolName: "get_weather", Arguments: `{"city":"Paris"}`}}, r.Messages[1].Parts)
+	assert.Equal(t, PartToolResult, r.Messages[2].Parts[0].Type)
+	require.Len(t, r.Tools, 1)
+	assert.Equal(t, &ToolChoice{Mode: ToolChoiceAny}, r.ToolChoice)
+}
+
+func TestToolChoiceMapping(t *testing.T) {
+	tests := []struct {
+		name      string
+		choice    *ToolChoice
+		openai    string
+		anthropic string
+	}{
+		{"auto", &ToolChoice{Mode: ToolChoiceAuto}, `"auto"`, `{"type":"auto"}`},
+		{"any", &ToolChoice{Mode: ToolChoiceAny}, `"required"`, `{"type":"any"}`},
+		{"none", &ToolChoice{Mode: ToolChoiceNone}, `"none"`, `{"type":"none"}`},
+		{"tool", &ToolChoice{Mode: ToolChoiceTool, Name: "f"}, `{"function":{"name":"f"},"type":"function"}`, `{"name":"f","type":"tool"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			openaiChoice := encodeOpenAIToolChoice(tt.choice)
+			raw, err := json.Marshal(openaiChoice)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.openai, string(raw))
+			assert.Equal(t, tt.choice, decodeOpenAIToolChoice(&openaiChoice))
+
+			anthropicChoice := encodeAnthropicToolChoice(tt.choice)
+			raw, err = json.Marshal(anthropicChoice)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.anthropic, string(raw))
+			assert.Equal(t, tt.choice, decodeAnthropicToolChoice(&anthropicChoice))
+		})
+	}
+}
+
+func TestOpenAIToAnthropicRequestThroughIR(t *testing.T) {
+	var req openai.ChatCompletionNewParams
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"model": "claude",
+		"messages": [
+			{"role": "user", "content": "Hi"},
+			{"role": "assistant", "content": "Calling", "tool_calls": [{"id": "call_1", "type": "function", "function": {"name": "f", "arguments": "{}"}}]},
+			{"role": "tool", "tool_call_id": "call_1", "content": "done"}
+		]
+	}`), &req))
+
+	params, err := EncodeAnthropicRequest(DecodeOpenAIRequest(&req), 1024, ConvertOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1024), params.MaxTokens)
+	require.Len(t, params.Messages, 3)
+	require.Len(t, params.Messages[1].Content, 2)
+	assert.Equal(t, "Calling", params.Messages[1].Content[0].OfText.Text)
+	assert.Equal(t, "call_1", params.Messages[1].Content[1].OfToolUse.ID)
+	assert.Equal(t, "call_1", params.Messages[2].Content[0].OfToolResult.ToolUseID)
+}
+
+func TestSamplingParameters(t *testing.T) {
+	temperature, topP, seed := 0.5, 0.9, int64(7)
+	r := &Request{
+		Model:       "model",
+		Messages:    []Message{{Role: RoleUser, Parts: []Part{{Type: PartText, Text: "hi"}}}},
+		Temperature: &temperature,
+		TopP:        &topP,
+		Stop:        []string{"END"},
+		Seed:        &seed,
+	}
+
+	openAIReq, err := EncodeOpenAIRequest(r, ConvertOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 0.5, openAIReq.Temperature.Value)
+	assert.Equal(t, 0.9, openAIReq.TopP.Value)
+	assert.Equal(t, []string{"END"}, openAIReq.Stop.OfStringArray)
+	assert.Equal(t, int64(7), openAIReq.Seed.Value)
+
+	anthropicReq, err := EncodeAnthropicRequest(r, 1024, ConvertOptions{})
+	require.NoError(t, err)
+	assert.False(t, anthropicReq.Temperature.Valid())
+	assert.Equal(t, 0.9, anthropicReq.TopP.Value)
+	assert.Equal(t, []string{"END"}, anthropicReq.StopSequences)
+
+	// Thinking leaves top_p to its default
+	r.ThinkingBudget = 2048
+	anthropicReq, err = EncodeAnthropicRequest(r, 4096, ConvertOptions{})
+	require.NoError(t, err)
+	assert.False(t, anthropicReq.TopP.Valid())
+}
+
+func TestAnthropicStreamToOpenAIChunks(t *testing.T) {
+	events := []string{
+		`{"type":"message_start","message":{"id":"msg_1","usage":{"input_tokens":10}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"toolu_1","name":"lookup","input":{}}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{\"q\":1}"}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":5}}`,
+		`{"type":"message_stop"}`,
+	}
+
+	decoder := newAnthropicStreamDecoder(0)
+	encoder := newOpenAIStreamEncoder("chatcmpl-1", 1, "gpt-4o")
+	var chunks []map[string]interface{}
+	for _, raw := range events {
+		var event anthropic.MessageStreamEventUnion
+		require.NoError(t, json.Unmarshal([]byte(raw), &event))
+		chunks = append(chunks, encoder.encode(decoder.decode(event))...)
+	}
+
+	require.Len(t, chunks, 4)
+	delta := func(i int) map[string]interface{} {
+		return chunks[i]["choices"].([]map[string]interface{})[0]["delta"].(map[string]interface{})
+	}
+	assert.Equal(t, "assistant", delta(0)["role"])
+	assert.Equal(t, "toolu_1", delta(1)["tool_calls"].([]map[string]interface{})[0]["id"])
+	assert.Equal(t, "tool_calls", chunks[3]["choices"].([]map[string]interface{})[0]["finish_reason"])
+
+	usage := encoder.usageChunk()["usage"].(map[string]interface{})
+	assert.EqualValues(t, 10, usage["prompt_tokens"])
+	assert.EqualValues(t, 5, usage["completion_tokens"])
+}
+
+func TestAnthropicStreamEncoderStartUsage(t *testing.T) {
+	encoder := newAnthropicStreamEncoder("msg_1", "claude", 42)
+	events := encoder.encode([]StreamEvent{{
+		Type:  EventStart,
+		Usage: &Usage{InputTokens: 10, CacheReadTokens: 100, CacheCreationTokens: 20},
+	}})
+
+	require.Len(t, events, 1)
+	assert.Equal(t, eventTypeMessageStart, events[0].Event)
+	usage := events[0].Data["message"].(map[string]interface{})["usage"].(map[string]interface{})
+	assert.EqualValues(t, 10, usage["input_tokens"])
+	assert.EqualValues(t, 100, usage["cache_read_input_tokens"])
+	assert.EqualValues(t, 20, usage["cache_creation_input_tokens"])
+}
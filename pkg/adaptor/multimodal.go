@@ -0,0 +1,167 @@
+package adaptor
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/openai/openai-go/v3"
+)
+
+// MaxInlineImageBytes caps the size of a remote image downloaded for inlining
+const MaxInlineImageBytes = 20 << 20
+
+// imagePartFromURL converts an image URL, turning data URLs into inline data
+func imagePartFromURL(url string) Part {
+	if mediaType, data, ok := parseDataURL(url); ok {
+		return Part{Type: PartImage, MediaType: mediaType, Data: data}
+	}
+	return Part{Type: PartImage, URL: url}
+}
+
+// anthropicImageToPart converts an Anthropic image block, reporting whether it has a source
+func anthropicImageToPart(img *anthropic.ImageBlockParam) (Part, bool) {
+	part := Part{Type: PartImage, CacheControl: cacheControlFromAnthropic(img.CacheControl)}
+	switch {
+	case img.Source.OfBase64 != nil:
+		part.MediaType, part.Data = string(img.Source.OfBase64.MediaType), img.Source.OfBase64.Data
+	case img.Source.OfURL != nil:
+		part.URL = img.Source.OfURL.URL
+	default:
+		return part, false
+	}
+	return part, true
+}
+
+// openAIImagePart converts an image into an OpenAI image_url part, turning inline data into a data URL
+func openAIImagePart(image Part) (openai.ChatCompletionContentPartUnionParam, bool) {
+	url := image.URL
+	if image.Data != "" {
+		url = "data:" + image.MediaType + ";base64," + image.Data
+	}
+	if url == "" {
+		return openai.ChatCompletionContentPartUnionParam{}, false
+	}
+	return openai.ImageContentPart(openai.ChatCompletionContentPartImageImageURLParam{URL: url}), true
+}
+
+// anthropicImage converts an image into an Anthropic image block, turning inline data into a base64 source
+func anthropicImage(image Part) (*anthropic.ImageBlockParam, bool) {
+	switch {
+	case image.Data != "":
+		return anthropic.NewImageBlockBase64(image.MediaType, image.Data).OfImage, true
+	case image.URL != "":
+		return anthropic.NewImageBlock(anthropic.URLImageSourceParam{URL: image.URL}).OfImage, true
+	}
+	return nil, false
+}
+
+// InlineAnthropicImages downloads URL image sources, including those inside tool results,
+// and replaces them with base64 sources for providers that do not fetch remote images
+func InlineAnthropicImages(ctx context.Context, req *anthropic.MessageNewParams, httpClient *http.Client) error {
+	cache := make(map[string]*anthropic.Base64ImageSourceParam)
+	inline := func(img *anthropic.ImageBlockParam) error {
+		if img == nil || img.Source.OfURL == nil {
+			return nil
+		}
+		url := img.Source.OfURL.URL
+		source, ok := cache[url]
+		if !ok {
+			mediaType, data, err := FetchImageAsBase64(ctx, httpClient, url)
+			if err != nil {
+				return err
+			}
+			source = &anthropic.Base64ImageSourceParam{MediaType: anthropic.Base64ImageSourceMediaType(mediaType), Data: data}
+			cache[url] = source
+		}
+		img.Source = anthropic.ImageBlockParamSourceUnion{OfBase64: source}
+		return nil
+	}
+
+	for i := range req.Messages {
+		for j := range req.Messages[i].Content {
+			block := &req.Messages[i].Content[j]
+			if err := inline(block.OfImage); err != nil {
+				return err
+			}
+			if block.OfToolResult != nil {
+				for k := range block.OfToolResult.Content {
+					if err := inline(block.OfToolResult.Content[k].OfImage); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// InlineOpenAIImages downloads remote image_url parts and replaces them with data URLs
+func InlineOpenAIImages(ctx context.Context, req *openai.ChatCompletionNewParams, httpClient *http.Client) error {
+	cache := make(map[string]string)
+	for i := range req.Messages {
+		user := req.Messages[i].OfUser
+		if user == nil {
+			continue
+		}
+		for j := range user.Content.OfArrayOfContentParts {
+			img := user.Content.OfArrayOfContentParts[j].OfImageURL
+			if img == nil || strings.HasPrefix(img.ImageURL.URL, "data:") {
+				continue
+			}
+			url := img.ImageURL.URL
+			dataURL, ok := cache[url]
+			if !ok {
+				mediaType, data, err := FetchImageAsBase64(ctx, httpClient, url)
+				if err != nil {
+					return err
+				}
+				dataURL = "data:" + mediaType + ";base64," + data
+				cache[url] = dataURL
+			}
+			img.ImageURL.URL = dataURL
+		}
+	}
+	return nil
+}
+
+// FetchImageAsBase64 downloads an image and returns its media type and base64 encoded data
+func FetchImageAsBase64(ctx context.Context, httpClient *http.Client, url string) (string, string, error) {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid image url %q: %w", url, err)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to download image %q: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("failed to download image %q: status %d", url, resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxInlineImageBytes+1))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to download image %q: %w", url, err)
+	}
+	if len(data) > MaxInlineImageBytes {
+		return "", "", fmt.Errorf("image %q exceeds %d bytes", url, MaxInlineImageBytes)
+	}
+
+	mediaType, _, _ := strings.Cut(resp.Header.Get("Content-Type"), ";")
+	mediaType = strings.TrimSpace(mediaType)
+	if !strings.HasPrefix(mediaType, "image/") {
+		mediaType = http.DetectContentType(data)
+	}
+	if !strings.HasPrefix(mediaType, "image/") {
+		mediaType = guessImageMimeType(url)
+	}
+	return mediaType, base64.StdEncoding.EncodeToString(data), nil
+}
@@ -0,0 +1,123 @@
+package adaptor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertAnthropicImagesToOpenAI(t *testing.T) {
+	req := &anthropic.MessageNewParams{
+		Model:     "claude-sonnet-4",
+		MaxTokens: 100,
+		Messages: []anthropic.MessageParam{
+			anthropic.NewUserMessage(
+				anthropic.NewTextBlock("What is in these images?"),
+				anthropic.NewImageBlockBase64("image/png", "iVBORw0KGgo="),
+				anthropic.NewImageBlock(anthropic.URLImageSourceParam{URL: "https://example.com/cat.jpg"}),
+			),
+			anthropic.NewAssistantMessage(anthropic.NewToolUseBlock("toolu_1", map[string]any{}, "screenshot")),
+			anthropic.NewUserMessage(anthropic.ContentBlockParamUnion{OfToolResult: &anthropic.ToolResultBlockParam{
+				ToolUseID: "toolu_1",
+				Content: []anthropic.ToolResultBlockParamContentUnion{
+					{OfText: &anthropic.TextBlockParam{Text: "captured"}},
+					{OfImage: anthropic.NewImageBlockBase64("image/jpeg", "/9j/4AAQ").OfImage},
+				},
+			}}),
+		},
+	}
+
+	openaiReq := ConvertAnthropicToOpenAIRequest(req)
+	raw, err := json.Marshal(openaiReq)
+	require.NoError(t, err)
+
+	var decoded struct {
+		Messages []struct {
+			Role       string          `json:"role"`
+			Content    json.RawMessage `json:"content"`
+			ToolCallID string          `json:"tool_call_id"`
+		} `json:"messages"`
+	}
+	require.NoError(t, json.Unmarshal(raw, &decoded))
+	require.Len(t, decoded.Messages, 4)
+
+	var parts []map[string]interface{}
+	require.NoError(t, json.Unmarshal(decoded.Messages[0].Content, &parts))
+	require.Len(t, parts, 3)
+	assert.Equal(t, "What is in these images?", parts[0]["text"])
+	assert.Equal(t, "data:image/png;base64,iVBORw0KGgo=", parts[1]["image_url"].(map[string]interface{})["url"])
+	assert.Equal(t, "https://example.com/cat.jpg", parts[2]["image_url"].(map[string]interface{})["url"])
+
+	// The tool result text stays in the tool message and its image follows as user content
+	assert.Equal(t, "tool", decoded.Messages[2].Role)
+	assert.JSONEq(t, `"captured"`, string(decoded.Messages[2].Content))
+	assert.Equal(t, "user", decoded.Messages[3].Role)
+	assert.Contains(t, string(decoded.Messages[3].Content), "data:image/jpeg;base64,/9j/4AAQ")
+}
+
+func TestConvertOpenAIImagesToAnthropic(t *testing.T) {
+	var req openai.ChatCompletionNewParams
+	require.NoError(t, json.Unmarshal([]byte(`{"model":"gpt-4o","messages":[
+		{"role":"user","content":[
+			{"type":"text","text":"Compare"},
+			{"type":"image_url","image_url":{"url":"data:image/webp;base64,UklGRg=="}},
+			{"type":"image_url","image_url":{"url":"https://example.com/dog.png","detail":"high"}}]},
+		{"role":"tool","tool_call_id":"call_1","content":[{"type":"text","text":"done"}]}]}`), &req))
+
+	anthropicReq := ConvertOpenAIToAnthropicRequest(&req, 1024)
+	require.Len(t, anthropicReq.Messages, 2)
+
+	blocks := anthropicReq.Messages[0].Content
+	require.Len(t, blocks, 3)
+	assert.Equal(t, "Compare", blocks[0].OfText.Text)
+	require.NotNil(t, blocks[1].OfImage.Source.OfBase64)
+	assert.Equal(t, anthropic.Base64ImageSourceMediaType("image/webp"), blocks[1].OfImage.Source.OfBase64.MediaType)
+	assert.Equal(t, "UklGRg==", blocks[1].OfImage.Source.OfBase64.Data)
+	require.NotNil(t, blocks[2].OfImage.Source.OfURL)
+	assert.Equal(t, "https://example.com/dog.png", blocks[2].OfImage.Source.OfURL.URL)
+
+	toolResult := anthropicReq.Messages[1].Content[0].OfToolResult
+	require.NotNil(t, toolResult)
+	assert.Equal(t, "done", toolResult.Content[0].OfText.Text)
+}
+
+func TestInlineRemoteImages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing.png" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write([]byte("png"))
+	}))
+	defer server.Close()
+
+	anthropicReq := &anthropic.MessageNewParams{Messages: []anthropic.MessageParam{
+		anthropic.NewUserMessage(anthropic.NewImageBlock(anthropic.URLImageSourceParam{URL: server.URL + "/a.png"})),
+	}}
+	require.NoError(t, InlineAnthropicImages(context.Background(), anthropicReq, server.Client()))
+	source := anthropicReq.Messages[0].Content[0].OfImage.Source
+	require.NotNil(t, source.OfBase64)
+	assert.Equal(t, anthropic.Base64ImageSourceMediaType("image/png"), source.OfBase64.MediaType)
+	assert.Equal(t, "cG5n", source.OfBase64.Data)
+
+	openaiReq := &openai.ChatCompletionNewParams{Messages: []openai.ChatCompletionMessageParamUnion{
+		openai.UserMessage([]openai.ChatCompletionContentPartUnionParam{
+			openai.ImageContentPart(openai.ChatCompletionContentPartImageImageURLParam{URL: server.URL + "/a.png"}),
+		}),
+	}}
+	require.NoError(t, InlineOpenAIImages(context.Background(), openaiReq, server.Client()))
+	assert.Equal(t, "data:image/png;base64,cG5n", openaiReq.Messages[0].OfUser.Content.OfArrayOfContentParts[0].OfImageURL.ImageURL.URL)
+
+	missing := &anthropic.MessageNewParams{Messages: []anthropic.MessageParam{
+		anthropic.NewUserMessage(anthropic.NewImageBlock(anthropic.URLImageSourceParam{URL: server.URL + "/missing.png"})),
+	}}
+	assert.Error(t, InlineAnthropicImages(context.Background(), missing, server.Client()))
+}
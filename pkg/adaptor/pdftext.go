@@ -0,0 +1,141 @@
+package adaptor
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// pdfStreamPattern matches a PDF stream object: its dictionary and raw data
+var pdfStreamPattern = regexp.MustCompile(`(?s)<<(.*?)>>\s*stream\r?\n(.*?)\r?\nendstream`)
+
+// maxPDFStreamBytes caps the inflated size of a single content stream
+const maxPDFStreamBytes = 16 << 20
+
+// ExtractPDFText extracts the text shown by a PDF's content streams. It understands
+// uncompressed and FlateDecode streams with literal string operators, which covers most
+// generated PDFs; scanned pages and CID-encoded fonts yield no text.
+func ExtractPDFText(data []byte) string {
+	var out strings.Builder
+	for _, match := range pdfStreamPattern.FindAllSubmatch(data, -1) {
+		dict, raw := match[1], match[2]
+		if bytes.Contains(dict, []byte("/Subtype/Image")) || bytes.Contains(dict, []byte("/Subtype /Image")) {
+			continue
+		}
+
+		content := raw
+		if bytes.Contains(dict, []byte("/FlateDecode")) {
+			reader, err := zlib.NewReader(bytes.NewReader(raw))
+			if err != nil {
+				continue
+			}
+			content, err = io.ReadAll(io.LimitReader(reader, maxPDFStreamBytes))
+			reader.Close()
+			if err != nil && len(content) == 0 {
+				continue
+			}
+		} else if bytes.Contains(dict, []byte("/Filter")) {
+			continue
+		}
+		extractPDFContentText(content, &out)
+	}
+	return strings.TrimSpace(out.String())
+}
+
+// extractPDFContentText appends the strings shown by Tj, TJ, ' and " operators, breaking lines
+// on text positioning operators
+func extractPDFContentText(content []byte, out *strings.Builder) {
+	var pending []string
+	for i := 0; i < len(content); {
+		ch := content[i]
+		switch {
+		case ch == '(':
+			s, next := readPDFString(content, i)
+			pending = append(pending, s)
+			i = next
+		case ch == '%':
+			for i < len(content) && content[i] != '\n' && content[i] != '\r' {
+				i++
+			}
+		case isPDFOperatorChar(ch):
+			start := i
+			for i < len(content) && isPDFOperatorChar(content[i]) {
+				i++
+			}
+			switch string(content[start:i]) {
+			case "Tj", "TJ":
+				out.WriteString(strings.Join(pending, ""))
+			case "'", "\"":
+				out.WriteString("\n" + strings.Join(pending, ""))
+			case "Td", "TD", "T*", "ET":
+				if out.Len() > 0 && !strings.HasSuffix(out.String(), "\n") {
+					out.WriteString("\n")
+				}
+			}
+			pending = pending[:0]
+		default:
+			i++
+		}
+	}
+}
+
+// isPDFOperatorChar reports whether ch can be part of a content stream operator
+func isPDFOperatorChar(ch byte) bool {
+	return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || ch == '*' || ch == '\'' || ch == '"'
+}
+
+// readPDFString decodes a literal string starting at the opening parenthesis
+func readPDFString(content []byte, start int) (string, int) {
+	var s strings.Builder
+	depth := 0
+	i := start
+	for ; i < len(content); i++ {
+		ch := content[i]
+		switch ch {
+		case '\\':
+			i++
+			if i >= len(content) {
+				break
+			}
+			switch esc := content[i]; esc {
+			case 'n':
+				s.WriteByte('\n')
+			case 'r':
+				s.WriteByte('\r')
+			case 't':
+				s.WriteByte('\t')
+			case 'b', 'f':
+			case '\r', '\n':
+				// Line continuation
+			default:
+				if esc >= '0' && esc <= '7' {
+					value := 0
+					for n := 0; n < 3 && i < len(content) && content[i] >= '0' && content[i] <= '7'; n++ {
+						value = value*8 + int(content[i]-'0')
+						i++
+					}
+					i--
+					s.WriteByte(byte(value))
+				} else {
+					s.WriteByte(esc)
+				}
+			}
+		case '(':
+			if depth > 0 {
+				s.WriteByte(ch)
+			}
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return s.String(), i + 1
+			}
+			s.WriteByte(ch)
+		default:
+			s.WriteByte(ch)
+		}
+	}
+	return s.String(), i
+}
@@ -0,0 +1,301 @@
+package adaptor
+
+import (
+	"encoding/json"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/openai/openai-go/v3"
+)
+
+const (
+	// openaiFieldCacheControl carries Anthropic cache_control on OpenAI content parts, messages and
+	// tools, as accepted by OpenRouter and LiteLLM
+	openaiFieldCacheControl = "cache_control"
+
+	// openaiFieldCacheCreationTokens reports cache writes in OpenAI usage, which has no standard field
+	openaiFieldCacheCreationTokens = "cache_creation_input_tokens"
+
+	// MaxCacheBreakpoints is the number of cache_control breakpoints Anthropic accepts per request
+	MaxCacheBreakpoints = 4
+
+	// DefaultAutoCacheTurns is the number of recent user turns auto cache marks
+	DefaultAutoCacheTurns = 2
+)
+
+// hasCacheControl reports whether a cache_control breakpoint is set
+func hasCacheControl(cc *anthropic.CacheControlEphemeralParam) bool {
+	return cc != nil && cc.Type != ""
+}
+
+// cacheControlToValue renders a cache_control breakpoint as an OpenAI extra field value
+func cacheControlToValue(cc *CacheControl) map[string]interface{} {
+	value := map[string]interface{}{"type": "ephemeral"}
+	if cc.TTL != "" {
+		value["ttl"] = cc.TTL
+	}
+	return value
+}
+
+// cacheControlFromValue parses a cache_control field of an OpenAI request, returning nil when unset
+func cacheControlFromValue(value interface{}) *CacheControl {
+	fields, ok := value.(map[string]interface{})
+	if !ok || fields["type"] != "ephemeral" {
+		return nil
+	}
+	ttl, _ := fields["ttl"].(string)
+	return &CacheControl{TTL: ttl}
+}
+
+// cacheControlFromAnthropic converts an Anthropic breakpoint, returning nil when unset
+func cacheControlFromAnthropic(cc anthropic.CacheControlEphemeralParam) *CacheControl {
+	if !hasCacheControl(&cc) {
+		return nil
+	}
+	return &CacheControl{TTL: string(cc.TTL)}
+}
+
+// anthropic renders a breakpoint as Anthropic cache_control, unset when cc is nil
+func (cc *CacheControl) anthropic() anthropic.CacheControlEphemeralParam {
+	if cc == nil {
+		return anthropic.CacheControlEphemeralParam{}
+	}
+	param := anthropic.NewCacheControlEphemeralParam()
+	param.TTL = anthropic.CacheControlEphemeralTTL(cc.TTL)
+	return param
+}
+
+// setBlockCacheControl sets a breakpoint on a block, reporting whether the block can carry one
+func setBlockCacheControl(block *anthropic.ContentBlockParamUnion, cc anthropic.CacheControlEphemeralParam) bool {
+	if target := block.GetCacheControl(); target != nil {
+		*target = cc
+		return true
+	}
+	return false
+}
+
+// mergeExtraFields adds fields to existing extra fields
+func mergeExtraFields(existing map[string]any, fields map[string]any) map[string]any {
+	if existing == nil {
+		existing = make(map[string]any, len(fields))
+	}
+	for key, value := range fields {
+		existing[key] = value
+	}
+	return existing
+}
+
+// PreserveCacheControl copies the cache_control fields of a raw chat completion request onto the
+// parsed params, whose types drop unknown fields: on content parts, messages and tools
+func PreserveCacheControl(req *openai.ChatCompletionNewParams, body []byte) {
+	var raw struct {
+		Messages []struct {
+			CacheControl json.RawMessage `json:"cache_control"`
+			Content      json.RawMessage `json:"content"`
+		} `json:"messages"`
+		Tools []struct {
+			CacheControl json.RawMessage `json:"cache_control"`
+		} `json:"tools"`
+	}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return
+	}
+	decode := func(value json.RawMessage) (map[string]any, bool) {
+		var decoded interface{}
+		if len(value) == 0 || json.Unmarshal(value, &decoded) != nil || decoded == nil {
+			return nil, false
+		}
+		return map[string]any{openaiFieldCacheControl: decoded}, true
+	}
+
+	for i, rawMsg := range raw.Messages {
+		if i >= len(req.Messages) {
+			break
+		}
+		msg := &req.Messages[i]
+		if fields, ok := decode(rawMsg.CacheControl); ok {
+			switch {
+			case msg.OfSystem != nil:
+				msg.OfSystem.SetExtraFields(mergeExtraFields(msg.OfSystem.ExtraFields(), fields))
+			case msg.OfDeveloper != nil:
+				msg.OfDeveloper.SetExtraFields(mergeExtraFields(msg.OfDeveloper.ExtraFields(), fields))
+			case msg.OfUser != nil:
+				msg.OfUser.SetExtraFields(mergeExtraFields(msg.OfUser.ExtraFields(), fields))
+			case msg.OfAssistant != nil:
+				msg.OfAssistant.SetExtraFields(mergeExtraFields(msg.OfAssistant.ExtraFields(), fields))
+			case msg.OfTool != nil:
+				msg.OfTool.SetExtraFields(mergeExtraFields(msg.OfTool.ExtraFields(), fields))
+			}
+		}
+
+		var parts []struct {
+			CacheControl json.RawMessage `json:"cache_control"`
+		}
+		if json.Unmarshal(rawMsg.Content, &parts) != nil {
+			continue
+		}
+		for j, part := range parts {
+			fields, ok := decode(part.CacheControl)
+			if !ok {
+				continue
+			}
+			switch {
+			case msg.OfSystem != nil && j < len(msg.OfSystem.Content.OfArrayOfContentParts):
+				p := &msg.OfSystem.Content.OfArrayOfContentParts[j]
+				p.SetExtraFields(mergeExtraFields(p.ExtraFields(), fields))
+			case msg.OfDeveloper != nil && j < len(msg.OfDeveloper.Content.OfArrayOfContentParts):
+				p := &msg.OfDeveloper.Content.OfArrayOfContentParts[j]
+				p.SetExtraFields(mergeExtraFields(p.ExtraFields(), fields))
+			case msg.OfTool != nil && j < len(msg.OfTool.Content.OfArrayOfContentParts):
+				p := &msg.OfTool.Content.OfArrayOfContentParts[j]
+				p.SetExtraFields(mergeExtraFields(p.ExtraFields(), fields))
+			case msg.OfUser != nil && j < len(msg.OfUser.Content.OfArrayOfContentParts):
+				p := &msg.OfUser.Content.OfArrayOfContentParts[j]
+				switch {
+				case p.OfText != nil:
+					p.OfText.SetExtraFields(mergeExtraFields(p.OfText.ExtraFields(), fields))
+				case p.OfImageURL != nil:
+					p.OfImageURL.SetExtraFields(mergeExtraFields(p.OfImageURL.ExtraFields(), fields))
+				case p.OfFile != nil:
+					p.OfFile.SetExtraFields(mergeExtraFields(p.OfFile.ExtraFields(), fields))
+				}
+			}
+		}
+	}
+
+	for i, rawTool := range raw.Tools {
+		if i >= len(req.Tools) || req.Tools[i].OfFunction == nil {
+			continue
+		}
+		if fields, ok := decode(rawTool.CacheControl); ok {
+			fn := req.Tools[i].OfFunction
+			fn.SetExtraFields(mergeExtraFields(fn.ExtraFields(), fields))
+		}
+	}
+}
+
+// toolResultCacheControl returns the breakpoint of a tool result, set on the result itself or
+// on any of its content
+func toolResultCacheControl(result Part) *CacheControl {
+	if result.CacheControl != nil {
+		return result.CacheControl
+	}
+	for _, part := range result.Content {
+		if part.CacheControl != nil {
+			return part.CacheControl
+		}
+	}
+	return nil
+}
+
+// countCacheBreakpoints counts the cache_control breakpoints of an Anthropic request
+func countCacheBreakpoints(req *anthropic.MessageNewParams) int {
+	count := 0
+	for i := range req.System {
+		if hasCacheControl(&req.System[i].CacheControl) {
+			count++
+		}
+	}
+	for i := range req.Tools {
+		if hasCacheControl(req.Tools[i].GetCacheControl()) {
+			count++
+		}
+	}
+	for i := range req.Messages {
+		for j := range req.Messages[i].Content {
+			block := &req.Messages[i].Content[j]
+			if hasCacheControl(block.GetCacheControl()) {
+				count++
+			}
+			if block.OfToolResult != nil {
+				for k := range block.OfToolResult.Content {
+					if hasCacheControl(block.OfToolResult.Content[k].GetCacheControl()) {
+						count++
+					}
+				}
+			}
+		}
+	}
+	return count
+}
+
+// ApplyAutoCache inserts cache_control breakpoints on the tools, the system prompt and the
+// last turns user messages, within the limit Anthropic accepts. Requests that already set
+// breakpoints are left to the client.
+func ApplyAutoCache(req *anthropic.MessageNewParams, turns int) {
+	if countCacheBreakpoints(req) > 0 {
+		return
+	}
+	if turns <= 0 {
+		turns = DefaultAutoCacheTurns
+	}
+	budget := MaxCacheBreakpoints
+	cc := anthropic.NewCacheControlEphemeralParam()
+
+	// Tools and system render before messages, so one breakpoint at the end of each caches them all
+	if n := len(req.Tools); n > 0 {
+		if target := req.Tools[n-1].GetCacheControl(); target != nil {
+			*target = cc
+			budget--
+		}
+	}
+	if n := len(req.System); n > 0 {
+		req.System[n-1].CacheControl = cc
+		budget--
+	}
+
+	for i := len(req.Messages) - 1; i >= 0 && turns > 0 && budget > 0; i-- {
+		msg := &req.Messages[i]
+		if msg.Role != anthropic.MessageParamRoleUser {
+			continue
+		}
+		for j := len(msg.Content) - 1; j >= 0; j-- {
+			if setBlockCacheControl(&msg.Content[j], cc) {
+				budget--
+				break
+			}
+		}
+		turns--
+	}
+}
+
+// openAIUsage renders usage as OpenAI usage. Canonical input tokens exclude cached tokens, which
+// OpenAI counts in prompt_tokens and reports as cached_tokens.
+func openAIUsage(u Usage) map[string]interface{} {
+	promptTokens := u.InputTokens + u.CacheReadTokens + u.CacheCreationTokens
+	usage := map[string]interface{}{
+		"prompt_tokens":     promptTokens,
+		"completion_tokens": u.OutputTokens,
+		"total_tokens":      promptTokens + u.OutputTokens,
+	}
+	if u.CacheReadTokens > 0 || u.CacheCreationTokens > 0 {
+		usage["prompt_tokens_details"] = map[string]interface{}{"cached_tokens": u.CacheReadTokens}
+	}
+	if u.CacheCreationTokens > 0 {
+		usage[openaiFieldCacheCreationTokens] = u.CacheCreationTokens
+	}
+	return usage
+}
+
+// usageFromOpenAI splits OpenAI prompt tokens into uncached input, cache read and cache
+// creation tokens
+func usageFromOpenAI(usage openai.CompletionUsage) Usage {
+	u := Usage{OutputTokens: usage.CompletionTokens, CacheReadTokens: usage.PromptTokensDetails.CachedTokens}
+	if field, ok := usage.JSON.ExtraFields[openaiFieldCacheCreationTokens]; ok {
+		_ = json.Unmarshal([]byte(field.Raw()), &u.CacheCreationTokens)
+	}
+	u.InputTokens = usage.PromptTokens - u.CacheReadTokens - u.CacheCreationTokens
+	if u.InputTokens < 0 {
+		u.InputTokens = 0
+	}
+	return u
+}
+
+// anthropicUsage renders usage as Anthropic usage
+func anthropicUsage(u Usage) map[string]interface{} {
+	return map[string]interface{}{
+		"input_tokens":                u.InputTokens,
+		"output_tokens":               u.OutputTokens,
+		"cache_read_input_tokens":     u.CacheReadTokens,
+		"cache_creation_input_tokens": u.CacheCreationTokens,
+	}
+}
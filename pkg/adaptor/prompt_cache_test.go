@@ -0,0 +1,133 @@
+package adaptor
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAICacheControlToAnthropic(t *testing.T) {
+	body := []byte(`{"model":"claude-sonnet-4","messages":[
+		{"role":"system","content":[{"type":"text","text":"Long instructions","cache_control":{"type":"ephemeral","ttl":"1h"}}]},
+		{"role":"user","content":[{"type":"text","text":"Big document"},{"type":"text","text":"Question","cache_control":{"type":"ephemeral"}}]},
+		{"role":"assistant","content":"Answer"},
+		{"role":"user","content":"Follow up","cache_control":{"type":"ephemeral"}}],
+		"tools":[{"type":"function","function":{"name":"lookup","parameters":{"type":"object","properties":{}}},"cache_control":{"type":"ephemeral"}}]}`)
+	var req openai.ChatCompletionNewParams
+	require.NoError(t, json.Unmarshal(body, &req))
+	PreserveCacheControl(&req, body)
+
+	params := ConvertOpenAIToAnthropicRequest(&req, 1024)
+	require.Len(t, params.System, 1)
+	assert.Equal(t, "Long instructions", params.System[0].Text)
+	assert.Equal(t, anthropic.CacheControlEphemeralTTLTTL1h, params.System[0].CacheControl.TTL)
+
+	first := params.Messages[0].Content
+	require.Len(t, first, 2)
+	assert.False(t, hasCacheControl(&first[0].OfText.CacheControl))
+	assert.True(t, hasCacheControl(&first[1].OfText.CacheControl))
+	assert.True(t, hasCacheControl(&params.Messages[2].Content[0].OfText.CacheControl))
+	assert.True(t, hasCacheControl(&params.Tools[0].OfTool.CacheControl))
+}
+
+func TestAnthropicCacheControlToOpenAI(t *testing.T) {
+	req := &anthropic.MessageNewParams{
+		Model:     "claude-sonnet-4",
+		MaxTokens: 1024,
+		System: []anthropic.TextBlockParam{
+			{Text: "Long instructions", CacheControl: anthropic.NewCacheControlEphemeralParam()},
+		},
+		Messages: []anthropic.MessageParam{
+			anthropic.NewUserMessage(anthropic.ContentBlockParamUnion{OfText: &anthropic.TextBlockParam{
+				Text: "Question", CacheControl: anthropic.NewCacheControlEphemeralParam(),
+			}}),
+		},
+	}
+
+	// Only forwarded when the provider accepts it
+	raw, err := json.Marshal(ConvertAnthropicToOpenAIRequest(req))
+	require.NoError(t, err)
+	assert.NotContains(t, string(raw), "cache_control")
+
+	openaiReq, err := ConvertAnthropicToOpenAIRequestWithOptions(req, ConvertOptions{ForwardCacheControl: true})
+	require.NoError(t, err)
+	raw, err = json.Marshal(openaiReq)
+	require.NoError(t, err)
+
+	var decoded struct {
+		Messages []struct {
+			Content []map[string]interface{} `json:"content"`
+		} `json:"messages"`
+	}
+	require.NoError(t, json.Unmarshal(raw, &decoded))
+	require.Len(t, decoded.Messages, 2)
+	assert.Equal(t, map[string]interface{}{"type": "ephemeral"}, decoded.Messages[0].Content[0]["cache_control"])
+	assert.Equal(t, "Question", decoded.Messages[1].Content[0]["text"])
+	assert.Equal(t, map[string]interface{}{"type": "ephemeral"}, decoded.Messages[1].Content[0]["cache_control"])
+}
+
+func TestApplyAutoCache(t *testing.T) {
+	newRequest := func() *anthropic.MessageNewParams {
+		return &anthropic.MessageNewParams{
+			System: []anthropic.TextBlockParam{{Text: "a"}, {Text: "b"}},
+			Tools: []anthropic.ToolUnionParam{
+				{OfTool: &anthropic.ToolParam{Name: "one"}},
+				{OfTool: &anthropic.ToolParam{Name: "two"}},
+			},
+			Messages: []anthropic.MessageParam{
+				anthropic.NewUserMessage(anthropic.NewTextBlock("turn 1")),
+				anthropic.NewAssistantMessage(anthropic.NewTextBlock("reply 1")),
+				anthropic.NewUserMessage(anthropic.NewTextBlock("turn 2")),
+				anthropic.NewAssistantMessage(anthropic.NewTextBlock("reply 2")),
+				anthropic.NewUserMessage(anthropic.NewTextBlock("turn 3a"), anthropic.NewTextBlock("turn 3b")),
+			},
+		}
+	}
+
+	req := newRequest()
+	ApplyAutoCache(req, 0)
+	assert.Equal(t, MaxCacheBreakpoints, countCacheBreakpoints(req))
+	assert.True(t, hasCacheControl(&req.Tools[1].OfTool.CacheControl))
+	assert.False(t, hasCacheControl(&req.Tools[0].OfTool.CacheControl))
+	assert.True(t, hasCacheControl(&req.System[1].CacheControl))
+	assert.True(t, hasCacheControl(&req.Messages[4].Content[1].OfText.CacheControl))
+	assert.True(t, hasCacheControl(&req.Messages[2].Content[0].OfText.CacheControl))
+	assert.False(t, hasCacheControl(&req.Messages[0].Content[0].OfText.CacheControl))
+
+	// More turns than the breakpoint limit allows
+	req = newRequest()
+	ApplyAutoCache(req, 5)
+	assert.Equal(t, MaxCacheBreakpoints, countCacheBreakpoints(req))
+
+	// Breakpoints set by the client are left alone
+	req = newRequest()
+	req.Messages[0].Content[0].OfText.CacheControl = anthropic.NewCacheControlEphemeralParam()
+	ApplyAutoCache(req, 2)
+	assert.Equal(t, 1, countCacheBreakpoints(req))
+}
+
+func TestCacheUsageMapping(t *testing.T) {
+	var resp anthropic.Message
+	require.NoError(t, json.Unmarshal([]byte(`{"id":"msg_1","type":"message","role":"assistant","model":"claude",
+		"stop_reason":"end_turn","content":[{"type":"text","text":"hi"}],
+		"usage":{"input_tokens":10,"output_tokens":5,"cache_read_input_tokens":1000,"cache_creation_input_tokens":200}}`), &resp))
+
+	usage := ConvertAnthropicToOpenAIResponse(&resp, "claude")["usage"].(map[string]interface{})
+	assert.Equal(t, int64(1210), usage["prompt_tokens"])
+	assert.Equal(t, int64(1215), usage["total_tokens"])
+	assert.Equal(t, map[string]interface{}{"cached_tokens": int64(1000)}, usage["prompt_tokens_details"])
+	assert.Equal(t, int64(200), usage["cache_creation_input_tokens"])
+
+	var openaiResp openai.ChatCompletion
+	require.NoError(t, json.Unmarshal([]byte(`{"id":"c1","object":"chat.completion","model":"m","created":1,
+		"choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"hi"}}],
+		"usage":{"prompt_tokens":1210,"completion_tokens":5,"total_tokens":1215,"prompt_tokens_details":{"cached_tokens":1000}}}`), &openaiResp))
+	msg := ConvertOpenAIToAnthropicResponse(&openaiResp, "m")
+	assert.Equal(t, int64(210), msg.Usage.InputTokens)
+	assert.Equal(t, int64(1000), msg.Usage.CacheReadInputTokens)
+	assert.Equal(t, int64(5), msg.Usage.OutputTokens)
+}
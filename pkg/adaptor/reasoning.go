@@ -0,0 +1,275 @@
+package adaptor
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/openai/openai-go/v3"
+)
+
+// Reasoning fields of OpenAI-compatible APIs. reasoning_content is used by DeepSeek, Qwen and
+// most resellers, reasoning by OpenRouter and vLLM; reasoning_details (OpenRouter) is the only
+// one able to carry Anthropic thinking signatures and redacted thinking.
+const (
+	openaiFieldReasoning        = "reasoning"
+	openaiFieldReasoningDetails = "reasoning_details"
+	openaiFieldThinking         = "thinking"
+
+	ReasoningDetailText      = "reasoning.text"
+	ReasoningDetailEncrypted = "reasoning.encrypted"
+	ReasoningDetailSummary   = "reasoning.summary"
+
+	// reasoningDetailFormat marks details that hold Anthropic signatures
+	reasoningDetailFormat = "anthropic-claude-v1"
+
+	// placeholderSignaturePrefix marks signatures made up for reasoning that came without one;
+	// they are never sent upstream
+	placeholderSignaturePrefix = "thinking-"
+
+	// minThinkingBudget is the smallest budget_tokens Anthropic accepts
+	minThinkingBudget = 1024
+)
+
+// Thinking budgets used for each OpenAI reasoning effort
+var reasoningEffortBudgets = map[string]int64{
+	"minimal": 1024,
+	"low":     2048,
+	"medium":  8192,
+	"high":    16384,
+}
+
+// ThinkingBudgetForEffort maps an OpenAI reasoning_effort to an Anthropic thinking budget,
+// returning 0 when reasoning is off or the effort is unknown
+func ThinkingBudgetForEffort(effort string) int64 {
+	return reasoningEffortBudgets[strings.ToLower(effort)]
+}
+
+// ReasoningEffortForBudget maps an Anthropic thinking budget to the closest OpenAI reasoning_effort
+func ReasoningEffortForBudget(budget int64) string {
+	switch {
+	case budget <= reasoningEffortBudgets["low"]:
+		return "low"
+	case budget <= reasoningEffortBudgets["medium"]:
+		return "medium"
+	default:
+		return "high"
+	}
+}
+
+// ReasoningDetail is one entry of reasoning_details
+type ReasoningDetail struct {
+	Type      string `json:"type"`
+	Text      string `json:"text,omitempty"`
+	Summary   string `json:"summary,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	Data      string `json:"data,omitempty"`
+	Format    string `json:"format,omitempty"`
+	Index     int    `json:"index"`
+}
+
+// placeholderSignature makes up a signature for thinking that arrived without one, since
+// Anthropic clients expect every thinking block to be signed
+func placeholderSignature() string {
+	return placeholderSignaturePrefix + uuid.New().String()[0:6]
+}
+
+// isRealSignature reports whether a signature was issued by Anthropic rather than made up here
+func isRealSignature(signature string) bool {
+	return signature != "" && !strings.HasPrefix(signature, placeholderSignaturePrefix)
+}
+
+// reasoningFromFields extracts the reasoning text and details of an OpenAI message or delta
+func reasoningFromFields(fields map[string]interface{}) (string, []ReasoningDetail) {
+	var text string
+	if s, ok := fields[openaiFieldReasoningContent].(string); ok && s != "" {
+		text = s
+	} else if s, ok := fields[openaiFieldReasoning].(string); ok {
+		text = s
+	}
+
+	var details []ReasoningDetail
+	if raw, ok := fields[openaiFieldReasoningDetails]; ok && raw != nil {
+		if b, err := json.Marshal(raw); err == nil {
+			_ = json.Unmarshal(b, &details)
+		}
+	}
+	return text, details
+}
+
+// isReasoningField reports whether an OpenAI extra field carries reasoning
+func isReasoningField(key string) bool {
+	return key == openaiFieldReasoningContent || key == openaiFieldReasoning || key == openaiFieldReasoningDetails
+}
+
+// thinkingPartsFromReasoning converts OpenAI reasoning into thinking and redacted thinking parts.
+// Details carry the text and signature of each block; without them the plain reasoning text
+// becomes a single unsigned part.
+func thinkingPartsFromReasoning(text string, details []ReasoningDetail) []Part {
+	var parts []Part
+	hasThinking := false
+	addThinking := func(thinking, signature string) {
+		hasThinking = true
+		if thinking == "" && signature == "" {
+			return
+		}
+		parts = append(parts, Part{Type: PartThinking, Text: thinking, Signature: signature})
+	}
+
+	// Details with a signature but no text sign the plain reasoning text
+	var detailText strings.Builder
+	var signature string
+	for _, detail := range details {
+		switch detail.Type {
+		case ReasoningDetailText:
+			if detail.Text != "" && detail.Signature != "" {
+				addThinking(detail.Text, detail.Signature)
+				continue
+			}
+			detailText.WriteString(detail.Text)
+			if detail.Signature != "" {
+				signature = detail.Signature
+			}
+		case ReasoningDetailEncrypted:
+			if detail.Data != "" {
+				parts = append(parts, Part{Type: PartRedactedThinking, Data: detail.Data})
+			}
+		}
+	}
+
+	if !hasThinking || signature != "" {
+		thinking := detailText.String()
+		if thinking == "" {
+			thinking = text
+		}
+		addThinking(thinking, signature)
+	}
+	return parts
+}
+
+// reasoningFromThinking collects Anthropic thinking into OpenAI reasoning_content and
+// reasoning_details; details are only returned when there is a signature or redacted data to keep
+func reasoningFromThinking(blocks []ReasoningDetail) (string, []ReasoningDetail) {
+	var text strings.Builder
+	var details []ReasoningDetail
+	keep := false
+	for i, block := range blocks {
+		block.Index = i
+		switch block.Type {
+		case ReasoningDetailText:
+			text.WriteString(block.Text)
+			if isRealSignature(block.Signature) {
+				block.Format = reasoningDetailFormat
+				keep = true
+			} else {
+				block.Signature = ""
+			}
+		case ReasoningDetailEncrypted:
+			block.Format = reasoningDetailFormat
+			keep = true
+		}
+		details = append(details, block)
+	}
+	if !keep {
+		details = nil
+	}
+	return text.String(), details
+}
+
+// reasoningDetailsFromParts lists the thinking of message parts
+func reasoningDetailsFromParts(parts []Part) []ReasoningDetail {
+	var details []ReasoningDetail
+	for _, part := range parts {
+		switch part.Type {
+		case PartThinking:
+			details = append(details, ReasoningDetail{Type: ReasoningDetailText, Text: part.Text, Signature: part.Signature})
+		case PartRedactedThinking:
+			details = append(details, ReasoningDetail{Type: ReasoningDetailEncrypted, Data: part.Data})
+		}
+	}
+	return details
+}
+
+// openAIThinkingBudget derives a thinking budget from an OpenAI request: an Anthropic-style
+// "thinking" extra field, OpenRouter's "reasoning" object or reasoning_effort. 0 means thinking is off.
+func openAIThinkingBudget(req *openai.ChatCompletionNewParams) int64 {
+	extra := req.ExtraFields()
+	if thinking, ok := extra[openaiFieldThinking].(map[string]interface{}); ok {
+		if thinking["type"] != "enabled" {
+			return 0
+		}
+		if v, ok := thinking["budget_tokens"].(float64); ok && v > 0 {
+			return int64(v)
+		}
+		return reasoningEffortBudgets["medium"]
+	}
+	if reasoning, ok := extra[openaiFieldReasoning].(map[string]interface{}); ok {
+		if v, ok := reasoning["max_tokens"].(float64); ok && v > 0 {
+			return int64(v)
+		}
+		effort, _ := reasoning["effort"].(string)
+		return ThinkingBudgetForEffort(effort)
+	}
+	return ThinkingBudgetForEffort(string(req.ReasoningEffort))
+}
+
+// clampThinkingBudget keeps a thinking budget below maxTokens as Anthropic requires, turning
+// thinking off when too little is left
+func clampThinkingBudget(budget, maxTokens int64) int64 {
+	if budget >= maxTokens {
+		budget = maxTokens - 1
+	}
+	if budget < minThinkingBudget {
+		return 0
+	}
+	return budget
+}
+
+// PreserveReasoningFields copies the reasoning fields of a raw chat completion request onto
+// the parsed params, whose types drop unknown fields: thinking and reasoning at the top level,
+// and reasoning_content, reasoning and reasoning_details on assistant messages
+func PreserveReasoningFields(req *openai.ChatCompletionNewParams, body []byte) {
+	var raw struct {
+		Thinking  json.RawMessage `json:"thinking"`
+		Reasoning json.RawMessage `json:"reasoning"`
+		Messages  []map[string]json.RawMessage
+	}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return
+	}
+
+	extra := req.ExtraFields()
+	if extra == nil {
+		extra = map[string]any{}
+	}
+	changed := false
+	for key, value := range map[string]json.RawMessage{openaiFieldThinking: raw.Thinking, openaiFieldReasoning: raw.Reasoning} {
+		var decoded interface{}
+		if len(value) > 0 && json.Unmarshal(value, &decoded) == nil && decoded != nil {
+			extra[key] = decoded
+			changed = true
+		}
+	}
+	if changed {
+		req.SetExtraFields(extra)
+	}
+
+	for i, msg := range raw.Messages {
+		if i >= len(req.Messages) || req.Messages[i].OfAssistant == nil {
+			continue
+		}
+		fields := map[string]any{}
+		for key, value := range msg {
+			if !isReasoningField(key) {
+				continue
+			}
+			var decoded interface{}
+			if json.Unmarshal(value, &decoded) == nil && decoded != nil {
+				fields[key] = decoded
+			}
+		}
+		if len(fields) > 0 {
+			req.Messages[i].OfAssistant.SetExtraFields(fields)
+		}
+	}
+}
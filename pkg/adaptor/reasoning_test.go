@@ -0,0 +1,209 @@
+package adaptor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	anthropicOption "github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/gin-gonic/gin"
+	"github.com/openai/openai-go/v3"
+	openaiOption "github.com/openai/openai-go/v3/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReasoningEffortBudgets(t *testing.T) {
+	assert.Equal(t, int64(2048), ThinkingBudgetForEffort("low"))
+	assert.Equal(t, int64(0), ThinkingBudgetForEffort("none"))
+	assert.Equal(t, "low", ReasoningEffortForBudget(1024))
+	assert.Equal(t, "medium", ReasoningEffortForBudget(8192))
+	assert.Equal(t, "high", ReasoningEffortForBudget(32000))
+}
+
+func TestConvertAnthropicThinkingToOpenAIRequest(t *testing.T) {
+	req := &anthropic.MessageNewParams{
+		Model:     "claude-sonnet-4",
+		MaxTokens: 16000,
+		Thinking:  anthropic.ThinkingConfigParamOfEnabled(10000),
+		Messages: []anthropic.MessageParam{
+			anthropic.NewUserMessage(anthropic.NewTextBlock("Weather in Paris?")),
+			anthropic.NewAssistantMessage(
+				anthropic.NewThinkingBlock("sig-abc", "I should call the tool."),
+				anthropic.NewRedactedThinkingBlock("opaque"),
+				anthropic.NewToolUseBlock("toolu_1", map[string]any{"city": "Paris"}, "get_weather"),
+			),
+			anthropic.NewUserMessage(anthropic.NewToolResultBlock("toolu_1", "sunny", false)),
+			anthropic.NewAssistantMessage(
+				anthropic.NewThinkingBlock(placeholderSignature(), "Unsigned thought."),
+				anthropic.NewTextBlock("It is sunny."),
+			),
+		},
+	}
+
+	raw, err := json.Marshal(ConvertAnthropicToOpenAIRequest(req))
+	require.NoError(t, err)
+
+	var decoded struct {
+		ReasoningEffort string                   `json:"reasoning_effort"`
+		Thinking        map[string]interface{}   `json:"thinking"`
+		Messages        []map[string]interface{} `json:"messages"`
+	}
+	require.NoError(t, json.Unmarshal(raw, &decoded))
+	assert.Equal(t, "high", decoded.ReasoningEffort)
+	assert.Equal(t, float64(10000), decoded.Thinking["budget_tokens"])
+
+	assistant := decoded.Messages[1]
+	assert.Equal(t, "I should call the tool.", assistant["reasoning_content"])
+	details := assistant["reasoning_details"].([]interface{})
+	require.Len(t, details, 2)
+	assert.Equal(t, "sig-abc", details[0].(map[string]interface{})["signature"])
+	assert.Equal(t, "opaque", details[1].(map[string]interface{})["data"])
+
+	// Placeholder signatures are never sent upstream
+	assert.Equal(t, "Unsigned thought.", decoded.Messages[3]["reasoning_content"])
+	assert.NotContains(t, decoded.Messages[3], "reasoning_details")
+}
+
+func TestConvertOpenAIReasoningToAnthropicRequest(t *testing.T) {
+	body := []byte(`{"model":"claude-sonnet-4","max_tokens":4096,"reasoning_effort":"low","messages":[
+		{"role":"user","content":"Weather in Paris?"},
+		{"role":"assistant","content":null,"reasoning_content":"I should call the tool.",
+			"reasoning_details":[{"type":"reasoning.text","text":"I should call the tool.","signature":"sig-abc","index":0}],
+			"tool_calls":[{"id":"toolu_1","type":"function","function":{"name":"get_weather","arguments":"{\"city\":\"Paris\"}"}}]},
+		{"role":"tool","tool_call_id":"toolu_1","content":"sunny"},
+		{"role":"assistant","content":"It is sunny.","reasoning_content":"Unsigned thought."}]}`)
+	var req openai.ChatCompletionNewParams
+	require.NoError(t, json.Unmarshal(body, &req))
+	PreserveReasoningFields(&req, body)
+
+	params := ConvertOpenAIToAnthropicRequest(&req, 8192)
+	require.NotNil(t, params.Thinking.OfEnabled)
+	assert.Equal(t, int64(2048), params.Thinking.OfEnabled.BudgetTokens)
+
+	toolTurn := params.Messages[1].Content
+	require.NotNil(t, toolTurn[0].OfThinking)
+	assert.Equal(t, "sig-abc", toolTurn[0].OfThinking.Signature)
+	assert.Equal(t, "I should call the tool.", toolTurn[0].OfThinking.Thinking)
+	assert.NotNil(t, toolTurn[1].OfToolUse)
+
+	// Unsigned reasoning cannot be replayed to Anthropic
+	finalTurn := params.Messages[3].Content
+	require.Len(t, finalTurn, 1)
+	assert.NotNil(t, finalTurn[0].OfText)
+}
+
+func TestConvertReasoningResponses(t *testing.T) {
+	var openaiResp openai.ChatCompletion
+	require.NoError(t, json.Unmarshal([]byte(`{"id":"c1","object":"chat.completion","model":"m","created":1,
+		"choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"Answer",
+			"reasoning_content":"Thinking it over",
+			"reasoning_details":[{"type":"reasoning.text","signature":"sig-xyz","index":0}]}}],
+		"usage":{"prompt_tokens":1,"completion_tokens":2,"total_tokens":3}}`), &openaiResp))
+
+	msg := ConvertOpenAIToAnthropicResponse(&openaiResp, "m")
+	require.Len(t, msg.Content, 2)
+	assert.Equal(t, "thinking", msg.Content[0].Type)
+	assert.Equal(t, "Thinking it over", msg.Content[0].Thinking)
+	assert.Equal(t, "sig-xyz", msg.Content[0].Signature)
+	assert.Equal(t, "Answer", msg.Content[1].Text)
+
+	// And back: the signature rides along in reasoning_details
+	result := ConvertAnthropicToOpenAIResponse(&msg, "m")
+	message := result["choices"].([]map[string]interface{})[0]["message"].(map[string]interface{})
+	assert.Equal(t, "Thinking it over", message["reasoning_content"])
+	details := message["reasoning_details"].([]ReasoningDetail)
+	require.Len(t, details, 1)
+	assert.Equal(t, "sig-xyz", details[0].Signature)
+}
+
+// sseServer serves a fixed SSE body for any request
+func sseServer(body string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte(body))
+	}))
+}
+
+func TestOpenAIReasoningStreamToAnthropic(t *testing.T) {
+	server := sseServer(`data: {"id":"c1","object":"chat.completion.chunk","created":1,"model":"m","choices":[{"index":0,"delta":{"role":"assistant","reasoning_content":"Let me "}}]}
+
+data: {"id":"c1","object":"chat.completion.chunk","created":1,"model":"m","choices":[{"index":0,"delta":{"reasoning_content":"think.","reasoning_details":[{"type":"reasoning.text","text":"think.","signature":"sig-stream","index":0}]}}]}
+
+data: {"id":"c1","object":"chat.completion.chunk","created":1,"model":"m","choices":[{"index":0,"delta":{"content":"Done"}}]}
+
+data: {"id":"c1","object":"chat.completion.chunk","created":1,"model":"m","choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}
+
+data: [DONE]
+
+`)
+	defer server.Close()
+
+	client := openai.NewClient(openaiOption.WithBaseURL(server.URL), openaiOption.WithAPIKey("test"))
+	stream := client.Chat.Completions.NewStreaming(context.Background(), openai.ChatCompletionNewParams{Model: "m"})
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	require.NoError(t, HandleOpenAIToAnthropicStreamResponse(c, stream, "m"))
+
+	out := w.Body.String()
+	thinking := strings.Index(out, `"thinking":"Let me "`)
+	signature := strings.Index(out, `"signature":"sig-stream"`)
+	text := strings.Index(out, `"text":"Done"`)
+	require.True(t, thinking >= 0 && signature >= 0 && text >= 0, out)
+	// The thinking block is signed and closed before the text block starts
+	assert.Less(t, thinking, signature)
+	assert.Less(t, signature, text)
+	assert.Equal(t, 1, strings.Count(out, `"thinking":"think."`))
+}
+
+func TestAnthropicThinkingStreamToOpenAI(t *testing.T) {
+	server := sseServer(`event: message_start
+data: {"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude","content":[],"usage":{"input_tokens":5,"output_tokens":0}}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":"","signature":""}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"Pondering"}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"signature_delta","signature":"sig-claude"}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":0}
+
+event: content_block_start
+data: {"type":"content_block_start","index":1,"content_block":{"type":"redacted_thinking","data":"opaque"}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":1}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":3}}
+
+event: message_stop
+data: {"type":"message_stop"}
+
+`)
+	defer server.Close()
+
+	client := anthropic.NewClient(anthropicOption.WithBaseURL(server.URL), anthropicOption.WithAPIKey("test"))
+	stream := client.Messages.NewStreaming(context.Background(), anthropic.MessageNewParams{Model: "claude", MaxTokens: 10})
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	require.NoError(t, HandleAnthropicToOpenAIStreamResponse(c, stream, "claude"))
+
+	out := w.Body.String()
+	assert.Contains(t, out, `"reasoning_content":"Pondering"`)
+	assert.Contains(t, out, `"signature":"sig-claude"`)
+	assert.Contains(t, out, `"type":"reasoning.encrypted"`)
+	assert.Contains(t, out, `"data":"opaque"`)
+}
@@ -175,3 +175,28 @@ func TestConvertOpenAIToAnthropicResponse(t *testing.T) {
 		})
 	}
 }
+
+func TestConvertAnthropicToOpenAIChoicesResponse(t *testing.T) {
+	newMessage := func(text string) *anthropic.Message {
+		return &anthropic.Message{
+			ID:         "msg_" + text,
+			Content:    []anthropic.ContentBlockUnion{{Type: "text", Text: text}},
+			StopReason: "end_turn",
+			Usage:      anthropic.Usage{InputTokens: 10, OutputTokens: 3, CacheReadInputTokens: 100},
+		}
+	}
+
+	result := ConvertAnthropicToOpenAIChoicesResponse([]*anthropic.Message{newMessage("one"), newMessage("two")}, "claude")
+	choices := result["choices"].([]map[string]interface{})
+	require.Len(t, choices, 2)
+	for i, want := range []string{"one", "two"} {
+		assert.Equal(t, i, choices[i]["index"])
+		assert.Equal(t, want, choices[i]["message"].(map[string]interface{})["content"])
+	}
+	assert.Equal(t, "msg_one", result["id"])
+
+	usage := result["usage"].(map[string]interface{})
+	assert.Equal(t, int64(220), usage["prompt_tokens"])
+	assert.Equal(t, int64(6), usage["completion_tokens"])
+	assert.Equal(t, map[string]interface{}{"cached_tokens": int64(200)}, usage["prompt_tokens_details"])
+}
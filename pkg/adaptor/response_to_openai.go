@@ -2,7 +2,6 @@ package adaptor
 
 import (
 	"encoding/json"
-	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
 )
@@ -12,88 +11,62 @@ func ConvertAnthropicToOpenAIResponse(
 	anthropicResp *anthropic.Message,
 	responseModel string,
 ) map[string]interface{} {
+	return EncodeOpenAIResponse(DecodeAnthropicResponse(anthropicResp), responseModel)
+}
 
-	message := make(map[string]interface{})
-	var toolCalls []map[string]interface{}
-	var textContent string
-	var thinking string
-
-	// Walk Anthropic content blocks
-	for _, block := range anthropicResp.Content {
-
-		switch block.Type {
-
-		case "text":
-			textContent += block.Text
-
-		case "tool_use":
-			// Anthropic → OpenAI tool call
-			toolCalls = append(toolCalls, map[string]interface{}{
-				"id":   block.ID,
-				"type": "function",
-				"function": map[string]interface{}{
-					"name":      block.Name,
-					"arguments": block.Input, // map[string]any (NOT stringified yet)
-				},
-			})
-
-		case "thinking":
-			// Collect thinking content for reasoning_content field
-			thinking += block.Text
-		}
+// ConvertAnthropicToOpenAIChoicesResponse converts the responses of parallel Anthropic requests into one
+// OpenAI response, with a choice per response and their usage summed
+func ConvertAnthropicToOpenAIChoicesResponse(anthropicResps []*anthropic.Message, responseModel string) map[string]interface{} {
+	if len(anthropicResps) == 0 {
+		return nil
 	}
-
-	// OpenAI expects arguments as STRING
-	for _, tc := range toolCalls {
-		fn := tc["function"].(map[string]interface{})
-		if args, ok := fn["arguments"]; ok {
-			if b, err := json.Marshal(args); err == nil {
-				fn["arguments"] = string(b)
-			}
+	merged := &Response{Usage: &Usage{}}
+	for i, anthropicResp := range anthropicResps {
+		resp := DecodeAnthropicResponse(anthropicResp)
+		if i == 0 {
+			merged.ID = resp.ID
 		}
+		merged.Choices = append(merged.Choices, resp.Choices...)
+		merged.Usage.InputTokens += resp.Usage.InputTokens
+		merged.Usage.OutputTokens += resp.Usage.OutputTokens
+		merged.Usage.CacheReadTokens += resp.Usage.CacheReadTokens
+		merged.Usage.CacheCreationTokens += resp.Usage.CacheCreationTokens
 	}
+	return EncodeOpenAIResponse(merged, responseModel)
+}
 
-	// Set role from Anthropic response (required by OpenAI format)
-	message["role"] = string(anthropicResp.Role)
-
-	if textContent != "" {
-		message["content"] = textContent
-	}
-	if len(toolCalls) > 0 {
-		message["tool_calls"] = toolCalls
-	}
-	// Add reasoning_content if thinking blocks were present
-	if thinking != "" {
-		message["reasoning_content"] = thinking
-	}
+// GeminiResponse is a generateContent response, also used for each streamed chunk
+type GeminiResponse struct {
+	ResponseID string `json:"responseId"`
+	Candidates []struct {
+		Index        int           `json:"index"`
+		Content      GeminiContent `json:"content"`
+		FinishReason string        `json:"finishReason"`
+	} `json:"candidates"`
+	UsageMetadata *struct {
+		PromptTokenCount     int64 `json:"promptTokenCount"`
+		CandidatesTokenCount int64 `json:"candidatesTokenCount"`
+		ThoughtsTokenCount   int64 `json:"thoughtsTokenCount"`
+		TotalTokenCount      int64 `json:"totalTokenCount"`
+	} `json:"usageMetadata"`
+	// Error is set when a stream fails after it started
+	Error *GeminiError `json:"error"`
+}
 
-	// Map stop reason
-	finishReason := "stop"
-	switch anthropicResp.StopReason {
-	case "tool_use":
-		finishReason = "tool_calls"
-	case "max_tokens":
-		finishReason = "length"
-	}
+// GeminiError is the error object of a Gemini API response
+type GeminiError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Status  string `json:"status"`
+}
 
-	response := map[string]interface{}{
-		"id":      anthropicResp.ID,
-		"object":  "chat.completion",
-		"created": time.Now().Unix(),
-		"model":   responseModel,
-		"choices": []map[string]interface{}{
-			{
-				"index":         0,
-				"message":       message,
-				"finish_reason": finishReason,
-			},
-		},
-		"usage": map[string]interface{}{
-			"prompt_tokens":     anthropicResp.Usage.InputTokens,
-			"completion_tokens": anthropicResp.Usage.OutputTokens,
-			"total_tokens":      anthropicResp.Usage.InputTokens + anthropicResp.Usage.OutputTokens,
-		},
+// ConvertGeminiToOpenAIResponse converts a Gemini generateContent response body to an
+// OpenAI chat completion, one choice per candidate
+func ConvertGeminiToOpenAIResponse(body []byte, responseModel string) (map[string]interface{}, error) {
+	var geminiResp GeminiResponse
+	if err := json.Unmarshal(body, &geminiResp); err != nil {
+		return nil, err
 	}
 
-	return response
+	return EncodeOpenAIResponse(DecodeGeminiResponse(&geminiResp), responseModel), nil
 }
@@ -0,0 +1,60 @@
+package adaptor
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// EventStream is a stream of upstream events, as returned by the streaming calls of the OpenAI
+// and Anthropic SDKs
+type EventStream[T any] interface {
+	Next() bool
+	Current() T
+	Err() error
+}
+
+// SSEEvent is a server-sent event; an empty Event sends the data alone
+type SSEEvent struct {
+	Event string
+	Data  map[string]interface{}
+}
+
+// writeSSEEvent writes an event in SSE format: event: <type>\ndata: <json>\n\n
+func writeSSEEvent(w io.Writer, event SSEEvent) error {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return err
+	}
+	if event.Event != "" {
+		if _, err := fmt.Fprintf(w, "event: %s\n", event.Event); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "data: %s\n\n", data)
+	return err
+}
+
+// writeSSEData writes an event holding only data, as OpenAI streams do
+func writeSSEData(w io.Writer, chunk map[string]interface{}) error {
+	data, err := json.Marshal(chunk)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "data: %s\n\n", data)
+	return err
+}
+
+// writeSSEDone writes the [DONE] marker ending an OpenAI stream
+func writeSSEDone(w io.Writer) error {
+	_, err := io.WriteString(w, "data: [DONE]\n\n")
+	return err
+}
+
+// flush sends buffered events to the client when w supports it
+func flush(w io.Writer) {
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
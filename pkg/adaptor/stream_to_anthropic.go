@@ -4,8 +4,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
-	"sort"
 	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
@@ -38,18 +38,28 @@ const (
 	eventTypeError             = "error"
 
 	// Anthropic block types
-	blockTypeText     = "text"
-	blockTypeThinking = "thinking"
-	blockTypeToolUse  = "tool_use"
+	blockTypeText             = "text"
+	blockTypeThinking         = "thinking"
+	blockTypeRedactedThinking = "redacted_thinking"
+	blockTypeToolUse          = "tool_use"
 
 	// Anthropic delta types
 	deltaTypeTextDelta      = "text_delta"
 	deltaTypeThinkingDelta  = "thinking_delta"
+	deltaTypeSignatureDelta = "signature_delta"
 	deltaTypeInputJSONDelta = "input_json_delta"
 )
 
 // HandleOpenAIToAnthropicStreamResponse processes OpenAI streaming events and converts them to Anthropic format
 func HandleOpenAIToAnthropicStreamResponse(c *gin.Context, stream *openaistream.Stream[openai.ChatCompletionChunk], responseModel string) error {
+	return HandleOpenAIToAnthropicStreamResponseWithOptions(c, stream, responseModel, StreamOptions{})
+}
+
+// HandleOpenAIToAnthropicStreamResponseWithOptions processes OpenAI streaming chunks and converts them to
+// Anthropic events. message_start reports opts.InputTokens; message_delta reports the usage of the
+// upstream, which arrives after the last choice when the request sets stream_options.include_usage.
+// An upstream failing before it sent anything is returned as an *UpstreamError.
+func HandleOpenAIToAnthropicStreamResponseWithOptions(c *gin.Context, stream *openaistream.Stream[openai.ChatCompletionChunk], responseModel string, opts StreamOptions) error {
 	logrus.Info("Starting OpenAI to Anthropic streaming response handler")
 	defer func() {
 		if r := recover(); r != nil {
@@ -77,236 +87,105 @@ func HandleOpenAIToAnthropicStreamResponse(c *gin.Context, stream *openaistream.
 	c.Header("Access-Control-Allow-Origin", "*")
 	c.Header("Access-Control-Allow-Headers", "Cache-Control")
 
-	flusher, ok := c.Writer.(http.Flusher)
-	if !ok {
-
+	if _, ok := c.Writer.(http.Flusher); !ok {
 		return errors.New("Streaming not supported by this connection")
 	}
 
-	// Generate message ID for Anthropic format
-	messageID := fmt.Sprintf("msg_%d", time.Now().Unix())
-
-	// Initialize streaming state
-	state := newStreamState()
-
-	// Send message_start event first
-	messageStartEvent := map[string]interface{}{
-		"type": eventTypeMessageStart,
-		"message": map[string]interface{}{
-			"id":            messageID,
-			"type":          "message",
-			"role":          "assistant",
-			"content":       []interface{}{},
-			"model":         responseModel,
-			"stop_reason":   nil,
-			"stop_sequence": nil,
-			"usage": map[string]interface{}{
-				"input_tokens":  0,
-				"output_tokens": 0,
-			},
-		},
+	err := ConvertOpenAIToAnthropicStream(stream, c.Writer, responseModel, opts)
+	var upstreamErr *UpstreamError
+	if errors.As(err, &upstreamErr) {
+		return upstreamErr
 	}
-	sendAnthropicStreamEvent(c, eventTypeMessageStart, messageStartEvent, flusher)
-
-	// Process the stream
-	chunkCount := 0
-	for stream.Next() {
-		chunkCount++
-		chunk := stream.Current()
-
-		// Skip empty chunks (no choices)
-		if len(chunk.Choices) == 0 {
-			// Check for usage info in the last chunk
-			if chunk.Usage.PromptTokens > 0 || chunk.Usage.CompletionTokens > 0 {
-				state.inputTokens = chunk.Usage.PromptTokens
-				state.outputTokens = chunk.Usage.CompletionTokens
-			}
-			continue
-		}
-
-		choice := chunk.Choices[0]
-
-		logrus.Debugf("Processing chunk #%d: len(choices)=%d, content=%q, finish_reason=%q",
-			chunkCount, len(chunk.Choices),
-			choice.Delta.Content, choice.FinishReason)
-
-		// Log first few chunks in detail for debugging
-		if chunkCount <= 5 || choice.FinishReason != "" {
-			logrus.Debugf("Full chunk #%d: %+v", chunkCount, chunk)
-		}
-
-		delta := choice.Delta
-
-		// Collect extra fields from this delta (for final message_delta)
-		// Handle special fields that need dedicated content blocks
-		if extras := parseRawJSON(delta.RawJSON()); extras != nil {
-			for k, v := range extras {
-				// Handle reasoning_content -> thinking block
-				if k == openaiFieldReasoningContent {
-					// Initialize thinking block on first occurrence
-					if state.thinkingBlockIndex == -1 {
-						state.thinkingBlockIndex = state.nextBlockIndex
-						state.nextBlockIndex++
-						sendContentBlockStart(c, state.thinkingBlockIndex, blockTypeThinking, map[string]interface{}{
-							"thinking": "",
-						}, flusher)
-					}
-
-					// Extract thinking content (handle different types)
-					thinkingText := extractString(v)
-					if thinkingText != "" {
-						// Send content_block_delta with thinking_delta
-						sendContentBlockDelta(c, state.thinkingBlockIndex, map[string]interface{}{
-							"type":     deltaTypeThinkingDelta,
-							"thinking": thinkingText,
-						}, flusher)
-					}
-
-					// Don't add to deltaExtras (already handled as thinking block)
-					continue
-				}
-
-				// Other extra fields: collect for final message_delta
-				state.deltaExtras[k] = v
-			}
-		}
-
-		// Handle refusal (when model refuses to respond due to safety policies)
-		if delta.Refusal != "" {
-			// Refusal should be sent as content
-			if state.textBlockIndex == -1 {
-				state.textBlockIndex = state.nextBlockIndex
-				state.nextBlockIndex++
-				sendContentBlockStart(c, state.textBlockIndex, blockTypeText, map[string]interface{}{
-					"text": "",
-				}, flusher)
-			}
-			state.hasTextContent = true
-
-			sendContentBlockDelta(c, state.textBlockIndex, map[string]interface{}{
-				"type": deltaTypeTextDelta,
-				"text": delta.Refusal,
-			}, flusher)
-		}
-
-		// Handle content delta
-		if delta.Content != "" {
-			state.hasTextContent = true
-
-			// Initialize text block on first content
-			if state.textBlockIndex == -1 {
-				state.textBlockIndex = state.nextBlockIndex
-				state.nextBlockIndex++
-				sendContentBlockStart(c, state.textBlockIndex, blockTypeText, map[string]interface{}{
-					"text": "",
-				}, flusher)
-			}
-
-			// Parse delta raw JSON to get extra fields
-			currentExtras := parseRawJSON(delta.RawJSON())
-			currentExtras = filterSpecialFields(currentExtras)
-
-			// Send content_block_delta with actual content
-			deltaMap := map[string]interface{}{
-				"type": deltaTypeTextDelta,
-				"text": delta.Content,
-			}
-			deltaMap = mergeMaps(deltaMap, currentExtras)
-			sendContentBlockDelta(c, state.textBlockIndex, deltaMap, flusher)
-		} else if choice.FinishReason == "" && state.textBlockIndex != -1 {
-			// Send empty delta for empty chunks to keep client informed
-			// Only if text block has been initialized
-			currentExtras := parseRawJSON(delta.RawJSON())
-			currentExtras = filterSpecialFields(currentExtras)
+	if err != nil {
+		// The client went away; there is no one left to answer
+		logrus.Errorf("Error writing Anthropic stream: %v", err)
+	}
+	return nil
+}
 
-			deltaMap := map[string]interface{}{
-				"type": deltaTypeTextDelta,
-				"text": "",
-			}
-			deltaMap = mergeMaps(deltaMap, currentExtras)
-			sendContentBlockDelta(c, state.textBlockIndex, deltaMap, flusher)
-		}
+// OpenAIToAnthropicStreamConverter converts OpenAI chat completion chunks into Anthropic stream
+// events, one chunk at a time. Only the first choice is converted.
+type OpenAIToAnthropicStreamConverter struct {
+	decoder *openAIStreamDecoder
+	encoder *anthropicStreamEncoder
+}
 
-		// Handle tool_calls delta
-		if len(delta.ToolCalls) > 0 {
-			for _, toolCall := range delta.ToolCalls {
-				openaiIndex := int(toolCall.Index)
+// NewOpenAIToAnthropicStreamConverter creates a converter whose message_start reports opts.InputTokens
+func NewOpenAIToAnthropicStreamConverter(responseModel string, opts StreamOptions) *OpenAIToAnthropicStreamConverter {
+	return &OpenAIToAnthropicStreamConverter{
+		decoder: newOpenAIStreamDecoder(),
+		encoder: newAnthropicStreamEncoder(fmt.Sprintf("msg_%d", time.Now().Unix()), responseModel, opts.InputTokens),
+	}
+}
 
-				// Map OpenAI tool index to Anthropic block index
-				anthropicIndex, exists := state.toolIndexToBlockIndex[openaiIndex]
-				if !exists {
-					anthropicIndex = state.nextBlockIndex
-					state.toolIndexToBlockIndex[openaiIndex] = anthropicIndex
-					state.nextBlockIndex++
+// Convert returns the events of one chunk
+func (s *OpenAIToAnthropicStreamConverter) Convert(chunk openai.ChatCompletionChunk) []SSEEvent {
+	return s.encoder.encode(s.decoder.decode(chunk))
+}
 
-					// Initialize pending tool call
-					state.pendingToolCalls[anthropicIndex] = &pendingToolCall{
-						id:   toolCall.ID,
-						name: toolCall.Function.Name,
-					}
+// Finish returns the events ending the message once the upstream is done: message_start when no
+// chunk came, and message_delta and message_stop once the choice finished
+func (s *OpenAIToAnthropicStreamConverter) Finish() []SSEEvent {
+	return s.encoder.finish()
+}
 
-					// Send content_block_start for tool_use
-					sendContentBlockStart(c, anthropicIndex, blockTypeToolUse, map[string]interface{}{
-						"id":   toolCall.ID,
-						"name": toolCall.Function.Name,
-					}, flusher)
-				}
+// Started reports whether message_start was returned
+func (s *OpenAIToAnthropicStreamConverter) Started() bool {
+	return s.encoder.started
+}
 
-				// Accumulate arguments and send delta
-				if toolCall.Function.Arguments != "" {
-					state.pendingToolCalls[anthropicIndex].input += toolCall.Function.Arguments
+// ConvertOpenAIToAnthropicStream reads OpenAI chunks from stream and writes them to w as Anthropic
+// SSE events, flushing each when w supports it. message_start reports opts.InputTokens; message_delta
+// reports the usage of the upstream, which arrives after the last choice when the request sets
+// stream_options.include_usage. An upstream failing before anything was written is returned as an
+// *UpstreamError, later failures are written as an error event. Pings are written while the upstream
+// is silent for opts.Heartbeat.
+func ConvertOpenAIToAnthropicStream(stream EventStream[openai.ChatCompletionChunk], w io.Writer, responseModel string, opts StreamOptions) error {
+	heartbeat := StartHeartbeat(w, opts.Heartbeat, AnthropicPing)
+	defer heartbeat.Stop()
+	w = heartbeat
 
-					// Send content_block_delta with input_json_delta
-					sendContentBlockDelta(c, anthropicIndex, map[string]interface{}{
-						"type":         deltaTypeInputJSONDelta,
-						"partial_json": toolCall.Function.Arguments,
-					}, flusher)
-				}
+	converter := NewOpenAIToAnthropicStreamConverter(responseModel, opts)
+	write := func(events []SSEEvent) error {
+		for _, event := range events {
+			if err := writeSSEEvent(w, event); err != nil {
+				return err
 			}
 		}
+		flush(w)
+		return nil
+	}
 
-		// Track usage from chunk
-		if chunk.Usage.CompletionTokens > 0 {
-			state.inputTokens = chunk.Usage.PromptTokens
-			state.outputTokens = chunk.Usage.CompletionTokens
+	// message_start waits for the upstream, so a failed request is still answered with its status
+	chunkCount := 0
+	for stream.Next() {
+		chunkCount++
+		chunk := stream.Current()
+		if chunkCount <= 5 {
+			logrus.Debugf("Full chunk #%d: %+v", chunkCount, chunk)
 		}
-
-		// Handle finish_reason (last chunk for this choice)
-		if choice.FinishReason != "" {
-			sendStopEvents(c, state, flusher)
-			sendMessageDelta(c, state, mapOpenAIFinishReasonToAnthropic(choice.FinishReason), flusher)
-			sendMessageStop(c, messageID, responseModel, state, mapOpenAIFinishReasonToAnthropic(choice.FinishReason), flusher)
-			return nil
+		if err := write(converter.Convert(chunk)); err != nil {
+			return err
 		}
 	}
 
-	// Check for stream errors
+	// A ping landing after the check would commit the response the caller answers with the status
+	heartbeat.Stop()
 	if err := stream.Err(); err != nil {
-		logrus.Errorf("OpenAI stream error: %v", err)
-		errorEvent := map[string]interface{}{
-			"type": "error",
-			"error": map[string]interface{}{
-				"message": err.Error(),
-				"type":    "stream_error",
-				"code":    "stream_failed",
-			},
+		if !heartbeat.Written() {
+			return ParseUpstreamError(err)
 		}
-		sendAnthropicStreamEvent(c, "error", errorEvent, flusher)
-		return nil
+		return writeAnthropicStreamError(w, err)
 	}
-	return nil
+	return write(converter.Finish())
 }
 
 // sendAnthropicStreamEvent helper function to send an event in Anthropic SSE format
 func sendAnthropicStreamEvent(c *gin.Context, eventType string, eventData map[string]interface{}, flusher http.Flusher) {
-	eventJSON, err := json.Marshal(eventData)
-	if err != nil {
-		logrus.Errorf("Failed to marshal Anthropic stream event: %v", err)
+	if err := writeSSEEvent(c.Writer, SSEEvent{Event: eventType, Data: eventData}); err != nil {
+		logrus.Errorf("Failed to write Anthropic stream event: %v", err)
 		return
 	}
-
-	// Anthropic SSE format: event: <type>\ndata: <json>\n\n
-	c.SSEvent(eventType, string(eventJSON))
 	flusher.Flush()
 }
 
@@ -321,201 +200,3 @@ func parseRawJSON(rawJSON string) map[string]interface{} {
 	}
 	return result
 }
-
-// mergeMaps merges extra fields into the base map
-func mergeMaps(base map[string]interface{}, extra map[string]interface{}) map[string]interface{} {
-	if extra == nil || len(extra) == 0 {
-		return base
-	}
-	if base == nil {
-		base = make(map[string]interface{})
-	}
-	for k, v := range extra {
-		base[k] = v
-	}
-	return base
-}
-
-// pendingToolCall tracks a tool call being assembled from stream chunks
-type pendingToolCall struct {
-	id    string
-	name  string
-	input string
-}
-
-// streamState tracks the streaming conversion state
-type streamState struct {
-	textBlockIndex        int
-	thinkingBlockIndex    int
-	hasTextContent        bool
-	nextBlockIndex        int
-	pendingToolCalls      map[int]*pendingToolCall
-	toolIndexToBlockIndex map[int]int
-	deltaExtras           map[string]interface{}
-	outputTokens          int64
-	inputTokens           int64
-}
-
-// newStreamState creates a new streamState
-func newStreamState() *streamState {
-	return &streamState{
-		textBlockIndex:        -1,
-		thinkingBlockIndex:    -1,
-		nextBlockIndex:        0,
-		pendingToolCalls:      make(map[int]*pendingToolCall),
-		toolIndexToBlockIndex: make(map[int]int),
-		deltaExtras:           make(map[string]interface{}),
-	}
-}
-
-// sendContentBlockStart sends a content_block_start event
-func sendContentBlockStart(c *gin.Context, index int, blockType string, initialContent map[string]interface{}, flusher http.Flusher) {
-	contentBlock := map[string]interface{}{
-		"type": blockType,
-	}
-	for k, v := range initialContent {
-		contentBlock[k] = v
-	}
-
-	event := map[string]interface{}{
-		"type":          eventTypeContentBlockStart,
-		"index":         index,
-		"content_block": contentBlock,
-	}
-	sendAnthropicStreamEvent(c, eventTypeContentBlockStart, event, flusher)
-}
-
-// sendContentBlockDelta sends a content_block_delta event
-func sendContentBlockDelta(c *gin.Context, index int, content map[string]interface{}, flusher http.Flusher) {
-	event := map[string]interface{}{
-		"type":  eventTypeContentBlockDelta,
-		"index": index,
-		"delta": content,
-	}
-	sendAnthropicStreamEvent(c, eventTypeContentBlockDelta, event, flusher)
-}
-
-// sendContentBlockStop sends a content_block_stop event
-func sendContentBlockStop(c *gin.Context, index int, flusher http.Flusher) {
-	event := map[string]interface{}{
-		"type":  eventTypeContentBlockStop,
-		"index": index,
-	}
-	sendAnthropicStreamEvent(c, eventTypeContentBlockStop, event, flusher)
-}
-
-// mapOpenAIFinishReasonToAnthropic converts OpenAI finish_reason to Anthropic stop_reason
-func mapOpenAIFinishReasonToAnthropic(finishReason string) string {
-	switch finishReason {
-	case string(openai.CompletionChoiceFinishReasonStop):
-		return anthropicStopReasonEndTurn
-	case string(openai.CompletionChoiceFinishReasonLength):
-		return anthropicStopReasonMaxTokens
-	case openaiFinishReasonToolCalls:
-		return anthropicStopReasonToolUse
-	case string(openai.CompletionChoiceFinishReasonContentFilter):
-		return anthropicStopReasonContentFilter
-	default:
-		return anthropicStopReasonEndTurn
-	}
-}
-
-// extractString extracts string value from interface{}, handling different types
-func extractString(v interface{}) string {
-	switch tv := v.(type) {
-	case string:
-		return tv
-	case []byte:
-		return string(tv)
-	default:
-		return fmt.Sprintf("%v", tv)
-	}
-}
-
-// sendStopEvents sends content_block_stop events for all active blocks in index order
-func sendStopEvents(c *gin.Context, state *streamState, flusher http.Flusher) {
-	// Collect block indices to stop
-	var blockIndices []int
-	if state.thinkingBlockIndex != -1 {
-		blockIndices = append(blockIndices, state.thinkingBlockIndex)
-	}
-	if state.hasTextContent {
-		blockIndices = append(blockIndices, state.textBlockIndex)
-	}
-	for i := range state.pendingToolCalls {
-		blockIndices = append(blockIndices, i)
-	}
-
-	// Sort by index to stop in order
-	sort.Ints(blockIndices)
-
-	// Send stop events in sorted order
-	for _, idx := range blockIndices {
-		sendContentBlockStop(c, idx, flusher)
-	}
-}
-
-// sendMessageDelta sends message_delta event
-func sendMessageDelta(c *gin.Context, state *streamState, stopReason string, flusher http.Flusher) {
-	// Build delta with accumulated extras
-	deltaMap := map[string]interface{}{
-		"stop_reason":   stopReason,
-		"stop_sequence": nil,
-	}
-	// Merge all collected extra fields
-	for k, v := range state.deltaExtras {
-		deltaMap[k] = v
-	}
-
-	event := map[string]interface{}{
-		"type":  eventTypeMessageDelta,
-		"delta": deltaMap,
-		"usage": map[string]interface{}{
-			"output_tokens": state.outputTokens,
-			"input_tokens":  state.inputTokens,
-		},
-	}
-	sendAnthropicStreamEvent(c, eventTypeMessageDelta, event, flusher)
-}
-
-// sendMessageStop sends message_stop event
-func sendMessageStop(c *gin.Context, messageID, model string, state *streamState, stopReason string, flusher http.Flusher) {
-	// Send message_stop with detailed data
-	messageData := map[string]interface{}{
-		"id":            messageID,
-		"type":          "message",
-		"role":          "assistant",
-		"content":       []interface{}{},
-		"model":         model,
-		"stop_reason":   stopReason,
-		"stop_sequence": nil,
-		"usage": map[string]interface{}{
-			"input_tokens":  state.inputTokens,
-			"output_tokens": state.outputTokens,
-		},
-	}
-	event := map[string]interface{}{
-		"type":    eventTypeMessageStop,
-		"message": messageData,
-	}
-	sendAnthropicStreamEvent(c, eventTypeMessageStop, event, flusher)
-
-	// Send final simple data with type (without event, aka empty)
-	c.SSEvent("", map[string]interface{}{"type": eventTypeMessageStop})
-	flusher.Flush()
-}
-
-// filterSpecialFields removes special fields that have dedicated content blocks
-// e.g., reasoning_content is handled as thinking block, not merged into text_delta
-func filterSpecialFields(extras map[string]interface{}) map[string]interface{} {
-	if extras == nil || len(extras) == 0 {
-		return extras
-	}
-	result := make(map[string]interface{})
-	for k, v := range extras {
-		if k != openaiFieldReasoningContent {
-			result[k] = v
-		}
-	}
-	return result
-}
@@ -3,6 +3,7 @@ package adaptor
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http/httptest"
 	"os"
 	"strings"
@@ -278,3 +279,118 @@ func parseSSEEvents(body string) map[string]map[string]interface{} {
 
 	return events
 }
+
+func TestOpenAIToAnthropicStreamUsage(t *testing.T) {
+	// With include_usage, the usage arrives after the finish_reason chunk
+	server := sseServer(`data: {"id":"c1","object":"chat.completion.chunk","created":1,"model":"m","choices":[{"index":0,"delta":{"role":"assistant","content":"Hi"}}]}
+
+data: {"id":"c1","object":"chat.completion.chunk","created":1,"model":"m","choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}
+
+data: {"id":"c1","object":"chat.completion.chunk","created":1,"model":"m","choices":[],"usage":{"prompt_tokens":130,"completion_tokens":7,"total_tokens":137,"prompt_tokens_details":{"cached_tokens":100}}}
+
+data: [DONE]
+
+`)
+	defer server.Close()
+
+	client := openai.NewClient(openaiOption.WithBaseURL(server.URL), openaiOption.WithAPIKey("test"))
+	stream := client.Chat.Completions.NewStreaming(context.Background(), openai.ChatCompletionNewParams{Model: "m"})
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	require.NoError(t, HandleOpenAIToAnthropicStreamResponseWithOptions(c, stream, "m", StreamOptions{InputTokens: 42}))
+
+	usage := map[string]map[string]interface{}{}
+	for _, line := range strings.Split(w.Body.String(), "\n") {
+		data := strings.TrimPrefix(line, "data:")
+		if data == line {
+			continue
+		}
+		var event map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(data), &event))
+		switch event["type"] {
+		case "message_start":
+			usage["message_start"] = event["message"].(map[string]interface{})["usage"].(map[string]interface{})
+		case "message_delta":
+			usage["message_delta"] = event["usage"].(map[string]interface{})
+		}
+	}
+
+	// message_start carries the estimate, message_delta the upstream usage
+	assert.Equal(t, float64(42), usage["message_start"]["input_tokens"])
+	assert.Equal(t, float64(30), usage["message_delta"]["input_tokens"])
+	assert.Equal(t, float64(100), usage["message_delta"]["cache_read_input_tokens"])
+	assert.Equal(t, float64(7), usage["message_delta"]["output_tokens"])
+}
+
+// sliceStream is an EventStream over fixed events, failing with err at the end
+type sliceStream[T any] struct {
+	events []T
+	pos    int
+	err    error
+}
+
+func (s *sliceStream[T]) Next() bool {
+	s.pos++
+	return s.pos <= len(s.events)
+}
+
+func (s *sliceStream[T]) Current() T {
+	return s.events[s.pos-1]
+}
+
+func (s *sliceStream[T]) Err() error {
+	return s.err
+}
+
+// unmarshalEvents decodes one JSON event per string
+func unmarshalEvents[T any](t *testing.T, raw ...string) []T {
+	events := make([]T, len(raw))
+	for i, r := range raw {
+		require.NoError(t, json.Unmarshal([]byte(r), &events[i]))
+	}
+	return events
+}
+
+func TestConvertOpenAIToAnthropicStream(t *testing.T) {
+	stream := &sliceStream[openai.ChatCompletionChunk]{events: unmarshalEvents[openai.ChatCompletionChunk](t,
+		`{"id":"c1","object":"chat.completion.chunk","created":1,"model":"m","choices":[{"index":0,"delta":{"role":"assistant","content":"Hi"}}]}`,
+		`{"id":"c1","object":"chat.completion.chunk","created":1,"model":"m","choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}`,
+	)}
+
+	var out strings.Builder
+	require.NoError(t, ConvertOpenAIToAnthropicStream(stream, &out, "m", StreamOptions{}))
+
+	body := out.String()
+	assert.True(t, strings.HasPrefix(body, "event: message_start\ndata: "))
+	assert.Contains(t, body, `"text":"Hi"`)
+	assert.Contains(t, body, `"stop_reason":"end_turn"`)
+	assert.True(t, strings.HasSuffix(body, "data: {\"type\":\"message_stop\"}\n\n"))
+}
+
+func TestConvertOpenAIToAnthropicStreamUpstreamError(t *testing.T) {
+	stream := &sliceStream[openai.ChatCompletionChunk]{err: errors.New(`received error while streaming: {"error":{"message":"Rate limit reached","type":"rate_limit_exceeded"}}`)}
+
+	var out strings.Builder
+	err := ConvertOpenAIToAnthropicStream(stream, &out, "m", StreamOptions{})
+	var upstreamErr *UpstreamError
+	require.ErrorAs(t, err, &upstreamErr)
+	assert.Empty(t, out.String())
+}
+
+func TestOpenAIToAnthropicStreamConverter(t *testing.T) {
+	converter := NewOpenAIToAnthropicStreamConverter("m", StreamOptions{InputTokens: 7})
+	assert.False(t, converter.Started())
+
+	chunk := unmarshalEvents[openai.ChatCompletionChunk](t, `{"id":"c1","choices":[{"index":0,"delta":{"content":"Hi"}}]}`)[0]
+	events := converter.Convert(chunk)
+	require.Len(t, events, 3)
+	assert.Equal(t, "message_start", events[0].Event)
+	assert.Equal(t, "content_block_start", events[1].Event)
+	assert.Equal(t, "content_block_delta", events[2].Event)
+	assert.True(t, converter.Started())
+
+	// Without a finish_reason the message is left open
+	assert.Empty(t, converter.Finish())
+}
@@ -1,11 +1,15 @@
 package adaptor
 
 import (
+	"bufio"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
@@ -14,8 +18,27 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// StreamOptions tunes stream conversion between API styles
+type StreamOptions struct {
+	// IncludeUsage sends OpenAI clients a final chunk with the usage, as stream_options.include_usage asks
+	IncludeUsage bool
+	// InputTokens is the estimated prompt size reported to Anthropic clients in message_start, before
+	// an OpenAI-style upstream reports usage at the end of the stream
+	InputTokens int64
+	// Heartbeat is how long the upstream may be silent before a keepalive is sent: a ping event to
+	// Anthropic clients, an SSE comment to OpenAI clients. 0 sends none.
+	Heartbeat time.Duration
+}
+
 // HandleAnthropicToOpenAIStreamResponse processes Anthropic streaming events and converts them to OpenAI format
 func HandleAnthropicToOpenAIStreamResponse(c *gin.Context, stream *anthropicstream.Stream[anthropic.MessageStreamEventUnion], responseModel string) error {
+	return HandleAnthropicToOpenAIStreamResponseWithOptions(c, stream, responseModel, StreamOptions{})
+}
+
+// HandleAnthropicToOpenAIStreamResponseWithOptions processes Anthropic streaming events and converts them
+// to OpenAI format, ending with a usage chunk when opts.IncludeUsage is set. An upstream failing before
+// it sent anything is returned as an *UpstreamError.
+func HandleAnthropicToOpenAIStreamResponseWithOptions(c *gin.Context, stream *anthropicstream.Stream[anthropic.MessageStreamEventUnion], responseModel string, opts StreamOptions) error {
 	logrus.Info("Starting Anthropic to OpenAI streaming response handler")
 	defer func() {
 		if r := recover(); r != nil {
@@ -38,154 +61,272 @@ func HandleAnthropicToOpenAIStreamResponse(c *gin.Context, stream *anthropicstre
 		logrus.Info("Finished Anthropic to OpenAI streaming response handler")
 	}()
 
-	// Set SSE headers
-	c.Header("Content-Type", "text/event-stream")
-	c.Header("Cache-Control", "no-cache")
-	c.Header("Connection", "keep-alive")
-	c.Header("Access-Control-Allow-Origin", "*")
-	c.Header("Access-Control-Allow-Headers", "Cache-Control")
+	if _, err := startOpenAIStream(c); err != nil {
+		return err
+	}
 
-	// Create a flusher to ensure immediate sending of data
-	flusher, ok := c.Writer.(http.Flusher)
-	if !ok {
+	err := ConvertAnthropicToOpenAIStream(stream, c.Writer, responseModel, opts)
+	var upstreamErr *UpstreamError
+	if errors.As(err, &upstreamErr) {
+		return upstreamErr
+	}
+	if err != nil {
+		// The client went away; there is no one left to answer
+		logrus.Errorf("Error writing OpenAI stream: %v", err)
+	}
+	return nil
+}
 
-		return errors.New("Streaming not supported by this connection")
+// AnthropicToOpenAIStreamConverter converts the events of an Anthropic stream into OpenAI chat
+// completion chunks, one event at a time
+type AnthropicToOpenAIStreamConverter struct {
+	decoder *anthropicStreamDecoder
+	encoder *openAIStreamEncoder
+	opts    StreamOptions
+	done    bool
+}
+
+// NewAnthropicToOpenAIStreamConverter creates a converter whose chunks name responseModel
+func NewAnthropicToOpenAIStreamConverter(responseModel string, opts StreamOptions) *AnthropicToOpenAIStreamConverter {
+	return &AnthropicToOpenAIStreamConverter{
+		decoder: newAnthropicStreamDecoder(0),
+		encoder: newOpenAIStreamEncoder(fmt.Sprintf("chatcmpl-%d", time.Now().Unix()), time.Now().Unix(), responseModel),
+		opts:    opts,
 	}
+}
 
-	// Track streaming state
-	var (
-		chatID      = fmt.Sprintf("chatcmpl-%d", time.Now().Unix())
-		created     = time.Now().Unix()
-		contentText = strings.Builder{}
-		usage       *anthropic.MessageDeltaUsage
-	)
+// Convert returns the chunks of one event, ending with the usage chunk when opts.IncludeUsage is
+// set and the event completes the message
+func (s *AnthropicToOpenAIStreamConverter) Convert(event anthropic.MessageStreamEventUnion) []map[string]interface{} {
+	events := s.decoder.decode(event)
+	chunks := s.encoder.encode(events)
+	if len(events) > 0 && events[len(events)-1].Type == EventStop {
+		s.done = true
+		if usage := s.encoder.usageChunk(); s.opts.IncludeUsage && usage != nil {
+			chunks = append(chunks, usage)
+		}
+	}
+	return chunks
+}
+
+// Done reports whether the message is complete
+func (s *AnthropicToOpenAIStreamConverter) Done() bool {
+	return s.done
+}
+
+// ConvertAnthropicToOpenAIStream reads Anthropic events from stream and writes them to w as OpenAI
+// chunks, flushing each when w supports it, and ends with [DONE] once the message is complete. An
+// upstream failing before anything was written is returned as an *UpstreamError, later failures are
+// written as an error chunk. SSE comments are written while the upstream is silent for opts.Heartbeat.
+func ConvertAnthropicToOpenAIStream(stream EventStream[anthropic.MessageStreamEventUnion], w io.Writer, responseModel string, opts StreamOptions) error {
+	heartbeat := StartHeartbeat(w, opts.Heartbeat, SSEKeepalive)
+	defer heartbeat.Stop()
+	w = heartbeat
 
-	// Process the stream
+	converter := NewAnthropicToOpenAIStreamConverter(responseModel, opts)
 	for stream.Next() {
-		event := stream.Current()
-
-		// Handle different event types
-		switch event.Type {
-		case "message_start":
-			// Send initial chat completion chunk
-			chunk := map[string]interface{}{
-				"id":      chatID,
-				"object":  "chat.completion.chunk",
-				"created": created,
-				"model":   responseModel,
-				"choices": []map[string]interface{}{
-					{
-						"index":         0,
-						"delta":         map[string]interface{}{"role": "assistant"},
-						"finish_reason": nil,
-					},
-				},
+		for _, chunk := range converter.Convert(stream.Current()) {
+			if err := writeSSEData(w, chunk); err != nil {
+				return err
 			}
-			sendOpenAIStreamChunk(c, chunk, flusher)
-
-		case "content_block_start":
-			// Content block starting (usually text)
-			if event.ContentBlock.Type == "text" {
-				// Reset content builder for new block
-				contentText.Reset()
+		}
+		if converter.Done() {
+			if err := writeSSEDone(w); err != nil {
+				return err
 			}
+			flush(w)
+			return nil
+		}
+		flush(w)
+	}
 
-		case "content_block_delta":
-			// Text delta - send as OpenAI chunk
-			if event.Delta.Type == "text_delta" && event.Delta.Text != "" {
-				text := event.Delta.Text
-				contentText.WriteString(text)
-
-				chunk := map[string]interface{}{
-					"id":      chatID,
-					"object":  "chat.completion.chunk",
-					"created": created,
-					"model":   responseModel,
-					"choices": []map[string]interface{}{
-						{
-							"index":         0,
-							"delta":         map[string]interface{}{"content": text},
-							"finish_reason": nil,
-						},
-					},
-				}
-				sendOpenAIStreamChunk(c, chunk, flusher)
+	heartbeat.Stop()
+	if err := stream.Err(); err != nil {
+		if !heartbeat.Written() {
+			// Nothing was sent yet, so the caller can still respond with the upstream status
+			return ParseUpstreamError(err)
+		}
+		return writeOpenAIStreamError(w, err)
+	}
+	return nil
+}
+
+// HandleAnthropicToOpenAIMultiStreamResponse merges parallel Anthropic streams into one OpenAI stream,
+// the choice index of each chunk being the index of its stream. With opts.IncludeUsage, usage of all
+// streams is summed and sent in a final chunk. Keepalives are sent while all streams are silent for
+// opts.Heartbeat. cancel aborts the upstream requests of all streams: the first stream failure or
+// client write failure calls it, so the other choices stop generating. The first stream failure is
+// returned as an *UpstreamError when nothing was sent yet, or sent as an error chunk otherwise.
+func HandleAnthropicToOpenAIMultiStreamResponse(c *gin.Context, streams []*anthropicstream.Stream[anthropic.MessageStreamEventUnion], cancel context.CancelFunc, responseModel string, opts StreamOptions) error {
+	logrus.Infof("Starting Anthropic to OpenAI streaming response handler for %d choices", len(streams))
+	defer func() {
+		for _, stream := range streams {
+			if err := stream.Close(); err != nil {
+				logrus.Errorf("Error closing Anthropic stream: %v", err)
 			}
+		}
+		logrus.Info("Finished Anthropic to OpenAI streaming response handler")
+	}()
 
-		case "content_block_stop":
-			// Content block finished - no specific action needed
+	flusher, err := startOpenAIStream(c)
+	if err != nil {
+		return err
+	}
 
-		case "message_delta":
-			// Message delta (includes usage info)
-			if event.Usage.InputTokens != 0 || event.Usage.OutputTokens != 0 {
-				usage = &event.Usage
-			}
+	// Keepalives go through the same writer as the chunks, until all streams ended
+	heartbeat := StartHeartbeat(c.Writer, opts.Heartbeat, SSEKeepalive)
+	defer heartbeat.Stop()
 
-		case "message_stop":
-			// Send final chunk with finish_reason and usage
-			chunk := map[string]interface{}{
-				"id":      chatID,
-				"object":  "chat.completion.chunk",
-				"created": created,
-				"model":   responseModel,
-				"choices": []map[string]interface{}{
-					{
-						"index":         0,
-						"delta":         map[string]interface{}{},
-						"finish_reason": "stop",
-					},
-				},
+	var (
+		encoder = newOpenAIStreamEncoder(fmt.Sprintf("chatcmpl-%d", time.Now().Unix()), time.Now().Unix(), responseModel)
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		// failed is the first stream failure and failedChoice its stream; writeErr is set once the
+		// client went away. Both are guarded by mu.
+		failed       error
+		failedChoice int
+		writeErr     error
+	)
+	fail := func(i int, err error) {
+		mu.Lock()
+		if failed == nil && writeErr == nil {
+			failed, failedChoice = err, i
+		}
+		mu.Unlock()
+		cancel()
+	}
+	// The encoder is shared by all choices, so each event is encoded and sent under the lock
+	emit := func(events []StreamEvent) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if writeErr != nil {
+			return writeErr
+		}
+		for _, chunk := range encoder.encode(events) {
+			if err := writeSSEData(heartbeat, chunk); err != nil {
+				writeErr = err
+				return err
 			}
+			heartbeat.Flush()
+		}
+		return nil
+	}
 
-			// Add usage if available
-			if usage != nil {
-				chunk["usage"] = map[string]interface{}{
-					"prompt_tokens":     usage.InputTokens,
-					"completion_tokens": usage.OutputTokens,
-					"total_tokens":      usage.InputTokens + usage.OutputTokens,
+	for i, stream := range streams {
+		wg.Add(1)
+		go func(i int, stream *anthropicstream.Stream[anthropic.MessageStreamEventUnion]) {
+			defer wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					logrus.Errorf("Panic in Anthropic to OpenAI streaming handler for choice %d: %v", i, r)
+					fail(i, fmt.Errorf("internal streaming error"))
+				}
+			}()
+			decoder := newAnthropicStreamDecoder(i)
+			for stream.Next() {
+				events := decoder.decode(stream.Current())
+				if err := emit(events); err != nil {
+					// No one is left to read any choice
+					cancel()
+					return
+				}
+				if len(events) > 0 && events[len(events)-1].Type == EventStop {
+					return
 				}
 			}
-
-			sendOpenAIStreamChunk(c, chunk, flusher)
-			// Send final [DONE] message
-			c.Writer.Write([]byte("data: [DONE]\n\n"))
-			flusher.Flush()
-			return nil
-		}
+			if err := stream.Err(); err != nil {
+				fail(i, err)
+			}
+		}(i, stream)
 	}
+	wg.Wait()
+	heartbeat.Stop()
 
-	// Check for stream errors
-	if err := stream.Err(); err != nil {
-		logrus.Errorf("Anthropic stream error: %v", err)
-		// Send error event
-		errorChunk := map[string]interface{}{
-			"error": map[string]interface{}{
-				"message": err.Error(),
-				"type":    "stream_error",
-				"code":    "stream_failed",
-			},
-		}
-		errorJSON, marshalErr := json.Marshal(errorChunk)
-		if marshalErr != nil {
-			logrus.Errorf("Failed to marshal error chunk: %v", marshalErr)
-			c.Writer.Write([]byte("data: {\"error\":{\"message\":\"Failed to marshal error\",\"type\":\"internal_error\"}}\n\n"))
-		} else {
-			c.Writer.Write([]byte(fmt.Sprintf("data: %s\n\n", string(errorJSON))))
-		}
-		flusher.Flush()
+	if writeErr != nil {
+		logrus.Errorf("Error writing OpenAI stream: %v", writeErr)
+		return nil
+	}
+	if failed != nil {
+		logrus.Errorf("Anthropic stream error for choice %d", failedChoice)
+		if !c.Writer.Written() {
+			return ParseUpstreamError(failed)
+		}
+		SendOpenAIStreamError(c, failed, flusher)
 		return nil
 	}
 
+	if usage := encoder.usageChunk(); opts.IncludeUsage && usage != nil {
+		sendOpenAIStreamChunk(c, usage, flusher)
+	}
+	c.Writer.Write([]byte("data: [DONE]\n\n"))
+	flusher.Flush()
 	return nil
 }
 
+// startOpenAIStream sets the SSE headers and returns the flusher of the connection
+func startOpenAIStream(c *gin.Context) (http.Flusher, error) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("Access-Control-Allow-Origin", "*")
+	c.Header("Access-Control-Allow-Headers", "Cache-Control")
+
+	// Create a flusher to ensure immediate sending of data
+	flusher, ok := c.Writer.(http.Flusher)
+	if !ok {
+		return nil, errors.New("Streaming not supported by this connection")
+	}
+	return flusher, nil
+}
+
 // sendOpenAIStreamChunk helper function to send a chunk in OpenAI format
 func sendOpenAIStreamChunk(c *gin.Context, chunk map[string]interface{}, flusher http.Flusher) {
-	chunkJSON, err := json.Marshal(chunk)
-	if err != nil {
-		logrus.Errorf("Failed to marshal OpenAI stream chunk: %v", err)
+	if err := writeSSEData(c.Writer, chunk); err != nil {
+		logrus.Errorf("Failed to write OpenAI stream chunk: %v", err)
 		return
 	}
-	c.Writer.Write([]byte(fmt.Sprintf("data: %s\n\n", string(chunkJSON))))
 	flusher.Flush()
 }
+
+// ConvertGeminiToOpenAIStream reads a Gemini streamGenerateContent SSE stream (alt=sse) and
+// writes OpenAI chat completion chunks, ending with a usage chunk when includeUsage is set
+// and the [DONE] marker
+func ConvertGeminiToOpenAIStream(r io.Reader, w io.Writer, responseModel string, includeUsage bool) error {
+	decoder := newGeminiStreamDecoder()
+	encoder := newOpenAIStreamEncoder(fmt.Sprintf("chatcmpl-%d", time.Now().UnixNano()), time.Now().Unix(), responseModel)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		var geminiResp GeminiResponse
+		if err := json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(line, "data:"))), &geminiResp); err != nil {
+			return fmt.Errorf("invalid gemini stream chunk: %w", err)
+		}
+		if geminiResp.Error != nil {
+			streamErr := geminiStreamError(geminiResp.Error)
+			countStreamError(streamErr)
+			if err := writeSSEData(w, streamErr.OpenAIChunk()); err != nil {
+				return err
+			}
+			return writeSSEDone(w)
+		}
+		for _, chunk := range encoder.encode(decoder.decode(&geminiResp)) {
+			if err := writeSSEData(w, chunk); err != nil {
+				return err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if usage := encoder.usageChunk(); includeUsage && usage != nil {
+		if err := writeSSEData(w, usage); err != nil {
+			return err
+		}
+	}
+	return writeSSEDone(w)
+}
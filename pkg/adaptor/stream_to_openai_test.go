@@ -10,6 +10,7 @@ import (
 
 	"github.com/anthropics/anthropic-sdk-go"
 	anthropicOption "github.com/anthropics/anthropic-sdk-go/option"
+	anthropicstream "github.com/anthropics/anthropic-sdk-go/packages/ssestream"
 	"github.com/gin-gonic/gin"
 	"github.com/openai/openai-go/v3"
 	"github.com/stretchr/testify/assert"
@@ -121,3 +122,141 @@ func TestSendOpenAIStreamChunk(t *testing.T) {
 	assert.Contains(t, body, `"id":"test-id"`)
 	assert.Contains(t, body, `"object":"chat.completion.chunk"`)
 }
+
+func TestHandleAnthropicToOpenAIMultiStreamResponse(t *testing.T) {
+	server := sseServer(`event: message_start
+data: {"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude","content":[],"usage":{"input_tokens":10,"output_tokens":1}}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Sample"}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":0}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":4}}
+
+event: message_stop
+data: {"type":"message_stop"}
+
+`)
+	defer server.Close()
+
+	client := anthropic.NewClient(anthropicOption.WithBaseURL(server.URL), anthropicOption.WithAPIKey("test"))
+	streams := make([]*anthropicstream.Stream[anthropic.MessageStreamEventUnion], 3)
+	for i := range streams {
+		streams[i] = client.Messages.NewStreaming(context.Background(), anthropic.MessageNewParams{Model: "claude", MaxTokens: 10})
+	}
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	require.NoError(t, HandleAnthropicToOpenAIMultiStreamResponse(c, streams, func() {}, "claude", StreamOptions{IncludeUsage: true}))
+
+	var (
+		content = map[int]string{}
+		finish  = map[int]interface{}{}
+		usage   map[string]interface{}
+	)
+	lines := strings.Split(w.Body.String(), "\n")
+	for _, line := range lines {
+		data := strings.TrimPrefix(line, "data: ")
+		if data == line || data == "[DONE]" {
+			continue
+		}
+		var chunk map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(data), &chunk))
+		if u, ok := chunk["usage"].(map[string]interface{}); ok {
+			usage = u
+		}
+		for _, ch := range chunk["choices"].([]interface{}) {
+			choice := ch.(map[string]interface{})
+			index := int(choice["index"].(float64))
+			if text, ok := choice["delta"].(map[string]interface{})["content"].(string); ok {
+				content[index] += text
+			}
+			if choice["finish_reason"] != nil {
+				finish[index] = choice["finish_reason"]
+			}
+		}
+	}
+
+	assert.Equal(t, map[int]string{0: "Sample", 1: "Sample", 2: "Sample"}, content)
+	assert.Len(t, finish, 3)
+	require.NotNil(t, usage)
+	assert.Equal(t, float64(30), usage["prompt_tokens"])
+	assert.Equal(t, float64(12), usage["completion_tokens"])
+	assert.Equal(t, 1, strings.Count(w.Body.String(), "[DONE]"))
+}
+
+func TestAnthropicToOpenAIStreamIncludeUsage(t *testing.T) {
+	body := `event: message_start
+data: {"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude","content":[],"usage":{"input_tokens":10,"output_tokens":1,"cache_creation_input_tokens":20}}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hi"}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":0}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":5}}
+
+event: message_stop
+data: {"type":"message_stop"}
+
+`
+	run := func(opts StreamOptions) string {
+		server := sseServer(body)
+		defer server.Close()
+		client := anthropic.NewClient(anthropicOption.WithBaseURL(server.URL), anthropicOption.WithAPIKey("test"))
+		stream := client.Messages.NewStreaming(context.Background(), anthropic.MessageNewParams{Model: "claude", MaxTokens: 10})
+
+		gin.SetMode(gin.TestMode)
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		require.NoError(t, HandleAnthropicToOpenAIStreamResponseWithOptions(c, stream, "claude", opts))
+		return w.Body.String()
+	}
+
+	assert.NotContains(t, run(StreamOptions{}), `"usage"`)
+
+	out := run(StreamOptions{IncludeUsage: true})
+	chunks := strings.Split(strings.TrimSpace(out), "\n\n")
+	require.GreaterOrEqual(t, len(chunks), 2)
+	assert.Equal(t, "data: [DONE]", chunks[len(chunks)-1])
+
+	var last map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(chunks[len(chunks)-2], "data: ")), &last))
+	assert.Empty(t, last["choices"])
+	usage := last["usage"].(map[string]interface{})
+	assert.Equal(t, float64(30), usage["prompt_tokens"])
+	assert.Equal(t, float64(5), usage["completion_tokens"])
+	assert.Equal(t, float64(20), usage["cache_creation_input_tokens"])
+}
+
+func TestConvertAnthropicToOpenAIStream(t *testing.T) {
+	stream := &sliceStream[anthropic.MessageStreamEventUnion]{events: unmarshalEvents[anthropic.MessageStreamEventUnion](t,
+		`{"type":"message_start","message":{"id":"msg_1","usage":{"input_tokens":10}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hi"}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":2}}`,
+		`{"type":"message_stop"}`,
+	)}
+
+	var out strings.Builder
+	require.NoError(t, ConvertAnthropicToOpenAIStream(stream, &out, "gpt-4o", StreamOptions{IncludeUsage: true}))
+
+	body := out.String()
+	assert.Contains(t, body, `"content":"Hi"`)
+	assert.Contains(t, body, `"finish_reason":"stop"`)
+	assert.Contains(t, body, `"prompt_tokens":10`)
+	assert.True(t, strings.HasSuffix(body, "data: [DONE]\n\n"))
+}
@@ -0,0 +1,318 @@
+package adaptor
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/openai/openai-go/v3"
+)
+
+// StructuredOutputToolName is the synthetic tool used to emulate OpenAI response_format on
+// Anthropic-style providers; its input is returned to the client as the message content
+const StructuredOutputToolName = "structured_output"
+
+const (
+	structuredOutputDescription = "Return the final response as JSON matching the input schema."
+	structuredOutputInstruction = "When you have the final answer, respond by calling the " + StructuredOutputToolName + " tool with it."
+)
+
+// StructuredOutputSchema returns the JSON schema requested by an OpenAI response_format:
+// the given schema for json_schema and any object for json_object
+func StructuredOutputSchema(req *openai.ChatCompletionNewParams) (map[string]interface{}, bool) {
+	switch {
+	case req.ResponseFormat.OfJSONSchema != nil:
+		schema := map[string]interface{}{}
+		if raw, err := json.Marshal(req.ResponseFormat.OfJSONSchema.JSONSchema.Schema); err == nil {
+			_ = json.Unmarshal(raw, &schema)
+		}
+		if schema == nil {
+			schema = map[string]interface{}{}
+		}
+		if _, ok := schema["type"]; !ok {
+			schema["type"] = "object"
+		}
+		return schema, true
+	case req.ResponseFormat.OfJSONObject != nil:
+		return map[string]interface{}{"type": "object"}, true
+	}
+	return nil, false
+}
+
+// applyStructuredOutput adds the synthetic tool to an Anthropic request and makes the model call
+// it. With other tools the model must call one of them; extended thinking does not allow forcing
+// a tool, so there the model is only instructed to use it.
+func applyStructuredOutput(rf *ResponseFormat, params *anthropic.MessageNewParams) {
+	if rf == nil {
+		return
+	}
+
+	description := structuredOutputDescription
+	if rf.Description != "" {
+		description = rf.Description
+	}
+	params.Tools = append(params.Tools, anthropic.ToolUnionParam{OfTool: &anthropic.ToolParam{
+		Name:        StructuredOutputToolName,
+		Description: anthropic.String(description),
+		InputSchema: anthropicInputSchema(rf.Schema),
+	}})
+
+	switch {
+	case params.Thinking.OfEnabled != nil:
+		params.ToolChoice = anthropic.ToolChoiceUnionParam{OfAuto: &anthropic.ToolChoiceAutoParam{}}
+		params.System = append(params.System, anthropic.TextBlockParam{Text: structuredOutputInstruction})
+	case params.ToolChoice.OfTool != nil:
+		// The client forced one of its own tools
+	case len(params.Tools) > 1:
+		params.ToolChoice = anthropic.ToolChoiceUnionParam{OfAny: &anthropic.ToolChoiceAnyParam{}}
+	default:
+		params.ToolChoice = anthropic.ToolChoiceParamOfTool(StructuredOutputToolName)
+	}
+}
+
+// anthropicInputSchema converts a JSON schema into a tool input schema, keeping keywords such as
+// $defs and additionalProperties as extra fields
+func anthropicInputSchema(schema map[string]interface{}) anthropic.ToolInputSchemaParam {
+	param := anthropic.ToolInputSchemaParam{Properties: schema["properties"]}
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, name := range required {
+			if s, ok := name.(string); ok {
+				param.Required = append(param.Required, s)
+			}
+		}
+	}
+	extra := map[string]any{}
+	for key, value := range schema {
+		if key != "type" && key != "properties" && key != "required" {
+			extra[key] = value
+		}
+	}
+	if len(extra) > 0 {
+		param.ExtraFields = extra
+	}
+	return param
+}
+
+// ValidateStructuredOutput checks that an Anthropic response holds structured output matching the
+// schema, either as a call to the synthetic tool or as plain JSON text
+func ValidateStructuredOutput(resp *anthropic.Message, schema map[string]interface{}) error {
+	var text strings.Builder
+	for _, block := range resp.Content {
+		switch {
+		case block.Type == "tool_use" && block.Name == StructuredOutputToolName:
+			var value interface{}
+			if err := json.Unmarshal(block.Input, &value); err != nil {
+				return fmt.Errorf("invalid JSON: %w", err)
+			}
+			return validateJSONSchema(schema, schema, value, "$")
+		case block.Type == "tool_use":
+			// The model called a client tool; the output comes on a later turn
+			return nil
+		case block.Type == "text":
+			text.WriteString(block.Text)
+		}
+	}
+	if resp.StopReason == anthropic.StopReasonMaxTokens {
+		return nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(text.String())), &value); err != nil {
+		return fmt.Errorf("the response did not call the %s tool", StructuredOutputToolName)
+	}
+	return validateJSONSchema(schema, schema, value, "$")
+}
+
+// AppendStructuredOutputRetry appends an invalid response and the validation error to a request,
+// so that the model can correct its output on a second attempt
+func AppendStructuredOutputRetry(params *anthropic.MessageNewParams, resp *anthropic.Message, validationErr error) {
+	// Append to a copy, the messages may be shared with parallel requests for other choices
+	params.Messages = append(params.Messages[:len(params.Messages):len(params.Messages)], resp.ToParam())
+	feedback := fmt.Sprintf("The output does not match the required schema: %v. Call the %s tool again with corrected output.", validationErr, StructuredOutputToolName)
+	for _, block := range resp.Content {
+		if block.Type == "tool_use" && block.Name == StructuredOutputToolName {
+			params.Messages = append(params.Messages, anthropic.NewUserMessage(anthropic.NewToolResultBlock(block.ID, feedback, true)))
+			return
+		}
+	}
+	params.Messages = append(params.Messages, anthropic.NewUserMessage(anthropic.NewTextBlock(feedback)))
+}
+
+// validateJSONSchema validates a value against the JSON schema keywords structured outputs use:
+// type, enum, const, properties, required, additionalProperties, items, anyOf and local $ref
+func validateJSONSchema(root, schema map[string]interface{}, value interface{}, path string) error {
+	if ref, ok := schema["$ref"].(string); ok {
+		resolved, ok := resolveSchemaRef(root, ref)
+		if !ok {
+			return nil
+		}
+		return validateJSONSchema(root, resolved, value, path)
+	}
+
+	if anyOf, ok := schema["anyOf"].([]interface{}); ok && len(anyOf) > 0 {
+		var firstErr error
+		for _, option := range anyOf {
+			optionSchema, _ := option.(map[string]interface{})
+			err := validateJSONSchema(root, optionSchema, value, path)
+			if err == nil {
+				firstErr = nil
+				break
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+		if firstErr != nil {
+			return firstErr
+		}
+	}
+
+	if types := schemaTypes(schema["type"]); len(types) > 0 {
+		matched := false
+		for _, t := range types {
+			if jsonTypeMatches(t, value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("%s: expected %s", path, strings.Join(types, " or "))
+		}
+	}
+
+	if constant, ok := schema["const"]; ok && !jsonEqual(constant, value) {
+		return fmt.Errorf("%s: expected %v", path, constant)
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, option := range enum {
+			if jsonEqual(option, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: %v is not one of %v", path, value, enum)
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, name := range required {
+				if s, ok := name.(string); ok {
+					if _, present := v[s]; !present {
+						return fmt.Errorf("%s: missing required property %q", path, s)
+					}
+				}
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if propSchema, ok := properties[key].(map[string]interface{}); ok {
+				if err := validateJSONSchema(root, propSchema, v[key], path+"."+key); err != nil {
+					return err
+				}
+				continue
+			}
+			switch additional := schema["additionalProperties"].(type) {
+			case bool:
+				if !additional {
+					return fmt.Errorf("%s: unexpected property %q", path, key)
+				}
+			case map[string]interface{}:
+				if err := validateJSONSchema(root, additional, v[key], path+"."+key); err != nil {
+					return err
+				}
+			}
+		}
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				if err := validateJSONSchema(root, items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// resolveSchemaRef resolves a reference such as #/$defs/Item within the root schema
+func resolveSchemaRef(root map[string]interface{}, ref string) (map[string]interface{}, bool) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, false
+	}
+	current := root
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#"), "/") {
+		if part == "" {
+			continue
+		}
+		part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+		next, ok := current[part].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		current = next
+	}
+	return current, true
+}
+
+// schemaTypes returns the types allowed by a schema's type keyword
+func schemaTypes(t interface{}) []string {
+	switch v := t.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var types []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+// jsonTypeMatches reports whether a decoded JSON value has the given JSON schema type
+func jsonTypeMatches(t string, value interface{}) bool {
+	switch t {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == math.Trunc(f)
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	}
+	return true
+}
+
+// jsonEqual compares two decoded JSON values
+func jsonEqual(a, b interface{}) bool {
+	aj, _ := json.Marshal(a)
+	bj, _ := json.Marshal(b)
+	return string(aj) == string(bj)
+}
@@ -0,0 +1,138 @@
+package adaptor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	anthropicOption "github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/gin-gonic/gin"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const weatherSchemaRequest = `{"model":"claude-sonnet-4","messages":[{"role":"user","content":"Weather in Paris?"}],
+	"response_format":{"type":"json_schema","json_schema":{"name":"weather","strict":true,"schema":{
+		"type":"object","additionalProperties":false,"required":["city","temperature","conditions"],
+		"properties":{"city":{"type":"string"},"temperature":{"type":"integer"},
+			"conditions":{"type":"array","items":{"$ref":"#/$defs/condition"}}},
+		"$defs":{"condition":{"type":"string","enum":["sunny","cloudy","rain"]}}}}}}`
+
+func parseOpenAIRequest(t *testing.T, body string) *openai.ChatCompletionNewParams {
+	var req openai.ChatCompletionNewParams
+	require.NoError(t, json.Unmarshal([]byte(body), &req))
+	return &req
+}
+
+func TestStructuredOutputRequest(t *testing.T) {
+	params := ConvertOpenAIToAnthropicRequest(parseOpenAIRequest(t, weatherSchemaRequest), 1024)
+	require.Len(t, params.Tools, 1)
+	tool := params.Tools[0].OfTool
+	assert.Equal(t, StructuredOutputToolName, tool.Name)
+	assert.Equal(t, []string{"city", "temperature", "conditions"}, tool.InputSchema.Required)
+	assert.Contains(t, tool.InputSchema.ExtraFields, "$defs")
+	assert.Equal(t, false, tool.InputSchema.ExtraFields["additionalProperties"])
+	require.NotNil(t, params.ToolChoice.OfTool)
+	assert.Equal(t, StructuredOutputToolName, params.ToolChoice.OfTool.Name)
+
+	// With client tools the model may call those or return the output
+	req := parseOpenAIRequest(t, `{"model":"m","messages":[{"role":"user","content":"hi"}],
+		"tools":[{"type":"function","function":{"name":"lookup","parameters":{"type":"object"}}}],
+		"response_format":{"type":"json_object"}}`)
+	params = ConvertOpenAIToAnthropicRequest(req, 1024)
+	require.Len(t, params.Tools, 2)
+	assert.NotNil(t, params.ToolChoice.OfAny)
+
+	// Extended thinking cannot force a tool
+	req = parseOpenAIRequest(t, `{"model":"m","max_tokens":8000,"reasoning_effort":"low","messages":[{"role":"user","content":"hi"}],
+		"response_format":{"type":"json_object"}}`)
+	params = ConvertOpenAIToAnthropicRequest(req, 1024)
+	assert.NotNil(t, params.ToolChoice.OfAuto)
+	require.Len(t, params.System, 1)
+	assert.Contains(t, params.System[0].Text, StructuredOutputToolName)
+}
+
+func TestStructuredOutputResponse(t *testing.T) {
+	var resp anthropic.Message
+	require.NoError(t, json.Unmarshal([]byte(`{"id":"msg_1","type":"message","role":"assistant","model":"claude",
+		"stop_reason":"tool_use","usage":{"input_tokens":10,"output_tokens":5},
+		"content":[{"type":"tool_use","id":"toolu_1","name":"structured_output",
+			"input":{"city":"Paris","temperature":21,"conditions":["sunny"]}}]}`), &resp))
+
+	result := ConvertAnthropicToOpenAIResponse(&resp, "claude")
+	choice := result["choices"].([]map[string]interface{})[0]
+	message := choice["message"].(map[string]interface{})
+	assert.JSONEq(t, `{"city":"Paris","temperature":21,"conditions":["sunny"]}`, message["content"].(string))
+	assert.NotContains(t, message, "tool_calls")
+	assert.Equal(t, "stop", choice["finish_reason"])
+}
+
+func TestValidateStructuredOutput(t *testing.T) {
+	schema, ok := StructuredOutputSchema(parseOpenAIRequest(t, weatherSchemaRequest))
+	require.True(t, ok)
+
+	message := func(input string) *anthropic.Message {
+		var resp anthropic.Message
+		require.NoError(t, json.Unmarshal([]byte(`{"id":"msg_1","type":"message","role":"assistant","stop_reason":"tool_use",
+			"content":[{"type":"tool_use","id":"toolu_1","name":"structured_output","input":`+input+`}]}`), &resp))
+		return &resp
+	}
+
+	assert.NoError(t, ValidateStructuredOutput(message(`{"city":"Paris","temperature":21,"conditions":["sunny","rain"]}`), schema))
+	assert.ErrorContains(t, ValidateStructuredOutput(message(`{"city":"Paris","temperature":21.5,"conditions":[]}`), schema), "$.temperature")
+	assert.ErrorContains(t, ValidateStructuredOutput(message(`{"city":"Paris","temperature":21,"conditions":["snow"]}`), schema), "$.conditions[0]")
+	assert.ErrorContains(t, ValidateStructuredOutput(message(`{"city":"Paris","conditions":[]}`), schema), "temperature")
+	assert.ErrorContains(t, ValidateStructuredOutput(message(`{"city":"Paris","temperature":1,"conditions":[],"x":1}`), schema), `"x"`)
+
+	// Invalid output is sent back as an error tool result
+	invalid := message(`{"city":"Paris"}`)
+	params := ConvertOpenAIToAnthropicRequest(parseOpenAIRequest(t, weatherSchemaRequest), 1024)
+	AppendStructuredOutputRetry(&params, invalid, ValidateStructuredOutput(invalid, schema))
+	require.Len(t, params.Messages, 3)
+	result := params.Messages[2].Content[0].OfToolResult
+	require.NotNil(t, result)
+	assert.Equal(t, "toolu_1", result.ToolUseID)
+	assert.True(t, result.IsError.Value)
+}
+
+func TestStructuredOutputStream(t *testing.T) {
+	server := sseServer(`event: message_start
+data: {"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude","content":[],"usage":{"input_tokens":5,"output_tokens":0}}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"toolu_1","name":"structured_output","input":{}}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{\"city\": "}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"\"Paris\"}"}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":0}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":3}}
+
+event: message_stop
+data: {"type":"message_stop"}
+
+`)
+	defer server.Close()
+
+	client := anthropic.NewClient(anthropicOption.WithBaseURL(server.URL), anthropicOption.WithAPIKey("test"))
+	stream := client.Messages.NewStreaming(context.Background(), anthropic.MessageNewParams{Model: "claude", MaxTokens: 10})
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	require.NoError(t, HandleAnthropicToOpenAIStreamResponse(c, stream, "claude"))
+
+	out := w.Body.String()
+	assert.Contains(t, out, `"content":"{\"city\": "`)
+	assert.Contains(t, out, `"content":"\"Paris\"}"`)
+	assert.Contains(t, out, `"finish_reason":"stop"`)
+}
@@ -0,0 +1,154 @@
+package adaptor
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync/atomic"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// ToolCallRepair names a fix applied to a tool call from an OpenAI-compatible upstream
+type ToolCallRepair string
+
+const (
+	// RepairUnterminatedJSON closes arguments cut off mid-object
+	RepairUnterminatedJSON ToolCallRepair = "unterminated_json"
+	// RepairTrailingData drops data after complete arguments, such as arguments sent twice
+	RepairTrailingData ToolCallRepair = "trailing_data"
+	// RepairInvalidJSON replaces arguments beyond repair with an empty object
+	RepairInvalidJSON ToolCallRepair = "invalid_json"
+	// RepairSplitArguments joins arguments arriving under a new index to the previous call
+	RepairSplitArguments ToolCallRepair = "split_arguments"
+	// RepairMissingID makes up an ID for a call without one
+	RepairMissingID ToolCallRepair = "missing_id"
+	// RepairDuplicateID makes up an ID for a call reusing the ID of an earlier call
+	RepairDuplicateID ToolCallRepair = "duplicate_id"
+)
+
+// toolCallRepairs counts each repair since the process started
+var toolCallRepairs = map[ToolCallRepair]*atomic.Int64{
+	RepairUnterminatedJSON: {},
+	RepairTrailingData:     {},
+	RepairInvalidJSON:      {},
+	RepairSplitArguments:   {},
+	RepairMissingID:        {},
+	RepairDuplicateID:      {},
+}
+
+// ToolCallRepairCounts returns how often each tool call repair occurred
+func ToolCallRepairCounts() map[ToolCallRepair]int64 {
+	counts := make(map[ToolCallRepair]int64, len(toolCallRepairs))
+	for repair, count := range toolCallRepairs {
+		counts[repair] = count.Load()
+	}
+	return counts
+}
+
+// countToolCallRepair records a repair of the named tool call
+func countToolCallRepair(repair ToolCallRepair, tool string) {
+	toolCallRepairs[repair].Add(1)
+	logrus.Debugf("Repaired tool call %s: %s", tool, repair)
+}
+
+// newToolUseID makes up a tool_use ID
+func newToolUseID() string {
+	return "toolu_" + strings.ReplaceAll(uuid.NewString(), "-", "")[:24]
+}
+
+// RepairToolArguments returns tool call arguments as a JSON object, closing unterminated JSON and
+// dropping trailing data. Arguments beyond repair become an empty object. Empty arguments stay
+// empty. The repair is empty when the arguments were valid.
+func RepairToolArguments(args string) (string, ToolCallRepair) {
+	trimmed := strings.TrimSpace(args)
+	if trimmed == "" {
+		return "", ""
+	}
+	if isJSONObject(trimmed) {
+		return trimmed, ""
+	}
+
+	// A complete object followed by more data
+	var first json.RawMessage
+	if json.NewDecoder(strings.NewReader(trimmed)).Decode(&first) == nil {
+		if isJSONObject(string(first)) {
+			return string(first), RepairTrailingData
+		}
+		return "{}", RepairInvalidJSON
+	}
+
+	if closed, ok := closeJSON(trimmed); ok {
+		return closed, RepairUnterminatedJSON
+	}
+	return "{}", RepairInvalidJSON
+}
+
+// isJSONObject reports whether s is exactly one JSON object
+func isJSONObject(s string) bool {
+	var object map[string]json.RawMessage
+	return json.Unmarshal([]byte(s), &object) == nil && object != nil
+}
+
+// closeJSON completes JSON that was cut off, closing the open string, arrays and objects and
+// finishing a dangling key or value
+func closeJSON(s string) (string, bool) {
+	var closers []byte
+	inString, escaped := false, false
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case ch == '\\':
+				escaped = true
+			case ch == '"':
+				inString = false
+			}
+			continue
+		}
+		switch ch {
+		case '"':
+			inString = true
+		case '{':
+			closers = append(closers, '}')
+		case '[':
+			closers = append(closers, ']')
+		case '}', ']':
+			if len(closers) == 0 || closers[len(closers)-1] != ch {
+				return "", false
+			}
+			closers = closers[:len(closers)-1]
+		}
+	}
+	if len(closers) == 0 || closers[0] != '}' {
+		return "", false
+	}
+
+	var base bytes.Buffer
+	if inString {
+		if escaped {
+			// Drop the escape that was cut off
+			s = s[:len(s)-1]
+		}
+		base.WriteString(s)
+		base.WriteByte('"')
+	} else {
+		base.WriteString(strings.TrimRight(s, " \t\r\n"))
+		base.Truncate(len(strings.TrimSuffix(base.String(), ",")))
+	}
+
+	var tail bytes.Buffer
+	for i := len(closers) - 1; i >= 0; i-- {
+		tail.WriteByte(closers[i])
+	}
+	for _, middle := range []string{"", "null", ":null"} {
+		candidate := base.String() + middle + tail.String()
+		if isJSONObject(candidate) {
+			return candidate, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,45 @@
+package adaptor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepairToolArguments(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     string
+		expected string
+		repair   ToolCallRepair
+	}{
+		{"valid", `{"a":1}`, `{"a":1}`, ""},
+		{"empty", "  ", "", ""},
+		{"unterminated string", `{"path":"main.go`, `{"path":"main.go"}`, RepairUnterminatedJSON},
+		{"unterminated array", `{"ids":[1,2`, `{"ids":[1,2]}`, RepairUnterminatedJSON},
+		{"trailing comma", `{"a":1, `, `{"a":1}`, RepairUnterminatedJSON},
+		{"dangling key", `{"a":1,"b"`, `{"a":1,"b":null}`, RepairUnterminatedJSON},
+		{"dangling colon", `{"a":`, `{"a":null}`, RepairUnterminatedJSON},
+		{"cut escape", `{"a":"x\`, `{"a":"x"}`, RepairUnterminatedJSON},
+		{"repeated", `{"a":1}{"a":1}`, `{"a":1}`, RepairTrailingData},
+		{"not an object", `[1,2]`, `{}`, RepairInvalidJSON},
+		{"garbage", `{"a":tru`, `{}`, RepairInvalidJSON},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, repair := RepairToolArguments(tt.args)
+			assert.Equal(t, tt.expected, args)
+			assert.Equal(t, tt.repair, repair)
+		})
+	}
+}
+
+func TestToolCallRepairCounts(t *testing.T) {
+	before := ToolCallRepairCounts()
+	countToolCallRepair(RepairMissingID, "lookup")
+	countToolCallRepair(RepairMissingID, "lookup")
+	after := ToolCallRepairCounts()
+	assert.Equal(t, before[RepairMissingID]+2, after[RepairMissingID])
+	assert.Equal(t, before[RepairDuplicateID], after[RepairDuplicateID])
+	assert.Len(t, after, 6)
+}
@@ -0,0 +1,260 @@
+package adaptor
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/packages/param"
+	"github.com/sirupsen/logrus"
+)
+
+// SchemaPolicy describes the JSON Schema an upstream accepts for tool parameters. The zero policy
+// leaves schemas alone.
+type SchemaPolicy struct {
+	// DropKeywords are removed wherever they appear as schema keywords
+	DropKeywords []string
+	// AllowedFormats keeps only these string formats; nil keeps every format
+	AllowedFormats []string
+	// OneOfToAnyOf rewrites oneOf as anyOf
+	OneOfToAnyOf bool
+	// ConstToEnum rewrites const as a single value enum
+	ConstToEnum bool
+	// MaxDepth collapses schemas nested deeper than this to their type; 0 means no limit
+	MaxDepth int
+	// MaxDescriptionLength truncates tool and schema descriptions to this many characters; 0 means no limit
+	MaxDescriptionLength int
+}
+
+// IsZero reports whether the policy leaves schemas alone
+func (p SchemaPolicy) IsZero() bool {
+	return len(p.DropKeywords) == 0 && p.AllowedFormats == nil && !p.OneOfToAnyOf && !p.ConstToEnum &&
+		p.MaxDepth == 0 && p.MaxDescriptionLength == 0
+}
+
+// schemaMapKeywords hold a map of names to schemas
+var schemaMapKeywords = map[string]bool{
+	"properties": true, "patternProperties": true, "$defs": true, "definitions": true, "dependentSchemas": true,
+}
+
+// schemaListKeywords hold a list of schemas
+var schemaListKeywords = map[string]bool{
+	"anyOf": true, "oneOf": true, "allOf": true, "prefixItems": true,
+}
+
+// schemaKeywords hold a single schema; items may also hold a list
+var schemaKeywords = map[string]bool{
+	"items": true, "additionalProperties": true, "not": true, "contains": true, "if": true, "then": true,
+	"else": true, "propertyNames": true, "unevaluatedProperties": true, "unevaluatedItems": true,
+}
+
+// schemaSanitizer rewrites one schema and records what it changed
+type schemaSanitizer struct {
+	policy  SchemaPolicy
+	drop    map[string]bool
+	formats map[string]bool
+	changes []string
+}
+
+// SanitizeSchema rewrites a JSON schema into what the policy accepts and describes each change.
+// The input is not modified.
+func SanitizeSchema(schema map[string]interface{}, policy SchemaPolicy) (map[string]interface{}, []string) {
+	if schema == nil || policy.IsZero() {
+		return schema, nil
+	}
+	s := &schemaSanitizer{policy: policy, drop: make(map[string]bool)}
+	for _, keyword := range policy.DropKeywords {
+		s.drop[keyword] = true
+	}
+	if policy.AllowedFormats != nil {
+		s.formats = make(map[string]bool)
+		for _, format := range policy.AllowedFormats {
+			s.formats[format] = true
+		}
+	}
+	out, _ := s.schema(schema, "$", 0).(map[string]interface{})
+	return out, s.changes
+}
+
+func (s *schemaSanitizer) change(path, format string, args ...interface{}) {
+	s.changes = append(s.changes, path+": "+fmt.Sprintf(format, args...))
+}
+
+// schema sanitizes a schema node; boolean schemas and other values are returned as they are
+func (s *schemaSanitizer) schema(node interface{}, path string, depth int) interface{} {
+	in, ok := node.(map[string]interface{})
+	if !ok {
+		return node
+	}
+
+	if s.policy.MaxDepth > 0 && depth > s.policy.MaxDepth {
+		out := make(map[string]interface{})
+		for _, key := range []string{"type", "description"} {
+			if value, ok := in[key]; ok {
+				out[key], _ = s.keyword(key, value, path)
+			}
+		}
+		s.change(path, "collapsed schema nested deeper than %d", s.policy.MaxDepth)
+		return out
+	}
+
+	keys := make([]string, 0, len(in))
+	for key := range in {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	out := make(map[string]interface{}, len(in))
+	for _, key := range keys {
+		value := in[key]
+		if s.drop[key] {
+			s.change(path, "dropped %s", key)
+			continue
+		}
+
+		switch {
+		case schemaMapKeywords[key]:
+			if named, ok := value.(map[string]interface{}); ok {
+				schemas := make(map[string]interface{}, len(named))
+				for name, sub := range named {
+					schemas[name] = s.schema(sub, path+"."+key+"."+name, depth+1)
+				}
+				value = schemas
+			}
+		case schemaListKeywords[key] || (key == "items" && isList(value)):
+			if list, ok := value.([]interface{}); ok {
+				schemas := make([]interface{}, len(list))
+				for i, sub := range list {
+					schemas[i] = s.schema(sub, fmt.Sprintf("%s.%s[%d]", path, key, i), depth+1)
+				}
+				value = schemas
+			}
+		case schemaKeywords[key]:
+			value = s.schema(value, path+"."+key, depth+1)
+		default:
+			var keep bool
+			if value, keep = s.keyword(key, value, path); !keep {
+				continue
+			}
+		}
+
+		switch {
+		case key == "oneOf" && s.policy.OneOfToAnyOf && in["anyOf"] == nil:
+			s.change(path, "rewrote oneOf as anyOf")
+			key = "anyOf"
+		case key == "const" && s.policy.ConstToEnum && in["enum"] == nil:
+			s.change(path, "rewrote const as enum")
+			key, value = "enum", []interface{}{value}
+		}
+		out[key] = value
+	}
+	return out
+}
+
+// keyword sanitizes a keyword that does not hold schemas; ok is false when it is removed
+func (s *schemaSanitizer) keyword(key string, value interface{}, path string) (interface{}, bool) {
+	switch key {
+	case "format":
+		if format, ok := value.(string); ok && s.formats != nil && !s.formats[format] {
+			s.change(path, "dropped format %q", format)
+			return nil, false
+		}
+	case "description":
+		if text, ok := value.(string); ok {
+			if truncated, cut := truncateDescription(text, s.policy.MaxDescriptionLength); cut {
+				s.change(path, "truncated description to %d characters", s.policy.MaxDescriptionLength)
+				return truncated, true
+			}
+		}
+	}
+	return value, true
+}
+
+func isList(value interface{}) bool {
+	_, ok := value.([]interface{})
+	return ok
+}
+
+// truncateDescription cuts text to at most max characters; max 0 means no limit
+func truncateDescription(text string, max int) (string, bool) {
+	if max <= 0 || len(text) <= max {
+		return text, false
+	}
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text, false
+	}
+	return string(runes[:max]), true
+}
+
+// logSchemaChanges reports what sanitizing changed in a tool
+func logSchemaChanges(tool string, changes []string) {
+	for _, change := range changes {
+		logrus.Debugf("Sanitized schema of tool %s: %s", tool, change)
+	}
+}
+
+// sanitizeToolDescription truncates a tool description and reports the change
+func sanitizeToolDescription(tool, description string, policy SchemaPolicy) string {
+	truncated, cut := truncateDescription(description, policy.MaxDescriptionLength)
+	if cut {
+		logSchemaChanges(tool, []string{fmt.Sprintf("description: truncated to %d characters", policy.MaxDescriptionLength)})
+	}
+	return truncated
+}
+
+// SanitizeOpenAITools applies the policy to the function tools of an OpenAI request. Tools are copied
+// before they are changed.
+func SanitizeOpenAITools(tools []openai.ChatCompletionToolUnionParam, policy SchemaPolicy) []openai.ChatCompletionToolUnionParam {
+	if len(tools) == 0 || policy.IsZero() {
+		return tools
+	}
+	out := make([]openai.ChatCompletionToolUnionParam, len(tools))
+	for i, tool := range tools {
+		out[i] = tool
+		if tool.OfFunction == nil {
+			continue
+		}
+		fn := *tool.OfFunction
+		name := fn.Function.Name
+		if fn.Function.Description.Valid() {
+			fn.Function.Description = param.NewOpt(sanitizeToolDescription(name, fn.Function.Description.Value, policy))
+		}
+		parameters, changes := SanitizeSchema(fn.Function.Parameters, policy)
+		logSchemaChanges(name, changes)
+		fn.Function.Parameters = parameters
+		out[i].OfFunction = &fn
+	}
+	return out
+}
+
+// SanitizeAnthropicTools applies the policy to the custom tools of an Anthropic request. Tools are
+// copied before they are changed.
+func SanitizeAnthropicTools(tools []anthropic.ToolUnionParam, policy SchemaPolicy) []anthropic.ToolUnionParam {
+	if len(tools) == 0 || policy.IsZero() {
+		return tools
+	}
+	out := make([]anthropic.ToolUnionParam, len(tools))
+	for i, tool := range tools {
+		out[i] = tool
+		if tool.OfTool == nil {
+			continue
+		}
+		t := *tool.OfTool
+		if t.Description.Valid() {
+			t.Description = anthropic.Opt(sanitizeToolDescription(t.Name, t.Description.Value, policy))
+		}
+		var schema map[string]interface{}
+		if data, err := json.Marshal(t.InputSchema); err == nil && json.Unmarshal(data, &schema) == nil {
+			sanitized, changes := SanitizeSchema(schema, policy)
+			if len(changes) > 0 {
+				logSchemaChanges(t.Name, changes)
+				t.InputSchema = anthropicInputSchema(sanitized)
+			}
+		}
+		out[i].OfTool = &t
+	}
+	return out
+}
@@ -0,0 +1,113 @@
+package adaptor
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const mcpToolSchema = `{"$schema":"http://json-schema.org/draft-07/schema#","type":"object","additionalProperties":false,
+	"properties":{
+		"format":{"type":"string","enum":["json","text"]},
+		"url":{"type":"string","format":"uri"},
+		"since":{"type":"string","format":"date-time"},
+		"mode":{"const":"fast"},
+		"target":{"oneOf":[{"type":"string"},{"type":"object","additionalProperties":false,"properties":{"id":{"type":"string"}}}]}
+	},
+	"required":["url"]}`
+
+func parseSchema(t *testing.T, schema string) map[string]interface{} {
+	var out map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(schema), &out))
+	return out
+}
+
+func TestSanitizeSchema(t *testing.T) {
+	schema := parseSchema(t, mcpToolSchema)
+	profile, _ := DialectGemini.Profile()
+	out, changes := SanitizeSchema(schema, profile.ToolSchema)
+
+	assert.NotContains(t, out, "$schema")
+	assert.NotContains(t, out, "additionalProperties")
+	properties := out["properties"].(map[string]interface{})
+	// A property named like a keyword is kept
+	assert.Contains(t, properties, "format")
+	assert.NotContains(t, properties["url"], "format")
+	assert.Equal(t, "date-time", properties["since"].(map[string]interface{})["format"])
+	assert.Equal(t, []interface{}{"fast"}, properties["mode"].(map[string]interface{})["enum"])
+	target := properties["target"].(map[string]interface{})
+	require.Contains(t, target, "anyOf")
+	assert.NotContains(t, target["anyOf"].([]interface{})[1], "additionalProperties")
+	assert.Equal(t, []interface{}{"url"}, out["required"])
+
+	assert.Contains(t, changes, "$: dropped $schema")
+	assert.Contains(t, changes, `$.properties.url: dropped format "uri"`)
+	assert.Contains(t, changes, "$.properties.target.oneOf[1]: dropped additionalProperties")
+
+	// The input is left alone
+	assert.Contains(t, schema, "$schema")
+	assert.Equal(t, "uri", schema["properties"].(map[string]interface{})["url"].(map[string]interface{})["format"])
+
+	// The zero policy changes nothing
+	out, changes = SanitizeSchema(schema, SchemaPolicy{})
+	assert.Equal(t, schema, out)
+	assert.Empty(t, changes)
+}
+
+func TestSanitizeSchemaDepthAndDescriptions(t *testing.T) {
+	schema := parseSchema(t, `{"type":"object","description":"A long description","properties":{
+		"a":{"type":"object","properties":{"b":{"type":"object","description":"deep","properties":{"c":{"type":"string"}}}}}}}`)
+	out, changes := SanitizeSchema(schema, SchemaPolicy{MaxDepth: 1, MaxDescriptionLength: 6})
+
+	assert.Equal(t, "A long", out["description"])
+	b := out["properties"].(map[string]interface{})["a"].(map[string]interface{})["properties"].(map[string]interface{})["b"]
+	assert.Equal(t, map[string]interface{}{"type": "object", "description": "deep"}, b)
+	assert.Contains(t, changes, "$.properties.a.properties.b: collapsed schema nested deeper than 1")
+}
+
+func TestSanitizeTools(t *testing.T) {
+	policy := SchemaPolicy{DropKeywords: []string{"$schema", "additionalProperties"}, MaxDescriptionLength: 4}
+
+	// OpenAI tools are copied before they are changed
+	req := parseOpenAIRequest(t, `{"model":"m","messages":[{"role":"user","content":"hi"}],"tools":[{"type":"function",
+		"function":{"name":"fetch","description":"Fetch a URL","parameters":`+mcpToolSchema+`}}]}`)
+	tools := SanitizeOpenAITools(req.Tools, policy)
+	assert.Equal(t, "Fetc", tools[0].OfFunction.Function.Description.Value)
+	assert.NotContains(t, tools[0].OfFunction.Function.Parameters, "$schema")
+	assert.Contains(t, req.Tools[0].OfFunction.Function.Parameters, "$schema")
+
+	// Anthropic tools keep the keywords without a field of their own
+	anthropicTools := SanitizeAnthropicTools([]anthropic.ToolUnionParam{{OfTool: &anthropic.ToolParam{
+		Name:        "fetch",
+		Description: anthropic.String("Fetch a URL"),
+		InputSchema: anthropicInputSchema(parseSchema(t, `{"type":"object","$schema":"x","$defs":{"id":{"type":"string"}},
+			"properties":{"id":{"$ref":"#/$defs/id"}},"required":["id"]}`)),
+	}}}, policy)
+	tool := anthropicTools[0].OfTool
+	assert.Equal(t, "Fetc", tool.Description.Value)
+	assert.NotContains(t, tool.InputSchema.ExtraFields, "$schema")
+	assert.Contains(t, tool.InputSchema.ExtraFields, "$defs")
+	assert.Equal(t, []string{"id"}, tool.InputSchema.Required)
+}
+
+func TestConvertToolsWithSchemaPolicy(t *testing.T) {
+	opts := ConvertOptions{ToolSchema: SchemaPolicy{DropKeywords: []string{"$schema"}}}
+
+	req := parseOpenAIRequest(t, `{"model":"m","messages":[{"role":"user","content":"hi"}],"tools":[{"type":"function",
+		"function":{"name":"fetch","parameters":`+mcpToolSchema+`}}]}`)
+	anthropicTools := ConvertOpenAIToAnthropicToolsWithOptions(req.Tools, opts)
+	require.Len(t, anthropicTools, 1)
+	assert.NotContains(t, anthropicTools[0].OfTool.InputSchema.ExtraFields, "$schema")
+	assert.Equal(t, false, anthropicTools[0].OfTool.InputSchema.ExtraFields["additionalProperties"])
+
+	openaiTools := ConvertAnthropicToolsToOpenAIWithOptions(anthropicTools, opts)
+	require.Len(t, openaiTools, 1)
+	data, err := json.Marshal(openaiTools[0])
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "$schema")
+	assert.Equal(t, "fetch", openaiTools[0].OfFunction.Function.Name)
+	assert.Equal(t, false, openaiTools[0].OfFunction.Function.Parameters["additionalProperties"])
+}
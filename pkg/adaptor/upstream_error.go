@@ -0,0 +1,342 @@
+package adaptor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/gin-gonic/gin"
+	"github.com/openai/openai-go/v3"
+	"github.com/sirupsen/logrus"
+)
+
+// sdkStreamErrorPrefix starts the errors both SDKs return for an error sent in the stream
+const sdkStreamErrorPrefix = "received error while streaming: "
+
+// Anthropic error types, which the gateway uses to classify upstream errors
+const (
+	ErrorTypeInvalidRequest  = "invalid_request_error"
+	ErrorTypeAuthentication  = "authentication_error"
+	ErrorTypePermission      = "permission_error"
+	ErrorTypeNotFound        = "not_found_error"
+	ErrorTypeRequestTooLarge = "request_too_large"
+	ErrorTypeRateLimit       = "rate_limit_error"
+	ErrorTypeAPI             = "api_error"
+	ErrorTypeOverloaded      = "overloaded_error"
+)
+
+// UpstreamError is an error of an upstream API, classified by Anthropic error type
+type UpstreamError struct {
+	// Status is the HTTP status of the upstream response; 0 when the upstream did not answer with
+	// an error status, as for transport failures and errors sent in a stream
+	Status int
+	Type   string
+	// Message is the message of the upstream
+	Message string
+	// RetryAfter is the Retry-After header of the upstream response
+	RetryAfter string
+	// Timeout is set when the upstream did not answer in time
+	Timeout bool
+	// openAI is the error object of an OpenAI upstream, passed on as it is to OpenAI clients
+	openAI map[string]interface{}
+}
+
+func (e *UpstreamError) Error() string {
+	if e.Status != 0 {
+		return fmt.Sprintf("upstream returned %d %s: %s", e.Status, e.Type, e.Message)
+	}
+	return fmt.Sprintf("upstream %s: %s", e.Type, e.Message)
+}
+
+// streamErrors counts stream errors by type since the process started
+var streamErrors = struct {
+	sync.Mutex
+	counts map[string]int64
+}{counts: make(map[string]int64)}
+
+// StreamErrorCounts returns how often each type of stream error occurred
+func StreamErrorCounts() map[string]int64 {
+	streamErrors.Lock()
+	defer streamErrors.Unlock()
+	counts := make(map[string]int64, len(streamErrors.counts))
+	for errorType, count := range streamErrors.counts {
+		counts[errorType] = count
+	}
+	return counts
+}
+
+// countStreamError records a stream error
+func countStreamError(e *UpstreamError) {
+	streamErrors.Lock()
+	streamErrors.counts[e.Type]++
+	streamErrors.Unlock()
+	logrus.Errorf("Upstream stream error (%s): %s", e.Type, e.Message)
+}
+
+// ParseUpstreamError classifies an error returned by either SDK. Error responses keep their status,
+// type and message, as do errors the upstream sent in a stream; transport failures become api_error.
+func ParseUpstreamError(err error) *UpstreamError {
+	var upstreamErr *UpstreamError
+	if errors.As(err, &upstreamErr) {
+		return upstreamErr
+	}
+
+	var anthropicErr *anthropic.Error
+	if errors.As(err, &anthropicErr) {
+		e := parseAnthropicError(anthropicErr.RawJSON())
+		if e == nil {
+			e = &UpstreamError{Type: ErrorTypeForStatus(anthropicErr.StatusCode), Message: anthropicErr.RawJSON()}
+		}
+		e.Status = anthropicErr.StatusCode
+		if anthropicErr.Response != nil {
+			e.RetryAfter = anthropicErr.Response.Header.Get("Retry-After")
+		}
+		return e
+	}
+
+	var openaiErr *openai.Error
+	if errors.As(err, &openaiErr) {
+		e := parseOpenAIError(openaiErr.RawJSON(), openaiErr.StatusCode)
+		if e == nil {
+			e = &UpstreamError{Type: ErrorTypeForStatus(openaiErr.StatusCode), Message: openaiErr.Message}
+		}
+		e.Status = openaiErr.StatusCode
+		if openaiErr.Response != nil {
+			e.RetryAfter = openaiErr.Response.Header.Get("Retry-After")
+		}
+		return e
+	}
+
+	text := err.Error()
+	payload, ok := strings.CutPrefix(text, sdkStreamErrorPrefix)
+	if !ok {
+		return &UpstreamError{Type: ErrorTypeAPI, Message: text, Timeout: errors.Is(err, context.DeadlineExceeded)}
+	}
+	if e := parseAnthropicError(payload); e != nil {
+		return e
+	}
+	if e := parseOpenAIError(payload, 0); e != nil {
+		return e
+	}
+	return &UpstreamError{Type: ErrorTypeAPI, Message: payload}
+}
+
+// parseAnthropicError parses an Anthropic error body or event; it returns nil for other payloads
+func parseAnthropicError(payload string) *UpstreamError {
+	var event struct {
+		Type  string `json:"type"`
+		Error struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal([]byte(payload), &event) != nil || event.Type != "error" || event.Error.Type == "" {
+		return nil
+	}
+	return &UpstreamError{Type: event.Error.Type, Message: event.Error.Message}
+}
+
+// parseOpenAIError parses an OpenAI error object; it returns nil for other payloads
+func parseOpenAIError(payload string, status int) *UpstreamError {
+	var object map[string]interface{}
+	if json.Unmarshal([]byte(payload), &object) != nil || object == nil {
+		return nil
+	}
+	message, _ := object["message"].(string)
+	errorType, _ := object["type"].(string)
+	return &UpstreamError{Type: openAIErrorTypeToAnthropic(errorType, object["code"], message, status), Message: message, openAI: object}
+}
+
+// openAIErrorTypeToAnthropic classifies an OpenAI error by its type, code and message, then by status
+func openAIErrorTypeToAnthropic(errorType string, code interface{}, message string, status int) string {
+	codeText := fmt.Sprint(code)
+	switch {
+	case errorType == "requests" || errorType == "tokens" || errorType == ErrorTypeRateLimit ||
+		codeText == "rate_limit_exceeded" || codeText == "429":
+		return ErrorTypeRateLimit
+	case errorType == ErrorTypeAuthentication || errorType == ErrorTypePermission || errorType == ErrorTypeNotFound:
+		return errorType
+	case codeText == "overloaded" || codeText == "503" || codeText == "529" ||
+		strings.Contains(strings.ToLower(message), "overload"):
+		return ErrorTypeOverloaded
+	case status != 0:
+		return ErrorTypeForStatus(status)
+	case errorType == ErrorTypeInvalidRequest:
+		return errorType
+	}
+	return ErrorTypeAPI
+}
+
+// ErrorTypeForStatus returns the Anthropic error type of an HTTP status
+func ErrorTypeForStatus(status int) string {
+	switch status {
+	case http.StatusUnauthorized:
+		return ErrorTypeAuthentication
+	case http.StatusForbidden:
+		return ErrorTypePermission
+	case http.StatusNotFound:
+		return ErrorTypeNotFound
+	case http.StatusRequestEntityTooLarge:
+		return ErrorTypeRequestTooLarge
+	case http.StatusTooManyRequests:
+		return ErrorTypeRateLimit
+	case http.StatusServiceUnavailable, 529:
+		return ErrorTypeOverloaded
+	}
+	if status >= 400 && status < 500 {
+		return ErrorTypeInvalidRequest
+	}
+	return ErrorTypeAPI
+}
+
+// StatusForErrorType returns the HTTP status Anthropic uses for an error type
+func StatusForErrorType(errorType string) int {
+	switch errorType {
+	case ErrorTypeInvalidRequest:
+		return http.StatusBadRequest
+	case ErrorTypeAuthentication:
+		return http.StatusUnauthorized
+	case ErrorTypePermission:
+		return http.StatusForbidden
+	case ErrorTypeNotFound:
+		return http.StatusNotFound
+	case ErrorTypeRequestTooLarge:
+		return http.StatusRequestEntityTooLarge
+	case ErrorTypeRateLimit:
+		return http.StatusTooManyRequests
+	case ErrorTypeOverloaded:
+		return 529
+	}
+	return http.StatusInternalServerError
+}
+
+// OpenAIErrorType returns the OpenAI error type and code of an Anthropic error type
+func OpenAIErrorType(errorType string) (string, string) {
+	switch errorType {
+	case ErrorTypeOverloaded:
+		return "server_error", "overloaded"
+	case ErrorTypeAPI:
+		return "server_error", ""
+	case ErrorTypeRateLimit:
+		return ErrorTypeRateLimit, "rate_limit_exceeded"
+	case ErrorTypeRequestTooLarge:
+		return ErrorTypeInvalidRequest, "request_too_large"
+	}
+	return errorType, ""
+}
+
+// OpenAIType returns the OpenAI error type and code of the error, as the upstream sent them when it
+// is an OpenAI upstream
+func (e *UpstreamError) OpenAIType() (string, string) {
+	if e.openAI != nil {
+		errorType, _ := e.openAI["type"].(string)
+		if errorType != "" {
+			code, _ := e.openAI["code"].(string)
+			return errorType, code
+		}
+	}
+	return OpenAIErrorType(e.Type)
+}
+
+// geminiStreamError classifies an error object of a Gemini stream by its status
+func geminiStreamError(e *GeminiError) *UpstreamError {
+	errorType := ErrorTypeAPI
+	switch e.Status {
+	case "INVALID_ARGUMENT", "FAILED_PRECONDITION", "OUT_OF_RANGE":
+		errorType = ErrorTypeInvalidRequest
+	case "UNAUTHENTICATED":
+		errorType = ErrorTypeAuthentication
+	case "PERMISSION_DENIED":
+		errorType = ErrorTypePermission
+	case "NOT_FOUND":
+		errorType = ErrorTypeNotFound
+	case "RESOURCE_EXHAUSTED":
+		errorType = ErrorTypeRateLimit
+	case "UNAVAILABLE":
+		errorType = ErrorTypeOverloaded
+	}
+	return &UpstreamError{Type: errorType, Message: e.Message}
+}
+
+// AnthropicEvent renders the error as an Anthropic error event
+func (e *UpstreamError) AnthropicEvent() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "error",
+		"error": map[string]interface{}{
+			"type":    e.Type,
+			"message": e.Message,
+		},
+	}
+}
+
+// OpenAIChunk renders the error as an OpenAI error chunk
+func (e *UpstreamError) OpenAIChunk() map[string]interface{} {
+	if e.openAI != nil {
+		return map[string]interface{}{"error": e.openAI}
+	}
+
+	errorType, code := OpenAIErrorType(e.Type)
+	object := map[string]interface{}{
+		"message": e.Message,
+		"type":    errorType,
+		"code":    nil,
+	}
+	if code != "" {
+		object["code"] = code
+	}
+	return map[string]interface{}{"error": object}
+}
+
+// SendAnthropicStreamError sends the error of an upstream stream as an Anthropic error event followed
+// by message_stop, and counts it
+func SendAnthropicStreamError(c *gin.Context, err error, flusher http.Flusher) {
+	if err := writeAnthropicStreamError(c.Writer, err); err != nil {
+		logrus.Errorf("Failed to write Anthropic stream error: %v", err)
+	}
+	flusher.Flush()
+}
+
+// SendOpenAIStreamError sends the error of an upstream stream as an OpenAI error chunk followed by
+// [DONE], and counts it
+func SendOpenAIStreamError(c *gin.Context, err error, flusher http.Flusher) {
+	if err := writeOpenAIStreamError(c.Writer, err); err != nil {
+		logrus.Errorf("Failed to write OpenAI stream error: %v", err)
+	}
+	flusher.Flush()
+}
+
+// writeAnthropicStreamError writes the error of an upstream stream as an Anthropic error event
+// followed by message_stop
+func writeAnthropicStreamError(w io.Writer, err error) error {
+	streamErr := ParseUpstreamError(err)
+	countStreamError(streamErr)
+	if err := writeSSEEvent(w, SSEEvent{Event: eventTypeError, Data: streamErr.AnthropicEvent()}); err != nil {
+		return err
+	}
+	if err := writeSSEEvent(w, SSEEvent{Event: eventTypeMessageStop, Data: map[string]interface{}{"type": eventTypeMessageStop}}); err != nil {
+		return err
+	}
+	flush(w)
+	return nil
+}
+
+// writeOpenAIStreamError writes the error of an upstream stream as an OpenAI error chunk followed by
+// [DONE]
+func writeOpenAIStreamError(w io.Writer, err error) error {
+	streamErr := ParseUpstreamError(err)
+	countStreamError(streamErr)
+	if err := writeSSEData(w, streamErr.OpenAIChunk()); err != nil {
+		return err
+	}
+	if err := writeSSEDone(w); err != nil {
+		return err
+	}
+	flush(w)
+	return nil
+}
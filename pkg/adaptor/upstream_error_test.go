@@ -0,0 +1,141 @@
+package adaptor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	anthropicOption "github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/gin-gonic/gin"
+	"github.com/openai/openai-go/v3"
+	openaiOption "github.com/openai/openai-go/v3/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseUpstreamError(t *testing.T) {
+	tests := []struct {
+		err         string
+		wantType    string
+		wantMessage string
+	}{
+		{sdkStreamErrorPrefix + `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`, "overloaded_error", "Overloaded"},
+		{sdkStreamErrorPrefix + `{"message":"Rate limit reached","type":"requests","code":"rate_limit_exceeded"}`, "rate_limit_error", "Rate limit reached"},
+		{sdkStreamErrorPrefix + `{"message":"The server is overloaded","type":"server_error"}`, "overloaded_error", "The server is overloaded"},
+		{sdkStreamErrorPrefix + `{"message":"Bad tool","type":"invalid_request_error"}`, "invalid_request_error", "Bad tool"},
+		{sdkStreamErrorPrefix + `{"message":"boom","type":"server_error"}`, "api_error", "boom"},
+		{sdkStreamErrorPrefix + `upstream went away`, "api_error", "upstream went away"},
+		{"unexpected EOF", "api_error", "unexpected EOF"},
+	}
+	for _, tt := range tests {
+		upstreamErr := ParseUpstreamError(errors.New(tt.err))
+		assert.Equal(t, tt.wantType, upstreamErr.Type, tt.err)
+		assert.Equal(t, tt.wantMessage, upstreamErr.Message, tt.err)
+		assert.Zero(t, upstreamErr.Status, tt.err)
+	}
+}
+
+func TestUpstreamErrorOpenAIChunk(t *testing.T) {
+	chunk := (&UpstreamError{Type: "overloaded_error", Message: "Overloaded"}).OpenAIChunk()
+	assert.Equal(t, map[string]interface{}{"message": "Overloaded", "type": "server_error", "code": "overloaded"}, chunk["error"])
+
+	chunk = (&UpstreamError{Type: "rate_limit_error", Message: "slow down"}).OpenAIChunk()
+	assert.Equal(t, "rate_limit_exceeded", chunk["error"].(map[string]interface{})["code"])
+
+	// Errors of OpenAI upstreams reach OpenAI clients as they are
+	upstreamErr := ParseUpstreamError(errors.New(sdkStreamErrorPrefix + `{"message":"Rate limit reached","type":"tokens","code":"rate_limit_exceeded","param":null}`))
+	assert.Equal(t, map[string]interface{}{"message": "Rate limit reached", "type": "tokens", "code": "rate_limit_exceeded", "param": nil},
+		upstreamErr.OpenAIChunk()["error"])
+}
+
+func TestParseUpstreamErrorResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":{"message":"Incorrect API key provided","type":"invalid_request_error","code":"invalid_api_key"}}`))
+	}))
+	defer server.Close()
+
+	client := openai.NewClient(openaiOption.WithBaseURL(server.URL), openaiOption.WithAPIKey("test"), openaiOption.WithMaxRetries(0))
+	_, err := client.Chat.Completions.New(context.Background(), openai.ChatCompletionNewParams{Model: "m"})
+	require.Error(t, err)
+
+	// The status classifies the error; OpenAI clients get the type and code of the upstream
+	upstreamErr := ParseUpstreamError(fmt.Errorf("failed to create chat completion: %w", err))
+	assert.Equal(t, http.StatusUnauthorized, upstreamErr.Status)
+	assert.Equal(t, "authentication_error", upstreamErr.Type)
+	assert.Equal(t, "Incorrect API key provided", upstreamErr.Message)
+	errorType, code := upstreamErr.OpenAIType()
+	assert.Equal(t, "invalid_request_error", errorType)
+	assert.Equal(t, "invalid_api_key", code)
+
+	assert.Equal(t, "overloaded_error", ErrorTypeForStatus(529))
+	assert.Equal(t, "request_too_large", ErrorTypeForStatus(http.StatusRequestEntityTooLarge))
+	assert.Equal(t, "invalid_request_error", ErrorTypeForStatus(http.StatusUnprocessableEntity))
+	assert.Equal(t, "api_error", ErrorTypeForStatus(http.StatusBadGateway))
+}
+
+func TestAnthropicStreamErrorToOpenAI(t *testing.T) {
+	server := sseServer(`event: message_start
+data: {"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude","content":[],"usage":{"input_tokens":10,"output_tokens":1}}}
+
+event: error
+data: {"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}
+
+`)
+	defer server.Close()
+
+	client := anthropic.NewClient(anthropicOption.WithBaseURL(server.URL), anthropicOption.WithAPIKey("test"))
+	stream := client.Messages.NewStreaming(context.Background(), anthropic.MessageNewParams{Model: "claude", MaxTokens: 10})
+
+	before := StreamErrorCounts()["overloaded_error"]
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	require.NoError(t, HandleAnthropicToOpenAIStreamResponse(c, stream, "claude"))
+
+	body := w.Body.String()
+	assert.Contains(t, body, `data: {"error":{"code":"overloaded","message":"Overloaded","type":"server_error"}}`)
+	assert.True(t, strings.HasSuffix(body, "data: [DONE]\n\n"))
+	assert.Equal(t, before+1, StreamErrorCounts()["overloaded_error"])
+}
+
+func TestOpenAIStreamErrorToAnthropic(t *testing.T) {
+	server := sseServer(`data: {"id":"c1","object":"chat.completion.chunk","created":1,"model":"m","choices":[{"index":0,"delta":{"role":"assistant","content":"Hi"}}]}
+
+data: {"error":{"message":"Rate limit reached","type":"requests","code":"rate_limit_exceeded"}}
+
+`)
+	defer server.Close()
+
+	client := openai.NewClient(openaiOption.WithBaseURL(server.URL), openaiOption.WithAPIKey("test"))
+	stream := client.Chat.Completions.NewStreaming(context.Background(), openai.ChatCompletionNewParams{Model: "m"})
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	require.NoError(t, HandleOpenAIToAnthropicStreamResponse(c, stream, "m"))
+
+	body := w.Body.String()
+	assert.Contains(t, body, "event: error\ndata: {\"error\":{\"message\":\"Rate limit reached\",\"type\":\"rate_limit_error\"},\"type\":\"error\"}")
+	assert.True(t, strings.HasSuffix(body, "event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n"))
+}
+
+func TestGeminiStreamErrorToOpenAI(t *testing.T) {
+	stream := "data: {\"candidates\":[{\"index\":0,\"content\":{\"parts\":[{\"text\":\"Hel\"}]}}]}\n\n" +
+		"data: {\"error\":{\"code\":503,\"message\":\"The model is overloaded.\",\"status\":\"UNAVAILABLE\"}}\n\n"
+
+	var out bytes.Buffer
+	require.NoError(t, ConvertGeminiToOpenAIStream(strings.NewReader(stream), &out, "gemini", true))
+
+	events := strings.Split(strings.TrimSpace(out.String()), "\n\n")
+	require.Len(t, events, 3)
+	assert.Equal(t, `data: {"error":{"code":"overloaded","message":"The model is overloaded.","type":"server_error"}}`, events[1])
+	assert.Equal(t, "data: [DONE]", events[2])
+}
@@ -0,0 +1,127 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+
+	openaiOption "github.com/openai/openai-go/v3/option"
+)
+
+// azureDeploymentRoutes are the OpenAI routes Azure serves under /openai/deployments/{deployment}
+var azureDeploymentRoutes = map[string]bool{
+	"/completions":          true,
+	"/chat/completions":     true,
+	"/embeddings":           true,
+	"/audio/speech":         true,
+	"/audio/transcriptions": true,
+	"/audio/translations":   true,
+	"/images/generations":   true,
+	"/images/edits":         true,
+}
+
+// AzureEndpoint normalizes an Azure OpenAI API base to the resource endpoint with a trailing slash,
+// e.g. "https://res.openai.azure.com/openai/v1" -> "https://res.openai.azure.com/"
+func AzureEndpoint(apiBase string) string {
+	endpoint := strings.TrimSuffix(apiBase, "/")
+	endpoint = strings.TrimSuffix(endpoint, "/v1")
+	endpoint = strings.TrimSuffix(endpoint, "/openai")
+	return endpoint + "/"
+}
+
+// AzureURL builds an Azure OpenAI URL for the given path below /openai, adding the api-version
+func AzureURL(apiBase, path, apiVersion string) string {
+	u := AzureEndpoint(apiBase) + "openai" + path
+	if strings.Contains(u, "?") {
+		return u + "&api-version=" + url.QueryEscape(apiVersion)
+	}
+	return u + "?api-version=" + url.QueryEscape(apiVersion)
+}
+
+// SetAzureAuth replaces bearer authentication with the api-key header Azure expects
+func SetAzureAuth(header http.Header, apiKey string) {
+	header.Del("Authorization")
+	if apiKey != "" {
+		header.Set("api-key", apiKey)
+	}
+}
+
+// WithAzureOpenAI configures an OpenAI SDK client for an Azure OpenAI resource.
+// Requests are rewritten to deployment-based paths, using deploymentFor to map the
+// request model to a deployment, and carry the api-version query parameter and api-key header.
+func WithAzureOpenAI(apiBase, apiKey, apiVersion string, deploymentFor func(model string) string) []openaiOption.RequestOption {
+	endpoint := AzureEndpoint(apiBase)
+	basePath := "/"
+	if u, err := url.Parse(endpoint); err == nil && u.Path != "" {
+		basePath = u.Path
+	}
+
+	return []openaiOption.RequestOption{
+		openaiOption.WithBaseURL(endpoint),
+		openaiOption.WithMiddleware(func(req *http.Request, next openaiOption.MiddlewareNext) (*http.Response, error) {
+			route := "/" + strings.TrimPrefix(strings.TrimPrefix(req.URL.Path, basePath), "/")
+			if azureDeploymentRoutes[route] {
+				model, err := azureRequestModel(req)
+				if err != nil {
+					return nil, err
+				}
+				req.URL.Path = basePath + "openai/deployments/" + url.PathEscape(deploymentFor(model)) + route
+			} else {
+				req.URL.Path = basePath + "openai" + route
+			}
+			req.URL.RawPath = ""
+
+			query := req.URL.Query()
+			if query.Get("api-version") == "" {
+				query.Set("api-version", apiVersion)
+				req.URL.RawQuery = query.Encode()
+			}
+			SetAzureAuth(req.Header, apiKey)
+			return next(req)
+		}),
+	}
+}
+
+// azureRequestModel reads the model from a JSON or multipart request body, restoring the body afterwards
+func azureRequestModel(req *http.Request) (string, error) {
+	if req.Body == nil {
+		return "", nil
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return "", err
+	}
+	req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+
+	mediaType, params, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		reader := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+		for {
+			part, err := reader.NextPart()
+			if err != nil {
+				return "", nil
+			}
+			if part.FormName() == "model" && part.FileName() == "" {
+				value, err := io.ReadAll(part)
+				return string(value), err
+			}
+		}
+	}
+
+	var payload struct {
+		Model string `json:"model"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return "", nil
+	}
+	return payload.Model, nil
+}
@@ -0,0 +1,231 @@
+package client
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	anthropicOption "github.com/anthropics/anthropic-sdk-go/option"
+)
+
+const (
+	// BedrockAnthropicVersion is the anthropic_version Bedrock expects in InvokeModel bodies
+	BedrockAnthropicVersion = "bedrock-2023-05-31"
+	// bedrockService is the SigV4 signing name of both Bedrock endpoints
+	bedrockService = "bedrock"
+)
+
+// bedrockExceptionTypes maps Bedrock stream exceptions to Anthropic error types
+var bedrockExceptionTypes = map[string]string{
+	"throttlingException":         "rate_limit_error",
+	"validationException":         "invalid_request_error",
+	"serviceUnavailableException": "overloaded_error",
+	"modelTimeoutException":       "api_error",
+	"modelStreamErrorException":   "api_error",
+	"internalServerException":     "api_error",
+}
+
+// BedrockEndpoint returns the Bedrock runtime endpoint, preferring a configured API base
+func BedrockEndpoint(apiBase, region string) string {
+	if apiBase != "" {
+		return strings.TrimSuffix(apiBase, "/")
+	}
+	return fmt.Sprintf("https://bedrock-runtime.%s.amazonaws.com", region)
+}
+
+// BedrockControlEndpoint returns the Bedrock control plane endpoint (model listing)
+// belonging to a runtime endpoint
+func BedrockControlEndpoint(apiBase, region string) string {
+	return strings.Replace(BedrockEndpoint(apiBase, region), "://bedrock-runtime.", "://bedrock.", 1)
+}
+
+// SignBedrockRequest signs a request for the Bedrock endpoints
+func SignBedrockRequest(req *http.Request, payload []byte, creds AWSCredentials, region string) {
+	SignSigV4(req, payload, creds, region, bedrockService, time.Now())
+}
+
+// WithBedrock configures an Anthropic SDK client for Amazon Bedrock. Messages requests are
+// rewritten to the InvokeModel APIs with the model moved into the path, signed with SigV4,
+// and streamed event-stream responses are converted back into Anthropic SSE events.
+func WithBedrock(apiBase, region string, credentials func() (AWSCredentials, error)) []anthropicOption.RequestOption {
+	return []anthropicOption.RequestOption{
+		anthropicOption.WithBaseURL(BedrockEndpoint(apiBase, region) + "/"),
+		anthropicOption.WithMiddleware(func(req *http.Request, next anthropicOption.MiddlewareNext) (*http.Response, error) {
+			creds, err := credentials()
+			if err != nil {
+				return nil, err
+			}
+
+			body, err := readRequestBody(req)
+			if err != nil {
+				return nil, err
+			}
+			stream := false
+			if req.Method == http.MethodPost && strings.HasSuffix(req.URL.Path, "/v1/messages") {
+				var model string
+				if body, model, stream, err = bedrockInvokeBody(body, req.Header.Values("Anthropic-Beta")); err != nil {
+					return nil, err
+				}
+				action := "invoke"
+				if stream {
+					action = "invoke-with-response-stream"
+				}
+				prefix := strings.TrimSuffix(req.URL.Path, "/v1/messages")
+				req.URL.Path = prefix + "/model/" + model + "/" + action
+				req.URL.RawPath = prefix + "/model/" + url.QueryEscape(model) + "/" + action
+				req.Header.Del("Anthropic-Beta")
+			}
+			if body != nil {
+				setRequestBody(req, body)
+			}
+
+			req.Header.Del("X-Api-Key")
+			req.Header.Del("Authorization")
+			SignBedrockRequest(req, body, creds, region)
+
+			resp, err := next(req)
+			if err != nil {
+				return resp, err
+			}
+			if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/vnd.amazon.eventstream") {
+				resp.Body = newBedrockSSEReader(resp.Body)
+				resp.Header.Set("Content-Type", "text/event-stream")
+				resp.Header.Del("Content-Length")
+				resp.ContentLength = -1
+			}
+			return resp, nil
+		}),
+	}
+}
+
+// bedrockInvokeBody turns a Messages API body into an InvokeModel body: model and stream
+// are removed, anthropic_version is set and beta headers move into anthropic_beta
+func bedrockInvokeBody(body []byte, betas []string) ([]byte, string, bool, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, "", false, fmt.Errorf("invalid messages request body: %w", err)
+	}
+
+	var model string
+	var stream bool
+	if raw, ok := fields["model"]; ok {
+		_ = json.Unmarshal(raw, &model)
+	}
+	if raw, ok := fields["stream"]; ok {
+		_ = json.Unmarshal(raw, &stream)
+	}
+	if model == "" {
+		return nil, "", false, fmt.Errorf("bedrock request requires a model")
+	}
+	delete(fields, "model")
+	delete(fields, "stream")
+
+	if _, ok := fields["anthropic_version"]; !ok {
+		fields["anthropic_version"], _ = json.Marshal(BedrockAnthropicVersion)
+	}
+	if len(betas) > 0 {
+		var list []string
+		for _, beta := range betas {
+			for _, b := range strings.Split(beta, ",") {
+				if b = strings.TrimSpace(b); b != "" {
+					list = append(list, b)
+				}
+			}
+		}
+		fields["anthropic_beta"], _ = json.Marshal(list)
+	}
+
+	out, err := json.Marshal(fields)
+	return out, model, stream, err
+}
+
+// newBedrockSSEReader exposes a Bedrock event-stream body as Anthropic SSE
+func newBedrockSSEReader(body io.ReadCloser) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(writeBedrockSSE(pw, body))
+	}()
+	return &pipeBody{PipeReader: pr, upstream: body}
+}
+
+// writeBedrockSSE decodes event-stream messages and writes the Anthropic events they carry.
+// Stream exceptions become an Anthropic error event.
+func writeBedrockSSE(w io.Writer, body io.Reader) error {
+	decoder := NewEventStreamDecoder(body)
+	for {
+		msg, err := decoder.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch msg.Headers[":message-type"] {
+		case "event":
+			if msg.Headers[":event-type"] != "chunk" {
+				continue
+			}
+			var chunk struct {
+				Bytes string `json:"bytes"`
+			}
+			if err := json.Unmarshal(msg.Payload, &chunk); err != nil {
+				return fmt.Errorf("invalid bedrock chunk: %w", err)
+			}
+			data, err := base64.StdEncoding.DecodeString(chunk.Bytes)
+			if err != nil {
+				return fmt.Errorf("invalid bedrock chunk: %w", err)
+			}
+			var event struct {
+				Type string `json:"type"`
+			}
+			_ = json.Unmarshal(data, &event)
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+				return err
+			}
+		case "exception", "error":
+			return writeBedrockErrorEvent(w, msg)
+		}
+	}
+}
+
+func writeBedrockErrorEvent(w io.Writer, msg *EventStreamMessage) error {
+	code := msg.Headers[":exception-type"]
+	if code == "" {
+		code = msg.Headers[":error-code"]
+	}
+	var payload struct {
+		Message      string `json:"message"`
+		MessageUpper string `json:"Message"`
+	}
+	_ = json.Unmarshal(msg.Payload, &payload)
+	message := payload.Message
+	if message == "" {
+		message = payload.MessageUpper
+	}
+	if message == "" {
+		message = msg.Headers[":error-message"]
+	}
+
+	errorType, ok := bedrockExceptionTypes[code]
+	if !ok {
+		errorType = "api_error"
+	}
+	data, err := json.Marshal(map[string]interface{}{
+		"type": "error",
+		"error": map[string]string{
+			"type":    errorType,
+			"message": fmt.Sprintf("%s: %s", code, message),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: error\ndata: %s\n\n", data)
+	return err
+}
@@ -0,0 +1,112 @@
+package client
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// encodeEventStreamMessage builds an event-stream message with string headers
+func encodeEventStreamMessage(headers map[string]string, payload []byte) []byte {
+	var hdr bytes.Buffer
+	for name, value := range headers {
+		hdr.WriteByte(byte(len(name)))
+		hdr.WriteString(name)
+		hdr.WriteByte(7)
+		binary.Write(&hdr, binary.BigEndian, uint16(len(value)))
+		hdr.WriteString(value)
+	}
+
+	total := 12 + hdr.Len() + len(payload) + 4
+	var msg bytes.Buffer
+	binary.Write(&msg, binary.BigEndian, uint32(total))
+	binary.Write(&msg, binary.BigEndian, uint32(hdr.Len()))
+	binary.Write(&msg, binary.BigEndian, crc32.ChecksumIEEE(msg.Bytes()))
+	msg.Write(hdr.Bytes())
+	msg.Write(payload)
+	binary.Write(&msg, binary.BigEndian, crc32.ChecksumIEEE(msg.Bytes()))
+	return msg.Bytes()
+}
+
+func bedrockChunk(event string) []byte {
+	payload := `{"bytes":"` + base64.StdEncoding.EncodeToString([]byte(event)) + `"}`
+	return encodeEventStreamMessage(map[string]string{
+		":message-type": "event",
+		":event-type":   "chunk",
+		":content-type": "application/json",
+	}, []byte(payload))
+}
+
+// TestSignSigV4 checks the signer against the get-vanilla case of the AWS SigV4 test suite
+func TestSignSigV4(t *testing.T) {
+	req, err := http.NewRequest("GET", "https://example.amazonaws.com/", nil)
+	require.NoError(t, err)
+
+	creds := AWSCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+	SignSigV4(req, nil, creds, "us-east-1", "service", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	assert.Equal(t, "20150830T123600Z", req.Header.Get("X-Amz-Date"))
+	assert.Equal(t, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, "+
+		"SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		req.Header.Get("Authorization"))
+}
+
+func TestSigV4EscapePath(t *testing.T) {
+	assert.Equal(t, "/", sigV4EscapePath(""))
+	assert.Equal(t, "/model/anthropic.claude-v2%253A1/invoke", sigV4EscapePath("/model/anthropic.claude-v2%3A1/invoke"))
+}
+
+func TestEventStreamDecoder(t *testing.T) {
+	stream := append(bedrockChunk(`{"type":"message_start"}`), bedrockChunk(`{"type":"message_stop"}`)...)
+	decoder := NewEventStreamDecoder(bytes.NewReader(stream))
+
+	msg, err := decoder.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "chunk", msg.Headers[":event-type"])
+	assert.Contains(t, string(msg.Payload), `"bytes"`)
+
+	_, err = decoder.Next()
+	require.NoError(t, err)
+	_, err = decoder.Next()
+	assert.Equal(t, io.EOF, err)
+
+	corrupted := bedrockChunk(`{"type":"ping"}`)
+	corrupted[len(corrupted)-6] ^= 0xff
+	_, err = NewEventStreamDecoder(bytes.NewReader(corrupted)).Next()
+	assert.ErrorContains(t, err, "checksum")
+}
+
+func TestBedrockEventStreamToSSE(t *testing.T) {
+	stream := append(bedrockChunk(`{"type":"message_start","message":{"id":"msg_1"}}`),
+		encodeEventStreamMessage(map[string]string{
+			":message-type":   "exception",
+			":exception-type": "throttlingException",
+		}, []byte(`{"message":"Too many requests"}`))...)
+
+	sse, err := io.ReadAll(newBedrockSSEReader(io.NopCloser(bytes.NewReader(stream))))
+	require.NoError(t, err)
+
+	events := strings.Split(strings.TrimSpace(string(sse)), "\n\n")
+	require.Len(t, events, 2)
+	assert.Equal(t, "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_1\"}}", events[0])
+	assert.Contains(t, events[1], "event: error")
+	assert.Contains(t, events[1], `"type":"rate_limit_error"`)
+	assert.Contains(t, events[1], "throttlingException: Too many requests")
+}
+
+func TestBedrockInvokeBody(t *testing.T) {
+	body, model, stream, err := bedrockInvokeBody([]byte(`{"model":"anthropic.claude-v2","stream":true,"max_tokens":5}`), []string{"a, b"})
+	require.NoError(t, err)
+	assert.Equal(t, "anthropic.claude-v2", model)
+	assert.True(t, stream)
+	assert.JSONEq(t, `{"max_tokens":5,"anthropic_version":"bedrock-2023-05-31","anthropic_beta":["a","b"]}`, string(body))
+}
@@ -1,9 +1,15 @@
 package client
 
 import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
 	"fmt"
+	"net"
 	"net/http"
 	"net/url"
+	"time"
 
 	oauth2 "tingly-box/pkg/oauth"
 
@@ -125,6 +131,89 @@ func CreateHTTPClientWithProxy(proxyURL string) *http.Client {
 	}
 }
 
+// DialContextWithProxy opens a TCP connection to addr (host:port), through proxyURL when set.
+// It supports the same proxy schemes as CreateHTTPClientWithProxy and serves connections the
+// HTTP client cannot make, such as WebSockets.
+func DialContextWithProxy(ctx context.Context, proxyURL, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{}
+	if proxyURL == "" {
+		return dialer.DialContext(ctx, "tcp", addr)
+	}
+
+	parsedURL, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL %s: %w", proxyURL, err)
+	}
+
+	switch parsedURL.Scheme {
+	case "socks5":
+		var auth *proxy.Auth
+		if parsedURL.User != nil {
+			password, _ := parsedURL.User.Password()
+			auth = &proxy.Auth{User: parsedURL.User.Username(), Password: password}
+		}
+		socks, err := proxy.SOCKS5("tcp", parsedURL.Host, auth, dialer)
+		if err != nil {
+			return nil, err
+		}
+		return socks.(proxy.ContextDialer).DialContext(ctx, "tcp", addr)
+	case "http", "https":
+		conn, err := dialer.DialContext(ctx, "tcp", parsedURL.Host)
+		if err != nil {
+			return nil, err
+		}
+		if parsedURL.Scheme == "https" {
+			tlsConn := tls.Client(conn, &tls.Config{ServerName: parsedURL.Hostname()})
+			if err := tlsConn.HandshakeContext(ctx); err != nil {
+				conn.Close()
+				return nil, err
+			}
+			conn = tlsConn
+		}
+		if err := connectThroughProxy(ctx, conn, parsedURL, addr); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return conn, nil
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %s, supported schemes are http, https, socks5", parsedURL.Scheme)
+	}
+}
+
+// connectThroughProxy asks an HTTP proxy to open a tunnel to addr over conn
+func connectThroughProxy(ctx context.Context, conn net.Conn, proxyURL *url.URL, addr string) error {
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: http.Header{},
+	}
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(proxyURL.User.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := req.Write(conn); err != nil {
+		return err
+	}
+
+	// The tunnel carries nothing before the client speaks, so the reader holds no data past the response
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("proxy refused CONNECT to %s: %s", addr, resp.Status)
+	}
+	return nil
+}
+
 // CreateHTTPClientForProvider creates an HTTP client configured for the given provider
 // It handles proxy and OAuth hooks if applicable
 //
@@ -0,0 +1,112 @@
+package client
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// eventStreamMaxMessage bounds a single event-stream message (16 MiB as in the AWS SDKs)
+const eventStreamMaxMessage = 16 * 1024 * 1024
+
+// EventStreamMessage is one message of the AWS binary event-stream encoding
+// (application/vnd.amazon.eventstream). Only string headers are kept.
+type EventStreamMessage struct {
+	Headers map[string]string
+	Payload []byte
+}
+
+// EventStreamDecoder reads event-stream messages from a response body
+type EventStreamDecoder struct {
+	r io.Reader
+}
+
+// NewEventStreamDecoder creates a decoder reading from r
+func NewEventStreamDecoder(r io.Reader) *EventStreamDecoder {
+	return &EventStreamDecoder{r: r}
+}
+
+// Next decodes the next message, returning io.EOF at a clean end of stream
+func (d *EventStreamDecoder) Next() (*EventStreamMessage, error) {
+	prelude := make([]byte, 12)
+	if _, err := io.ReadFull(d.r, prelude); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, fmt.Errorf("event-stream: truncated prelude")
+		}
+		return nil, err
+	}
+
+	totalLen := binary.BigEndian.Uint32(prelude[0:4])
+	headersLen := binary.BigEndian.Uint32(prelude[4:8])
+	if crc32.ChecksumIEEE(prelude[0:8]) != binary.BigEndian.Uint32(prelude[8:12]) {
+		return nil, fmt.Errorf("event-stream: prelude checksum mismatch")
+	}
+	if totalLen < 16 || totalLen > eventStreamMaxMessage || headersLen > totalLen-16 {
+		return nil, fmt.Errorf("event-stream: invalid message length %d", totalLen)
+	}
+
+	message := make([]byte, totalLen)
+	copy(message, prelude)
+	if _, err := io.ReadFull(d.r, message[12:]); err != nil {
+		return nil, fmt.Errorf("event-stream: truncated message: %w", err)
+	}
+	if crc32.ChecksumIEEE(message[:totalLen-4]) != binary.BigEndian.Uint32(message[totalLen-4:]) {
+		return nil, fmt.Errorf("event-stream: message checksum mismatch")
+	}
+
+	headers, err := decodeEventStreamHeaders(message[12 : 12+headersLen])
+	if err != nil {
+		return nil, err
+	}
+	return &EventStreamMessage{
+		Headers: headers,
+		Payload: message[12+headersLen : totalLen-4],
+	}, nil
+}
+
+// decodeEventStreamHeaders parses the header block, keeping string values and skipping the rest
+func decodeEventStreamHeaders(data []byte) (map[string]string, error) {
+	headers := make(map[string]string)
+	for len(data) > 0 {
+		nameLen := int(data[0])
+		if len(data) < 1+nameLen+1 {
+			return nil, fmt.Errorf("event-stream: truncated header")
+		}
+		name := string(data[1 : 1+nameLen])
+		valueType := data[1+nameLen]
+		data = data[2+nameLen:]
+
+		var size int
+		switch valueType {
+		case 0, 1: // bool true / false
+			size = 0
+		case 2: // byte
+			size = 1
+		case 3: // int16
+			size = 2
+		case 4: // int32
+			size = 4
+		case 5, 8: // int64, timestamp
+			size = 8
+		case 9: // uuid
+			size = 16
+		case 6, 7: // bytes, string
+			if len(data) < 2 {
+				return nil, fmt.Errorf("event-stream: truncated header %s", name)
+			}
+			size = 2 + int(binary.BigEndian.Uint16(data[0:2]))
+		default:
+			return nil, fmt.Errorf("event-stream: unknown header type %d", valueType)
+		}
+		if len(data) < size {
+			return nil, fmt.Errorf("event-stream: truncated header %s", name)
+		}
+		if valueType == 7 {
+			headers[name] = string(data[2:size])
+		}
+		data = data[size:]
+	}
+	return headers, nil
+}
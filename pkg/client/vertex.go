@@ -0,0 +1,228 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	anthropicOption "github.com/anthropics/anthropic-sdk-go/option"
+	openaiOption "github.com/openai/openai-go/v3/option"
+
+	"tingly-box/pkg/adaptor"
+)
+
+const (
+	// VertexAnthropicVersion is the anthropic_version Vertex AI expects in rawPredict bodies
+	VertexAnthropicVersion = "vertex-2023-10-16"
+	// DefaultVertexRegion is used when a Vertex provider has no region configured
+	DefaultVertexRegion = "global"
+)
+
+// VertexEndpoint returns the Vertex AI endpoint for a region with a trailing slash.
+// A configured API base wins unless it is another Google API host (e.g. the Gemini API).
+func VertexEndpoint(apiBase, region string) string {
+	if apiBase != "" && !strings.Contains(apiBase, "googleapis.com") {
+		return strings.TrimSuffix(apiBase, "/") + "/"
+	}
+	if region == "" || region == "global" {
+		return "https://aiplatform.googleapis.com/"
+	}
+	return fmt.Sprintf("https://%s-aiplatform.googleapis.com/", region)
+}
+
+// VertexModelPath returns the path of a publisher model below the endpoint,
+// e.g. v1/projects/p/locations/us-east5/publishers/anthropic/models/claude-sonnet-4
+func VertexModelPath(projectID, region, publisher, model string) string {
+	if region == "" {
+		region = DefaultVertexRegion
+	}
+	return fmt.Sprintf("v1/projects/%s/locations/%s/publishers/%s/models/%s", projectID, region, publisher, model)
+}
+
+// WithVertexAnthropic configures an Anthropic SDK client for Claude on Vertex AI. Messages
+// requests go to rawPredict / streamRawPredict with the model in the URL and anthropic_version
+// in the body; the OAuth access token is sent as a bearer token.
+func WithVertexAnthropic(apiBase, region, projectID, accessToken string) []anthropicOption.RequestOption {
+	endpoint := VertexEndpoint(apiBase, region)
+	basePath := endpointPath(endpoint)
+
+	return []anthropicOption.RequestOption{
+		anthropicOption.WithBaseURL(endpoint),
+		anthropicOption.WithMiddleware(func(req *http.Request, next anthropicOption.MiddlewareNext) (*http.Response, error) {
+			route := "/" + strings.TrimPrefix(strings.TrimPrefix(req.URL.Path, basePath), "/")
+			if req.Method == http.MethodPost && (route == "/v1/messages" || route == "/v1/messages/count_tokens") {
+				if projectID == "" {
+					return nil, fmt.Errorf("vertex provider requires a project ID")
+				}
+				body, err := readRequestBody(req)
+				if err != nil {
+					return nil, err
+				}
+				var fields map[string]json.RawMessage
+				if err := json.Unmarshal(body, &fields); err != nil {
+					return nil, fmt.Errorf("invalid messages request body: %w", err)
+				}
+				if _, ok := fields["anthropic_version"]; !ok {
+					fields["anthropic_version"], _ = json.Marshal(VertexAnthropicVersion)
+				}
+
+				if route == "/v1/messages" {
+					var model string
+					var stream bool
+					_ = json.Unmarshal(fields["model"], &model)
+					_ = json.Unmarshal(fields["stream"], &stream)
+					delete(fields, "model")
+					specifier := "rawPredict"
+					if stream {
+						specifier = "streamRawPredict"
+					}
+					req.URL.Path = basePath + VertexModelPath(projectID, region, "anthropic", model) + ":" + specifier
+				} else {
+					req.URL.Path = basePath + VertexModelPath(projectID, region, "anthropic", "count-tokens") + ":rawPredict"
+				}
+				req.URL.RawPath = ""
+
+				if body, err = json.Marshal(fields); err != nil {
+					return nil, err
+				}
+				setRequestBody(req, body)
+			}
+
+			req.Header.Del("X-Api-Key")
+			req.Header.Set("Authorization", "Bearer "+accessToken)
+			return next(req)
+		}),
+	}
+}
+
+// WithVertexGemini configures an OpenAI SDK client for Gemini on Vertex AI. Chat completions
+// are converted to generateContent / streamGenerateContent and the responses back to the
+// OpenAI format; other routes use Vertex's OpenAI-compatible endpoint.
+func WithVertexGemini(apiBase, region, projectID, accessToken string) []openaiOption.RequestOption {
+	endpoint := VertexEndpoint(apiBase, region)
+	basePath := endpointPath(endpoint)
+	location := region
+	if location == "" {
+		location = DefaultVertexRegion
+	}
+
+	return []openaiOption.RequestOption{
+		openaiOption.WithAPIKey(accessToken),
+		openaiOption.WithBaseURL(endpoint),
+		openaiOption.WithMiddleware(func(req *http.Request, next openaiOption.MiddlewareNext) (*http.Response, error) {
+			if projectID == "" {
+				return nil, fmt.Errorf("vertex provider requires a project ID")
+			}
+			route := "/" + strings.TrimPrefix(strings.TrimPrefix(req.URL.Path, basePath), "/")
+			if req.Method != http.MethodPost || route != "/chat/completions" {
+				req.URL.Path = basePath + fmt.Sprintf("v1/projects/%s/locations/%s/endpoints/openapi", projectID, location) + route
+				req.URL.RawPath = ""
+				return next(req)
+			}
+
+			body, err := readRequestBody(req)
+			if err != nil {
+				return nil, err
+			}
+			chatReq, err := adaptor.ConvertOpenAIToGeminiRequest(body)
+			if err != nil {
+				return nil, err
+			}
+			if body, err = json.Marshal(chatReq.Body); err != nil {
+				return nil, err
+			}
+			setRequestBody(req, body)
+
+			model := strings.TrimPrefix(chatReq.Model, "google/")
+			path := basePath + VertexModelPath(projectID, region, "google", model)
+			query := url.Values{}
+			if chatReq.Stream {
+				path += ":streamGenerateContent"
+				query.Set("alt", "sse")
+			} else {
+				path += ":generateContent"
+			}
+			req.URL.Path = path
+			req.URL.RawPath = ""
+			req.URL.RawQuery = query.Encode()
+
+			resp, err := next(req)
+			if err != nil || resp.StatusCode != http.StatusOK {
+				return resp, err
+			}
+
+			if chatReq.Stream {
+				upstream := resp.Body
+				pr, pw := io.Pipe()
+				go func() {
+					pw.CloseWithError(adaptor.ConvertGeminiToOpenAIStream(upstream, pw, chatReq.Model, chatReq.IncludeUsage))
+				}()
+				resp.Body = &pipeBody{PipeReader: pr, upstream: upstream}
+				resp.Header.Set("Content-Type", "text/event-stream")
+				resp.Header.Del("Content-Length")
+				resp.ContentLength = -1
+				return resp, nil
+			}
+
+			geminiBody, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+			converted, err := adaptor.ConvertGeminiToOpenAIResponse(geminiBody, chatReq.Model)
+			if err != nil {
+				return nil, fmt.Errorf("invalid gemini response: %w", err)
+			}
+			openaiBody, err := json.Marshal(converted)
+			if err != nil {
+				return nil, err
+			}
+			resp.Body = io.NopCloser(bytes.NewReader(openaiBody))
+			resp.Header.Set("Content-Type", "application/json")
+			resp.Header.Del("Content-Length")
+			resp.ContentLength = int64(len(openaiBody))
+			return resp, nil
+		}),
+	}
+}
+
+// pipeBody is a converted response body that also closes the upstream body
+type pipeBody struct {
+	*io.PipeReader
+	upstream io.ReadCloser
+}
+
+func (b *pipeBody) Close() error {
+	b.PipeReader.Close()
+	return b.upstream.Close()
+}
+
+// endpointPath returns the path of an endpoint URL, "/" when it has none
+func endpointPath(endpoint string) string {
+	if u, err := url.Parse(endpoint); err == nil && u.Path != "" {
+		return u.Path
+	}
+	return "/"
+}
+
+// readRequestBody reads and closes a request body
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+	defer req.Body.Close()
+	return io.ReadAll(req.Body)
+}
+
+// setRequestBody replaces a request body, keeping it replayable for retries
+func setRequestBody(req *http.Request, body []byte) {
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	req.ContentLength = int64(len(body))
+}